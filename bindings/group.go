@@ -3,11 +3,30 @@ package bindings
 type CreateGroup struct {
 	Name        string `json:"name" form:"name"`
 	Description string `json:"description" form:"description"`
+	Unit        string `json:"unit" form:"unit"`
 	OnlyAdmin   bool   `json:"onlyAdmin" form:"onlyAdmin"`
 }
 
 type UpdateGroup struct {
 	Description string `json:"description" from:"description"`
+	Unit        string `json:"unit" form:"unit"`
+}
+
+type GroupSettings struct {
+	Unit       string `json:"unit" form:"unit"`
+	MinBalance int    `json:"minBalance" form:"minBalance"`
+	MaxBalance int    `json:"maxBalance" form:"maxBalance"`
+
+	OverdraftProtection bool `json:"overdraftProtection" form:"overdraftProtection"`
+
+	LowBalanceAlert     bool `json:"lowBalanceAlert" form:"lowBalanceAlert"`
+	LowBalanceThreshold int  `json:"lowBalanceThreshold" form:"lowBalanceThreshold"`
+
+	// "keep", "bank" or "redistribute", defaults to "keep"
+	LeaveBalancePolicy string `json:"leaveBalancePolicy" form:"leaveBalancePolicy"`
+
+	// Defaults to "Bank"
+	BankName string `json:"bankName" form:"bankName"`
 }
 
 type CreateTransaction struct {
@@ -18,6 +37,20 @@ type CreateTransaction struct {
 	FromBank    bool   `json:"fromBank" form:"fromBank"`
 }
 
+// The authenticated user is the sender.
+type Transfer struct {
+	ReceiverId  string `json:"receiverId" form:"receiverId"`
+	Amount      int    `json:"amount" form:"amount"`
+	Title       string `json:"title" form:"title"`
+	Description string `json:"description" form:"description"`
+}
+
+// The coins and notes are the ones handed over to or received from the bank.
+type CreateCashTransaction struct {
+	AddCashLogEntry
+	Deposit bool `json:"deposit"`
+}
+
 type CreatePaymentPlan struct {
 	Name         string `json:"name" form:"name"`
 	Description  string `json:"description" form:"description"`
@@ -26,23 +59,49 @@ type CreatePaymentPlan struct {
 	FromBank     bool   `json:"fromBank" form:"fromBank"`
 	Schedule     uint   `json:"schedule" form:"schedule"`
 	ScheduleUnit string `json:"scheduleUnit" form:"scheduleUnit"`
-	// UTC date of first payment with format "YYYY-MM-DD"
+	// Date of first payment with format "YYYY-MM-DD" in TimeZone
 	FirstPayment string `json:"firstPayment"`
 	// negative payment count for unlimited payments
 	PaymentCount int `json:"paymentCount"`
+	// IANA time zone, e.g. "Europe/Berlin", in whose midnight the payments are executed. UTC if empty.
+	TimeZone string `json:"timeZone" form:"timeZone"`
+}
+
+type CreateBulkPaymentPlans struct {
+	CreatePaymentPlan
+	// One payment plan is created for every sender
+	SenderIds []string `json:"senderIds" form:"senderIds"`
+}
+
+type DuplicatePaymentPlan struct {
+	// Replaces the receiver or, for payment plans managed by an admin, the member paying to or receiving from the bank.
+	// "bank" for the bank, empty to keep the original.
+	CounterpartyId string `json:"counterpartyId" form:"counterpartyId"`
+	// Date of first payment with format "YYYY-MM-DD" in the time zone of the original, empty to keep the next payment of the original
+	FirstPayment string `json:"firstPayment"`
 }
 
 type UpdatePaymentPlan struct {
 	Name        string `json:"name" form:"name"`
 	Description string `json:"description" form:"description"`
 	Amount      uint   `json:"amount" form:"amount"`
-	// UTC date of next payment with format "YYYY-MM-DD"
+	// Date of next payment with format "YYYY-MM-DD" in the time zone of the payment plan
 	NextPayment  string `json:"nextPayment"`
 	Schedule     uint   `json:"schedule" form:"schedule"`
 	ScheduleUnit string `json:"scheduleUnit" form:"scheduleUnit"`
+	// negative or zero payment count for unlimited payments
+	PaymentCount int `json:"paymentCount"`
+	// Names of the fields to update, e.g. ["description", "paymentCount"]. All fields are updated if empty.
+	Fields []string `json:"fields"`
 }
 
 type CreateInvitation struct {
 	Message string `json:"message" form:"message"`
 	UserId  string `json:"userId" form:"userId"`
+	// Balance the user starts with after accepting the invitation. Negative values are debts to the bank.
+	OpeningBalance int `json:"openingBalance" form:"openingBalance"`
+}
+
+type ReinviteMember struct {
+	Message string `json:"message" form:"message"`
 }
@@ -5,9 +5,17 @@ type DeleteUser struct {
 	TwoFAToken string `json:"twoFAToken" form:"twoFAToken"`
 }
 
+type DeleteCashLog struct {
+	// Has to be set because the cash log can't be restored
+	Confirm bool `json:"confirm" form:"confirm"`
+}
+
 type UpdateUser struct {
 	PubliclyVisible         bool `json:"publiclyVisible" form:"publiclyVisible"`
 	DontSendInvitationEmail bool `json:"dontSendInvitationEmail" form:"dontSendInvitationEmail"`
+	SendReceiptEmail        bool `json:"sendReceiptEmail" form:"sendReceiptEmail"`
+	// Kept if nil
+	DefaultSortDescending *bool `json:"defaultSortDescending" form:"defaultSortDescending"`
 }
 
 type AddCashLogEntry struct {
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/juho05/h-bank/config"
+	"github.com/juho05/h-bank/models"
+	"github.com/juho05/h-bank/services"
+)
+
+var StopInactiveAccountTicker = make(chan struct{})
+
+func StartInactiveAccountTicker(us models.UserStore, gs models.GroupStore) {
+	log.Println("[inactive-accounts] Starting ticker...")
+	ticker := time.NewTicker(24 * time.Hour)
+	go func() {
+		for {
+			processInactiveAccounts(us, gs)
+			select {
+			case <-ticker.C:
+				continue
+			case <-StopInactiveAccountTicker:
+				log.Println("[inactive-accounts] Stopping ticker...")
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Warns users who will reach the configured inactivity period within the warning period
+// and deletes users who are still inactive after the warning period passed.
+func processInactiveAccounts(us models.UserStore, gs models.GroupStore) {
+	now := time.Now().Unix()
+
+	// Users who didn't log in since logins are tracked get the full retention period starting now.
+	err := us.InitLastLogin(context.Background(), now)
+	if err != nil {
+		log.Println("[inactive-accounts] ERROR: Couldn't initialize last logins:", err)
+		return
+	}
+
+	users, err := us.GetInactiveUsers(context.Background(), now-config.Data.InactiveAccountRetention+config.Data.InactiveAccountWarning)
+	if err != nil {
+		log.Println("[inactive-accounts] ERROR: Couldn't retrieve inactive users:", err)
+		return
+	}
+
+	deleteBefore := now - config.Data.InactiveAccountRetention
+	for _, u := range users {
+		if u.InactivityWarningSent == 0 {
+			err = warnInactiveUser(us, &u, now)
+		} else if u.LastLogin < deleteBefore && u.InactivityWarningSent <= now-config.Data.InactiveAccountWarning {
+			err = deleteInactiveUser(us, gs, &u)
+		}
+		if err != nil {
+			log.Printf("[inactive-accounts] ERROR: Couldn't process inactive user with id '%s': %s", u.Id, err)
+		}
+	}
+}
+
+func warnInactiveUser(us models.UserStore, user *models.User, now int64) error {
+	type templateData struct {
+		Name         string
+		DeletionDate string
+		LoginURL     string
+	}
+	deletion := max(user.LastLogin+config.Data.InactiveAccountRetention, now+config.Data.InactiveAccountWarning)
+	body, err := services.ParseEmailTemplate("inactiveAccount", user.Language, templateData{
+		Name:         user.Name,
+		DeletionDate: time.Unix(deletion, 0).UTC().Format("2006-01-02"),
+		LoginURL:     config.Data.BaseURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = us.UpdateInactivityWarningSent(context.Background(), user, now)
+	if err != nil {
+		return err
+	}
+
+	go services.SendEmail([]string{user.Email}, config.Data.EmailProductName+" "+services.Tr("Inactive account", user.Language), body, true)
+	return nil
+}
+
+// Users who are the sole admin of a group with other users are kept to not leave the group without an admin.
+// Groups without other users are deleted together with the user.
+// The balances in the remaining groups are settled according to the policies of the groups before the user is deleted.
+func deleteInactiveUser(us models.UserStore, gs models.GroupStore, user *models.User) error {
+	groups, err := gs.GetAllByUser(context.Background(), user, "", -1, -1, false)
+	if err != nil {
+		return err
+	}
+
+	var emptyGroups []models.Group
+	for _, g := range groups {
+		isAdmin, err := gs.IsAdmin(context.Background(), &g, user)
+		if err != nil {
+			return err
+		}
+		if !isAdmin {
+			continue
+		}
+
+		userCount, err := gs.GetUserCount(context.Background(), &g)
+		if err != nil {
+			return err
+		}
+		if userCount == 1 {
+			emptyGroups = append(emptyGroups, g)
+			continue
+		}
+
+		adminCount, err := gs.AdminCount(context.Background(), &g)
+		if err != nil {
+			return err
+		}
+		if adminCount == 1 {
+			log.Printf("[inactive-accounts] Keeping inactive user with id '%s' because they are the sole admin of the group with id '%s'", user.Id, g.Id)
+			return nil
+		}
+	}
+
+	for _, g := range emptyGroups {
+		err = gs.Delete(context.Background(), &g)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, g := range groups {
+		if slices.ContainsFunc(emptyGroups, func(e models.Group) bool { return e.Id == g.Id }) {
+			continue
+		}
+		isMember, err := gs.IsMember(context.Background(), &g, user)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			continue
+		}
+		_, err = gs.SettleBalanceAndRemoveMember(context.Background(), &g, user, "Balance settled on leaving the group")
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[inactive-accounts] Deleting inactive user with id '%s'", user.Id)
+	return us.Delete(context.Background(), user)
+}
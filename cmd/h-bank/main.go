@@ -20,6 +20,7 @@ import (
 	"github.com/juho05/h-bank/config"
 	"github.com/juho05/h-bank/db"
 	"github.com/juho05/h-bank/handlers"
+	"github.com/juho05/h-bank/models"
 	"github.com/juho05/h-bank/router"
 	"github.com/juho05/h-bank/services"
 )
@@ -91,16 +92,48 @@ func run(r *echo.Echo) error {
 	log.Printf("Listening on port %d", config.Data.ServerPort)
 
 	StartPaymentPlanTicker(us, gs)
+	if config.Data.InactiveAccountRetention > 0 {
+		StartInactiveAccountTicker(us, gs)
+	}
+
+	if config.Data.EmailEnabled {
+		services.FailedEmailHandler = us.StoreFailedEmail
+		services.StartEmailWorkers()
+
+		db.LowBalanceHandler = func(group *models.Group, user *models.User, balance int) {
+			type templateData struct {
+				Name      string
+				GroupName string
+				Balance   string
+				Threshold string
+			}
+			body, err := services.ParseEmailTemplate("lowBalance", user.Language, templateData{
+				Name:      user.Name,
+				GroupName: group.Name,
+				Balance:   services.FormatAmount(balance, group.Unit),
+				Threshold: services.FormatAmount(group.LowBalanceThreshold, group.Unit),
+			})
+			if err != nil {
+				log.Println("[email] ERROR: Couldn't send low balance alert:", err)
+				return
+			}
+			go services.SendEmail([]string{user.Email}, config.Data.EmailProductName+" "+services.Tr("Low balance", user.Language), body, false)
+		}
+	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	close(StopPaymentPlanTicker)
+	if config.Data.InactiveAccountRetention > 0 {
+		close(StopInactiveAccountTicker)
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := r.Shutdown(ctx); err != nil {
 		return err
 	}
+	services.StopEmailWorkers(ctx)
 	return nil
 }
 
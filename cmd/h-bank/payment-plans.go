@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"time"
 
@@ -29,7 +31,7 @@ func StartPaymentPlanTicker(us models.UserStore, gs models.GroupStore) {
 }
 
 func executePaymentPlans(us models.UserStore, gs models.GroupStore) {
-	paymentPlans, err := gs.GetPaymentPlansThatNeedToBeExecuted()
+	paymentPlans, err := gs.GetPaymentPlansThatNeedToBeExecuted(context.Background())
 	if err != nil {
 		log.Println("[payment-plans] ERROR: Couldn't retrieve payment plans:", err)
 		return
@@ -49,53 +51,77 @@ func executePaymentPlans(us models.UserStore, gs models.GroupStore) {
 
 func executePaymentPlan(userStore models.UserStore, groupStore models.GroupStore, paymentPlan *models.PaymentPlan) error {
 	for paymentPlan.NextExecute <= time.Now().Unix() {
-		group, err := groupStore.GetById(paymentPlan.GroupId)
+		group, err := groupStore.GetById(context.Background(), paymentPlan.GroupId)
 		if err != nil {
 			return err
 		}
 		if group == nil {
-			return groupStore.Delete(group)
+			return groupStore.Delete(context.Background(), group)
 		}
 
-		sender, err := userStore.GetById(paymentPlan.SenderId)
+		sender, err := userStore.GetById(context.Background(), paymentPlan.SenderId)
 		if err != nil {
 			return err
 		}
 
-		receiver, err := userStore.GetById(paymentPlan.ReceiverId)
+		receiver, err := userStore.GetById(context.Background(), paymentPlan.ReceiverId)
 		if err != nil {
 			return err
 		}
 
-		if !paymentPlan.SenderIsBank {
-			balance, err := groupStore.GetUserBalance(group, sender)
+		if !paymentPlan.SenderIsBank && group.OverdraftProtection {
+			balance, err := groupStore.GetUserBalance(context.Background(), group, sender)
 			if err != nil {
 				return err
 			}
-			if balance-paymentPlan.Amount < 0 {
+			if balance-paymentPlan.Amount < group.MinBalance {
 				break
 			}
 		}
 
-		_, err = groupStore.CreateTransactionFromPaymentPlan(group, paymentPlan.SenderIsBank, paymentPlan.ReceiverIsBank, sender, receiver, paymentPlan.Name, paymentPlan.Description, paymentPlan.Amount, paymentPlan.Id)
+		if !paymentPlan.ReceiverIsBank && group.MaxBalance != 0 {
+			balance, err := groupStore.GetUserBalance(context.Background(), group, receiver)
+			if err != nil {
+				return err
+			}
+			if balance+paymentPlan.Amount > group.MaxBalance {
+				break
+			}
+		}
+
+		count, err := groupStore.PaymentPlanTransactionCount(context.Background(), paymentPlan)
 		if err != nil {
 			return err
 		}
-
-		paymentPlan.NextExecute = services.AddTime(paymentPlan.NextExecute, paymentPlan.Schedule, paymentPlan.ScheduleUnit)
-
+		remaining := -1
 		if paymentPlan.PaymentCount >= 0 {
-			paymentPlan.PaymentCount -= 1
-
-			if paymentPlan.PaymentCount <= 0 {
-				return groupStore.DeletePaymentPlan(paymentPlan)
+			remaining = paymentPlan.PaymentCount - 1
+		}
+		loc := services.PaymentPlanLocation(paymentPlan.TimeZone)
+		title := services.ExpandPaymentPlanTemplate(paymentPlan.Name, paymentPlan.NextExecute, loc, int(count)+1, remaining)
+		description := services.ExpandPaymentPlanTemplate(paymentPlan.Description, paymentPlan.NextExecute, loc, int(count)+1, remaining)
+
+		_, err = groupStore.ExecutePaymentPlan(context.Background(), group, paymentPlan, sender, receiver, title, description)
+		if errors.Is(err, models.ErrInsufficientFunds) {
+			// The balance changed since it was checked above.
+			break
+		}
+		if errors.Is(err, models.ErrPaymentPlanChanged) {
+			// The execution was skipped or the payment plan was deleted in the meantime.
+			changed, err := groupStore.GetPaymentPlanById(context.Background(), group, paymentPlan.Id)
+			if err != nil || changed == nil {
+				return err
 			}
+			*paymentPlan = *changed
+			continue
 		}
-
-		err = groupStore.UpdatePaymentPlan(paymentPlan)
 		if err != nil {
 			return err
 		}
+		if paymentPlan.PaymentCount == 0 {
+			// The last payment deleted the payment plan.
+			break
+		}
 	}
 
 	return nil
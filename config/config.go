@@ -3,8 +3,12 @@ package config
 import (
 	"encoding/json"
 	"log"
+	"net"
+	"net/mail"
 	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -16,55 +20,124 @@ const (
 )
 
 type ConfigData struct {
-	Debug                     bool     `json:"debug"`
-	DBEngine                  DBEngine `json:"dbEngine"`
-	DBPath                    string   `json:"dbPath"`
-	DBHost                    string   `json:"dbHost"`
-	DBPort                    int      `json:"dbPort"`
-	DBUser                    string   `json:"dbUser"`
-	DBPassword                string   `json:"dbPassword"`
-	DBName                    string   `json:"dbName"`
-	DBVerbose                 bool     `json:"dbVerbose"`
-	ServerPort                int      `json:"serverPort"`
-	SSL                       bool     `json:"ssl"`
-	SSLCertPath               string   `json:"sslCertPath"`
-	SSLKeyPath                string   `json:"sslKeyPath"`
-	BaseURL                   string   `json:"baseURL"`
-	DomainName                string   `json:"-"`
-	EmailEnabled              bool     `json:"emailEnabled"`
-	EmailHost                 string   `json:"emailHost"`
-	EmailPort                 int      `json:"emailPort"`
-	EmailUsername             string   `json:"emailUsername"`
-	EmailPassword             string   `json:"emailPassword"`
-	MinNameLength             int      `json:"minNameLength"`
-	MaxNameLength             int      `json:"maxNameLength"`
-	MinDescriptionLength      int      `json:"minDescriptionLength"`
-	MaxDescriptionLength      int      `json:"maxDescriptionLength"`
-	MaxProfilePictureFileSize int64    `json:"maxProfilePictureFileSize"`
-	MaxPageSize               int      `json:"maxPageSize"`
-	IDProvider                string   `json:"idProvider"`
-	InternalIDProvider        string `json:"internalIDProvider"`
-	ClientID                  string   `json:"clientID"`
-	ClientSecret              string   `json:"clientSecret"`
-	DevFrontend               string   `json:"devFrontend"`
-	FrontendDir               string   `json:"frontendDir"`
+	Debug                     bool         `json:"debug"`
+	DBEngine                  DBEngine     `json:"dbEngine"`
+	DBPath                    string       `json:"dbPath"`
+	DBHost                    string       `json:"dbHost"`
+	DBPort                    int          `json:"dbPort"`
+	DBUser                    string       `json:"dbUser"`
+	DBPassword                string       `json:"dbPassword"`
+	DBName                    string       `json:"dbName"`
+	DBVerbose                 bool         `json:"dbVerbose"`
+	ServerPort                int          `json:"serverPort"`
+	SSL                       bool         `json:"ssl"`
+	SSLCertPath               string       `json:"sslCertPath"`
+	SSLKeyPath                string       `json:"sslKeyPath"`
+	BaseURL                   string       `json:"baseURL"`
+	DomainName                string       `json:"-"`
+	EmailEnabled              bool         `json:"emailEnabled"`
+	EmailHost                 string       `json:"emailHost"`
+	EmailPort                 int          `json:"emailPort"`
+	EmailUsername             string       `json:"emailUsername"`
+	EmailPassword             string       `json:"emailPassword"`
+	EmailFrom                 string       `json:"emailFrom"`
+	EmailReplyTo              string       `json:"emailReplyTo"`
+	EmailAuthFrom             string       `json:"emailAuthFrom"`
+	EmailAuthReplyTo          string       `json:"emailAuthReplyTo"`
+	EmailWorkers              int          `json:"emailWorkers"`
+	EmailQueueSize            int          `json:"emailQueueSize"`
+	EmailMaxAttempts          int          `json:"emailMaxAttempts"`
+	EmailTemplateDir          string       `json:"emailTemplateDir"`
+	EmailProductName          string       `json:"emailProductName"`
+	EmailLogoURL              string       `json:"emailLogoURL"`
+	EmailPrimaryColor         string       `json:"emailPrimaryColor"`
+	EmailBorderColor          string       `json:"emailBorderColor"`
+	MinNameLength             int          `json:"minNameLength"`
+	MaxNameLength             int          `json:"maxNameLength"`
+	MinDescriptionLength      int          `json:"minDescriptionLength"`
+	MaxDescriptionLength      int          `json:"maxDescriptionLength"`
+	MaxUnitLength             int          `json:"maxUnitLength"`
+	MaxProfilePictureFileSize int64        `json:"maxProfilePictureFileSize"`
+	MaxImportFileSize         int64        `json:"maxImportFileSize"`
+	MaxPaymentPlansPerGroup   int          `json:"maxPaymentPlansPerGroup"`
+	MaxUsersPerGroup          int          `json:"maxUsersPerGroup"`
+	CashUnit                  string       `json:"cashUnit"`
+	CashDenominations         []int        `json:"cashDenominations"`
+	GroupDeletionWindow       int64        `json:"groupDeletionWindow"`
+	InvitationResendCooldown  int64        `json:"invitationResendCooldown"`
+	InactiveAccountRetention  int64        `json:"inactiveAccountRetention"`
+	InactiveAccountWarning    int64        `json:"inactiveAccountWarning"`
+	RefreshTokenLifetime      int64        `json:"refreshTokenLifetime"`
+	RememberMeTokenLifetime   int64        `json:"rememberMeTokenLifetime"`
+	LoginRateLimit            int          `json:"loginRateLimit"`
+	TransactionRateLimit      int          `json:"transactionRateLimit"`
+	EmailRateLimit            int          `json:"emailRateLimit"`
+	RequestTimeout            int64        `json:"requestTimeout"`
+	AllowedPictureFormats     []string     `json:"allowedPictureFormats"`
+	PictureQuality            int          `json:"pictureQuality"`
+	PictureProcessingLimit    int          `json:"pictureProcessingLimit"`
+	MaxPageSize               int          `json:"maxPageSize"`
+	IDProvider                string       `json:"idProvider"`
+	InternalIDProvider        string       `json:"internalIDProvider"`
+	ClientID                  string       `json:"clientID"`
+	ClientSecret              string       `json:"clientSecret"`
+	TokenIssuer               string       `json:"tokenIssuer"`
+	TokenAudience             string       `json:"tokenAudience"`
+	DevFrontend               string       `json:"devFrontend"`
+	FrontendDir               string       `json:"frontendDir"`
+	SiteAdmins                []string     `json:"siteAdmins"`
+	TrustedProxies            []string     `json:"trustedProxies"`
+	TrustedProxyNets          []*net.IPNet `json:"-"`
 }
 
 var defaultData = ConfigData{
 	ServerPort:                80,
 	BaseURL:                   "",
 	DBPath:                    "database.sqlite",
+	EmailWorkers:              2,
+	EmailQueueSize:            100,
+	EmailMaxAttempts:          5,
+	EmailProductName:          "H-Bank",
+	EmailPrimaryColor:         "#0E1EAE",
+	EmailBorderColor:          "#00063C",
 	MinNameLength:             3,
 	MaxNameLength:             30,
 	MinDescriptionLength:      0,
 	MaxDescriptionLength:      256,
+	MaxUnitLength:             10,
 	MaxProfilePictureFileSize: 10000000, // 10 MB
+	MaxImportFileSize:         1000000,  // 1 MB
+	GroupDeletionWindow:       86400,    // 24 hours
+	InvitationResendCooldown:  86400,    // 24 hours
+	InactiveAccountRetention:  0,
+	InactiveAccountWarning:    2592000, // 30 days
+	RefreshTokenLifetime:      86400,   // 24 hours
+	RememberMeTokenLifetime:   7257600, // 12 weeks
+	LoginRateLimit:            10,
+	TransactionRateLimit:      30,
+	EmailRateLimit:            10,
+	RequestTimeout:            30,
+	AllowedPictureFormats:     []string{"jpeg", "png", "gif"},
+	PictureQuality:            95,
+	PictureProcessingLimit:    4,
+	MaxPaymentPlansPerGroup:   0,
+	MaxUsersPerGroup:          0,
+	CashUnit:                  "€",
+	CashDenominations:         []int{50000, 20000, 10000, 5000, 2000, 1000, 500, 200, 100, 50, 20, 10, 5, 2, 1},
 	MaxPageSize:               100,
 	IDProvider:                "",
 }
 
 var Data = defaultData
 
+// Number of coin and note columns of the cash log.
+const MaxCashDenominations = 15
+
+// Picture formats which can be decoded.
+var supportedPictureFormats = []string{"jpeg", "png", "gif"}
+
+var colorRegex = regexp.MustCompile("^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$")
+
 // @param filepaths A slice of config filepaths (json files)
 // Will load only from the first valid config file in the list.
 func Load(filepaths []string) {
@@ -157,10 +230,75 @@ func verifyData() {
 		if Data.EmailPassword == "" {
 			log.Println("WARNING: No email password provided")
 		}
+
+		// Only an explicitly set sender is verified. SMTP usernames like "apikey" aren't addresses but were accepted as the sender before.
+		verifyEmailAddress("emailFrom", Data.EmailFrom)
+		if Data.EmailFrom == "" {
+			Data.EmailFrom = Data.EmailUsername
+		}
+		verifyEmailAddress("emailReplyTo", Data.EmailReplyTo)
+		verifyEmailAddress("emailAuthFrom", Data.EmailAuthFrom)
+		verifyEmailAddress("emailAuthReplyTo", Data.EmailAuthReplyTo)
+
+		if Data.EmailWorkers < 1 {
+			log.Println("WARNING: Invalid email worker count. Using default value:", defaultData.EmailWorkers)
+			Data.EmailWorkers = defaultData.EmailWorkers
+		}
+
+		if Data.EmailQueueSize < 1 {
+			log.Println("WARNING: Invalid email queue size. Using default value:", defaultData.EmailQueueSize)
+			Data.EmailQueueSize = defaultData.EmailQueueSize
+		}
+
+		if Data.EmailMaxAttempts < 1 {
+			log.Println("WARNING: Invalid email max attempts. Using default value:", defaultData.EmailMaxAttempts)
+			Data.EmailMaxAttempts = defaultData.EmailMaxAttempts
+		}
+
+		if Data.EmailTemplateDir != "" {
+			if info, err := os.Stat(Data.EmailTemplateDir); err != nil || !info.IsDir() {
+				log.Printf("WARNING: Cannot find email template directory `%s`. Using built-in templates.\n", Data.EmailTemplateDir)
+				Data.EmailTemplateDir = ""
+			}
+		}
+
+		if !colorRegex.MatchString(Data.EmailPrimaryColor) {
+			log.Println("WARNING: Invalid email primary color. Using default color:", defaultData.EmailPrimaryColor)
+			Data.EmailPrimaryColor = defaultData.EmailPrimaryColor
+		}
+
+		if !colorRegex.MatchString(Data.EmailBorderColor) {
+			log.Println("WARNING: Invalid email border color. Using default color:", defaultData.EmailBorderColor)
+			Data.EmailBorderColor = defaultData.EmailBorderColor
+		}
 	} else {
 		log.Println("WARNING: Email disabled")
 	}
 
+	if Data.RefreshTokenLifetime <= 0 {
+		log.Println("WARNING: Invalid refresh token lifetime. Using default value:", defaultData.RefreshTokenLifetime)
+		Data.RefreshTokenLifetime = defaultData.RefreshTokenLifetime
+	}
+
+	if Data.RememberMeTokenLifetime <= 0 {
+		log.Println("WARNING: Invalid remember me token lifetime. Using default value:", defaultData.RememberMeTokenLifetime)
+		Data.RememberMeTokenLifetime = defaultData.RememberMeTokenLifetime
+	}
+
+	if Data.RequestTimeout < 0 {
+		log.Println("WARNING: Invalid request timeout. Using default value:", defaultData.RequestTimeout)
+		Data.RequestTimeout = defaultData.RequestTimeout
+	}
+
+	if Data.InactiveAccountRetention > 0 {
+		if !Data.EmailEnabled {
+			log.Println("WARNING: Inactive accounts can't be deleted without sending a warning email. Deletion of inactive accounts is disabled.")
+			Data.InactiveAccountRetention = 0
+		} else if Data.InactiveAccountWarning <= 0 || Data.InactiveAccountWarning >= Data.InactiveAccountRetention {
+			log.Fatalln("ERROR: inactiveAccountWarning must be positive and shorter than inactiveAccountRetention")
+		}
+	}
+
 	if Data.ClientID == "" {
 		log.Fatalln("ERROR: Empty OAuth client ID")
 	}
@@ -187,8 +325,71 @@ func verifyData() {
 		Data.InternalIDProvider = Data.IDProvider
 	}
 
+	allowedPictureFormats := make([]string, 0, len(Data.AllowedPictureFormats))
+	for _, format := range Data.AllowedPictureFormats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "jpg" {
+			format = "jpeg"
+		}
+		if !slices.Contains(supportedPictureFormats, format) {
+			log.Printf("WARNING: Unsupported picture format `%s`. Supported formats: %s\n", format, strings.Join(supportedPictureFormats, ", "))
+			continue
+		}
+		allowedPictureFormats = append(allowedPictureFormats, format)
+	}
+	Data.AllowedPictureFormats = allowedPictureFormats
+	if len(Data.AllowedPictureFormats) == 0 {
+		log.Println("WARNING: No picture formats allowed. Picture uploads are disabled.")
+	}
+
+	if Data.PictureQuality < 1 || Data.PictureQuality > 100 {
+		log.Fatalln("ERROR: pictureQuality must be between 1 and 100")
+	}
+
+	if Data.PictureProcessingLimit < 0 {
+		log.Println("WARNING: Invalid picture processing limit. Using default value:", defaultData.PictureProcessingLimit)
+		Data.PictureProcessingLimit = defaultData.PictureProcessingLimit
+	}
+
+	if strings.TrimSpace(Data.CashUnit) == "" {
+		Data.CashUnit = defaultData.CashUnit
+	}
+	if len(Data.CashDenominations) == 0 || len(Data.CashDenominations) > MaxCashDenominations {
+		log.Fatalf("ERROR: cashDenominations must contain between 1 and %d values\n", MaxCashDenominations)
+	}
+	for i, value := range Data.CashDenominations {
+		if value <= 0 || (i > 0 && value >= Data.CashDenominations[i-1]) {
+			log.Fatalln("ERROR: cashDenominations must be positive and sorted from the highest to the lowest value")
+		}
+	}
+
+	Data.TrustedProxyNets = make([]*net.IPNet, 0, len(Data.TrustedProxies))
+	for _, proxy := range Data.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			log.Fatalf("ERROR: Invalid trusted proxy `%s`: %s\n", proxy, err)
+		}
+		Data.TrustedProxyNets = append(Data.TrustedProxyNets, ipNet)
+	}
+
 	if _, err := url.Parse(Data.DevFrontend); err != nil {
 		log.Println("WARNING: Invalid dev frontend URL:", err)
 		Data.DevFrontend = ""
 	}
 }
+
+func verifyEmailAddress(option, address string) {
+	if address == "" {
+		return
+	}
+	if _, err := mail.ParseAddress(address); err != nil {
+		log.Fatalf("ERROR: Invalid email address in %s: %s\n", option, err)
+	}
+}
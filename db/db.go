@@ -1,9 +1,12 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/glebarez/sqlite"
 	"github.com/google/uuid"
@@ -55,10 +58,31 @@ func DeleteTestDB(id string) {
 	}
 }
 
+// Indexes of frequently filtered columns. Most of them contain the creation time from the embedded models.Base,
+// so they can't be declared with struct tags.
+var indexes = []string{
+	// The cash log of a user is listed by creation time, their current cash is the entry with the highest sequence number.
+	"CREATE INDEX IF NOT EXISTS idx_cash_log_entries_user_created ON cash_log_entries (user_id, created)",
+	"CREATE INDEX IF NOT EXISTS idx_cash_log_entries_user_sequence ON cash_log_entries (user_id, sequence, created)",
+	"CREATE INDEX IF NOT EXISTS idx_transaction_log_entries_group_created ON transaction_log_entries (group_id, created)",
+	// Transaction logs and balances of users are filtered by sender or receiver and ordered by creation time.
+	"CREATE INDEX IF NOT EXISTS idx_transaction_log_entries_group_sender ON transaction_log_entries (group_id, sender_id, created)",
+	"CREATE INDEX IF NOT EXISTS idx_transaction_log_entries_group_receiver ON transaction_log_entries (group_id, receiver_id, created)",
+	// The current and historical balance of a user is read from their transaction with the highest sequence number.
+	"CREATE INDEX IF NOT EXISTS idx_transaction_log_entries_group_sender_sequence ON transaction_log_entries (group_id, sender_id, sequence)",
+	"CREATE INDEX IF NOT EXISTS idx_transaction_log_entries_group_receiver_sequence ON transaction_log_entries (group_id, receiver_id, sequence)",
+	"CREATE INDEX IF NOT EXISTS idx_payment_plans_group_sender ON payment_plans (group_id, sender_id)",
+	"CREATE INDEX IF NOT EXISTS idx_payment_plans_group_receiver ON payment_plans (group_id, receiver_id)",
+	"CREATE INDEX IF NOT EXISTS idx_payment_plans_next_execute ON payment_plans (next_execute)",
+	"CREATE INDEX IF NOT EXISTS idx_group_memberships_group_user ON group_memberships (group_id, user_id)",
+	"CREATE INDEX IF NOT EXISTS idx_group_memberships_user ON group_memberships (user_id)",
+}
+
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&models.User{},
 		&models.CashLogEntry{},
+		&models.FailedEmail{},
 
 		&models.Group{},
 		&models.GroupMembership{},
@@ -66,5 +90,107 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.GroupInvitation{},
 		&models.TransactionLogEntry{},
 		&models.PaymentPlan{},
+		&models.PaymentPlanExecution{},
+		&models.AdminChange{},
+		&models.GroupAuditLogEntry{},
 	)
+	if err != nil {
+		return err
+	}
+
+	// Transactions created before references were introduced need one before the unique index can be created.
+	err = assignMissingTransactionReferences(db)
+	if err != nil {
+		return err
+	}
+	err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_transaction_log_entries_reference ON transaction_log_entries (group_id, reference)").Error
+	if err != nil {
+		return err
+	}
+	err = assignMissingTransactionSequences(db)
+	if err != nil {
+		return err
+	}
+	err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_transaction_log_entries_sequence ON transaction_log_entries (group_id, sequence)").Error
+	if err != nil {
+		return err
+	}
+
+	err = assignMissingTotalPaymentCounts(db)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		err = db.Exec(index).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Payment plans created before the total payment count was stored get their remaining payments plus the executed ones.
+func assignMissingTotalPaymentCounts(db *gorm.DB) error {
+	return db.Exec(`UPDATE payment_plans SET total_payment_count = CASE WHEN payment_count < 0 THEN -1
+		ELSE payment_count + (SELECT COUNT(*) FROM transaction_log_entries t WHERE t.payment_plan_id = payment_plans.id) END
+		WHERE total_payment_count = 0`).Error
+}
+
+// Gives all transactions without a reference one in the order they were created.
+func assignMissingTransactionReferences(db *gorm.DB) error {
+	var groupIds []string
+	err := db.Model(&models.TransactionLogEntry{}).Distinct("group_id").Where("reference = ?", "").Pluck("group_id", &groupIds).Error
+	if err != nil {
+		return err
+	}
+
+	for _, groupId := range groupIds {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var transactions []models.TransactionLogEntry
+			err := tx.Select("id").Where("group_id = ? AND reference = ?", groupId, "").Order("created ASC, id ASC").Find(&transactions).Error
+			if err != nil {
+				return err
+			}
+			gs := &GroupStore{db: tx}
+			group := &models.Group{Base: models.Base{Id: groupId}}
+			for _, t := range transactions {
+				err := gs.assignReference(context.Background(), group, &t)
+				if err != nil {
+					return err
+				}
+				err = tx.Model(&models.TransactionLogEntry{}).Where("id = ?", t.Id).Updates(map[string]any{"reference": t.Reference, "sequence": t.Sequence}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Gives all transactions created before sequence numbers were introduced the number of their reference.
+func assignMissingTransactionSequences(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var transactions []models.TransactionLogEntry
+		err := tx.Select("id", "reference").Where("sequence = ?", 0).Find(&transactions).Error
+		if err != nil {
+			return err
+		}
+		for _, t := range transactions {
+			sequence, err := strconv.Atoi(t.Reference[strings.LastIndex(t.Reference, "-")+1:])
+			if err != nil {
+				return fmt.Errorf("invalid transaction reference %q: %w", t.Reference, err)
+			}
+			err = tx.Model(&models.TransactionLogEntry{}).Where("id = ?", t.Id).Update("sequence", sequence).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/juho05/h-bank/models"
+)
+
+// Returns the details of the SQLite query plan of the query built by query.
+func queryPlan(t testing.TB, database *gorm.DB, query func(tx *gorm.DB) *gorm.DB) []string {
+	var plan []struct {
+		Detail string
+	}
+	err := database.Raw("EXPLAIN QUERY PLAN " + database.ToSQL(query)).Scan(&plan).Error
+	if err != nil {
+		t.Fatalf("Couldn't get query plan: %s", err)
+	}
+	details := make([]string, len(plan))
+	for i, p := range plan {
+		details[i] = p.Detail
+	}
+	return details
+}
+
+func TestAutoMigrate_Indexes(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	group := &models.Group{Base: models.Base{Id: "group"}}
+	user := &models.User{Base: models.Base{Id: "user"}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		query func(tx *gorm.DB) *gorm.DB
+		// The query has to use one of these indexes.
+		wantIndex []string
+	}{
+		{
+			name: "Transaction log",
+			query: func(tx *gorm.DB) *gorm.DB {
+				gs := &GroupStore{db: tx}
+				return gs.filterTransactionLog(ctx, group, user, models.TransactionFilter{}).Order("created DESC").Limit(20).Find(&[]models.TransactionLogEntry{})
+			},
+			wantIndex: []string{"idx_transaction_log_entries_group_created", "idx_transaction_log_entries_group_sender", "idx_transaction_log_entries_group_receiver"},
+		},
+		{
+			name: "Transaction log with counterparty",
+			query: func(tx *gorm.DB) *gorm.DB {
+				gs := &GroupStore{db: tx}
+				return gs.filterTransactionLog(ctx, group, user, models.TransactionFilter{CounterpartyId: "other"}).Order("created DESC").Limit(20).Find(&[]models.TransactionLogEntry{})
+			},
+			wantIndex: []string{"idx_transaction_log_entries_group_created", "idx_transaction_log_entries_group_sender", "idx_transaction_log_entries_group_receiver"},
+		},
+		{
+			name: "Transactions in period",
+			query: func(tx *gorm.DB) *gorm.DB {
+				return tx.Where("group_id = ? AND created >= ? AND created <= ?", group.Id, 0, 100).Order("created ASC").Find(&[]models.TransactionLogEntry{})
+			},
+			wantIndex: []string{"idx_transaction_log_entries_group_created"},
+		},
+		{
+			name: "Payment plans",
+			query: func(tx *gorm.DB) *gorm.DB {
+				gs := &GroupStore{db: tx}
+				return gs.filterPaymentPlans(ctx, group, user, models.PaymentPlanFilter{}).Order("next_execute ASC").Find(&[]models.PaymentPlan{})
+			},
+			wantIndex: []string{"idx_payment_plans_group_sender", "idx_payment_plans_group_receiver"},
+		},
+		{
+			name: "Sending payment plans",
+			query: func(tx *gorm.DB) *gorm.DB {
+				gs := &GroupStore{db: tx}
+				return gs.filterPaymentPlans(ctx, group, user, models.PaymentPlanFilter{Direction: models.PaymentPlanDirectionSending}).Find(&[]models.PaymentPlan{})
+			},
+			wantIndex: []string{"idx_payment_plans_group_sender"},
+		},
+		{
+			name: "Due payment plans",
+			query: func(tx *gorm.DB) *gorm.DB {
+				return tx.Find(&[]models.PaymentPlan{}, "next_execute <= ?", 100)
+			},
+			wantIndex: []string{"idx_payment_plans_next_execute"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := strings.Join(queryPlan(t, database, tt.query), "\n")
+			assert.True(t, slices.ContainsFunc(tt.wantIndex, func(index string) bool {
+				return strings.Contains(plan, "USING INDEX "+index)
+			}), "query plan: %s", plan)
+			assert.NotContains(t, plan, "SCAN transaction_log_entries")
+			assert.NotContains(t, plan, "SCAN payment_plans")
+		})
+	}
+}
+
+func BenchmarkGroupStore_GetTransactionLog(b *testing.B) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		b.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		b.Fatalf("Couldn't auto migrate database")
+	}
+
+	const groupCount = 20
+	const userCount = 50
+	const transactionsPerGroup = 2000
+
+	transactions := make([]models.TransactionLogEntry, 0, groupCount*transactionsPerGroup)
+	for g := 0; g < groupCount; g++ {
+		for i := 0; i < transactionsPerGroup; i++ {
+			transactions = append(transactions, models.TransactionLogEntry{
+				Base:       models.Base{Created: int64(i + 1)},
+				GroupId:    fmt.Sprintf("group%d", g),
+				Reference:  models.TransactionReference(fmt.Sprintf("group%d", g), i+1),
+				Sequence:   i + 1,
+				Title:      "Transaction",
+				SenderId:   fmt.Sprintf("user%d", i%userCount),
+				ReceiverId: fmt.Sprintf("user%d", (i+1)%userCount),
+				Amount:     1,
+			})
+		}
+	}
+	err = database.CreateInBatches(transactions, 500).Error
+	if err != nil {
+		b.Fatalf("Couldn't create transactions: %s", err)
+	}
+
+	gs := NewGroupStore(database)
+	group := &models.Group{Base: models.Base{Id: "group0"}}
+	user := &models.User{Base: models.Base{Id: "user0"}}
+
+	run := func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := gs.GetTransactionLog(context.Background(), group, user, "", models.TransactionFilter{}, 0, 20, false)
+			if err != nil {
+				b.Fatal(err)
+			}
+			_, err = gs.GetUserBalance(context.Background(), group, user)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("Indexed", run)
+
+	for _, index := range []string{"idx_transaction_log_entries_group_created", "idx_transaction_log_entries_group_sender", "idx_transaction_log_entries_group_receiver"} {
+		err = database.Exec("DROP INDEX " + index).Error
+		if err != nil {
+			b.Fatalf("Couldn't drop index: %s", err)
+		}
+	}
+	b.Run("Unindexed", run)
+}
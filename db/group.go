@@ -1,15 +1,43 @@
 package db
 
 import (
+	"context"
 	"errors"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/juho05/h-bank/config"
 	"github.com/juho05/h-bank/models"
 	"github.com/juho05/h-bank/services"
 )
 
+// Called when a transaction drops the balance of a user below the low balance threshold of the group.
+var LowBalanceHandler func(group *models.Group, user *models.User, balance int)
+
+// Serializes reading the balances and inserting new transactions of a group within this process.
+// Row locks on the memberships additionally serialize multiple processes on databases which support them.
+var transactionLocks keyedMutex
+
+// Locks the transactions of the group, see transactionLocks.
+func lockTransactions(group *models.Group) (unlock func()) {
+	return transactionLocks.Lock("group:" + group.Id)
+}
+
+// Serializes adding members to the group within this process, so the maximum number of users can't be exceeded.
+func lockMemberships(group *models.Group) (unlock func()) {
+	return transactionLocks.Lock("members:" + group.Id)
+}
+
+// Locks the cash log of the user, which is shared by cash transactions of all groups.
+func lockCashLog(user *models.User) (unlock func()) {
+	return transactionLocks.Lock("cash:" + user.Id)
+}
+
 type GroupStore struct {
 	db *gorm.DB
 }
@@ -20,7 +48,8 @@ func NewGroupStore(db *gorm.DB) *GroupStore {
 	}
 }
 
-func (gs *GroupStore) GetAllByUser(user *models.User, page int, pageSize int, descending bool) ([]models.Group, error) {
+// Returns the groups of the user with the given role, see models.GroupRoleAdmin and models.GroupRoleMember. An empty role matches all groups.
+func (gs *GroupStore) GetAllByUser(ctx context.Context, user *models.User, role string, page int, pageSize int, descending bool) ([]models.Group, error) {
 	var memberships []models.GroupMembership
 	var err error
 
@@ -29,10 +58,11 @@ func (gs *GroupStore) GetAllByUser(user *models.User, page int, pageSize int, de
 		order = "DESC"
 	}
 
+	query, args := roleCondition(role)
 	if page < 0 || pageSize < 0 {
-		err = gs.db.Model(user).Order("group_name " + order).Association("GroupMemberships").Find(&memberships)
+		err = gs.db.WithContext(ctx).Model(user).Order("group_name "+order).Association("GroupMemberships").Find(&memberships, append([]interface{}{query}, args...)...)
 	} else {
-		err = gs.db.Model(user).Order("group_name " + order).Offset(page * pageSize).Limit(pageSize).Association("GroupMemberships").Find(&memberships)
+		err = gs.db.WithContext(ctx).Model(user).Order("group_name "+order).Offset(page*pageSize).Limit(pageSize).Association("GroupMemberships").Find(&memberships, append([]interface{}{query}, args...)...)
 	}
 
 	if err != nil {
@@ -45,20 +75,33 @@ func (gs *GroupStore) GetAllByUser(user *models.User, page int, pageSize int, de
 	}
 
 	var groups []models.Group
-	err = gs.db.Order("name "+order).Find(&groups, "id IN ?", groupIds).Error
+	err = gs.db.WithContext(ctx).Order("name "+order).Find(&groups, "id IN ?", groupIds).Error
 
 	return groups, err
 }
 
-func (gs *GroupStore) Count(user *models.User) (int64, error) {
+func (gs *GroupStore) Count(ctx context.Context, user *models.User, role string) (int64, error) {
 	var count int64
-	err := gs.db.Model(&models.GroupMembership{}).Where("user_id = ?", user.Id).Count(&count).Error
+	query, args := roleCondition(role)
+	err := gs.db.WithContext(ctx).Model(&models.GroupMembership{}).Where("user_id = ?", user.Id).Where(query, args...).Count(&count).Error
 	return count, err
 }
 
-func (gs *GroupStore) GetById(id string) (*models.Group, error) {
+// Returns the condition on group memberships matching the role.
+func roleCondition(role string) (string, []interface{}) {
+	switch role {
+	case models.GroupRoleAdmin:
+		return "is_admin = ?", []interface{}{true}
+	case models.GroupRoleMember:
+		return "is_member = ? AND is_admin = ?", []interface{}{true, false}
+	default:
+		return "is_member = ? OR is_admin = ?", []interface{}{true, true}
+	}
+}
+
+func (gs *GroupStore) GetById(ctx context.Context, id string) (*models.Group, error) {
 	var group models.Group
-	err := gs.db.First(&group, "id = ?", id).Error
+	err := gs.db.WithContext(ctx).First(&group, "id = ?", id).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -70,61 +113,81 @@ func (gs *GroupStore) GetById(id string) (*models.Group, error) {
 	return &group, nil
 }
 
-func (gs *GroupStore) Create(group *models.Group) error {
-	return gs.db.Create(group).Error
+// Groups the user is not a member or admin of are skipped.
+func (gs *GroupStore) GetByIds(ctx context.Context, ids []string, user *models.User) ([]models.Group, error) {
+	var groups []models.Group
+	memberships := gs.db.WithContext(ctx).Model(&models.GroupMembership{}).Select("group_id").Where("user_id = ? AND (is_member = ? OR is_admin = ?)", user.Id, true, true)
+	err := gs.db.WithContext(ctx).Order("name ASC").Where("id IN ?", ids).Where("id IN (?)", memberships).Find(&groups).Error
+	return groups, err
 }
 
-func (gs *GroupStore) Update(group *models.Group) error {
+func (gs *GroupStore) Create(ctx context.Context, group *models.Group) error {
+	return gs.db.WithContext(ctx).Create(group).Error
+}
+
+func (gs *GroupStore) Update(ctx context.Context, group *models.Group) error {
 	if group.Description == "" {
-		err := gs.db.Select("description").Updates(group).Error
+		err := gs.db.WithContext(ctx).Select("description").Updates(group).Error
 		if err != nil {
 			return err
 		}
 	}
-	return gs.db.Updates(group).Error
+	// The transaction count is only changed by assignReference, a stale value must not overwrite it.
+	return gs.db.WithContext(ctx).Omit("transaction_count").Updates(group).Error
+}
+
+func (gs *GroupStore) UpdateSettings(ctx context.Context, group *models.Group) error {
+	return gs.db.WithContext(ctx).Model(group).Select("unit", "min_balance", "max_balance", "overdraft_protection", "low_balance_alert", "low_balance_threshold", "leave_balance_policy", "bank_name").Updates(group).Error
 }
 
-func (gs *GroupStore) UpdateGroupPicture(group *models.Group, pic *models.GroupPicture) error {
-	err := gs.db.Select("group_picture_id").Updates(group).Error
+func (gs *GroupStore) UpdateDeletionRequest(ctx context.Context, group *models.Group) error {
+	return gs.db.WithContext(ctx).Model(group).Select("deletion_requested_by", "deletion_expires").Updates(group).Error
+}
+
+func (gs *GroupStore) UpdateGroupPicture(ctx context.Context, group *models.Group, pic *models.GroupPicture) error {
+	err := gs.db.WithContext(ctx).Select("group_picture_id").Updates(group).Error
 	if err != nil {
 		return err
 	}
 
 	var oldPic models.GroupPicture
-	err = gs.db.Model(group).Select("id").Association("GroupPicture").Find(&oldPic)
+	err = gs.db.WithContext(ctx).Model(group).Select("id").Association("GroupPicture").Find(&oldPic)
 	if err != nil {
 		return err
 	}
 
-	gs.db.Delete(&oldPic)
+	gs.db.WithContext(ctx).Delete(&oldPic)
 
-	return gs.db.Model(group).Association("GroupPicture").Append(pic)
+	return gs.db.WithContext(ctx).Model(group).Association("GroupPicture").Append(pic)
 }
 
-func (gs *GroupStore) Delete(group *models.Group) error {
-	gs.db.Delete(&models.GroupInvitation{}, "group_id = ?", group.Id)
-	gs.db.Delete(&models.GroupMembership{}, "group_id = ?", group.Id)
-	gs.db.Delete(&models.TransactionLogEntry{}, "group_id = ?", group.Id)
-	gs.db.Delete(&models.PaymentPlan{}, "group_id = ?", group.Id)
-	return gs.db.Delete(group).Error
+func (gs *GroupStore) Delete(ctx context.Context, group *models.Group) error {
+	gs.db.WithContext(ctx).Unscoped().Delete(&models.GroupInvitation{}, "group_id = ?", group.Id)
+	gs.db.WithContext(ctx).Delete(&models.GroupMembership{}, "group_id = ?", group.Id)
+	gs.db.WithContext(ctx).Delete(&models.TransactionLogEntry{}, "group_id = ?", group.Id)
+	gs.db.WithContext(ctx).Delete(&models.PaymentPlan{}, "group_id = ?", group.Id)
+	gs.db.WithContext(ctx).Delete(&models.PaymentPlanExecution{}, "group_id = ?", group.Id)
+	gs.db.WithContext(ctx).Delete(&models.AdminChange{}, "group_id = ?", group.Id)
+	gs.db.WithContext(ctx).Delete(&models.GroupAuditLogEntry{}, "group_id = ?", group.Id)
+	return gs.db.WithContext(ctx).Delete(group).Error
 }
 
-func (gs *GroupStore) DeleteById(id string) error {
-	group, err := gs.GetById(id)
+func (gs *GroupStore) DeleteById(ctx context.Context, id string) error {
+	group, err := gs.GetById(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	if group != nil {
-		return gs.Delete(group)
+		return gs.Delete(ctx, group)
 	}
 
 	return nil
 }
 
-func (gs *GroupStore) GetGroupPicture(group *models.Group, size services.PictureSize) ([]byte, error) {
+func (gs *GroupStore) GetGroupPicture(ctx context.Context, group *models.Group, size services.PictureSize) ([]byte, error) {
 	var pic models.GroupPicture
-	err := gs.db.Model(group).Select(string(size)).Association("GroupPicture").Find(&pic)
+	err := gs.db.WithContext(ctx).Model(group).Select(string(size)).Association("GroupPicture").Find(&pic)
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -150,7 +213,8 @@ func (gs *GroupStore) GetGroupPicture(group *models.Group, size services.Picture
 	}
 }
 
-func (gs *GroupStore) GetMembers(except *models.User, searchInput string, group *models.Group, page int, pageSize int, descending bool) ([]models.User, error) {
+// Former members (IsMember=false) are only included if includeFormer is true.
+func (gs *GroupStore) GetMembers(ctx context.Context, except *models.User, searchInput string, group *models.Group, page int, pageSize int, descending bool, includeFormer bool) ([]models.User, error) {
 	var memberships []models.GroupMembership
 	var err error
 
@@ -163,10 +227,15 @@ func (gs *GroupStore) GetMembers(except *models.User, searchInput string, group
 		except = &models.User{}
 	}
 
+	query := gs.db.WithContext(ctx).Model(group).Order("user_name "+order).Not("user_id = ?", except.Id)
+	if !includeFormer {
+		query = query.Where("is_member = ?", true)
+	}
+
 	if page < 0 || pageSize < 0 {
-		err = gs.db.Model(group).Order("user_name "+order).Not("user_id = ?", except.Id).Association("Memberships").Find(&memberships, "is_member = ? AND user_name LIKE ?", true, "%"+searchInput+"%")
+		err = query.Association("Memberships").Find(&memberships, "user_name LIKE ?", "%"+searchInput+"%")
 	} else {
-		err = gs.db.Model(group).Order("user_name "+order).Not("user_id = ?", except.Id).Offset(page*pageSize).Limit(pageSize).Association("Memberships").Find(&memberships, "is_member = ?  AND user_name LIKE ?", true, "%"+searchInput+"%")
+		err = query.Offset(page*pageSize).Limit(pageSize).Association("Memberships").Find(&memberships, "user_name LIKE ?", "%"+searchInput+"%")
 	}
 	if err != nil {
 		return nil, err
@@ -178,19 +247,23 @@ func (gs *GroupStore) GetMembers(except *models.User, searchInput string, group
 	}
 
 	var members []models.User
-	err = gs.db.Order("name "+order).Find(&members, "id IN ?", userIds).Error
+	err = gs.db.WithContext(ctx).Order("name "+order).Find(&members, "id IN ?", userIds).Error
 
 	return members, err
 }
 
-func (gs *GroupStore) MemberCount(group *models.Group) (int64, error) {
+func (gs *GroupStore) MemberCount(ctx context.Context, group *models.Group, includeFormer bool) (int64, error) {
 	var count int64
-	err := gs.db.Model(&models.GroupMembership{}).Where("group_id = ? AND is_member = ?", group.Id, true).Count(&count).Error
+	query := gs.db.WithContext(ctx).Model(&models.GroupMembership{}).Where("group_id = ?", group.Id)
+	if !includeFormer {
+		query = query.Where("is_member = ?", true)
+	}
+	err := query.Count(&count).Error
 	return count, err
 }
 
-func (gs *GroupStore) IsMember(group *models.Group, user *models.User) (bool, error) {
-	err := gs.db.First(&models.GroupMembership{}, "group_id = ? AND user_id = ? AND is_member = ?", group.Id, user.Id, true).Error
+func (gs *GroupStore) IsMember(ctx context.Context, group *models.Group, user *models.User) (bool, error) {
+	err := gs.db.WithContext(ctx).First(&models.GroupMembership{}, "group_id = ? AND user_id = ? AND is_member = ?", group.Id, user.Id, true).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -202,11 +275,42 @@ func (gs *GroupStore) IsMember(group *models.Group, user *models.User) (bool, er
 	return true, nil
 }
 
-func (gs *GroupStore) AddMember(group *models.Group, user *models.User) error {
+// Returns models.ErrGroupFull if the group already has config.Data.MaxUsersPerGroup members and admins.
+func (gs *GroupStore) AddMember(ctx context.Context, group *models.Group, user *models.User) error {
+	unlock := lockMemberships(group)
+	defer unlock()
+	return gs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return (&GroupStore{db: tx}).addMember(ctx, group, user)
+	})
+}
+
+// Must be called inside of a database transaction while holding the lock of lockMemberships.
+func (gs *GroupStore) addMember(ctx context.Context, group *models.Group, user *models.User) error {
+	// Locks the group row until the end of the database transaction, so other processes can't exceed the limit concurrently.
+	err := gs.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", group.Id).Find(&models.Group{}).Error
+	if err != nil {
+		return err
+	}
+
 	var membership models.GroupMembership
-	err := gs.db.First(&membership, "group_id = ? AND user_id = ?", group.Id, user.Id).Error
+	err = gs.db.WithContext(ctx).First(&membership, "group_id = ? AND user_id = ?", group.Id, user.Id).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	// Admins already count as users of the group.
+	if config.Data.MaxUsersPerGroup > 0 && !membership.IsMember && !membership.IsAdmin {
+		count, err := gs.GetUserCount(ctx, group)
+		if err != nil {
+			return err
+		}
+		if count >= int64(config.Data.MaxUsersPerGroup) {
+			return models.ErrGroupFull
+		}
+	}
+
 	if err == gorm.ErrRecordNotFound {
-		err = gs.db.Model(group).Select("is_member").Association("Memberships").Append(&models.GroupMembership{
+		err = gs.db.WithContext(ctx).Model(group).Select("is_member").Association("Memberships").Append(&models.GroupMembership{
 			IsMember:  true,
 			GroupId:   group.Id,
 			UserId:    user.Id,
@@ -214,33 +318,95 @@ func (gs *GroupStore) AddMember(group *models.Group, user *models.User) error {
 			UserName:  user.Name,
 		})
 	} else if err == nil {
+		// Former members reuse their old membership. The names are refreshed in case they changed in the meantime.
 		membership.IsMember = true
-		err = gs.db.Updates(&membership).Error
+		membership.UserName = user.Name
+		membership.GroupName = group.Name
+		err = gs.db.WithContext(ctx).Select("is_member", "user_name", "group_name").Updates(&membership).Error
 	}
 
 	return err
 }
 
-func (gs *GroupStore) RemoveMember(group *models.Group, user *models.User) error {
+func (gs *GroupStore) RemoveMember(ctx context.Context, group *models.Group, user *models.User) error {
 	var membership models.GroupMembership
-	err := gs.db.First(&membership, "group_id = ? AND user_id = ?", group.Id, user.Id).Error
+	err := gs.db.WithContext(ctx).First(&membership, "group_id = ? AND user_id = ?", group.Id, user.Id).Error
 	if err != nil {
 		return err
 	}
 
-	gs.db.Where("group_id = ? AND sender_id = ?", group.Id, user.Id).Or("group_id = ? AND receiver_id = ?", group.Id, user.Id).Delete(&models.PaymentPlan{})
+	gs.db.WithContext(ctx).Where("group_id = ? AND sender_id = ?", group.Id, user.Id).Or("group_id = ? AND receiver_id = ?", group.Id, user.Id).Delete(&models.PaymentPlan{})
 
-	if membership.IsAdmin {
-		membership.IsMember = false
-		err = gs.db.Select("is_member").Updates(&membership).Error
-	} else {
-		err = gs.db.Delete(&membership).Error
+	// The membership is kept so that the name of the user can still be resolved in old transactions.
+	membership.IsMember = false
+	return gs.db.WithContext(ctx).Select("is_member").Updates(&membership).Error
+}
+
+// Settles the balance of the member according to the LeaveBalancePolicy of the group and removes the member.
+// Balance limits don't apply to the settlement. Either all or none of the changes are made.
+// Returns the transactions created for the settlement.
+func (gs *GroupStore) SettleBalanceAndRemoveMember(ctx context.Context, group *models.Group, user *models.User, title string) ([]models.TransactionLogEntry, error) {
+	var transactions []models.TransactionLogEntry
+
+	unlock := lockTransactions(group)
+	err := gs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txStore := &GroupStore{db: tx}
+
+		balance, err := txStore.GetUserBalance(ctx, group, user)
+		if err != nil {
+			return err
+		}
+
+		if balance != 0 && (group.LeaveBalancePolicy == models.LeaveBalanceBank || group.LeaveBalancePolicy == models.LeaveBalanceRedistribute) {
+			// nil stands for the bank
+			var counterparties []*models.User
+			if group.LeaveBalancePolicy == models.LeaveBalanceRedistribute {
+				var memberships []models.GroupMembership
+				err = tx.Order("user_id ASC").Find(&memberships, "group_id = ? AND is_member = ? AND user_id <> ?", group.Id, true, user.Id).Error
+				if err != nil {
+					return err
+				}
+				for _, m := range memberships {
+					counterparties = append(counterparties, &models.User{Base: models.Base{Id: m.UserId}})
+				}
+			}
+			if len(counterparties) == 0 {
+				counterparties = append(counterparties, nil)
+			}
+
+			amount := balance
+			if amount < 0 {
+				amount = -amount
+			}
+			parts := services.DistributeAmount(amount, len(counterparties))
+			for i, counterparty := range counterparties {
+				if parts[i] == 0 {
+					continue
+				}
+				var transaction *models.TransactionLogEntry
+				if balance > 0 {
+					transaction, err = txStore.createTransaction(ctx, group, false, counterparty == nil, user, counterparty, title, "", parts[i], "", false)
+				} else {
+					transaction, err = txStore.createTransaction(ctx, group, counterparty == nil, false, counterparty, user, title, "", parts[i], "", false)
+				}
+				if err != nil {
+					return err
+				}
+				transactions = append(transactions, *transaction)
+			}
+		}
+
+		return txStore.RemoveMember(ctx, group, user)
+	})
+	unlock()
+	if err != nil {
+		return nil, err
 	}
 
-	return err
+	return transactions, nil
 }
 
-func (gs *GroupStore) GetAdmins(except *models.User, searchInput string, group *models.Group, page int, pageSize int, descending bool) ([]models.User, error) {
+func (gs *GroupStore) GetAdmins(ctx context.Context, except *models.User, searchInput string, group *models.Group, page int, pageSize int, descending bool) ([]models.User, error) {
 	var memberships []models.GroupMembership
 	var err error
 
@@ -254,9 +420,9 @@ func (gs *GroupStore) GetAdmins(except *models.User, searchInput string, group *
 	}
 
 	if page < 0 || pageSize < 0 {
-		err = gs.db.Model(group).Order("user_name "+order).Not("user_id = ?", except.Id).Association("Memberships").Find(&memberships, "is_admin = ? AND user_name LIKE ?", true, "%"+searchInput+"%")
+		err = gs.db.WithContext(ctx).Model(group).Order("user_name "+order).Not("user_id = ?", except.Id).Association("Memberships").Find(&memberships, "is_admin = ? AND user_name LIKE ?", true, "%"+searchInput+"%")
 	} else {
-		err = gs.db.Model(group).Order("user_name "+order).Not("user_id = ?", except.Id).Offset(page*pageSize).Limit(pageSize).Association("Memberships").Find(&memberships, "is_admin = ? AND user_name LIKE ?", true, "%"+searchInput+"%")
+		err = gs.db.WithContext(ctx).Model(group).Order("user_name "+order).Not("user_id = ?", except.Id).Offset(page*pageSize).Limit(pageSize).Association("Memberships").Find(&memberships, "is_admin = ? AND user_name LIKE ?", true, "%"+searchInput+"%")
 	}
 	if err != nil {
 		return nil, err
@@ -268,18 +434,18 @@ func (gs *GroupStore) GetAdmins(except *models.User, searchInput string, group *
 	}
 
 	var members []models.User
-	err = gs.db.Order("name "+order).Find(&members, "id IN ?", userIds).Error
+	err = gs.db.WithContext(ctx).Order("name "+order).Find(&members, "id IN ?", userIds).Error
 
 	return members, err
 }
 
-func (gs *GroupStore) AdminCount(group *models.Group) (int64, error) {
+func (gs *GroupStore) AdminCount(ctx context.Context, group *models.Group) (int64, error) {
 	var count int64
-	err := gs.db.Model(&models.GroupMembership{}).Where("group_id = ? AND is_admin = ?", group.Id, true).Count(&count).Error
+	err := gs.db.WithContext(ctx).Model(&models.GroupMembership{}).Where("group_id = ? AND is_admin = ?", group.Id, true).Count(&count).Error
 	return count, err
 }
 
-func (gs *GroupStore) GetMemberships(except *models.User, searchInput string, group *models.Group, page int, pageSize int, descending bool) ([]models.GroupMembership, error) {
+func (gs *GroupStore) GetMemberships(ctx context.Context, except *models.User, searchInput string, group *models.Group, page int, pageSize int, descending bool) ([]models.GroupMembership, error) {
 	var memberships []models.GroupMembership
 	var err error
 
@@ -293,22 +459,55 @@ func (gs *GroupStore) GetMemberships(except *models.User, searchInput string, gr
 	}
 
 	if page < 0 || pageSize < 0 {
-		err = gs.db.Model(group).Order("user_name "+order).Not("user_id = ?", except.Id).Association("Memberships").Find(&memberships, "user_name LIKE ?", "%"+searchInput+"%")
+		err = gs.db.WithContext(ctx).Model(group).Order("user_name "+order).Not("user_id = ?", except.Id).Association("Memberships").Find(&memberships, "(is_member = ? OR is_admin = ?) AND user_name LIKE ?", true, true, "%"+searchInput+"%")
 	} else {
-		err = gs.db.Model(group).Order("user_name "+order).Not("user_id = ?", except.Id).Offset(page*pageSize).Limit(pageSize).Association("Memberships").Find(&memberships, "user_name LIKE ?", "%"+searchInput+"%")
+		err = gs.db.WithContext(ctx).Model(group).Order("user_name "+order).Not("user_id = ?", except.Id).Offset(page*pageSize).Limit(pageSize).Association("Memberships").Find(&memberships, "(is_member = ? OR is_admin = ?) AND user_name LIKE ?", true, true, "%"+searchInput+"%")
 	}
 
 	return memberships, err
 }
 
-func (gs *GroupStore) MembershipCount(group *models.Group) (int64, error) {
+func (gs *GroupStore) GetMembership(ctx context.Context, group *models.Group, user *models.User) (*models.GroupMembership, error) {
+	var membership models.GroupMembership
+	err := gs.db.WithContext(ctx).First(&membership, "group_id = ? AND user_id = ?", group.Id, user.Id).Error
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+	return &membership, nil
+}
+
+// Returns the names of the users with the given ids which are or were part of the group.
+func (gs *GroupStore) GetUserNames(ctx context.Context, group *models.Group, userIds []string) (map[string]string, error) {
+	names := make(map[string]string, len(userIds))
+	if len(userIds) == 0 {
+		return names, nil
+	}
+
+	var memberships []models.GroupMembership
+	err := gs.db.WithContext(ctx).Select("user_id", "user_name").Find(&memberships, "group_id = ? AND user_id IN ?", group.Id, userIds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range memberships {
+		names[m.UserId] = m.UserName
+	}
+	return names, nil
+}
+
+func (gs *GroupStore) MembershipCount(ctx context.Context, group *models.Group) (int64, error) {
 	var count int64
-	err := gs.db.Model(&models.GroupMembership{}).Where("group_id = ?", group.Id).Count(&count).Error
+	err := gs.db.WithContext(ctx).Model(&models.GroupMembership{}).Where("group_id = ? AND (is_member = ? OR is_admin = ?)", group.Id, true, true).Count(&count).Error
 	return count, err
 }
 
-func (gs *GroupStore) IsAdmin(group *models.Group, user *models.User) (bool, error) {
-	err := gs.db.First(&models.GroupMembership{}, "group_id = ? AND user_id = ? AND is_admin = ?", group.Id, user.Id, true).Error
+func (gs *GroupStore) IsAdmin(ctx context.Context, group *models.Group, user *models.User) (bool, error) {
+	err := gs.db.WithContext(ctx).First(&models.GroupMembership{}, "group_id = ? AND user_id = ? AND is_admin = ?", group.Id, user.Id, true).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -320,11 +519,11 @@ func (gs *GroupStore) IsAdmin(group *models.Group, user *models.User) (bool, err
 	return true, nil
 }
 
-func (gs *GroupStore) AddAdmin(group *models.Group, user *models.User) error {
+func (gs *GroupStore) AddAdmin(ctx context.Context, group *models.Group, user *models.User) error {
 	var membership models.GroupMembership
-	err := gs.db.First(&membership, "group_id = ? AND user_id = ?", group.Id, user.Id).Error
+	err := gs.db.WithContext(ctx).First(&membership, "group_id = ? AND user_id = ?", group.Id, user.Id).Error
 	if err == gorm.ErrRecordNotFound {
-		err = gs.db.Model(group).Association("Memberships").Append(&models.GroupMembership{
+		err = gs.db.WithContext(ctx).Model(group).Association("Memberships").Append(&models.GroupMembership{
 			IsAdmin:   true,
 			GroupId:   group.Id,
 			UserId:    user.Id,
@@ -333,31 +532,43 @@ func (gs *GroupStore) AddAdmin(group *models.Group, user *models.User) error {
 		})
 	} else if err == nil {
 		membership.IsAdmin = true
-		err = gs.db.Select("is_admin").Updates(&membership).Error
+		err = gs.db.WithContext(ctx).Select("is_admin").Updates(&membership).Error
+	}
+	if err != nil {
+		return err
 	}
 
-	return err
+	return gs.db.WithContext(ctx).Create(&models.AdminChange{
+		GroupId:   group.Id,
+		GroupName: group.Name,
+		UserId:    user.Id,
+		Granted:   true,
+	}).Error
 }
 
-func (gs *GroupStore) RemoveAdmin(group *models.Group, user *models.User) error {
+func (gs *GroupStore) RemoveAdmin(ctx context.Context, group *models.Group, user *models.User) error {
 	var membership models.GroupMembership
-	err := gs.db.First(&membership, "group_id = ? AND user_id = ?", group.Id, user.Id).Error
+	err := gs.db.WithContext(ctx).First(&membership, "group_id = ? AND user_id = ?", group.Id, user.Id).Error
 	if err != nil {
 		return err
 	}
 
-	if membership.IsMember {
-		membership.IsAdmin = false
-		err = gs.db.Select("is_admin").Updates(&membership).Error
-	} else {
-		err = gs.db.Delete(&membership).Error
+	membership.IsAdmin = false
+	err = gs.db.WithContext(ctx).Select("is_admin").Updates(&membership).Error
+	if err != nil {
+		return err
 	}
 
-	return err
+	return gs.db.WithContext(ctx).Create(&models.AdminChange{
+		GroupId:   group.Id,
+		GroupName: group.Name,
+		UserId:    user.Id,
+		Granted:   false,
+	}).Error
 }
 
-func (gs *GroupStore) IsInGroup(group *models.Group, user *models.User) (bool, error) {
-	err := gs.db.Where("group_id = ? AND user_id = ? AND is_member = ?", group.Id, user.Id, true).Or("group_id = ? AND user_id = ? AND is_admin = ?", group.Id, user.Id, true).First(&models.GroupMembership{}).Error
+func (gs *GroupStore) IsInGroup(ctx context.Context, group *models.Group, user *models.User) (bool, error) {
+	err := gs.db.WithContext(ctx).Where("group_id = ? AND user_id = ? AND is_member = ?", group.Id, user.Id, true).Or("group_id = ? AND user_id = ? AND is_admin = ?", group.Id, user.Id, true).First(&models.GroupMembership{}).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -369,13 +580,13 @@ func (gs *GroupStore) IsInGroup(group *models.Group, user *models.User) (bool, e
 	return true, nil
 }
 
-func (gs *GroupStore) GetUserCount(group *models.Group) (int64, error) {
+func (gs *GroupStore) GetUserCount(ctx context.Context, group *models.Group) (int64, error) {
 	count := int64(0)
-	err := gs.db.Model(&models.GroupMembership{}).Where("group_id = ? AND is_member = ?", group.Id, true).Or("group_id = ? AND is_admin = ?", group.Id, true).Count(&count).Error
+	err := gs.db.WithContext(ctx).Model(&models.GroupMembership{}).Where("group_id = ? AND is_member = ?", group.Id, true).Or("group_id = ? AND is_admin = ?", group.Id, true).Count(&count).Error
 	return count, err
 }
 
-func (gs *GroupStore) GetTransactionLog(group *models.Group, user *models.User, searchInput string, page, pageSize int, oldestFirst bool) ([]models.TransactionLogEntry, error) {
+func (gs *GroupStore) GetTransactionLog(ctx context.Context, group *models.Group, user *models.User, searchInput string, filter models.TransactionFilter, page, pageSize int, oldestFirst bool) ([]models.TransactionLogEntry, error) {
 	var log []models.TransactionLogEntry
 	var err error
 
@@ -384,22 +595,53 @@ func (gs *GroupStore) GetTransactionLog(group *models.Group, user *models.User,
 		order = "ASC"
 	}
 
+	query := gs.filterTransactionLog(ctx, group, user, filter).Where("(title LIKE ? OR reference LIKE ?)", "%"+searchInput+"%", "%"+searchInput+"%")
+
 	if page < 0 || pageSize < 0 {
-		err = gs.db.Order("created "+order).Where("group_id = ? AND sender_id = ? AND title LIKE ?", group.Id, user.Id, "%"+searchInput+"%").Or("group_id = ? AND receiver_id = ? AND title LIKE ?", group.Id, user.Id, "%"+searchInput+"%").Find(&log).Error
+		err = query.Order("created " + order + ", sequence " + order).Find(&log).Error
 	} else {
-		err = gs.db.Order("created "+order).Offset(page*pageSize).Limit(pageSize).Where("group_id = ? AND sender_id = ? AND title LIKE ?", group.Id, user.Id, "%"+searchInput+"%").Or("group_id = ? AND receiver_id = ? AND title LIKE ?", group.Id, user.Id, "%"+searchInput+"%").Find(&log).Error
+		err = query.Order("created " + order + ", sequence " + order).Offset(page * pageSize).Limit(pageSize).Find(&log).Error
 	}
 
 	return log, err
 }
 
-func (gs *GroupStore) TransactionLogEntryCount(group *models.Group, user *models.User) (int64, error) {
+func (gs *GroupStore) TransactionLogEntryCount(ctx context.Context, group *models.Group, user *models.User, filter models.TransactionFilter) (int64, error) {
 	var count int64
-	err := gs.db.Model(&models.TransactionLogEntry{}).Where("group_id = ? AND sender_id = ?", group.Id, user.Id).Or("group_id = ? AND receiver_id = ?", group.Id, user.Id).Count(&count).Error
+	err := gs.filterTransactionLog(ctx, group, user, filter).Model(&models.TransactionLogEntry{}).Count(&count).Error
 	return count, err
 }
 
-func (gs *GroupStore) GetBankTransactionLog(group *models.Group, searchInput string, page, pageSize int, oldestFirst bool) ([]models.TransactionLogEntry, error) {
+// Returns a query matching the transactions of the user in the group which pass the filter.
+func (gs *GroupStore) filterTransactionLog(ctx context.Context, group *models.Group, user *models.User, filter models.TransactionFilter) *gorm.DB {
+	sending := "sender_id = ?"
+	sendingArgs := []any{user.Id}
+	receiving := "receiver_id = ?"
+	receivingArgs := []any{user.Id}
+
+	if filter.CounterpartyId == "bank" {
+		sending += " AND receiver_is_bank = ?"
+		sendingArgs = append(sendingArgs, true)
+		receiving += " AND sender_is_bank = ?"
+		receivingArgs = append(receivingArgs, true)
+	} else if filter.CounterpartyId != "" {
+		sending += " AND receiver_id = ?"
+		sendingArgs = append(sendingArgs, filter.CounterpartyId)
+		receiving += " AND sender_id = ?"
+		receivingArgs = append(receivingArgs, filter.CounterpartyId)
+	}
+
+	query := gs.db.WithContext(ctx).Where("group_id = ?", group.Id).Where("(("+sending+") OR ("+receiving+"))", append(sendingArgs, receivingArgs...)...)
+	if filter.MinAmount > 0 {
+		query = query.Where("amount >= ?", filter.MinAmount)
+	}
+	if filter.MaxAmount > 0 {
+		query = query.Where("amount <= ?", filter.MaxAmount)
+	}
+	return query
+}
+
+func (gs *GroupStore) GetBankTransactionLog(ctx context.Context, group *models.Group, searchInput string, page, pageSize int, oldestFirst bool) ([]models.TransactionLogEntry, error) {
 	var log []models.TransactionLogEntry
 	var err error
 
@@ -409,23 +651,38 @@ func (gs *GroupStore) GetBankTransactionLog(group *models.Group, searchInput str
 	}
 
 	if page < 0 || pageSize < 0 {
-		err = gs.db.Order("created "+order).Where("group_id = ? AND sender_is_bank = ? AND title LIKE ?", group.Id, true, "%"+searchInput+"%").Or("group_id = ? AND receiver_is_bank = ? AND title LIKE ?", group.Id, true, "%"+searchInput+"%").Find(&log).Error
+		err = gs.db.WithContext(ctx).Order("created "+order+", sequence "+order).Where("group_id = ? AND sender_is_bank = ? AND (title LIKE ? OR reference LIKE ?)", group.Id, true, "%"+searchInput+"%", "%"+searchInput+"%").Or("group_id = ? AND receiver_is_bank = ? AND (title LIKE ? OR reference LIKE ?)", group.Id, true, "%"+searchInput+"%", "%"+searchInput+"%").Find(&log).Error
 	} else {
-		err = gs.db.Order("created "+order).Offset(page*pageSize).Limit(pageSize).Where("group_id = ? AND sender_is_bank = ? AND title LIKE ?", group.Id, true, "%"+searchInput+"%").Or("group_id = ? AND receiver_is_bank = ? AND title LIKE ?", group.Id, true, "%"+searchInput+"%").Find(&log).Error
+		err = gs.db.WithContext(ctx).Order("created "+order+", sequence "+order).Offset(page*pageSize).Limit(pageSize).Where("group_id = ? AND sender_is_bank = ? AND (title LIKE ? OR reference LIKE ?)", group.Id, true, "%"+searchInput+"%", "%"+searchInput+"%").Or("group_id = ? AND receiver_is_bank = ? AND (title LIKE ? OR reference LIKE ?)", group.Id, true, "%"+searchInput+"%", "%"+searchInput+"%").Find(&log).Error
 	}
 
 	return log, err
 }
 
-func (gs *GroupStore) BankTransactionLogEntryCount(group *models.Group) (int64, error) {
+func (gs *GroupStore) BankTransactionLogEntryCount(ctx context.Context, group *models.Group) (int64, error) {
 	var count int64
-	err := gs.db.Model(&models.TransactionLogEntry{}).Where("group_id = ? AND sender_is_bank = ?", group.Id, true).Or("group_id = ? AND receiver_is_bank = ?", group.Id, true).Count(&count).Error
+	err := gs.db.WithContext(ctx).Model(&models.TransactionLogEntry{}).Where("group_id = ? AND sender_is_bank = ?", group.Id, true).Or("group_id = ? AND receiver_is_bank = ?", group.Id, true).Count(&count).Error
 	return count, err
 }
 
-func (gs *GroupStore) GetTransactionLogEntryById(group *models.Group, id string) (*models.TransactionLogEntry, error) {
+func (gs *GroupStore) GetTransactionLogEntryById(ctx context.Context, group *models.Group, id string) (*models.TransactionLogEntry, error) {
+	var entry models.TransactionLogEntry
+	err := gs.db.WithContext(ctx).First(&entry, "group_id = ? AND id = ?", group.Id, id).Error
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &entry, nil
+}
+
+func (gs *GroupStore) GetTransactionLogEntryByReference(ctx context.Context, group *models.Group, reference string) (*models.TransactionLogEntry, error) {
 	var entry models.TransactionLogEntry
-	err := gs.db.First(&entry, "group_id = ? AND id = ?", group.Id, id).Error
+	err := gs.db.WithContext(ctx).First(&entry, "group_id = ? AND reference = ?", group.Id, reference).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -438,9 +695,9 @@ func (gs *GroupStore) GetTransactionLogEntryById(group *models.Group, id string)
 	return &entry, nil
 }
 
-func (gs *GroupStore) GetLastTransactionLogEntry(group *models.Group, user *models.User) (*models.TransactionLogEntry, error) {
+func (gs *GroupStore) GetLastTransactionLogEntry(ctx context.Context, group *models.Group, user *models.User) (*models.TransactionLogEntry, error) {
 	var entry models.TransactionLogEntry
-	err := gs.db.Order("created DESC").Where("group_id = ? AND sender_id = ?", group.Id, user.Id).Or("group_id = ? AND receiver_id = ?", group.Id, user.Id).First(&entry).Error
+	err := gs.db.WithContext(ctx).Order("sequence DESC").Where("group_id = ? AND sender_id = ?", group.Id, user.Id).Or("group_id = ? AND receiver_id = ?", group.Id, user.Id).First(&entry).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -452,8 +709,8 @@ func (gs *GroupStore) GetLastTransactionLogEntry(group *models.Group, user *mode
 	return &entry, nil
 }
 
-func (gs *GroupStore) GetUserBalance(group *models.Group, user *models.User) (int, error) {
-	lastLogEntry, err := gs.GetLastTransactionLogEntry(group, user)
+func (gs *GroupStore) GetUserBalance(ctx context.Context, group *models.Group, user *models.User) (int, error) {
+	lastLogEntry, err := gs.GetLastTransactionLogEntry(ctx, group, user)
 	if err != nil {
 		return 0, err
 	}
@@ -468,31 +725,280 @@ func (gs *GroupStore) GetUserBalance(group *models.Group, user *models.User) (in
 	}
 }
 
-func (gs *GroupStore) CreateTransaction(group *models.Group, senderIsBank, receiverIsBank bool, sender *models.User, receiver *models.User, title, description string, amount int) (*models.TransactionLogEntry, error) {
-	return gs.CreateTransactionFromPaymentPlan(group, senderIsBank, receiverIsBank, sender, receiver, title, description, amount, "")
+// Returns the balance of the user at the given unix timestamp, including transactions made at that exact time.
+func (gs *GroupStore) GetUserBalanceAt(ctx context.Context, group *models.Group, user *models.User, time int64) (int, error) {
+	var entry models.TransactionLogEntry
+	err := gs.db.WithContext(ctx).Order("sequence DESC").Where("group_id = ? AND sender_id = ? AND created <= ?", group.Id, user.Id, time).Or("group_id = ? AND receiver_id = ? AND created <= ?", group.Id, user.Id, time).First(&entry).Error
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			return 0, nil
+		default:
+			return 0, err
+		}
+	}
+
+	if entry.SenderId == user.Id {
+		return entry.NewBalanceSender, nil
+	} else {
+		return entry.NewBalanceReceiver, nil
+	}
+}
+
+// Returns all transactions of the group created in [from, to), oldest first.
+func (gs *GroupStore) GetTransactionsInPeriod(ctx context.Context, group *models.Group, from, to int64) ([]models.TransactionLogEntry, error) {
+	var log []models.TransactionLogEntry
+	err := gs.db.WithContext(ctx).Order("created ASC, sequence ASC").Where("group_id = ? AND created >= ? AND created < ?", group.Id, from, to).Find(&log).Error
+	return log, err
 }
 
-func (gs *GroupStore) CreateTransactionFromPaymentPlan(group *models.Group, senderIsBank, receiverIsBank bool, sender *models.User, receiver *models.User, title, description string, amount int, paymentPlanId string) (*models.TransactionLogEntry, error) {
-	var err error
+func (gs *GroupStore) CreateTransaction(ctx context.Context, group *models.Group, senderIsBank, receiverIsBank bool, sender *models.User, receiver *models.User, title, description string, amount int) (*models.TransactionLogEntry, error) {
+	return gs.CreateTransactionFromPaymentPlan(ctx, group, senderIsBank, receiverIsBank, sender, receiver, title, description, amount, "")
+}
+
+// Creates the transactions in the given order keeping their creation time and computes the resulting balances.
+// Either all or none of the transactions are created. Returns models.ErrImportNotNewer if the group already has transactions
+// created at or after the first imported one.
+// The minimum and maximum balance of the group aren't enforced, even with overdraft protection, because the imported transactions already happened.
+func (gs *GroupStore) ImportTransactions(ctx context.Context, group *models.Group, transactions []models.TransactionLogEntry) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	unlock := lockTransactions(group)
+	defer unlock()
+
+	return gs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var newer int64
+		err := tx.Model(&models.TransactionLogEntry{}).Where("group_id = ? AND created >= ?", group.Id, transactions[0].Created).Count(&newer).Error
+		if err != nil {
+			return err
+		}
+		if newer > 0 {
+			return models.ErrImportNotNewer
+		}
+
+		txStore := &GroupStore{db: tx}
+		for i := range transactions {
+			t := &transactions[i]
+			if !validTransactionParties(t.SenderIsBank, t.ReceiverIsBank, t.SenderId != "", t.ReceiverId != "") {
+				return models.ErrInvalidTransactionParties
+			}
+			t.GroupId = group.Id
+			t.BalanceDifferenceSender = -t.Amount
+			t.BalanceDifferenceReceiver = t.Amount
+
+			if !t.SenderIsBank {
+				balance, err := txStore.GetUserBalance(ctx, group, &models.User{Base: models.Base{Id: t.SenderId}})
+				if err != nil {
+					return err
+				}
+				t.NewBalanceSender = balance - t.Amount
+			}
+			if !t.ReceiverIsBank {
+				balance, err := txStore.GetUserBalance(ctx, group, &models.User{Base: models.Base{Id: t.ReceiverId}})
+				if err != nil {
+					return err
+				}
+				t.NewBalanceReceiver = balance + t.Amount
+			}
+
+			if err := txStore.assignReference(ctx, group, t); err != nil {
+				return err
+			}
+
+			if err := tx.Create(t).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (gs *GroupStore) CreateTransactionFromPaymentPlan(ctx context.Context, group *models.Group, senderIsBank, receiverIsBank bool, sender *models.User, receiver *models.User, title, description string, amount int, paymentPlanId string) (*models.TransactionLogEntry, error) {
+	var transaction *models.TransactionLogEntry
+
+	unlock := lockTransactions(group)
+	err := gs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = (&GroupStore{db: tx}).createTransaction(ctx, group, senderIsBank, receiverIsBank, sender, receiver, title, description, amount, paymentPlanId, true)
+		return err
+	})
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	alertLowBalance(group, sender, transaction)
+
+	return transaction, nil
+}
+
+// Creates a transaction between the user and the bank together with the matching cash log entry of the user.
+// cash contains the deposited or withdrawn coins and notes. A deposit increases the balance of the user and
+// removes the cash from their cash log, a withdrawal does the opposite. Either both or none of the entries are created.
+func (gs *GroupStore) CreateCashTransaction(ctx context.Context, group *models.Group, user *models.User, deposit bool, title, description string, cash *models.CashLogEntry) (*models.TransactionLogEntry, *models.CashLogEntry, error) {
+	var transaction *models.TransactionLogEntry
+	var cashLogEntry *models.CashLogEntry
+
+	// Both locks are taken at once, see lockTransactions and lockCashLog.
+	unlock := transactionLocks.Lock("group:"+group.Id, "cash:"+user.Id)
+	err := gs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		userStore := &UserStore{db: tx}
+
+		lastEntry, err := userStore.GetLastCashLogEntry(ctx, user)
+		if err != nil {
+			return err
+		}
+		if lastEntry == nil {
+			lastEntry = &models.CashLogEntry{}
+		}
+
+		sign := 1
+		if deposit {
+			sign = -1
+		}
+		cashLogEntry = addCash(lastEntry, cash, sign)
+		cashLogEntry.ChangeTitle = title
+		cashLogEntry.ChangeDescription = description
+		if hasNegativeCash(cashLogEntry) {
+			return models.ErrNotEnoughCash
+		}
+
+		err = userStore.addCashLogEntry(ctx, user, cashLogEntry)
+		if err != nil {
+			return err
+		}
+
+		if deposit {
+			transaction, err = (&GroupStore{db: tx}).createTransaction(ctx, group, true, false, nil, user, title, description, cash.Value(), "", true)
+		} else {
+			transaction, err = (&GroupStore{db: tx}).createTransaction(ctx, group, false, true, user, nil, title, description, cash.Value(), "", true)
+		}
+		return err
+	})
+	unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !deposit {
+		alertLowBalance(group, user, transaction)
+	}
+
+	return transaction, cashLogEntry, nil
+}
+
+// Must be called inside of a database transaction while holding the lock of lockTransactions.
+// If checkMinBalance is set and the group has overdraft protection enabled, models.ErrInsufficientFunds is returned
+// when the balance of a sending member would drop below the minimum balance of the group.
+func (gs *GroupStore) createTransaction(ctx context.Context, group *models.Group, senderIsBank, receiverIsBank bool, sender *models.User, receiver *models.User, title, description string, amount int, paymentPlanId string, checkMinBalance bool) (*models.TransactionLogEntry, error) {
+	if !validTransactionParties(senderIsBank, receiverIsBank, sender != nil, receiver != nil) {
+		return nil, models.ErrInvalidTransactionParties
+	}
+
+	err := gs.lockMembers(ctx, group, senderIsBank, receiverIsBank, sender, receiver)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := gs.PreviewTransaction(ctx, group, senderIsBank, receiverIsBank, sender, receiver, title, description, amount)
+	if err != nil {
+		return nil, err
+	}
+	transaction.PaymentPlanId = paymentPlanId
+
+	// The balance is read while the sender is locked, so concurrent transactions can't overdraw it together.
+	if checkMinBalance && group.OverdraftProtection && !senderIsBank && transaction.NewBalanceSender < group.MinBalance {
+		return nil, models.ErrInsufficientFunds
+	}
+
+	err = gs.assignReference(ctx, group, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, gs.db.WithContext(ctx).Create(transaction).Error
+}
+
+// Increments the transaction count of the group and assigns the resulting sequence number and reference to the transaction.
+// The update locks the group row until the end of the database transaction, so concurrent transactions can't get the same number.
+func (gs *GroupStore) assignReference(ctx context.Context, group *models.Group, transaction *models.TransactionLogEntry) error {
+	err := gs.db.WithContext(ctx).Model(&models.Group{}).Where("id = ?", group.Id).Update("transaction_count", gorm.Expr("transaction_count + 1")).Error
+	if err != nil {
+		return err
+	}
+	var count int
+	err = gs.db.WithContext(ctx).Model(&models.Group{}).Select("transaction_count").Where("id = ?", group.Id).Scan(&count).Error
+	if err != nil {
+		return err
+	}
+	transaction.Sequence = count
+	transaction.Reference = models.TransactionReference(group.Id, count)
+	return nil
+}
+
+// Each side of a transaction has to be either the bank or a user. Transactions from the bank to the bank are rejected
+// because they wouldn't change any balance.
+func validTransactionParties(senderIsBank, receiverIsBank, hasSender, hasReceiver bool) bool {
+	return senderIsBank != hasSender && receiverIsBank != hasReceiver && !(senderIsBank && receiverIsBank)
+}
+
+func alertLowBalance(group *models.Group, sender *models.User, transaction *models.TransactionLogEntry) {
+	// Only alert when the threshold is crossed to avoid repeated alerts while the balance stays below it.
+	oldBalanceSender := transaction.NewBalanceSender + transaction.Amount
+	if group.LowBalanceAlert && LowBalanceHandler != nil && !transaction.SenderIsBank &&
+		oldBalanceSender >= group.LowBalanceThreshold && transaction.NewBalanceSender < group.LowBalanceThreshold {
+		LowBalanceHandler(group, sender, transaction.NewBalanceSender)
+	}
+}
+
+// Locks the memberships of the sender and receiver until the end of the database transaction.
+func (gs *GroupStore) lockMembers(ctx context.Context, group *models.Group, senderIsBank, receiverIsBank bool, sender *models.User, receiver *models.User) error {
+	var users []*models.User
+	if !senderIsBank {
+		users = append(users, sender)
+	}
+	if !receiverIsBank {
+		users = append(users, receiver)
+	}
+	// Always lock in the same order to avoid deadlocks.
+	slices.SortFunc(users, func(a, b *models.User) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	for _, u := range users {
+		var membership models.GroupMembership
+		err := gs.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("group_id = ? AND user_id = ?", group.Id, u.Id).Find(&membership).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Computes the transaction including the resulting balances without persisting it.
+func (gs *GroupStore) PreviewTransaction(ctx context.Context, group *models.Group, senderIsBank, receiverIsBank bool, sender *models.User, receiver *models.User, title, description string, amount int) (*models.TransactionLogEntry, error) {
+	if !validTransactionParties(senderIsBank, receiverIsBank, sender != nil, receiver != nil) {
+		return nil, models.ErrInvalidTransactionParties
+	}
 
-	oldBalanceSender := 0
 	newBalanceSender := 0
 	if !senderIsBank {
-		oldBalanceSender, err = gs.GetUserBalance(group, sender)
+		balance, err := gs.GetUserBalance(ctx, group, sender)
 		if err != nil {
 			return nil, err
 		}
-		newBalanceSender = oldBalanceSender - amount
+		newBalanceSender = balance - amount
 	}
 
-	oldBalanceReceiver := 0
 	newBalanceReceiver := 0
 	if !receiverIsBank {
-		oldBalanceReceiver, err = gs.GetUserBalance(group, receiver)
+		balance, err := gs.GetUserBalance(ctx, group, receiver)
 		if err != nil {
 			return nil, err
 		}
-		newBalanceReceiver = oldBalanceReceiver + amount
+		newBalanceReceiver = balance + amount
 	}
 
 	senderId := ""
@@ -520,31 +1026,33 @@ func (gs *GroupStore) CreateTransactionFromPaymentPlan(group *models.Group, send
 		ReceiverId:                receiverId,
 		BalanceDifferenceReceiver: amount,
 		NewBalanceReceiver:        newBalanceReceiver,
-
-		PaymentPlanId: paymentPlanId,
 	}
 
-	err = gs.db.Create(&transaction).Error
-
-	return &transaction, err
+	return &transaction, nil
 }
 
-func (gs *GroupStore) CreateInvitation(group *models.Group, user *models.User, message string) (*models.GroupInvitation, error) {
+// inviter may be nil if the invitation wasn't created by an admin.
+func (gs *GroupStore) CreateInvitation(ctx context.Context, group *models.Group, user, inviter *models.User, message string, openingBalance int) (*models.GroupInvitation, error) {
 	invitation := &models.GroupInvitation{
-		Message:   message,
-		GroupName: group.Name,
-		GroupId:   group.Id,
-		UserId:    user.Id,
+		Message:        message,
+		GroupName:      group.Name,
+		GroupId:        group.Id,
+		UserId:         user.Id,
+		OpeningBalance: openingBalance,
+		LastSent:       time.Now().Unix(),
+	}
+	if inviter != nil {
+		invitation.InviterId = inviter.Id
 	}
 
-	err := gs.db.Create(invitation).Error
+	err := gs.db.WithContext(ctx).Create(invitation).Error
 
 	return invitation, err
 }
 
-func (gs *GroupStore) GetInvitationById(id string) (*models.GroupInvitation, error) {
+func (gs *GroupStore) GetInvitationById(ctx context.Context, id string) (*models.GroupInvitation, error) {
 	var invitation models.GroupInvitation
-	err := gs.db.First(&invitation, "id = ?", id).Error
+	err := gs.db.WithContext(ctx).First(&invitation, "id = ?", id).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -557,7 +1065,7 @@ func (gs *GroupStore) GetInvitationById(id string) (*models.GroupInvitation, err
 	return &invitation, nil
 }
 
-func (gs *GroupStore) GetInvitationsByGroup(group *models.Group, page, pageSize int, oldestFirst bool) ([]models.GroupInvitation, error) {
+func (gs *GroupStore) GetInvitationsByGroup(ctx context.Context, group *models.Group, page, pageSize int, oldestFirst bool) ([]models.GroupInvitation, error) {
 	order := "DESC"
 	if oldestFirst {
 		order = "ASC"
@@ -566,21 +1074,21 @@ func (gs *GroupStore) GetInvitationsByGroup(group *models.Group, page, pageSize
 	var invitations []models.GroupInvitation
 	var err error
 	if page < 0 || pageSize < 0 {
-		err = gs.db.Order("created "+order).Find(&invitations, "group_id = ?", group.Id).Error
+		err = gs.db.WithContext(ctx).Order("created "+order).Find(&invitations, "group_id = ?", group.Id).Error
 	} else {
-		err = gs.db.Order("created "+order).Offset(page*pageSize).Limit(pageSize).Find(&invitations, "group_id = ?", group.Id).Error
+		err = gs.db.WithContext(ctx).Order("created "+order).Offset(page*pageSize).Limit(pageSize).Find(&invitations, "group_id = ?", group.Id).Error
 	}
 
 	return invitations, err
 }
 
-func (gs *GroupStore) InvitationCountByGroup(group *models.Group) (int64, error) {
+func (gs *GroupStore) InvitationCountByGroup(ctx context.Context, group *models.Group) (int64, error) {
 	var count int64
-	err := gs.db.Model(&models.GroupInvitation{}).Where("group_id = ?", group.Id).Count(&count).Error
+	err := gs.db.WithContext(ctx).Model(&models.GroupInvitation{}).Where("group_id = ?", group.Id).Count(&count).Error
 	return count, err
 }
 
-func (gs *GroupStore) GetInvitationsByUser(user *models.User, page, pageSize int, oldestFirst bool) ([]models.GroupInvitation, error) {
+func (gs *GroupStore) GetInvitationsByUser(ctx context.Context, user *models.User, page, pageSize int, oldestFirst bool) ([]models.GroupInvitation, error) {
 	order := "DESC"
 	if oldestFirst {
 		order = "ASC"
@@ -589,23 +1097,48 @@ func (gs *GroupStore) GetInvitationsByUser(user *models.User, page, pageSize int
 	var invitations []models.GroupInvitation
 	var err error
 	if page < 0 || pageSize < 0 {
-		err = gs.db.Order("created "+order).Find(&invitations, "user_id = ?", user.Id).Error
+		err = gs.db.WithContext(ctx).Order("created "+order).Find(&invitations, "user_id = ?", user.Id).Error
 	} else {
-		err = gs.db.Order("created "+order).Offset(page*pageSize).Limit(pageSize).Find(&invitations, "user_id = ?", user.Id).Error
+		err = gs.db.WithContext(ctx).Order("created "+order).Offset(page*pageSize).Limit(pageSize).Find(&invitations, "user_id = ?", user.Id).Error
 	}
 
 	return invitations, err
 }
 
-func (gs *GroupStore) InvitationCountByUser(user *models.User) (int64, error) {
+func (gs *GroupStore) InvitationCountByUser(ctx context.Context, user *models.User) (int64, error) {
+	var count int64
+	err := gs.db.WithContext(ctx).Model(&models.GroupInvitation{}).Where("user_id = ?", user.Id).Count(&count).Error
+	return count, err
+}
+
+func (gs *GroupStore) UnseenInvitationCountByUser(ctx context.Context, user *models.User) (int64, error) {
 	var count int64
-	err := gs.db.Model(&models.GroupInvitation{}).Where("user_id = ?", user.Id).Count(&count).Error
+	err := gs.db.WithContext(ctx).Model(&models.GroupInvitation{}).Where("user_id = ? AND seen = ?", user.Id, false).Count(&count).Error
 	return count, err
 }
 
-func (gs *GroupStore) GetInvitationByGroupAndUser(group *models.Group, user *models.User) (*models.GroupInvitation, error) {
+func (gs *GroupStore) MarkInvitationsAsSeen(ctx context.Context, invitations []models.GroupInvitation) error {
+	ids := make([]string, 0, len(invitations))
+	for _, in := range invitations {
+		if !in.Seen {
+			ids = append(ids, in.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return gs.db.WithContext(ctx).Model(&models.GroupInvitation{}).Where("id IN ?", ids).Update("seen", true).Error
+}
+
+// Marks all unseen invitations of the user as seen with a single UPDATE and returns the number of updated invitations.
+func (gs *GroupStore) MarkAllInvitationsAsSeen(ctx context.Context, user *models.User) (int64, error) {
+	result := gs.db.WithContext(ctx).Model(&models.GroupInvitation{}).Where("user_id = ? AND seen = ?", user.Id, false).Update("seen", true)
+	return result.RowsAffected, result.Error
+}
+
+func (gs *GroupStore) GetInvitationByGroupAndUser(ctx context.Context, group *models.Group, user *models.User) (*models.GroupInvitation, error) {
 	var invitation models.GroupInvitation
-	err := gs.db.First(&invitation, "group_id = ? AND user_id = ?", group.Id, user.Id).Error
+	err := gs.db.WithContext(ctx).First(&invitation, "group_id = ? AND user_id = ?", group.Id, user.Id).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -618,11 +1151,73 @@ func (gs *GroupStore) GetInvitationByGroupAndUser(group *models.Group, user *mod
 	return &invitation, nil
 }
 
-func (gs *GroupStore) DeleteInvitation(invitation *models.GroupInvitation) error {
-	return gs.db.Delete(invitation).Error
+func (gs *GroupStore) DeleteInvitation(ctx context.Context, invitation *models.GroupInvitation) error {
+	return gs.db.WithContext(ctx).Delete(invitation).Error
 }
 
-func (gs *GroupStore) GetPaymentPlans(group *models.Group, user *models.User, searchInput string, page, pageSize int, descending bool) ([]models.PaymentPlan, error) {
+// Deletes the invitation, adds the user as a member of the group and books the opening balance of the invitation
+// with the given title. The opening balance was set by an admin, so the balance limits of the group don't apply to it.
+// Returns false if the invitation doesn't exist anymore, so concurrent requests can't accept it twice.
+// Either all or none of the changes are made, so an invitation to a full group (models.ErrGroupFull) can still be
+// accepted once there is room again.
+func (gs *GroupStore) AcceptInvitation(ctx context.Context, invitation *models.GroupInvitation, group *models.Group, user *models.User, openingBalanceTitle string) (bool, error) {
+	claimed := false
+	var transaction *models.TransactionLogEntry
+
+	unlock := transactionLocks.Lock("group:"+group.Id, "members:"+group.Id)
+	err := gs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(invitation)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		claimed = true
+
+		txStore := &GroupStore{db: tx}
+		err := txStore.addMember(ctx, group, user)
+		if err != nil {
+			return err
+		}
+
+		if invitation.OpeningBalance > 0 {
+			_, err = txStore.createTransaction(ctx, group, true, false, nil, user, openingBalanceTitle, "", invitation.OpeningBalance, "", false)
+		} else if invitation.OpeningBalance < 0 {
+			transaction, err = txStore.createTransaction(ctx, group, false, true, user, nil, openingBalanceTitle, "", -invitation.OpeningBalance, "", false)
+		}
+		return err
+	})
+	unlock()
+	if err != nil {
+		return false, err
+	}
+
+	if transaction != nil {
+		alertLowBalance(group, user, transaction)
+	}
+
+	return claimed, nil
+}
+
+func (gs *GroupStore) UpdateInvitationLastSent(ctx context.Context, invitation *models.GroupInvitation, lastSent int64) error {
+	return gs.db.WithContext(ctx).Model(invitation).Update("last_sent", lastSent).Error
+}
+
+// Returns an invitation which has already been accepted or denied.
+func (gs *GroupStore) GetDeletedInvitationById(ctx context.Context, id string) (*models.GroupInvitation, error) {
+	var invitation models.GroupInvitation
+	err := gs.db.WithContext(ctx).Unscoped().First(&invitation, "id = ? AND deleted_at IS NOT NULL", id).Error
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &invitation, nil
+}
+
+func (gs *GroupStore) GetPaymentPlans(ctx context.Context, group *models.Group, user *models.User, searchInput string, filter models.PaymentPlanFilter, page, pageSize int, descending bool) ([]models.PaymentPlan, error) {
 	var paymentPlans []models.PaymentPlan
 	var err error
 
@@ -631,22 +1226,68 @@ func (gs *GroupStore) GetPaymentPlans(group *models.Group, user *models.User, se
 		order = "DESC"
 	}
 
+	query := gs.filterPaymentPlans(ctx, group, user, filter).Where("name LIKE ?", "%"+searchInput+"%")
+
 	if page < 0 || pageSize < 0 {
-		err = gs.db.Order("next_execute "+order).Where("group_id = ? AND sender_id = ? AND name LIKE ?", group.Id, user.Id, "%"+searchInput+"%").Or("group_id = ? AND receiver_id = ? AND name LIKE ?", group.Id, user.Id, "%"+searchInput+"%").Find(&paymentPlans).Error
+		err = query.Order("next_execute " + order).Find(&paymentPlans).Error
 	} else {
-		err = gs.db.Order("next_execute "+order).Offset(page*pageSize).Limit(pageSize).Where("group_id = ? AND sender_id = ? AND name LIKE ?", group.Id, user.Id, "%"+searchInput+"%").Or("group_id = ? AND receiver_id = ? AND name LIKE ?", group.Id, user.Id, "%"+searchInput+"%").Find(&paymentPlans).Error
+		err = query.Order("next_execute " + order).Offset(page * pageSize).Limit(pageSize).Find(&paymentPlans).Error
+	}
+
+	return paymentPlans, err
+}
+
+// Returns the payment plans of the user which will be executed next, including plans with the bank.
+// A negative until includes all plans.
+func (gs *GroupStore) GetUpcomingPaymentPlans(ctx context.Context, group *models.Group, user *models.User, until int64, limit int) ([]models.PaymentPlan, error) {
+	var paymentPlans []models.PaymentPlan
+
+	query := gs.db.WithContext(ctx).Where("group_id = ? AND (sender_id = ? OR receiver_id = ?)", group.Id, user.Id, user.Id)
+	if until >= 0 {
+		query = query.Where("next_execute <= ?", until)
 	}
 
+	err := query.Order("next_execute ASC").Limit(limit).Find(&paymentPlans).Error
 	return paymentPlans, err
 }
 
-func (gs *GroupStore) PaymentPlanCount(group *models.Group, user *models.User) (int64, error) {
+func (gs *GroupStore) PaymentPlanCount(ctx context.Context, group *models.Group, user *models.User, filter models.PaymentPlanFilter) (int64, error) {
 	var count int64
-	err := gs.db.Model(&models.PaymentPlan{}).Where("group_id = ? AND sender_id = ?", group.Id, user.Id).Or("group_id = ? AND receiver_id = ?", group.Id, user.Id).Count(&count).Error
+	err := gs.filterPaymentPlans(ctx, group, user, filter).Model(&models.PaymentPlan{}).Count(&count).Error
 	return count, err
 }
 
-func (gs *GroupStore) GetBankPaymentPlans(group *models.Group, searchInput string, page, pageSize int, descending bool) ([]models.PaymentPlan, error) {
+// Returns a query matching the payment plans of the user in the group which pass the filter.
+func (gs *GroupStore) filterPaymentPlans(ctx context.Context, group *models.Group, user *models.User, filter models.PaymentPlanFilter) *gorm.DB {
+	sending := "sender_id = ?"
+	sendingArgs := []any{user.Id}
+	receiving := "receiver_id = ?"
+	receivingArgs := []any{user.Id}
+
+	if filter.CounterpartyId == "bank" {
+		sending += " AND receiver_is_bank = ?"
+		sendingArgs = append(sendingArgs, true)
+		receiving += " AND sender_is_bank = ?"
+		receivingArgs = append(receivingArgs, true)
+	} else if filter.CounterpartyId != "" {
+		sending += " AND receiver_id = ?"
+		sendingArgs = append(sendingArgs, filter.CounterpartyId)
+		receiving += " AND sender_id = ?"
+		receivingArgs = append(receivingArgs, filter.CounterpartyId)
+	}
+
+	query := gs.db.WithContext(ctx).Where("group_id = ?", group.Id)
+	switch filter.Direction {
+	case models.PaymentPlanDirectionSending:
+		return query.Where(sending, sendingArgs...)
+	case models.PaymentPlanDirectionReceiving:
+		return query.Where(receiving, receivingArgs...)
+	default:
+		return query.Where("(("+sending+") OR ("+receiving+"))", append(sendingArgs, receivingArgs...)...)
+	}
+}
+
+func (gs *GroupStore) GetBankPaymentPlans(ctx context.Context, group *models.Group, searchInput string, page, pageSize int, descending bool) ([]models.PaymentPlan, error) {
 	var paymentPlans []models.PaymentPlan
 	var err error
 
@@ -656,29 +1297,36 @@ func (gs *GroupStore) GetBankPaymentPlans(group *models.Group, searchInput strin
 	}
 
 	if page < 0 || pageSize < 0 {
-		err = gs.db.Order("next_execute "+order).Where("group_id = ? AND sender_is_bank = ? AND name LIKE ?", group.Id, true, "%"+searchInput+"%").Or("group_id = ? AND receiver_is_bank = ? AND name LIKE ?", group.Id, true, "%"+searchInput+"%").Find(&paymentPlans).Error
+		err = gs.db.WithContext(ctx).Order("next_execute "+order).Where("group_id = ? AND sender_is_bank = ? AND name LIKE ?", group.Id, true, "%"+searchInput+"%", "%"+searchInput+"%").Or("group_id = ? AND receiver_is_bank = ? AND name LIKE ?", group.Id, true, "%"+searchInput+"%").Find(&paymentPlans).Error
 	} else {
-		err = gs.db.Order("next_execute "+order).Where("group_id = ? AND sender_is_bank = ? AND name LIKE ?", group.Id, true, "%"+searchInput+"%").Or("group_id = ? AND receiver_is_bank = ? AND name LIKE ?", group.Id, true, "%"+searchInput+"%").Offset(page * pageSize).Limit(pageSize).Find(&paymentPlans).Error
+		err = gs.db.WithContext(ctx).Order("next_execute "+order).Where("group_id = ? AND sender_is_bank = ? AND name LIKE ?", group.Id, true, "%"+searchInput+"%", "%"+searchInput+"%").Or("group_id = ? AND receiver_is_bank = ? AND name LIKE ?", group.Id, true, "%"+searchInput+"%").Offset(page * pageSize).Limit(pageSize).Find(&paymentPlans).Error
 	}
 
 	return paymentPlans, err
 }
 
-func (gs *GroupStore) BankPaymentPlanCount(group *models.Group) (int64, error) {
+func (gs *GroupStore) BankPaymentPlanCount(ctx context.Context, group *models.Group) (int64, error) {
 	var count int64
-	err := gs.db.Model(&models.PaymentPlan{}).Where("group_id = ? AND sender_is_bank = ?", group.Id, true).Or("group_id = ? AND receiver_is_bank = ?", group.Id, true).Count(&count).Error
+	err := gs.db.WithContext(ctx).Model(&models.PaymentPlan{}).Where("group_id = ? AND sender_is_bank = ?", group.Id, true).Or("group_id = ? AND receiver_is_bank = ?", group.Id, true).Count(&count).Error
 	return count, err
 }
 
-func (gs *GroupStore) GetPaymentPlansThatNeedToBeExecuted() ([]models.PaymentPlan, error) {
+// Finished payment plans are deleted, so all stored payment plans of the group are active.
+func (gs *GroupStore) ActivePaymentPlanCount(ctx context.Context, group *models.Group) (int64, error) {
+	var count int64
+	err := gs.db.WithContext(ctx).Model(&models.PaymentPlan{}).Where("group_id = ?", group.Id).Count(&count).Error
+	return count, err
+}
+
+func (gs *GroupStore) GetPaymentPlansThatNeedToBeExecuted(ctx context.Context) ([]models.PaymentPlan, error) {
 	var paymentPlans []models.PaymentPlan
-	err := gs.db.Find(&paymentPlans, "next_execute <= ?", time.Now().Unix()).Error
+	err := gs.db.WithContext(ctx).Find(&paymentPlans, "next_execute <= ?", time.Now().Unix()).Error
 	return paymentPlans, err
 }
 
-func (gs *GroupStore) GetPaymentPlanById(group *models.Group, id string) (*models.PaymentPlan, error) {
+func (gs *GroupStore) GetPaymentPlanById(ctx context.Context, group *models.Group, id string) (*models.PaymentPlan, error) {
 	var paymentPlan models.PaymentPlan
-	err := gs.db.First(&paymentPlan, "group_id = ? AND id = ?", group.Id, id).Error
+	err := gs.db.WithContext(ctx).First(&paymentPlan, "group_id = ? AND id = ?", group.Id, id).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -691,18 +1339,20 @@ func (gs *GroupStore) GetPaymentPlanById(group *models.Group, id string) (*model
 	return &paymentPlan, nil
 }
 
-func (gs *GroupStore) CreatePaymentPlan(group *models.Group, senderIsBank, receiverIsBank bool, sender *models.User, receiver *models.User, name, description string, amount, paymentCount, schedule int, scheduleUnit string, firstPayment int64) (*models.PaymentPlan, error) {
+func (gs *GroupStore) CreatePaymentPlan(ctx context.Context, group *models.Group, senderIsBank, receiverIsBank bool, sender *models.User, receiver *models.User, name, description string, amount, paymentCount, schedule int, scheduleUnit string, firstPayment int64, timeZone string) (*models.PaymentPlan, error) {
 	paymentPlan := models.PaymentPlan{
-		Name:           name,
-		Description:    description,
-		Amount:         amount,
-		PaymentCount:   paymentCount,
-		NextExecute:    firstPayment,
-		Schedule:       schedule,
-		ScheduleUnit:   scheduleUnit,
-		SenderIsBank:   senderIsBank,
-		ReceiverIsBank: receiverIsBank,
-		GroupId:        group.Id,
+		Name:              name,
+		Description:       description,
+		Amount:            amount,
+		PaymentCount:      paymentCount,
+		TotalPaymentCount: paymentCount,
+		NextExecute:       firstPayment,
+		Schedule:          schedule,
+		ScheduleUnit:      scheduleUnit,
+		TimeZone:          timeZone,
+		SenderIsBank:      senderIsBank,
+		ReceiverIsBank:    receiverIsBank,
+		GroupId:           group.Id,
 	}
 
 	if !senderIsBank {
@@ -713,29 +1363,208 @@ func (gs *GroupStore) CreatePaymentPlan(group *models.Group, senderIsBank, recei
 		paymentPlan.ReceiverId = receiver.Id
 	}
 
-	err := gs.db.Create(&paymentPlan).Error
+	err := gs.db.WithContext(ctx).Create(&paymentPlan).Error
 
 	return &paymentPlan, err
 }
 
-func (gs *GroupStore) UpdatePaymentPlan(paymentPlan *models.PaymentPlan) error {
-	return gs.db.Updates(paymentPlan).Error
+// Creates one payment plan for every sender. Either all or none of the payment plans are created.
+func (gs *GroupStore) CreatePaymentPlans(ctx context.Context, group *models.Group, senders []models.User, receiverIsBank bool, receiver *models.User, name, description string, amount, paymentCount, schedule int, scheduleUnit string, firstPayment int64, timeZone string) ([]models.PaymentPlan, error) {
+	paymentPlans := make([]models.PaymentPlan, len(senders))
+	for i, sender := range senders {
+		paymentPlans[i] = models.PaymentPlan{
+			Name:              name,
+			Description:       description,
+			Amount:            amount,
+			PaymentCount:      paymentCount,
+			TotalPaymentCount: paymentCount,
+			NextExecute:       firstPayment,
+			Schedule:          schedule,
+			ScheduleUnit:      scheduleUnit,
+			TimeZone:          timeZone,
+			SenderId:          sender.Id,
+			ReceiverIsBank:    receiverIsBank,
+			GroupId:           group.Id,
+		}
+		if !receiverIsBank {
+			paymentPlans[i].ReceiverId = receiver.Id
+		}
+	}
+
+	err := gs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range paymentPlans {
+			if err := tx.Create(&paymentPlans[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return paymentPlans, nil
+}
+
+// Updates the non-zero fields of the payment plan or, if specified, exactly the given columns.
+func (gs *GroupStore) UpdatePaymentPlan(ctx context.Context, paymentPlan *models.PaymentPlan, fields ...string) error {
+	if len(fields) > 0 {
+		return gs.db.WithContext(ctx).Model(paymentPlan).Select(fields).Updates(paymentPlan).Error
+	}
+	return gs.db.WithContext(ctx).Updates(paymentPlan).Error
+}
+
+func (gs *GroupStore) DeletePaymentPlan(ctx context.Context, paymentPlan *models.PaymentPlan) error {
+	gs.db.WithContext(ctx).Model(&models.TransactionLogEntry{}).Where("payment_plan_id = ?", paymentPlan.Id).Update("payment_plan_id", "")
+	gs.db.WithContext(ctx).Delete(&models.PaymentPlanExecution{}, "payment_plan_id = ?", paymentPlan.Id)
+	return gs.db.WithContext(ctx).Delete(paymentPlan).Error
+}
+
+func (gs *GroupStore) AddPaymentPlanExecution(ctx context.Context, execution *models.PaymentPlanExecution) error {
+	return gs.db.WithContext(ctx).Create(execution).Error
+}
+
+// Creates the transaction of the next execution of the payment plan, records the execution and advances the payment plan.
+// The payment plan is deleted after its last payment, which leaves its PaymentCount at 0.
+// Returns models.ErrPaymentPlanChanged if the next execution changed since paymentPlan was loaded.
+func (gs *GroupStore) ExecutePaymentPlan(ctx context.Context, group *models.Group, paymentPlan *models.PaymentPlan, sender, receiver *models.User, title, description string) (*models.TransactionLogEntry, error) {
+	var transaction *models.TransactionLogEntry
+	next := *paymentPlan
+
+	// Skipping an execution takes the same lock, see SkipPaymentPlanExecution.
+	unlock := lockTransactions(group)
+	err := gs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txStore := &GroupStore{db: tx}
+
+		err := txStore.checkPaymentPlanUnchanged(ctx, paymentPlan)
+		if err != nil {
+			return err
+		}
+
+		transaction, err = txStore.createTransaction(ctx, group, paymentPlan.SenderIsBank, paymentPlan.ReceiverIsBank, sender, receiver, title, description, paymentPlan.Amount, paymentPlan.Id, true)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&models.PaymentPlanExecution{
+			PaymentPlanId: paymentPlan.Id,
+			GroupId:       group.Id,
+			ScheduledFor:  paymentPlan.NextExecute,
+			TransactionId: transaction.Id,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		next.NextExecute = services.NextPaymentPlanExecution(next.NextExecute, next.Schedule, next.ScheduleUnit, next.TimeZone)
+		if next.PaymentCount >= 0 {
+			next.PaymentCount--
+			if next.PaymentCount <= 0 {
+				return txStore.DeletePaymentPlan(ctx, &next)
+			}
+		}
+		return tx.Model(&next).Select("next_execute", "payment_count").Updates(&next).Error
+	})
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+	*paymentPlan = next
+
+	alertLowBalance(group, sender, transaction)
+
+	return transaction, nil
+}
+
+// Records the next execution of the payment plan as skipped and advances the payment plan to the following one.
+// Returns models.ErrPaymentPlanChanged if the next execution changed since paymentPlan was loaded.
+func (gs *GroupStore) SkipPaymentPlanExecution(ctx context.Context, group *models.Group, paymentPlan *models.PaymentPlan) error {
+	next := *paymentPlan
+
+	// Executing the payment plan takes the same lock, so a skip can't be overwritten by a concurrent execution.
+	unlock := lockTransactions(group)
+	defer unlock()
+	err := gs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := (&GroupStore{db: tx}).checkPaymentPlanUnchanged(ctx, paymentPlan)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&models.PaymentPlanExecution{
+			PaymentPlanId: paymentPlan.Id,
+			GroupId:       group.Id,
+			ScheduledFor:  paymentPlan.NextExecute,
+			Skipped:       true,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		next.NextExecute = services.NextPaymentPlanExecution(next.NextExecute, next.Schedule, next.ScheduleUnit, next.TimeZone)
+		return tx.Model(&next).Update("next_execute", next.NextExecute).Error
+	})
+	if err != nil {
+		return err
+	}
+	*paymentPlan = next
+	return nil
+}
+
+// Returns models.ErrPaymentPlanChanged if the payment plan was deleted or its next execution changed since it was loaded.
+func (gs *GroupStore) checkPaymentPlanUnchanged(ctx context.Context, paymentPlan *models.PaymentPlan) error {
+	var current models.PaymentPlan
+	err := gs.db.WithContext(ctx).Select("next_execute").First(&current, "id = ?", paymentPlan.Id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ErrPaymentPlanChanged
+	}
+	if err != nil {
+		return err
+	}
+	if current.NextExecute != paymentPlan.NextExecute {
+		return models.ErrPaymentPlanChanged
+	}
+	return nil
 }
 
-func (gs *GroupStore) DeletePaymentPlan(paymentPlan *models.PaymentPlan) error {
-	gs.db.Model(&models.TransactionLogEntry{}).Where("payment_plan_id = ?", paymentPlan.Id).Update("payment_plan_id", "")
-	return gs.db.Delete(paymentPlan).Error
+func (gs *GroupStore) GetPaymentPlanExecutions(ctx context.Context, paymentPlan *models.PaymentPlan, page, pageSize int, oldestFirst bool) ([]models.PaymentPlanExecution, error) {
+	var executions []models.PaymentPlanExecution
+	var err error
+
+	order := "DESC"
+	if oldestFirst {
+		order = "ASC"
+	}
+
+	if page < 0 || pageSize < 0 {
+		err = gs.db.WithContext(ctx).Order("scheduled_for "+order).Where("payment_plan_id = ?", paymentPlan.Id).Find(&executions).Error
+	} else {
+		err = gs.db.WithContext(ctx).Order("scheduled_for "+order).Offset(page*pageSize).Limit(pageSize).Where("payment_plan_id = ?", paymentPlan.Id).Find(&executions).Error
+	}
+
+	return executions, err
 }
 
-func (gs *GroupStore) GetTotalMoney(group *models.Group) (int, error) {
-	users, err := gs.GetMembers(nil, "", group, -1, -1, false)
+func (gs *GroupStore) PaymentPlanTransactionCount(ctx context.Context, paymentPlan *models.PaymentPlan) (int64, error) {
+	var count int64
+	err := gs.db.WithContext(ctx).Model(&models.TransactionLogEntry{}).Where("payment_plan_id = ?", paymentPlan.Id).Count(&count).Error
+	return count, err
+}
+
+func (gs *GroupStore) PaymentPlanExecutionCount(ctx context.Context, paymentPlan *models.PaymentPlan) (int64, error) {
+	var count int64
+	err := gs.db.WithContext(ctx).Model(&models.PaymentPlanExecution{}).Where("payment_plan_id = ?", paymentPlan.Id).Count(&count).Error
+	return count, err
+}
+
+func (gs *GroupStore) GetTotalMoney(ctx context.Context, group *models.Group) (int, error) {
+	users, err := gs.GetMembers(ctx, nil, "", group, -1, -1, false, false)
 	if err != nil {
 		return 0, err
 	}
 
 	total := 0
 	for _, u := range users {
-		balance, err := gs.GetUserBalance(group, &u)
+		balance, err := gs.GetUserBalance(ctx, group, &u)
 		if err != nil {
 			return 0, err
 		}
@@ -745,11 +1574,199 @@ func (gs *GroupStore) GetTotalMoney(group *models.Group) (int, error) {
 	return total, nil
 }
 
-func (gs *GroupStore) AreInSameGroup(userId1, userId2 string) (bool, error) {
+// Returns the transactions sent directly from userA to userB or from userB to userA, newest first.
+func (gs *GroupStore) GetTransactionsBetween(ctx context.Context, group *models.Group, userA, userB *models.User) ([]models.TransactionLogEntry, error) {
+	var log []models.TransactionLogEntry
+	err := gs.db.WithContext(ctx).Order("created DESC, sequence DESC").Where("group_id = ? AND sender_id = ? AND receiver_id = ?", group.Id, userA.Id, userB.Id).Or("group_id = ? AND sender_id = ? AND receiver_id = ?", group.Id, userB.Id, userA.Id).Find(&log).Error
+	return log, err
+}
+
+// Selects the other party of a transaction of the user, "bank" for the bank. Takes the user id and true as arguments.
+const counterpartyColumn = "case when sender_id = ? then (case when receiver_is_bank = ? then 'bank' else receiver_id end) else (case when sender_is_bank = ? then 'bank' else sender_id end) end"
+
+// Returns the sums of the amounts the user sent to and received from every counterparty in the group,
+// ordered by the absolute net amount in descending order.
+func (gs *GroupStore) GetCounterpartyTotals(ctx context.Context, group *models.Group, user *models.User, page, pageSize int) ([]models.CounterpartyTotals, error) {
+	var totals []models.CounterpartyTotals
+	err := gs.db.WithContext(ctx).Raw(`select c.counterparty_id, cast(sum(c.sent) as bigint) as sent, cast(sum(c.received) as bigint) as received
+		from (
+			select `+counterpartyColumn+` as counterparty_id,
+			case when sender_id = ? then amount else 0 end as sent,
+			case when receiver_id = ? then amount else 0 end as received
+			from transaction_log_entries
+			where group_id = ? and (sender_id = ? or receiver_id = ?)
+		) c
+		group by c.counterparty_id
+		order by abs(sum(c.received) - sum(c.sent)) desc, c.counterparty_id
+		limit ? offset ?`, user.Id, true, true, user.Id, user.Id, group.Id, user.Id, user.Id, pageSize, page*pageSize).Scan(&totals).Error
+	return totals, err
+}
+
+func (gs *GroupStore) CounterpartyCount(ctx context.Context, group *models.Group, user *models.User) (int64, error) {
+	var count int64
+	err := gs.db.WithContext(ctx).Model(&models.TransactionLogEntry{}).
+		Select("count(distinct "+counterpartyColumn+")", user.Id, true, true).
+		Where("group_id = ? AND (sender_id = ? OR receiver_id = ?)", group.Id, user.Id, user.Id).
+		Scan(&count).Error
+	return count, err
+}
+
+// Returns the changes of the total balance of the members per day for all transactions created before the given time.
+// Transactions between members don't change the total and are ignored.
+func (gs *GroupStore) GetDailyBalanceChanges(ctx context.Context, group *models.Group, before int64) ([]models.DailyBalanceChange, error) {
+	var changes []models.DailyBalanceChange
+	err := gs.db.WithContext(ctx).Model(&models.TransactionLogEntry{}).
+		Select("created / 86400 as day, cast(sum(case when sender_is_bank = ? then amount else -amount end) as bigint) as change", true).
+		Where("group_id = ? AND created < ? AND (sender_is_bank = ? OR receiver_is_bank = ?)", group.Id, before, true, true).
+		Group("created / 86400").Order("day").Scan(&changes).Error
+	return changes, err
+}
+
+// Returns the balances of the user in all groups they are a member of, ordered by group name.
+func (gs *GroupStore) GetBalancesByUser(ctx context.Context, user *models.User) ([]models.GroupBalance, error) {
+	var balances []models.GroupBalance
+	err := gs.db.WithContext(ctx).Raw(`select g.id as group_id, g.name as group_name, g.unit as unit,
+		coalesce(case when t.sender_id = ? then t.new_balance_sender else t.new_balance_receiver end, 0) as balance
+		from group_memberships m
+		join "groups" g on g.id = m.group_id
+		left join transaction_log_entries t on t.id = (
+			select t2.id from transaction_log_entries t2
+			where t2.group_id = m.group_id and (t2.sender_id = ? or t2.receiver_id = ?)
+			order by t2.sequence desc limit 1
+		)
+		where m.user_id = ? and m.is_member = ?
+		order by g.name`, user.Id, user.Id, user.Id, user.Id, true).Scan(&balances).Error
+	return balances, err
+}
+
+// Returns the sum of the balances of the user in all groups the user is a member of.
+func (gs *GroupStore) GetTotalBalanceByUser(ctx context.Context, user *models.User) (int, error) {
+	var total int
+	err := gs.db.WithContext(ctx).Raw(`select cast(coalesce(sum(case when t.sender_id = ? then t.new_balance_sender else t.new_balance_receiver end), 0) as bigint)
+		from group_memberships m
+		join transaction_log_entries t on t.id = (
+			select t2.id from transaction_log_entries t2
+			where t2.group_id = m.group_id and (t2.sender_id = ? or t2.receiver_id = ?)
+			order by t2.sequence desc limit 1
+		)
+		where m.user_id = ? and m.is_member = ?`, user.Id, user.Id, user.Id, user.Id, true).Scan(&total).Error
+	return total, err
+}
+
+// Counts the groups of all users.
+func (gs *GroupStore) CountAll(ctx context.Context) (int64, error) {
+	var count int64
+	err := gs.db.WithContext(ctx).Model(&models.Group{}).Count(&count).Error
+	return count, err
+}
+
+// Returns the sum of the balances of all members of all groups.
+func (gs *GroupStore) GetTotalBalance(ctx context.Context) (int, error) {
+	var total int
+	err := gs.db.WithContext(ctx).Raw(`select cast(coalesce(sum(case when t.sender_id = m.user_id then t.new_balance_sender else t.new_balance_receiver end), 0) as bigint)
+		from group_memberships m
+		join transaction_log_entries t on t.id = (
+			select t2.id from transaction_log_entries t2
+			where t2.group_id = m.group_id and (t2.sender_id = m.user_id or t2.receiver_id = m.user_id)
+			order by t2.sequence desc limit 1
+		)
+		where m.is_member = ?`, true).Scan(&total).Error
+	return total, err
+}
+
+func (gs *GroupStore) AreInSameGroup(ctx context.Context, userId1, userId2 string) (bool, error) {
 	var count int
-	err := gs.db.Raw("select count(*) from group_memberships where group_memberships.user_id = ? and group_memberships.group_id in (select group_memberships.group_id from group_memberships where group_memberships.user_id = ?)", userId1, userId2).Scan(&count).Error
+	err := gs.db.WithContext(ctx).Raw("select count(*) from group_memberships where group_memberships.user_id = ? and (group_memberships.is_member = ? or group_memberships.is_admin = ?) and group_memberships.group_id in (select group_memberships.group_id from group_memberships where group_memberships.user_id = ? and (group_memberships.is_member = ? or group_memberships.is_admin = ?))", userId1, true, true, userId2, true, true).Scan(&count).Error
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
+
+func (gs *GroupStore) GetActivity(ctx context.Context, user *models.User, beforeTime int64, beforeId string, limit int) ([]models.Activity, error) {
+	cursor := gs.db.WithContext(ctx).Where("created < ?", beforeTime).Or("created = ? AND id < ?", beforeTime, beforeId)
+
+	var transactions []models.TransactionLogEntry
+	err := gs.db.WithContext(ctx).Where(cursor).Where(gs.db.WithContext(ctx).Where("sender_id = ?", user.Id).Or("receiver_id = ?", user.Id)).Order("created DESC, id DESC").Limit(limit).Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var invitations []models.GroupInvitation
+	err = gs.db.WithContext(ctx).Where(cursor).Where("user_id = ?", user.Id).Order("created DESC, id DESC").Limit(limit).Find(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var adminChanges []models.AdminChange
+	err = gs.db.WithContext(ctx).Where(cursor).Where("user_id = ?", user.Id).Order("created DESC, id DESC").Limit(limit).Find(&adminChanges).Error
+	if err != nil {
+		return nil, err
+	}
+
+	activities := make([]models.Activity, 0, len(transactions)+len(invitations)+len(adminChanges))
+	for i := range transactions {
+		activities = append(activities, models.Activity{
+			Type:        models.ActivityTransaction,
+			Time:        transactions[i].Created,
+			Id:          transactions[i].Id,
+			Transaction: &transactions[i],
+		})
+	}
+	for i := range invitations {
+		activities = append(activities, models.Activity{
+			Type:       models.ActivityInvitation,
+			Time:       invitations[i].Created,
+			Id:         invitations[i].Id,
+			Invitation: &invitations[i],
+		})
+	}
+	for i := range adminChanges {
+		activities = append(activities, models.Activity{
+			Type:        models.ActivityAdminChange,
+			Time:        adminChanges[i].Created,
+			Id:          adminChanges[i].Id,
+			AdminChange: &adminChanges[i],
+		})
+	}
+
+	sort.Slice(activities, func(i, j int) bool {
+		if activities[i].Time == activities[j].Time {
+			return activities[i].Id > activities[j].Id
+		}
+		return activities[i].Time > activities[j].Time
+	})
+
+	if len(activities) > limit {
+		activities = activities[:limit]
+	}
+
+	return activities, nil
+}
+
+func (gs *GroupStore) AddAuditLogEntry(ctx context.Context, entry *models.GroupAuditLogEntry) error {
+	return gs.db.WithContext(ctx).Create(entry).Error
+}
+
+func (gs *GroupStore) GetAuditLog(ctx context.Context, group *models.Group, page, pageSize int, oldestFirst bool) ([]models.GroupAuditLogEntry, error) {
+	order := "DESC"
+	if oldestFirst {
+		order = "ASC"
+	}
+
+	var entries []models.GroupAuditLogEntry
+	var err error
+	if page < 0 || pageSize < 0 {
+		err = gs.db.WithContext(ctx).Order("created "+order).Find(&entries, "group_id = ?", group.Id).Error
+	} else {
+		err = gs.db.WithContext(ctx).Order("created "+order).Offset(page*pageSize).Limit(pageSize).Find(&entries, "group_id = ?", group.Id).Error
+	}
+
+	return entries, err
+}
+
+func (gs *GroupStore) AuditLogEntryCount(ctx context.Context, group *models.Group) (int64, error) {
+	var count int64
+	err := gs.db.WithContext(ctx).Model(&models.GroupAuditLogEntry{}).Where("group_id = ?", group.Id).Count(&count).Error
+	return count, err
+}
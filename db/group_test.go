@@ -0,0 +1,840 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/juho05/h-bank/config"
+	"github.com/juho05/h-bank/models"
+)
+
+func TestGroupStore_CreateTransaction_LowBalance(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	user := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user)
+
+	group := &models.Group{
+		Name: "group",
+		GroupSettings: models.GroupSettings{
+			LowBalanceAlert:     true,
+			LowBalanceThreshold: 100,
+		},
+	}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user)
+
+	var alerts []int
+	LowBalanceHandler = func(group *models.Group, user *models.User, balance int) {
+		alerts = append(alerts, balance)
+	}
+	defer func() { LowBalanceHandler = nil }()
+
+	// Balances are taken from the latest transaction, so every transaction needs a distinct creation time.
+	created := time.Now().Unix()
+	transfer := func(fromBank bool, amount int) {
+		var transaction *models.TransactionLogEntry
+		if fromBank {
+			transaction, err = gs.CreateTransaction(context.Background(), group, true, false, nil, user, "Pocket money", "", amount)
+		} else {
+			transaction, err = gs.CreateTransaction(context.Background(), group, false, true, user, nil, "Snacks", "", amount)
+		}
+		if err != nil {
+			t.Fatalf("Couldn't create transaction")
+		}
+		created++
+		database.Model(transaction).Update("created", created)
+	}
+
+	transfer(true, 200)
+	transfer(false, 50)
+	assert.Empty(t, alerts)
+
+	transfer(false, 60)
+	assert.Equal(t, []int{90}, alerts)
+
+	transfer(false, 10)
+	assert.Equal(t, []int{90}, alerts, "no repeated alert while below the threshold")
+
+	transfer(true, 100)
+	transfer(false, 100)
+	assert.Equal(t, []int{90, 80}, alerts)
+}
+
+func TestGroupStore_CreateTransaction_Concurrent(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	user1 := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), user2)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+
+	_, err = gs.CreateTransaction(context.Background(), group, true, false, nil, user1, "Pocket money", "", 1000)
+	if err != nil {
+		t.Fatalf("Couldn't create transaction")
+	}
+
+	const count = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*count)
+	for i := 0; i < count; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := gs.CreateTransaction(context.Background(), group, false, false, user1, user2, "Gift", "", 10)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := gs.CreateTransaction(context.Background(), group, false, true, user2, nil, "Snacks", "", 1)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	balance1, err := gs.GetUserBalance(context.Background(), group, user1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000-count*10, balance1)
+
+	balance2, err := gs.GetUserBalance(context.Background(), group, user2)
+	assert.NoError(t, err)
+	assert.Equal(t, count*10-count*1, balance2)
+
+	transactions, err := gs.GetTransactionsInPeriod(context.Background(), group, 0, math.MaxInt64)
+	assert.NoError(t, err)
+	slices.SortFunc(transactions, func(a, b models.TransactionLogEntry) int {
+		return a.Sequence - b.Sequence
+	})
+	// Every transaction has to continue from the balances of the previous transaction of the member.
+	balances := make(map[string]int)
+	for i, transaction := range transactions {
+		assert.Equal(t, i+1, transaction.Sequence)
+		assert.LessOrEqual(t, transaction.Created, time.Now().Unix(), "creation times must not be in the future")
+		if !transaction.SenderIsBank {
+			assert.Equal(t, balances[transaction.SenderId]-transaction.Amount, transaction.NewBalanceSender)
+			balances[transaction.SenderId] = transaction.NewBalanceSender
+		}
+		if !transaction.ReceiverIsBank {
+			assert.Equal(t, balances[transaction.ReceiverId]+transaction.Amount, transaction.NewBalanceReceiver)
+			balances[transaction.ReceiverId] = transaction.NewBalanceReceiver
+		}
+	}
+
+	references := make(map[string]bool, len(transactions))
+	for _, transaction := range transactions {
+		assert.False(t, references[transaction.Reference], "references must be unique within the group")
+		references[transaction.Reference] = true
+	}
+	assert.True(t, references[models.TransactionReference(group.Id, 2*count+1)])
+}
+
+func TestGroupStore_CreateTransaction_OverdraftProtection(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	user1 := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), user2)
+
+	group := &models.Group{Name: "group"}
+	group.OverdraftProtection = true
+	group.MinBalance = -50
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+
+	unprotected := &models.Group{Name: "unprotected"}
+	gs.Create(context.Background(), unprotected)
+	gs.AddMember(context.Background(), unprotected, user1)
+	gs.AddMember(context.Background(), unprotected, user2)
+
+	_, err = gs.CreateTransaction(context.Background(), group, true, false, nil, user1, "Pocket money", "", 100)
+	assert.NoError(t, err)
+
+	_, err = gs.CreateTransaction(context.Background(), group, false, false, user1, user2, "Gift", "", 151)
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+	_, err = gs.CreateTransaction(context.Background(), group, false, true, user1, nil, "Fee", "", 151)
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+	_, err = gs.CreateTransaction(context.Background(), group, true, false, nil, user2, "Pocket money", "", 1000)
+	assert.NoError(t, err, "the bank is exempt")
+	_, err = gs.CreateTransaction(context.Background(), unprotected, false, false, user1, user2, "Gift", "", 151)
+	assert.NoError(t, err, "groups without overdraft protection don't check the minimum balance")
+
+	// Concurrent transactions must not overdraw the balance together.
+	const count = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := gs.CreateTransaction(context.Background(), group, false, false, user1, user2, "Gift", "", 50)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+		}
+	}
+	assert.Equal(t, 3, succeeded)
+
+	balance, err := gs.GetUserBalance(context.Background(), group, user1)
+	assert.NoError(t, err)
+	assert.Equal(t, -50, balance)
+}
+
+func TestGroupStore_GetTotalBalance(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	total, err := gs.GetTotalBalance(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	alice := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), alice)
+
+	group1 := &models.Group{Name: "group1"}
+	gs.Create(context.Background(), group1)
+	gs.AddMember(context.Background(), group1, bob)
+	gs.AddMember(context.Background(), group1, alice)
+	group2 := &models.Group{Name: "group2"}
+	gs.Create(context.Background(), group2)
+	gs.AddMember(context.Background(), group2, bob)
+
+	gs.CreateTransaction(context.Background(), group1, true, false, nil, bob, "Pocket money", "", 500)
+	gs.CreateTransaction(context.Background(), group1, false, false, bob, alice, "Gift", "", 200)
+	gs.CreateTransaction(context.Background(), group1, false, true, alice, nil, "Snacks", "", 50)
+	gs.CreateTransaction(context.Background(), group2, true, false, nil, bob, "Pocket money", "", 1000)
+
+	total, err = gs.GetTotalBalance(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 300+150+1000, total)
+
+	groups, err := gs.CountAll(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), groups)
+
+	us.AddCashLogEntry(context.Background(), bob, &models.CashLogEntry{ChangeTitle: "Count", Eur5: 1})
+	us.AddCashLogEntry(context.Background(), bob, &models.CashLogEntry{ChangeTitle: "Count", Eur1: 2})
+	us.AddCashLogEntry(context.Background(), alice, &models.CashLogEntry{ChangeTitle: "Count", Ct50: 1})
+
+	cash, err := us.GetTotalCash(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 200+50, cash)
+}
+
+func TestGroupStore_MarkAllInvitationsAsSeen(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	alice := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), alice)
+
+	for _, name := range []string{"group1", "group2", "group3"} {
+		group := &models.Group{Name: name}
+		gs.Create(context.Background(), group)
+		gs.CreateInvitation(context.Background(), group, bob, nil, "", 0)
+	}
+	group := &models.Group{Name: "group4"}
+	gs.Create(context.Background(), group)
+	gs.CreateInvitation(context.Background(), group, alice, nil, "", 0)
+
+	count, err := gs.MarkAllInvitationsAsSeen(context.Background(), bob)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	unseen, err := gs.UnseenInvitationCountByUser(context.Background(), bob)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), unseen)
+
+	unseen, err = gs.UnseenInvitationCountByUser(context.Background(), alice)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), unseen)
+
+	count, err = gs.MarkAllInvitationsAsSeen(context.Background(), bob)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestGroupStore_TransactionReference(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+
+	group1 := &models.Group{Name: "group1"}
+	gs.Create(context.Background(), group1)
+	gs.AddMember(context.Background(), group1, bob)
+	group2 := &models.Group{Name: "group2"}
+	gs.Create(context.Background(), group2)
+	gs.AddMember(context.Background(), group2, bob)
+
+	first, err := gs.CreateTransaction(context.Background(), group1, true, false, nil, bob, "Pocket money", "", 500)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionReference(group1.Id, 1), first.Reference)
+
+	second, err := gs.CreateTransaction(context.Background(), group1, false, true, bob, nil, "Snacks", "", 50)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionReference(group1.Id, 2), second.Reference)
+
+	// Updating a group with a stale transaction count must not reset the counter.
+	group1.Description = "description"
+	group1.TransactionCount = 1
+	assert.NoError(t, gs.Update(context.Background(), group1))
+	group, err := gs.GetById(context.Background(), group1.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, group.TransactionCount)
+
+	other, err := gs.CreateTransaction(context.Background(), group2, true, false, nil, bob, "Pocket money", "", 500)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionReference(group2.Id, 1), other.Reference)
+
+	log, err := gs.GetTransactionLog(context.Background(), group1, bob, second.Reference, models.TransactionFilter{}, -1, -1, false)
+	assert.NoError(t, err)
+	if assert.Len(t, log, 1) {
+		assert.Equal(t, second.Id, log[0].Id)
+	}
+
+	// Transactions created before references existed get one when migrating.
+	database.Exec("DROP INDEX idx_transaction_log_entries_reference")
+	database.Exec("DROP INDEX idx_transaction_log_entries_sequence")
+	database.Model(&models.TransactionLogEntry{}).Where("group_id = ?", group1.Id).Updates(map[string]any{"reference": "", "sequence": 0})
+	database.Model(&models.TransactionLogEntry{}).Where("id = ?", first.Id).Update("created", 100)
+	database.Model(&models.TransactionLogEntry{}).Where("id = ?", second.Id).Update("created", 200)
+	database.Model(&models.Group{}).Where("id = ?", group1.Id).Update("transaction_count", 0)
+	// Transactions created before sequence numbers existed get the number of their reference.
+	database.Model(&models.TransactionLogEntry{}).Where("group_id = ?", group2.Id).Update("sequence", 0)
+	err = AutoMigrate(database)
+	assert.NoError(t, err)
+
+	first, err = gs.GetTransactionLogEntryById(context.Background(), group1, first.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionReference(group1.Id, 1), first.Reference)
+	assert.Equal(t, 1, first.Sequence)
+	second, err = gs.GetTransactionLogEntryById(context.Background(), group1, second.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionReference(group1.Id, 2), second.Reference)
+	assert.Equal(t, 2, second.Sequence)
+	other, err = gs.GetTransactionLogEntryById(context.Background(), group2, other.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, other.Sequence)
+
+	third, err := gs.CreateTransaction(context.Background(), group1, false, true, bob, nil, "Snacks", "", 50)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionReference(group1.Id, 3), third.Reference)
+}
+
+func TestGroupStore_PaymentPlanTotalPaymentCount(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+
+	limited, err := gs.CreatePaymentPlan(context.Background(), group, true, false, nil, bob, "Pocket money", "", 100, 5, 1, models.ScheduleUnitWeek, 100, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, limited.TotalPaymentCount)
+	unlimited, err := gs.CreatePaymentPlan(context.Background(), group, true, false, nil, bob, "Pocket money", "", 100, -1, 1, models.ScheduleUnitWeek, 100, "")
+	assert.NoError(t, err)
+	assert.Equal(t, -1, unlimited.TotalPaymentCount)
+
+	gs.CreateTransactionFromPaymentPlan(context.Background(), group, true, false, nil, bob, "Pocket money", "", 100, limited.Id)
+	limited.PaymentCount = 4
+	assert.NoError(t, gs.UpdatePaymentPlan(context.Background(), limited))
+
+	// Payment plans created before the total was stored get it when migrating.
+	database.Model(&models.PaymentPlan{}).Where("group_id = ?", group.Id).Update("total_payment_count", 0)
+	err = AutoMigrate(database)
+	assert.NoError(t, err)
+
+	limited, err = gs.GetPaymentPlanById(context.Background(), group, limited.Id)
+	if assert.NoError(t, err) && assert.NotNil(t, limited) {
+		assert.Equal(t, 4, limited.PaymentCount)
+		assert.Equal(t, 5, limited.TotalPaymentCount)
+	}
+	unlimited, err = gs.GetPaymentPlanById(context.Background(), group, unlimited.Id)
+	if assert.NoError(t, err) && assert.NotNil(t, unlimited) {
+		assert.Equal(t, -1, unlimited.TotalPaymentCount)
+	}
+}
+
+func TestGroupStore_SettleBalanceAndRemoveMember(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	tests := []struct {
+		name             string
+		policy           string
+		balance          int
+		wantTransactions int
+		wantBalances     []int
+	}{
+		{name: "Keep", policy: models.LeaveBalanceKeep, balance: 100, wantTransactions: 0, wantBalances: []int{0, 0, 0}},
+		{name: "Bank", policy: models.LeaveBalanceBank, balance: 100, wantTransactions: 1, wantBalances: []int{0, 0, 0}},
+		{name: "Redistribute", policy: models.LeaveBalanceRedistribute, balance: 100, wantTransactions: 3, wantBalances: []int{34, 33, 33}},
+		{name: "Redistribute debt", policy: models.LeaveBalanceRedistribute, balance: -4, wantTransactions: 3, wantBalances: []int{-2, -1, -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &models.Group{Name: tt.name, GroupSettings: models.GroupSettings{LeaveBalancePolicy: tt.policy}}
+			gs.Create(context.Background(), group)
+
+			leaving := &models.User{Name: "bob", Email: fmt.Sprintf("bob-%s@gmail.com", tt.name)}
+			us.Create(context.Background(), leaving)
+			gs.AddMember(context.Background(), group, leaving)
+
+			remaining := make([]*models.User, len(tt.wantBalances))
+			for i := range remaining {
+				remaining[i] = &models.User{Name: "alice", Email: fmt.Sprintf("alice%d-%s@gmail.com", i, tt.name)}
+				us.Create(context.Background(), remaining[i])
+				gs.AddMember(context.Background(), group, remaining[i])
+			}
+			slices.SortFunc(remaining, func(a, b *models.User) int {
+				return strings.Compare(a.Id, b.Id)
+			})
+
+			if tt.balance > 0 {
+				gs.CreateTransaction(context.Background(), group, true, false, nil, leaving, "Pocket money", "", tt.balance)
+			} else {
+				gs.CreateTransaction(context.Background(), group, false, true, leaving, nil, "Snacks", "", -tt.balance)
+			}
+
+			transactions, err := gs.SettleBalanceAndRemoveMember(context.Background(), group, leaving, "Settlement")
+			assert.NoError(t, err)
+			assert.Len(t, transactions, tt.wantTransactions)
+
+			isMember, err := gs.IsMember(context.Background(), group, leaving)
+			assert.NoError(t, err)
+			assert.False(t, isMember)
+
+			balance, err := gs.GetUserBalance(context.Background(), group, leaving)
+			assert.NoError(t, err)
+			if tt.wantTransactions == 0 {
+				assert.Equal(t, tt.balance, balance)
+			} else {
+				assert.Equal(t, 0, balance)
+			}
+
+			for i, user := range remaining {
+				balance, err := gs.GetUserBalance(context.Background(), group, user)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantBalances[i], balance)
+			}
+		})
+	}
+}
+
+func TestGroupStore_GetCounterpartyTotals(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	alice := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), alice)
+	carol := &models.User{Name: "carol", Email: "carol@gmail.com"}
+	us.Create(context.Background(), carol)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+	gs.AddMember(context.Background(), group, alice)
+	gs.AddMember(context.Background(), group, carol)
+
+	gs.CreateTransaction(context.Background(), group, true, false, nil, bob, "Pocket money", "", 1000)
+	gs.CreateTransaction(context.Background(), group, false, true, bob, nil, "Fee", "", 50)
+	gs.CreateTransaction(context.Background(), group, false, false, bob, alice, "Gift", "", 300)
+	gs.CreateTransaction(context.Background(), group, false, false, alice, bob, "Refund", "", 100)
+	gs.CreateTransaction(context.Background(), group, false, false, carol, bob, "Dinner", "", 20)
+	gs.CreateTransaction(context.Background(), group, false, false, alice, carol, "Lunch", "", 500)
+
+	totals, err := gs.GetCounterpartyTotals(context.Background(), group, bob, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CounterpartyTotals{
+		{CounterpartyId: "bank", Sent: 50, Received: 1000},
+		{CounterpartyId: alice.Id, Sent: 300, Received: 100},
+		{CounterpartyId: carol.Id, Sent: 0, Received: 20},
+	}, totals)
+
+	count, err := gs.CounterpartyCount(context.Background(), group, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	totals, err = gs.GetCounterpartyTotals(context.Background(), group, bob, 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CounterpartyTotals{{CounterpartyId: carol.Id, Sent: 0, Received: 20}}, totals)
+}
+
+func TestGroupStore_GetByIds(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+
+	member := &models.Group{Name: "b-member"}
+	gs.Create(context.Background(), member)
+	gs.AddMember(context.Background(), member, bob)
+	admin := &models.Group{Name: "a-admin"}
+	gs.Create(context.Background(), admin)
+	gs.AddAdmin(context.Background(), admin, bob)
+	left := &models.Group{Name: "left"}
+	gs.Create(context.Background(), left)
+	gs.AddMember(context.Background(), left, bob)
+	gs.RemoveMember(context.Background(), left, bob)
+	foreign := &models.Group{Name: "foreign"}
+	gs.Create(context.Background(), foreign)
+
+	groups, err := gs.GetByIds(context.Background(), []string{member.Id, admin.Id, left.Id, foreign.Id, "unknown"}, bob)
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 2) {
+		assert.Equal(t, admin.Id, groups[0].Id)
+		assert.Equal(t, member.Id, groups[1].Id)
+	}
+}
+
+func TestGroupStore_CreateTransaction_Parties(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	alice := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), alice)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+	gs.AddMember(context.Background(), group, alice)
+
+	tests := []struct {
+		name           string
+		senderIsBank   bool
+		receiverIsBank bool
+		sender         *models.User
+		receiver       *models.User
+		wantErr        bool
+	}{
+		{name: "Member to member", sender: bob, receiver: alice},
+		{name: "Bank to member", senderIsBank: true, receiver: alice},
+		{name: "Member to bank", receiverIsBank: true, sender: bob},
+		{name: "Bank to bank", senderIsBank: true, receiverIsBank: true, wantErr: true},
+		{name: "Bank sender with user", senderIsBank: true, sender: bob, receiver: alice, wantErr: true},
+		{name: "Bank receiver with user", receiverIsBank: true, sender: bob, receiver: alice, wantErr: true},
+		{name: "Missing sender", receiver: alice, wantErr: true},
+		{name: "Missing receiver", sender: bob, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transaction, err := gs.CreateTransaction(context.Background(), group, tt.senderIsBank, tt.receiverIsBank, tt.sender, tt.receiver, "Test", "", 10)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, models.ErrInvalidTransactionParties)
+				assert.Nil(t, transaction)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, transaction)
+			}
+		})
+	}
+
+	err = gs.ImportTransactions(context.Background(), group, []models.TransactionLogEntry{
+		{Base: models.Base{Created: math.MaxInt32}, Title: "Import", Amount: 10, SenderIsBank: true, SenderId: bob.Id, ReceiverId: alice.Id},
+	})
+	assert.ErrorIs(t, err, models.ErrInvalidTransactionParties)
+
+	err = gs.ImportTransactions(context.Background(), group, []models.TransactionLogEntry{
+		{Base: models.Base{Created: 1}, Title: "Import", Amount: 10, SenderIsBank: true, ReceiverId: alice.Id},
+	})
+	assert.ErrorIs(t, err, models.ErrImportNotNewer)
+}
+
+func TestGroupStore_AddMember_MaxUsers(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	maxUsers := config.Data.MaxUsersPerGroup
+	t.Cleanup(func() {
+		config.Data.MaxUsersPerGroup = maxUsers
+	})
+	config.Data.MaxUsersPerGroup = 3
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	users := make([]*models.User, 6)
+	for i := range users {
+		users[i] = &models.User{Name: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@maxusers.com", i)}
+		us.Create(context.Background(), users[i])
+	}
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	assert.NoError(t, gs.AddAdmin(context.Background(), group, users[0]))
+	// Admins already count as users.
+	assert.NoError(t, gs.AddMember(context.Background(), group, users[0]))
+
+	invitation, err := gs.CreateInvitation(context.Background(), group, users[5], nil, "", 0)
+	if err != nil {
+		t.Fatalf("Couldn't create invitation")
+	}
+
+	// Concurrent additions must not exceed the limit together.
+	errs := make(chan error, 4)
+	var wg sync.WaitGroup
+	for _, u := range users[1:5] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- gs.AddMember(context.Background(), group, u)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	added := 0
+	for err := range errs {
+		if err == nil {
+			added++
+		} else {
+			assert.ErrorIs(t, err, models.ErrGroupFull)
+		}
+	}
+	assert.Equal(t, 2, added)
+
+	count, err := gs.GetUserCount(context.Background(), group)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	// The invitation to the full group is kept, so it can be accepted later.
+	claimed, err := gs.AcceptInvitation(context.Background(), invitation, group, users[5], "Opening balance")
+	assert.ErrorIs(t, err, models.ErrGroupFull)
+	assert.False(t, claimed)
+	stored, err := gs.GetInvitationById(context.Background(), invitation.Id)
+	assert.NoError(t, err)
+	assert.NotNil(t, stored)
+
+	config.Data.MaxUsersPerGroup = 0
+	claimed, err = gs.AcceptInvitation(context.Background(), invitation, group, users[5], "Opening balance")
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	isMember, err := gs.IsMember(context.Background(), group, users[5])
+	assert.NoError(t, err)
+	assert.True(t, isMember)
+
+	// A second request can't accept the invitation again.
+	claimed, err = gs.AcceptInvitation(context.Background(), invitation, group, users[5], "Opening balance")
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+}
+
+func TestGroupStore_SkipPaymentPlanExecution(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+
+	first := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()
+	paymentPlan, err := gs.CreatePaymentPlan(context.Background(), group, true, false, nil, bob, "Pocket money", "", 100, 3, 1, models.ScheduleUnitWeek, first, "")
+	if err != nil {
+		t.Fatalf("Couldn't create payment plan")
+	}
+	stale := *paymentPlan
+
+	err = gs.SkipPaymentPlanExecution(context.Background(), group, paymentPlan)
+	assert.NoError(t, err)
+	second := time.Date(2024, time.January, 8, 0, 0, 0, 0, time.UTC).Unix()
+	assert.Equal(t, second, paymentPlan.NextExecute)
+
+	// An execution of the skipped payment must neither happen nor overwrite the skip.
+	_, err = gs.ExecutePaymentPlan(context.Background(), group, &stale, nil, bob, "Pocket money", "")
+	assert.ErrorIs(t, err, models.ErrPaymentPlanChanged)
+	assert.Equal(t, first, stale.NextExecute)
+
+	transaction, err := gs.ExecutePaymentPlan(context.Background(), group, paymentPlan, nil, bob, "Pocket money", "")
+	assert.NoError(t, err)
+	if assert.NotNil(t, transaction) {
+		assert.Equal(t, paymentPlan.Id, transaction.PaymentPlanId)
+	}
+
+	stored, err := gs.GetPaymentPlanById(context.Background(), group, paymentPlan.Id)
+	if assert.NoError(t, err) && assert.NotNil(t, stored) {
+		assert.Equal(t, time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC).Unix(), stored.NextExecute)
+		assert.Equal(t, 2, stored.PaymentCount)
+	}
+
+	executions, err := gs.GetPaymentPlanExecutions(context.Background(), stored, -1, -1, true)
+	assert.NoError(t, err)
+	if assert.Len(t, executions, 2) {
+		assert.True(t, executions[0].Skipped)
+		assert.Equal(t, first, executions[0].ScheduledFor)
+		assert.False(t, executions[1].Skipped)
+		assert.Equal(t, second, executions[1].ScheduledFor)
+	}
+}
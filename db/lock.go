@@ -0,0 +1,60 @@
+package db
+
+import (
+	"slices"
+	"sync"
+)
+
+type keyedLock struct {
+	sync.Mutex
+	// Number of callers holding or waiting for the lock.
+	refs int
+}
+
+// Mutual exclusion per key, e.g. per group. Locks are created on demand and removed once nobody holds or waits for them.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+// Locks all keys and returns a function which unlocks them again.
+// The keys are always locked in the same order, so callers locking overlapping keys can't deadlock.
+func (m *keyedMutex) Lock(keys ...string) (unlock func()) {
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	locks := make([]*keyedLock, len(keys))
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = make(map[string]*keyedLock)
+	}
+	for i, key := range keys {
+		l, ok := m.locks[key]
+		if !ok {
+			l = &keyedLock{}
+			m.locks[key] = l
+		}
+		l.refs++
+		locks[i] = l
+	}
+	m.mu.Unlock()
+
+	for _, l := range locks {
+		l.Lock()
+	}
+
+	return func() {
+		for _, l := range locks {
+			l.Unlock()
+		}
+		m.mu.Lock()
+		for i, key := range keys {
+			locks[i].refs--
+			if locks[i].refs == 0 {
+				delete(m.locks, key)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	var m keyedMutex
+
+	unlockA := m.Lock("a")
+
+	// Other keys aren't blocked.
+	done := make(chan struct{})
+	go func() {
+		unlock := m.Lock("b")
+		unlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("locking another key blocked")
+	}
+
+	// Overlapping keys wait until the lock is released.
+	acquired := make(chan struct{})
+	released := make(chan struct{})
+	go func() {
+		unlock := m.Lock("b", "a")
+		close(acquired)
+		unlock()
+		close(released)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("locked key was acquired twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlockA()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("released key wasn't acquired")
+	}
+	<-released
+
+	m.mu.Lock()
+	assert.Empty(t, m.locks, "unused locks must be removed")
+	m.mu.Unlock()
+}
@@ -1,6 +1,10 @@
 package db
 
 import (
+	"context"
+	"log"
+	"strings"
+
 	"gorm.io/gorm"
 
 	"github.com/juho05/h-bank/models"
@@ -16,7 +20,7 @@ func NewUserStore(db *gorm.DB) *UserStore {
 	}
 }
 
-func (us *UserStore) GetAll(exclude []string, searchInput string, page, pageSize int, descending bool) ([]models.User, error) {
+func (us *UserStore) GetAll(ctx context.Context, exclude []string, searchInput string, page, pageSize int, descending bool) ([]models.User, error) {
 	var users []models.User
 	var err error
 
@@ -26,23 +30,30 @@ func (us *UserStore) GetAll(exclude []string, searchInput string, page, pageSize
 	}
 
 	if page < 0 || pageSize < 0 {
-		err = us.db.Not(map[string]interface{}{"id": exclude}).Order("name "+order).Find(&users, "name LIKE ? AND publicly_visible = ?", "%"+searchInput+"%", true).Error
+		err = us.db.WithContext(ctx).Not(map[string]interface{}{"id": exclude}).Order("name "+order).Find(&users, "name LIKE ? AND publicly_visible = ?", "%"+searchInput+"%", true).Error
 	} else {
-		err = us.db.Not(map[string]interface{}{"id": exclude}).Order("name "+order).Offset(page*pageSize).Limit(pageSize).Find(&users, "name LIKE ? AND publicly_visible = ?", "%"+searchInput+"%", true).Error
+		err = us.db.WithContext(ctx).Not(map[string]interface{}{"id": exclude}).Order("name "+order).Offset(page*pageSize).Limit(pageSize).Find(&users, "name LIKE ? AND publicly_visible = ?", "%"+searchInput+"%", true).Error
 	}
 
 	return users, err
 }
 
-func (us *UserStore) Count() (int64, error) {
+func (us *UserStore) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := us.db.WithContext(ctx).Model(&models.User{}).Where("publicly_visible = ?", true).Count(&count).Error
+	return count, err
+}
+
+// Counts all users including the ones which are not publicly visible.
+func (us *UserStore) CountAll(ctx context.Context) (int64, error) {
 	var count int64
-	err := us.db.Model(&models.User{}).Where("publicly_visible = ?", true).Count(&count).Error
+	err := us.db.WithContext(ctx).Model(&models.User{}).Count(&count).Error
 	return count, err
 }
 
-func (us *UserStore) GetById(id string) (*models.User, error) {
+func (us *UserStore) GetById(ctx context.Context, id string) (*models.User, error) {
 	var user models.User
-	err := us.db.First(&user, "id = ?", id).Error
+	err := us.db.WithContext(ctx).First(&user, "id = ?", id).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -54,9 +65,9 @@ func (us *UserStore) GetById(id string) (*models.User, error) {
 	return &user, nil
 }
 
-func (us *UserStore) GetByEmail(email string) (*models.User, error) {
+func (us *UserStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	err := us.db.First(&user, "email = ?", email).Error
+	err := us.db.WithContext(ctx).First(&user, "email = ?", email).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -68,85 +79,127 @@ func (us *UserStore) GetByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (us *UserStore) Create(user *models.User) error {
-	return us.db.Create(user).Error
+func (us *UserStore) Create(ctx context.Context, user *models.User) error {
+	return us.db.WithContext(ctx).Create(user).Error
 }
 
-func (us *UserStore) Update(user *models.User) error {
-	oldUser, err := us.GetById(user.Id)
+func (us *UserStore) Update(ctx context.Context, user *models.User) error {
+	oldUser, err := us.GetById(ctx, user.Id)
 	if err != nil {
 		return err
 	}
 	if oldUser.Name != user.Name {
-		us.db.Model(models.GroupMembership{}).Where("user_id = ?", user.Id).Update("user_name", user.Name)
+		us.db.WithContext(ctx).Model(models.GroupMembership{}).Where("user_id = ?", user.Id).Update("user_name", user.Name)
+	}
+	// The login fields are only changed by UpdateLastLogin and the inactivity worker, so a stale user must not overwrite them.
+	return us.db.WithContext(ctx).Select("*").Omit("last_login", "language", "inactivity_warning_sent").Updates(user).Error
+}
+
+// Only writes the name and email which are provided by the identity provider, so other columns of a stale user aren't overwritten.
+func (us *UserStore) UpdateProfile(ctx context.Context, user *models.User, name, email string) error {
+	if user.Name != name {
+		err := us.db.WithContext(ctx).Model(models.GroupMembership{}).Where("user_id = ?", user.Id).Update("user_name", name).Error
+		if err != nil {
+			return err
+		}
 	}
-	return us.db.Select("*").Updates(user).Error
+	user.Name = name
+	user.Email = email
+	return us.db.WithContext(ctx).Model(user).Select("name", "email").Updates(user).Error
+}
+
+// Also stores the language of the login and resets the inactivity warning of the user.
+func (us *UserStore) UpdateLastLogin(ctx context.Context, user *models.User, lastLogin int64, lang string) error {
+	user.LastLogin = lastLogin
+	user.Language = lang
+	user.InactivityWarningSent = 0
+	return us.db.WithContext(ctx).Model(user).Select("last_login", "language", "inactivity_warning_sent").Updates(user).Error
+}
+
+// Sets the last login of all users who didn't log in since logins are tracked.
+func (us *UserStore) InitLastLogin(ctx context.Context, lastLogin int64) error {
+	return us.db.WithContext(ctx).Model(&models.User{}).Where("last_login = ?", 0).Update("last_login", lastLogin).Error
+}
+
+func (us *UserStore) GetInactiveUsers(ctx context.Context, lastLoginBefore int64) ([]models.User, error) {
+	var users []models.User
+	err := us.db.WithContext(ctx).Where("last_login > ? AND last_login < ?", 0, lastLoginBefore).Find(&users).Error
+	return users, err
 }
 
-func (us *UserStore) Delete(user *models.User) error {
-	us.db.Delete(&models.CashLogEntry{}, "user_id = ?", user.Id)
-	us.db.Delete(&models.GroupInvitation{}, "user_id = ?", user.Id)
-	us.db.Delete(&models.GroupMembership{}, "user_id = ?", user.Id)
-	us.db.Where("sender_id = ?", user.Id).Or("receiver_id = ?", user.Id).Delete(&models.PaymentPlan{})
-	return us.db.Delete(user).Error
+func (us *UserStore) UpdateInactivityWarningSent(ctx context.Context, user *models.User, warningSent int64) error {
+	user.InactivityWarningSent = warningSent
+	return us.db.WithContext(ctx).Model(user).Update("inactivity_warning_sent", warningSent).Error
 }
 
-func (us *UserStore) DeleteById(id string) error {
-	user, err := us.GetById(id)
+func (us *UserStore) Delete(ctx context.Context, user *models.User) error {
+	err := us.DeleteCashLog(ctx, user)
+	if err != nil {
+		return err
+	}
+	us.db.WithContext(ctx).Unscoped().Delete(&models.GroupInvitation{}, "user_id = ?", user.Id)
+	us.db.WithContext(ctx).Delete(&models.GroupMembership{}, "user_id = ?", user.Id)
+	us.db.WithContext(ctx).Delete(&models.AdminChange{}, "user_id = ?", user.Id)
+	us.db.WithContext(ctx).Where("sender_id = ?", user.Id).Or("receiver_id = ?", user.Id).Delete(&models.PaymentPlan{})
+	return us.db.WithContext(ctx).Delete(user).Error
+}
+
+func (us *UserStore) DeleteById(ctx context.Context, id string) error {
+	user, err := us.GetById(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	if user != nil {
-		return us.Delete(user)
+		return us.Delete(ctx, user)
 	}
 
 	return nil
 }
 
-func (us *UserStore) DeleteByEmail(email string) error {
-	user, err := us.GetByEmail(email)
+func (us *UserStore) DeleteByEmail(ctx context.Context, email string) error {
+	user, err := us.GetByEmail(ctx, email)
 	if err != nil {
 		return err
 	}
 
 	if user != nil {
-		return us.Delete(user)
+		return us.Delete(ctx, user)
 	}
 
 	return nil
 }
 
-func (us *UserStore) GetCashLog(user *models.User, searchInput string, page, pageSize int, oldestFirst bool) ([]models.CashLogEntry, error) {
+func (us *UserStore) GetCashLog(ctx context.Context, user *models.User, searchInput string, page, pageSize int, oldestFirst bool) ([]models.CashLogEntry, error) {
 	var cashLog []models.CashLogEntry
 	var err error
 	if page < 0 || pageSize < 0 {
 		if oldestFirst {
-			err = us.db.Where("user_id = ? AND change_title LIKE ?", user.Id, "%"+searchInput+"%").Order("created ASC").Find(&cashLog).Error
+			err = us.db.WithContext(ctx).Where("user_id = ? AND change_title LIKE ?", user.Id, "%"+searchInput+"%").Order("created ASC, sequence ASC").Find(&cashLog).Error
 		} else {
-			err = us.db.Where("user_id = ? AND change_title LIKE ?", user.Id, "%"+searchInput+"%").Order("created DESC").Find(&cashLog).Error
+			err = us.db.WithContext(ctx).Where("user_id = ? AND change_title LIKE ?", user.Id, "%"+searchInput+"%").Order("created DESC, sequence DESC").Find(&cashLog).Error
 		}
 	} else {
 		offset := page * pageSize
 		if oldestFirst {
-			err = us.db.Where("user_id = ? AND change_title LIKE ?", user.Id, "%"+searchInput+"%").Order("created ASC").Offset(offset).Limit(pageSize).Find(&cashLog).Error
+			err = us.db.WithContext(ctx).Where("user_id = ? AND change_title LIKE ?", user.Id, "%"+searchInput+"%").Order("created ASC, sequence ASC").Offset(offset).Limit(pageSize).Find(&cashLog).Error
 		} else {
-			err = us.db.Where("user_id = ? AND change_title LIKE ?", user.Id, "%"+searchInput+"%").Order("created DESC").Offset(offset).Limit(pageSize).Find(&cashLog).Error
+			err = us.db.WithContext(ctx).Where("user_id = ? AND change_title LIKE ?", user.Id, "%"+searchInput+"%").Order("created DESC, sequence DESC").Offset(offset).Limit(pageSize).Find(&cashLog).Error
 		}
 	}
 
 	return cashLog, err
 }
 
-func (us *UserStore) CashLogEntryCount(user *models.User) (int64, error) {
+func (us *UserStore) CashLogEntryCount(ctx context.Context, user *models.User) (int64, error) {
 	var count int64
-	err := us.db.Model(&models.CashLogEntry{}).Where("user_id = ?", user.Id).Count(&count).Error
+	err := us.db.WithContext(ctx).Model(&models.CashLogEntry{}).Where("user_id = ?", user.Id).Count(&count).Error
 	return count, err
 }
 
-func (us *UserStore) GetLastCashLogEntry(user *models.User) (*models.CashLogEntry, error) {
+func (us *UserStore) GetLastCashLogEntry(ctx context.Context, user *models.User) (*models.CashLogEntry, error) {
 	var cashLog []models.CashLogEntry
-	err := us.db.Where("user_id = ?", user.Id).Order("created desc").Limit(1).Find(&cashLog).Error
+	err := us.db.WithContext(ctx).Where("user_id = ?", user.Id).Order("sequence desc, created desc").Limit(1).Find(&cashLog).Error
 	if err != nil {
 		return nil, err
 	}
@@ -158,9 +211,9 @@ func (us *UserStore) GetLastCashLogEntry(user *models.User) (*models.CashLogEntr
 	return &cashLog[0], nil
 }
 
-func (us *UserStore) GetCashLogEntryById(user *models.User, id string) (*models.CashLogEntry, error) {
+func (us *UserStore) GetCashLogEntryById(ctx context.Context, user *models.User, id string) (*models.CashLogEntry, error) {
 	var cashLogEntry models.CashLogEntry
-	err := us.db.First(&cashLogEntry, "id = ? AND user_id = ?", id, user.Id).Error
+	err := us.db.WithContext(ctx).First(&cashLogEntry, "id = ? AND user_id = ?", id, user.Id).Error
 	if err != nil {
 		switch err {
 		case gorm.ErrRecordNotFound:
@@ -172,38 +225,132 @@ func (us *UserStore) GetCashLogEntryById(user *models.User, id string) (*models.
 	return &cashLogEntry, nil
 }
 
-func (us *UserStore) AddCashLogEntry(user *models.User, entry *models.CashLogEntry) error {
-	lastEntry, err := us.GetLastCashLogEntry(user)
+// The coins and notes of entry are the complete cash of the user, the difference to the previous entry is computed automatically.
+func (us *UserStore) AddCashLogEntry(ctx context.Context, user *models.User, entry *models.CashLogEntry) error {
+	unlock := lockCashLog(user)
+	defer unlock()
+	return us.addCashLogEntry(ctx, user, entry)
+}
+
+// Must be called while holding the lock of lockCashLog.
+func (us *UserStore) addCashLogEntry(ctx context.Context, user *models.User, entry *models.CashLogEntry) error {
+	lastEntry, err := us.GetLastCashLogEntry(ctx, user)
 	if err != nil {
 		return err
 	}
 
-	totalAmount := 0
-
-	totalAmount += 1 * entry.Ct1
-	totalAmount += 2 * entry.Ct2
-	totalAmount += 5 * entry.Ct5
-	totalAmount += 10 * entry.Ct10
-	totalAmount += 20 * entry.Ct20
-	totalAmount += 50 * entry.Ct50
-
-	totalAmount += 100 * entry.Eur1
-	totalAmount += 200 * entry.Eur2
-	totalAmount += 500 * entry.Eur5
-	totalAmount += 1000 * entry.Eur10
-	totalAmount += 2000 * entry.Eur20
-	totalAmount += 5000 * entry.Eur50
-	totalAmount += 10000 * entry.Eur100
-	totalAmount += 20000 * entry.Eur200
-	totalAmount += 50000 * entry.Eur500
-
-	entry.TotalAmount = totalAmount
+	entry.TotalAmount = entry.Value()
 
 	if lastEntry != nil {
 		entry.ChangeDifference = entry.TotalAmount - lastEntry.TotalAmount
+		entry.Sequence = lastEntry.Sequence + 1
 	} else {
 		entry.ChangeDifference = entry.TotalAmount
+		entry.Sequence = 1
 	}
 
-	return us.db.Model(&user).Association("CashLog").Append(entry)
+	return us.db.WithContext(ctx).Model(&user).Association("CashLog").Append(entry)
+}
+
+// Permanently deletes all cash log entries of user. The current cash of the user is zero afterwards.
+func (us *UserStore) DeleteCashLog(ctx context.Context, user *models.User) error {
+	return us.db.WithContext(ctx).Delete(&models.CashLogEntry{}, "user_id = ?", user.Id).Error
+}
+
+// Returns the sum of the latest cash log entries of all users.
+func (us *UserStore) GetTotalCash(ctx context.Context) (int, error) {
+	var total int
+	err := us.db.WithContext(ctx).Raw(`select cast(coalesce(sum(c.total_amount), 0) as bigint) from cash_log_entries c
+		where c.id = (
+			select c2.id from cash_log_entries c2
+			where c2.user_id = c.user_id
+			order by c2.sequence desc, c2.created desc, c2.id limit 1
+		)`).Scan(&total).Error
+	return total, err
+}
+
+// Returns a new entry with the coins and notes of change added to (sign = 1) or removed from (sign = -1) the ones of entry.
+func addCash(entry *models.CashLogEntry, change *models.CashLogEntry, sign int) *models.CashLogEntry {
+	return &models.CashLogEntry{
+		Ct1:  entry.Ct1 + sign*change.Ct1,
+		Ct2:  entry.Ct2 + sign*change.Ct2,
+		Ct5:  entry.Ct5 + sign*change.Ct5,
+		Ct10: entry.Ct10 + sign*change.Ct10,
+		Ct20: entry.Ct20 + sign*change.Ct20,
+		Ct50: entry.Ct50 + sign*change.Ct50,
+
+		Eur1:   entry.Eur1 + sign*change.Eur1,
+		Eur2:   entry.Eur2 + sign*change.Eur2,
+		Eur5:   entry.Eur5 + sign*change.Eur5,
+		Eur10:  entry.Eur10 + sign*change.Eur10,
+		Eur20:  entry.Eur20 + sign*change.Eur20,
+		Eur50:  entry.Eur50 + sign*change.Eur50,
+		Eur100: entry.Eur100 + sign*change.Eur100,
+		Eur200: entry.Eur200 + sign*change.Eur200,
+		Eur500: entry.Eur500 + sign*change.Eur500,
+	}
+}
+
+func hasNegativeCash(entry *models.CashLogEntry) bool {
+	return min(entry.Ct1, entry.Ct2, entry.Ct5, entry.Ct10, entry.Ct20, entry.Ct50,
+		entry.Eur1, entry.Eur2, entry.Eur5, entry.Eur10, entry.Eur20, entry.Eur50,
+		entry.Eur100, entry.Eur200, entry.Eur500) < 0
+}
+
+func (us *UserStore) CreateFailedEmail(ctx context.Context, failedEmail *models.FailedEmail) error {
+	return us.db.WithContext(ctx).Create(failedEmail).Error
+}
+
+// Stores a critical email which could not be delivered so that an admin can resend it. Used as services.FailedEmailHandler.
+func (us *UserStore) StoreFailedEmail(addresses []string, subject string, msg []byte, reason string) {
+	err := us.CreateFailedEmail(context.Background(), &models.FailedEmail{
+		Addresses: strings.Join(addresses, ","),
+		Subject:   subject,
+		Message:   msg,
+		Reason:    reason,
+	})
+	if err != nil {
+		log.Println("[email] ERROR: Couldn't store failed email:", err)
+	}
+}
+
+func (us *UserStore) GetFailedEmails(ctx context.Context, page, pageSize int, oldestFirst bool) ([]models.FailedEmail, error) {
+	order := "DESC"
+	if oldestFirst {
+		order = "ASC"
+	}
+
+	var failedEmails []models.FailedEmail
+	var err error
+	if page < 0 || pageSize < 0 {
+		err = us.db.WithContext(ctx).Order("created " + order).Find(&failedEmails).Error
+	} else {
+		err = us.db.WithContext(ctx).Order("created " + order).Offset(page * pageSize).Limit(pageSize).Find(&failedEmails).Error
+	}
+
+	return failedEmails, err
+}
+
+func (us *UserStore) FailedEmailCount(ctx context.Context) (int64, error) {
+	var count int64
+	err := us.db.WithContext(ctx).Model(&models.FailedEmail{}).Count(&count).Error
+	return count, err
+}
+
+func (us *UserStore) GetFailedEmailById(ctx context.Context, id string) (*models.FailedEmail, error) {
+	var failedEmail models.FailedEmail
+	err := us.db.WithContext(ctx).First(&failedEmail, "id = ?", id).Error
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+	return &failedEmail, nil
+}
+
+func (us *UserStore) DeleteFailedEmail(ctx context.Context, failedEmail *models.FailedEmail) error {
+	return us.db.WithContext(ctx).Delete(failedEmail).Error
 }
@@ -0,0 +1,251 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/juho05/h-bank/config"
+	"github.com/juho05/h-bank/models"
+	"github.com/juho05/h-bank/services"
+)
+
+func TestUserStore_GetInactiveUsers(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+
+	untracked := &models.User{Name: "untracked", Email: "untracked@gmail.com"}
+	us.Create(context.Background(), untracked)
+	inactive := &models.User{Name: "inactive", Email: "inactive@gmail.com", LastLogin: 100}
+	us.Create(context.Background(), inactive)
+	active := &models.User{Name: "active", Email: "active@gmail.com", LastLogin: 1000}
+	us.Create(context.Background(), active)
+
+	users, err := us.GetInactiveUsers(context.Background(), 500)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, inactive.Id, users[0].Id)
+	}
+
+	err = us.InitLastLogin(context.Background(), 200)
+	assert.NoError(t, err)
+
+	users, err = us.GetInactiveUsers(context.Background(), 500)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	err = us.UpdateInactivityWarningSent(context.Background(), inactive, 300)
+	assert.NoError(t, err)
+
+	err = us.UpdateLastLogin(context.Background(), inactive, 600, "de")
+	assert.NoError(t, err)
+
+	users, err = us.GetInactiveUsers(context.Background(), 500)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, untracked.Id, users[0].Id)
+	}
+
+	user, err := us.GetById(context.Background(), inactive.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(600), user.LastLogin)
+	assert.Equal(t, "de", user.Language)
+	assert.Equal(t, int64(0), user.InactivityWarningSent)
+	assert.Equal(t, "inactive", user.Name)
+}
+
+func TestUserStore_Update_KeepsLastLogin(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+
+	user := &models.User{Name: "bob", Email: "bob@gmail.com", LastLogin: 100}
+	us.Create(context.Background(), user)
+
+	stale, err := us.GetById(context.Background(), user.Id)
+	if err != nil {
+		t.Fatalf("Couldn't load user")
+	}
+
+	us.UpdateLastLogin(context.Background(), user, 200, "de")
+	us.UpdateInactivityWarningSent(context.Background(), user, 300)
+
+	stale.Name = "alice"
+	stale.SendReceiptEmail = true
+	err = us.Update(context.Background(), stale)
+	assert.NoError(t, err)
+
+	got, err := us.GetById(context.Background(), user.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", got.Name)
+	assert.True(t, got.SendReceiptEmail)
+	assert.Equal(t, int64(200), got.LastLogin)
+	assert.Equal(t, "de", got.Language)
+	assert.Equal(t, int64(300), got.InactivityWarningSent)
+}
+
+func TestUserStore_UpdateProfile(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+	gs := NewGroupStore(database)
+
+	user := &models.User{Name: "bob", Email: "bob@gmail.com", PubliclyVisible: true}
+	us.Create(context.Background(), user)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user)
+
+	// A login loads the user before talking to the identity provider.
+	stale, err := us.GetById(context.Background(), user.Id)
+	if err != nil {
+		t.Fatalf("Couldn't load user")
+	}
+
+	database.Model(&models.User{}).Where("id = ?", user.Id).Updates(map[string]any{
+		"publicly_visible":   false,
+		"send_receipt_email": true,
+	})
+
+	err = us.UpdateProfile(context.Background(), stale, "alice", "alice@gmail.com")
+	assert.NoError(t, err)
+
+	got, err := us.GetById(context.Background(), user.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", got.Name)
+	assert.Equal(t, "alice@gmail.com", got.Email)
+	assert.False(t, got.PubliclyVisible)
+	assert.True(t, got.SendReceiptEmail)
+
+	members, err := gs.GetMembers(context.Background(), nil, "", group, -1, -1, false, false)
+	assert.NoError(t, err)
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, "alice", members[0].Name)
+	}
+}
+
+func TestUserStore_GetLastCashLogEntry(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+
+	user := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user)
+	other := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), other)
+
+	entry, err := us.GetLastCashLogEntry(context.Background(), user)
+	assert.NoError(t, err)
+	assert.Nil(t, entry)
+
+	// Entries without a sequence number are ordered by their creation time.
+	for i, created := range []int64{300, 100, 200} {
+		err = database.Create(&models.CashLogEntry{Base: models.Base{Created: created}, UserId: user.Id, TotalAmount: i}).Error
+		assert.NoError(t, err)
+	}
+	err = database.Create(&models.CashLogEntry{Base: models.Base{Created: 400}, UserId: other.Id, TotalAmount: 10}).Error
+	assert.NoError(t, err)
+
+	entry, err = us.GetLastCashLogEntry(context.Background(), user)
+	assert.NoError(t, err)
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, int64(300), entry.Created)
+		assert.Equal(t, 0, entry.TotalAmount)
+	}
+
+	// New entries follow the previous one even if they are created within the same second.
+	for _, eur := range []int{1, 2, 5} {
+		err = us.AddCashLogEntry(context.Background(), user, &models.CashLogEntry{Eur1: eur})
+		assert.NoError(t, err)
+	}
+	entry, err = us.GetLastCashLogEntry(context.Background(), user)
+	assert.NoError(t, err)
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, 3, entry.Sequence)
+		assert.Equal(t, 500, entry.TotalAmount)
+		assert.Equal(t, 300, entry.ChangeDifference)
+		assert.LessOrEqual(t, entry.Created, time.Now().Unix(), "creation times must not be in the future")
+	}
+
+	plan := queryPlan(t, database, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("user_id = ?", user.Id).Order("sequence desc, created desc").Limit(1).Find(&[]models.CashLogEntry{})
+	})
+	if assert.NotEmpty(t, plan) {
+		assert.Contains(t, plan[0], "idx_cash_log_entries_user_sequence")
+	}
+}
+
+func TestUserStore_StoreFailedEmail(t *testing.T) {
+	database, dbId, err := NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer DeleteTestDB(dbId)
+	err = AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := NewUserStore(database)
+
+	emailEnabled, emailFrom := config.Data.EmailEnabled, config.Data.EmailFrom
+	t.Cleanup(func() {
+		config.Data.EmailEnabled, config.Data.EmailFrom = emailEnabled, emailFrom
+		services.FailedEmailHandler = nil
+	})
+	config.Data.EmailEnabled = true
+	config.Data.EmailFrom = "H-Bank <noreply@hbank.example>"
+	services.FailedEmailHandler = us.StoreFailedEmail
+
+	// The email workers aren't running, so sending fails and only the critical email is stored.
+	err = services.SendEmail([]string{"bob@gmail.com"}, "Notification", "<p>Hello</p>", false)
+	assert.ErrorIs(t, err, services.ErrEmailQueueNotStarted)
+	err = services.SendEmail([]string{"bob@gmail.com", "alice@gmail.com"}, "Inactive account", "<p>Hello</p>", true)
+	assert.ErrorIs(t, err, services.ErrEmailQueueNotStarted)
+
+	failedEmails, err := us.GetFailedEmails(context.Background(), -1, -1, true)
+	assert.NoError(t, err)
+	if assert.Len(t, failedEmails, 1) {
+		assert.Equal(t, "bob@gmail.com,alice@gmail.com", failedEmails[0].Addresses)
+		assert.Equal(t, "Inactive account", failedEmails[0].Subject)
+		assert.NotEmpty(t, failedEmails[0].Message)
+		assert.Equal(t, services.ErrEmailQueueNotStarted.Error(), failedEmails[0].Reason)
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/juho05/h-bank/config"
+	"github.com/juho05/h-bank/responses"
+	"github.com/juho05/h-bank/services"
+)
+
+// /api/admin/stats (GET)
+func (h *Handler) GetStats(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	if !services.IsSiteAdmin(user.Id) {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Only site admins can access this resource", lang))
+	}
+
+	userCount, err := h.userStore.CountAll(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	groupCount, err := h.groupStore.CountAll(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	cash, err := h.userStore.GetTotalCash(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	balance, err := h.groupStore.GetTotalBalance(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewStats(userCount, groupCount, cash, balance))
+}
+
+// /api/admin/failedEmail?page=int&pageSize=int&oldestFirst=bool (GET)
+func (h *Handler) GetFailedEmails(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	if !services.IsSiteAdmin(user.Id) {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Only site admins can access this resource", lang))
+	}
+
+	page := 0
+	pageSize := 20
+
+	if c.QueryParam("page") != "" {
+		page, err = strconv.Atoi(c.QueryParam("page"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
+		}
+	}
+
+	if c.QueryParam("pageSize") != "" {
+		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+		}
+		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		}
+	}
+
+	oldestFirst := oldestFirstParam(c, user)
+
+	failedEmails, err := h.userStore.GetFailedEmails(c.Request().Context(), page, pageSize, oldestFirst)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	count, err := h.userStore.FailedEmailCount(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewFailedEmails(failedEmails, count))
+}
+
+// /api/admin/failedEmail/:id (POST)
+func (h *Handler) ResendFailedEmail(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	if !services.IsSiteAdmin(user.Id) {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Only site admins can access this resource", lang))
+	}
+
+	failedEmail, err := h.userStore.GetFailedEmailById(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if failedEmail == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Failed email not found", lang))
+	}
+
+	// A failed resend is stored as a new failed email by the email workers.
+	err = services.SendRawEmail(strings.Split(failedEmail.Addresses, ","), failedEmail.Subject, failedEmail.Message, true)
+	if err != nil {
+		return c.JSON(http.StatusOK, responses.New(false, "Couldn't queue email", lang))
+	}
+
+	err = h.userStore.DeleteFailedEmail(c.Request().Context(), failedEmail)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully queued email", lang))
+}
+
+// /api/admin/failedEmail/:id (DELETE)
+func (h *Handler) DeleteFailedEmail(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	if !services.IsSiteAdmin(user.Id) {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Only site admins can access this resource", lang))
+	}
+
+	failedEmail, err := h.userStore.GetFailedEmailById(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if failedEmail == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Failed email not found", lang))
+	}
+
+	err = h.userStore.DeleteFailedEmail(c.Request().Context(), failedEmail)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully deleted failed email", lang))
+}
+
+// /api/admin/emailPreview?type=string (GET)
+func (h *Handler) PreviewEmail(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	if !services.IsSiteAdmin(user.Id) {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Only site admins can access this resource", lang))
+	}
+
+	body, err := services.PreviewEmail(c.QueryParam("type"), lang)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownEmailType) {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unknown email type", lang))
+		}
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.HTML(http.StatusOK, body)
+}
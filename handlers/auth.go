@@ -10,11 +10,22 @@ import (
 	"github.com/juho05/h-bank/config"
 	"github.com/juho05/h-bank/models"
 	"github.com/juho05/h-bank/responses"
+	"github.com/juho05/h-bank/router/middlewares"
+	"github.com/juho05/h-bank/services"
 )
 
-// /api/auth/login (GET)
+// Kept by the OIDC client until the auth flow is finished.
+type loginData struct {
+	Redirect   string
+	RememberMe bool
+}
+
+// /api/auth/login?redirect=string&rememberMe=bool (GET)
 func (h *Handler) Login(c echo.Context) error {
-	h.oidcClient.InitiateAuthFlowWithData(c.Response().Writer, c.Request(), []string{"openid", "profile", "email"}, c.QueryParam("redirect"))
+	h.oidcClient.InitiateAuthFlowWithData(c.Response().Writer, c.Request(), []string{"openid", "profile", "email"}, loginData{
+		Redirect:   c.QueryParam("redirect"),
+		RememberMe: services.StrToBool(c.QueryParam("rememberMe")),
+	})
 	return nil
 }
 
@@ -37,6 +48,17 @@ func (h *Handler) Logout(c echo.Context) error {
 		Path:     "/",
 	})
 
+	c.SetCookie(&http.Cookie{
+		Name:     "Remember-Me",
+		Value:    "",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: sameSite,
+		Domain:   config.Data.DomainName,
+		Path:     "/",
+	})
+
 	c.SetCookie(&http.Cookie{
 		Name:     "ID-Token",
 		Value:    "",
@@ -68,18 +90,19 @@ func (h *Handler) LoginCallback(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, responses.NewUnexpectedError(err, lang))
 	}
+	login := data.(loginData)
 
 	info, err := h.oidcClient.FetchUserInfo(userID, access)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	user, err := h.userStore.GetById(userID)
+	user, err := h.userStore.GetById(c.Request().Context(), userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 	if user == nil {
-		err = h.userStore.Create(&models.User{
+		err = h.userStore.Create(c.Request().Context(), &models.User{
 			Base: models.Base{
 				Id: userID,
 			},
@@ -87,57 +110,21 @@ func (h *Handler) LoginCallback(c echo.Context) error {
 			Email:                   info.Email,
 			PubliclyVisible:         true,
 			DontSendInvitationEmail: false,
+			LastLogin:               time.Now().Unix(),
+			Language:                lang,
 		})
 	} else {
-		user.Name = info.Name
-		user.Email = info.Email
-		err = h.userStore.Update(user)
+		err = h.userStore.UpdateProfile(c.Request().Context(), user, info.Name, info.Email)
+		if err == nil {
+			err = h.userStore.UpdateLastLogin(c.Request().Context(), user, time.Now().Unix(), lang)
+		}
 	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	sameSite := http.SameSiteStrictMode
-
-	if config.Data.Debug {
-		sameSite = http.SameSiteNoneMode
-	}
-
-	c.SetCookie(&http.Cookie{
-		Name:     "Refresh-Token",
-		Value:    refresh,
-		MaxAge:   int((12 * 7 * 24 * time.Hour).Seconds()),
-		Secure:   true,
-		HttpOnly: true,
-		SameSite: sameSite,
-		Domain:   config.Data.DomainName,
-		Path:     "/",
-	})
-
-	idTokenParts := strings.Split(id, ".")
-
-	c.SetCookie(&http.Cookie{
-		Name:     "ID-Token",
-		Value:    strings.Join(idTokenParts[:2], "."),
-		MaxAge:   int((30 * time.Minute).Seconds()),
-		Secure:   true,
-		HttpOnly: false,
-		SameSite: sameSite,
-		Domain:   config.Data.DomainName,
-		Path:     "/",
-	})
-
-	c.SetCookie(&http.Cookie{
-		Name:     "ID-Token-Signature",
-		Value:    idTokenParts[2],
-		MaxAge:   int((30 * time.Minute).Seconds()),
-		Secure:   true,
-		HttpOnly: true,
-		SameSite: sameSite,
-		Domain:   config.Data.DomainName,
-		Path:     "/",
-	})
+	middlewares.SetAuthCookies(c, refresh, id, login.RememberMe)
 
-	c.Redirect(http.StatusSeeOther, config.Data.BaseURL+"/"+strings.TrimPrefix(data.(string), "/"))
+	c.Redirect(http.StatusSeeOther, config.Data.BaseURL+"/"+strings.TrimPrefix(login.Redirect, "/"))
 	return nil
 }
@@ -2,9 +2,15 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,11 +26,14 @@ import (
 	"github.com/juho05/h-bank/services"
 )
 
-// /api/group?page=int&pageSize=int&descending=bool (GET)
+// /api/group?role=admin|member&page=int&pageSize=int&descending=bool (GET)
+// /api/group?ids=id1,id2 (GET)
+// The role restricts the groups to the ones the user is an admin of or a member but not an admin of.
+// If ids is set, the groups with these ids are returned instead. Groups the user isn't part of are left out.
 func (h *Handler) GetGroups(c echo.Context) error {
 	lang := c.Get("lang").(string)
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -32,6 +41,26 @@ func (h *Handler) GetGroups(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
+	if c.QueryParam("ids") != "" {
+		ids := make([]string, 0)
+		for _, id := range strings.Split(c.QueryParam("ids"), ",") {
+			id = strings.TrimSpace(id)
+			if id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) > config.Data.MaxPageSize {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Too many ids", lang))
+		}
+
+		groups, err := h.groupStore.GetByIds(c.Request().Context(), ids, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		return c.JSON(http.StatusOK, responses.NewGroups(groups, int64(len(groups))))
+	}
+
 	page := 0
 	pageSize := 20
 
@@ -54,12 +83,17 @@ func (h *Handler) GetGroups(c echo.Context) error {
 
 	descending := services.StrToBool(c.QueryParam("descending"))
 
-	groups, err := h.groupStore.GetAllByUser(user, page, pageSize, descending)
+	role := strings.ToLower(c.QueryParam("role"))
+	if role != "" && role != models.GroupRoleAdmin && role != models.GroupRoleMember {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid role", lang))
+	}
+
+	groups, err := h.groupStore.GetAllByUser(c.Request().Context(), user, role, page, pageSize, descending)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	count, err := h.groupStore.Count(user)
+	count, err := h.groupStore.Count(c.Request().Context(), user, role)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -71,7 +105,7 @@ func (h *Handler) GetGroups(c echo.Context) error {
 func (h *Handler) GetGroupById(c echo.Context) error {
 	lang := c.Get("lang").(string)
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -84,7 +118,7 @@ func (h *Handler) GetGroupById(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
 
-	group, err := h.groupStore.GetById(id)
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -92,28 +126,33 @@ func (h *Handler) GetGroupById(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
 	}
 
-	isMember, err := h.groupStore.IsMember(group, user)
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	isAdmin, err := h.groupStore.IsAdmin(group, user)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	if isMember || isAdmin {
-		return c.JSON(http.StatusOK, responses.NewGroup(group, isMember, isAdmin))
-	} else {
+	if !isMember && !isAdmin {
 		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member/admin of the group", lang))
 	}
+
+	userCount, err := h.groupStore.GetUserCount(c.Request().Context(), group)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewGroupDetails(group, isMember, isAdmin, userCount, config.Data.MaxUsersPerGroup))
 }
 
 // /api/group (POST)
 func (h *Handler) CreateGroup(c echo.Context) error {
 	lang := c.Get("lang").(string)
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -129,6 +168,10 @@ func (h *Handler) CreateGroup(c echo.Context) error {
 
 	body.Name = strings.TrimSpace(body.Name)
 	body.Description = strings.TrimSpace(body.Description)
+	body.Unit = strings.TrimSpace(body.Unit)
+	if body.Unit == "" {
+		body.Unit = models.DefaultGroupUnit
+	}
 
 	if utf8.RuneCountInString(body.Name) > config.Data.MaxNameLength {
 		return c.JSON(http.StatusOK, responses.New(false, "Name too long", lang))
@@ -146,24 +189,31 @@ func (h *Handler) CreateGroup(c echo.Context) error {
 		return c.JSON(http.StatusOK, responses.New(false, "Description too short", lang))
 	}
 
+	if utf8.RuneCountInString(body.Unit) > config.Data.MaxUnitLength {
+		return c.JSON(http.StatusOK, responses.New(false, "Unit too long", lang))
+	}
+
 	group := &models.Group{
-		Name:           body.Name,
-		Description:    body.Description,
+		Name:        body.Name,
+		Description: body.Description,
+		GroupSettings: models.GroupSettings{
+			Unit: body.Unit,
+		},
 		GroupPictureId: uuid.NewString(),
 	}
 
-	err = h.groupStore.Create(group)
+	err = h.groupStore.Create(c.Request().Context(), group)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	err = h.groupStore.AddAdmin(group, user)
+	err = h.groupStore.AddAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
 	if !body.OnlyAdmin {
-		err = h.groupStore.AddMember(group, user)
+		err = h.groupStore.AddMember(c.Request().Context(), group, user)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
@@ -177,7 +227,7 @@ func (h *Handler) UpdateGroup(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -189,7 +239,7 @@ func (h *Handler) UpdateGroup(c echo.Context) error {
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -203,7 +253,7 @@ func (h *Handler) UpdateGroup(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
 	}
 
-	isAdmin, err := h.groupStore.IsAdmin(group, user)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -212,6 +262,11 @@ func (h *Handler) UpdateGroup(c echo.Context) error {
 	}
 
 	body.Description = strings.TrimSpace(body.Description)
+	// Clients which don't send the unit keep the current one.
+	body.Unit = strings.TrimSpace(body.Unit)
+	if body.Unit == "" {
+		body.Unit = group.Unit
+	}
 
 	if utf8.RuneCountInString(body.Description) > config.Data.MaxDescriptionLength {
 		return c.JSON(http.StatusOK, responses.New(false, "Description too long", lang))
@@ -221,10 +276,18 @@ func (h *Handler) UpdateGroup(c echo.Context) error {
 		return c.JSON(http.StatusOK, responses.New(false, "Description too short", lang))
 	}
 
+	if utf8.RuneCountInString(body.Unit) > config.Data.MaxUnitLength {
+		return c.JSON(http.StatusOK, responses.New(false, "Unit too long", lang))
+	}
+
 	group.Description = body.Description
-	h.groupStore.Update(group)
+	group.Unit = body.Unit
+	err = h.groupStore.Update(c.Request().Context(), group)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
 
-	isMember, err := h.groupStore.IsMember(group, user)
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -232,11 +295,12 @@ func (h *Handler) UpdateGroup(c echo.Context) error {
 	return c.JSON(http.StatusOK, responses.NewGroup(group, isMember, isAdmin))
 }
 
-// /api/group/:id/user (GET)
-func (h *Handler) GetGroupUsers(c echo.Context) error {
+// /api/group/:id/settings (GET)
+func (h *Handler) GetGroupSettings(c echo.Context) error {
 	lang := c.Get("lang").(string)
+
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -244,104 +308,137 @@ func (h *Handler) GetGroupUsers(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	id := c.Param("id")
-	if id == "" {
+	groupId := c.Param("id")
+	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-
-	page := 0
-	pageSize := 20
-
-	if c.QueryParam("page") != "" {
-		page, err = strconv.Atoi(c.QueryParam("page"))
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
-		}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-
-	if c.QueryParam("pageSize") != "" {
-		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
-		}
-		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
-		}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	descending := services.StrToBool(c.QueryParam("descending"))
-	includeSelf := services.StrToBool(c.QueryParam("includeSelf"))
-
-	group, err := h.groupStore.GetById(id)
+	isInGroup, err := h.groupStore.IsInGroup(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if group == nil {
-		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	if !isInGroup {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
 	}
 
-	isInGroup, err := h.groupStore.IsInGroup(group, user)
+	return c.JSON(http.StatusOK, responses.NewGroupSettings(group.GroupSettings))
+}
+
+// /api/group/:id/settings (PUT)
+func (h *Handler) UpdateGroupSettings(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if !isInGroup {
-		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member/admin of the group", lang))
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	var memberships []models.GroupMembership
-	if includeSelf {
-		memberships, err = h.groupStore.GetMemberships(nil, c.QueryParam("search"), group, page, pageSize, descending)
-	} else {
-		memberships, err = h.groupStore.GetMemberships(user, c.QueryParam("search"), group, page, pageSize, descending)
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	var body bindings.GroupSettings
+	err = c.Bind(&body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+	}
 
-	count, err := h.groupStore.MembershipCount(group)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
 
-	type dto struct {
-		Id     string `json:"id"`
-		Name   string `json:"name"`
-		Member bool   `json:"member"`
-		Admin  bool   `json:"admin"`
+	// Clients which don't send the unit keep the current one.
+	body.Unit = strings.TrimSpace(body.Unit)
+	if body.Unit == "" {
+		body.Unit = group.Unit
 	}
-	dtos := make([]dto, len(memberships))
-	for i, m := range memberships {
-		member, err := h.userStore.GetById(m.UserId)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-		}
-		dtos[i] = dto{
-			Id:     member.Id,
-			Name:   member.Name,
-			Member: m.IsMember,
-			Admin:  m.IsAdmin,
-		}
+
+	if utf8.RuneCountInString(body.Unit) > config.Data.MaxUnitLength {
+		return c.JSON(http.StatusOK, responses.New(false, "Unit too long", lang))
 	}
 
-	type response struct {
-		responses.Base
-		Users []dto `json:"users"`
-		Count int64 `json:"count"`
+	if body.MinBalance > 0 {
+		return c.JSON(http.StatusOK, responses.New(false, "Minimum balance must not be positive", lang))
 	}
-	return c.JSON(http.StatusOK, response{
-		Base: responses.Base{
-			Success: true,
-		},
-		Users: dtos,
-		Count: count,
-	})
+
+	if body.MaxBalance < 0 {
+		return c.JSON(http.StatusOK, responses.New(false, "Maximum balance must not be negative", lang))
+	}
+
+	if body.MaxBalance != 0 && body.MinBalance > body.MaxBalance {
+		return c.JSON(http.StatusOK, responses.New(false, "Minimum balance must not exceed maximum balance", lang))
+	}
+
+	if body.LeaveBalancePolicy == "" {
+		body.LeaveBalancePolicy = models.LeaveBalanceKeep
+	}
+	if body.LeaveBalancePolicy != models.LeaveBalanceKeep && body.LeaveBalancePolicy != models.LeaveBalanceBank && body.LeaveBalancePolicy != models.LeaveBalanceRedistribute {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid leave balance policy", lang))
+	}
+
+	body.BankName = strings.TrimSpace(body.BankName)
+	if body.BankName == "" {
+		body.BankName = models.DefaultBankName
+	}
+	if utf8.RuneCountInString(body.BankName) > config.Data.MaxNameLength {
+		return c.JSON(http.StatusOK, responses.New(false, "Bank name too long", lang))
+	}
+
+	group.GroupSettings = models.GroupSettings{
+		Unit:                body.Unit,
+		MinBalance:          body.MinBalance,
+		MaxBalance:          body.MaxBalance,
+		OverdraftProtection: body.OverdraftProtection,
+		LowBalanceAlert:     body.LowBalanceAlert,
+		LowBalanceThreshold: body.LowBalanceThreshold,
+		LeaveBalancePolicy:  body.LeaveBalancePolicy,
+		BankName:            body.BankName,
+	}
+
+	err = h.groupStore.UpdateSettings(c.Request().Context(), group)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewGroupSettings(group.GroupSettings))
 }
 
-// /api/group/:id/member (GET)
-func (h *Handler) GetGroupMembers(c echo.Context) error {
+// Returns whether the deletion of the group has been requested and the request has not expired yet.
+func deletionPending(group *models.Group) bool {
+	return group.DeletionRequestedBy != "" && group.DeletionExpires > time.Now().Unix()
+}
+
+// /api/group/:id/delete (POST)
+//
+// Marks the group as pending deletion. The deletion has to be confirmed by another admin within config.Data.GroupDeletionWindow.
+func (h *Handler) RequestGroupDeletion(c echo.Context) error {
 	lang := c.Get("lang").(string)
+
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -349,73 +446,114 @@ func (h *Handler) GetGroupMembers(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	id := c.Param("id")
-	if id == "" {
+	groupId := c.Param("id")
+	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
 
-	page := 0
-	pageSize := 20
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
 
-	if c.QueryParam("page") != "" {
-		page, err = strconv.Atoi(c.QueryParam("page"))
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
-		}
+	if deletionPending(group) {
+		return c.JSON(http.StatusOK, responses.New(false, "The deletion of the group has already been requested", lang))
 	}
 
-	if c.QueryParam("pageSize") != "" {
-		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
-		}
-		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
-		}
+	group.DeletionRequestedBy = user.Id
+	group.DeletionExpires = time.Now().Unix() + config.Data.GroupDeletionWindow
+	err = h.groupStore.UpdateDeletionRequest(c.Request().Context(), group)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	descending := services.StrToBool(c.QueryParam("descending"))
-	includeSelf := services.StrToBool(c.QueryParam("includeSelf"))
+	err = h.groupStore.AddAuditLogEntry(c.Request().Context(), &models.GroupAuditLogEntry{
+		GroupId:   group.Id,
+		Action:    models.AuditDeletionRequested,
+		ActorId:   user.Id,
+		ActorName: user.Name,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully requested the deletion of the group", lang))
+}
+
+// /api/group/:id/delete/confirm (POST)
+//
+// Deletes the group if its deletion was requested by another admin.
+// Groups with only one admin can be deleted by confirming the own request.
+func (h *Handler) ConfirmGroupDeletion(c echo.Context) error {
+	lang := c.Get("lang").(string)
 
-	group, err := h.groupStore.GetById(id)
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if group == nil {
-		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	isInGroup, err := h.groupStore.IsInGroup(group, user)
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if !isInGroup {
-		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member/admin of the group", lang))
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	var members []models.User
-	if includeSelf {
-		members, err = h.groupStore.GetMembers(nil, c.QueryParam("search"), group, page, pageSize, descending)
-	} else {
-		members, err = h.groupStore.GetMembers(user, c.QueryParam("search"), group, page, pageSize, descending)
-	}
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	if !deletionPending(group) {
+		return c.JSON(http.StatusOK, responses.New(false, "The deletion of the group has not been requested", lang))
+	}
+
+	if group.DeletionRequestedBy == user.Id {
+		adminCount, err := h.groupStore.AdminCount(c.Request().Context(), group)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if adminCount > 1 {
+			return c.JSON(http.StatusOK, responses.New(false, "The deletion has to be confirmed by another admin", lang))
+		}
+	}
 
-	count, err := h.groupStore.MemberCount(group)
+	err = h.groupStore.Delete(c.Request().Context(), group)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	return c.JSON(http.StatusOK, responses.NewUsers(members, count))
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully deleted group", lang))
 }
 
-// /api/group/:id/member (DELETE)
-func (h *Handler) LeaveGroup(c echo.Context) error {
+// /api/group/:id/delete (DELETE)
+func (h *Handler) CancelGroupDeletion(c echo.Context) error {
 	lang := c.Get("lang").(string)
+
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -423,40 +561,55 @@ func (h *Handler) LeaveGroup(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	id := c.Param("id")
-	if id == "" {
+	groupId := c.Param("id")
+	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-
-	group, err := h.groupStore.GetById(id)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 	if group == nil {
-		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	isMember, err := h.groupStore.IsMember(group, user)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if !isMember {
-		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	if !deletionPending(group) {
+		return c.JSON(http.StatusOK, responses.New(false, "The deletion of the group has not been requested", lang))
 	}
 
-	err = h.groupStore.RemoveMember(group, user)
+	group.DeletionRequestedBy = ""
+	group.DeletionExpires = 0
+	err = h.groupStore.UpdateDeletionRequest(c.Request().Context(), group)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	return c.JSON(http.StatusOK, responses.New(true, "Successfully left group", lang))
+	err = h.groupStore.AddAuditLogEntry(c.Request().Context(), &models.GroupAuditLogEntry{
+		GroupId:   group.Id,
+		Action:    models.AuditDeletionCancelled,
+		ActorId:   user.Id,
+		ActorName: user.Name,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully cancelled the deletion of the group", lang))
 }
 
-// /api/group/:id/admin (GET)
-func (h *Handler) GetGroupAdmins(c echo.Context) error {
+// /api/group/:id/user (GET)
+func (h *Handler) GetGroupUsers(c echo.Context) error {
 	lang := c.Get("lang").(string)
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -490,8 +643,9 @@ func (h *Handler) GetGroupAdmins(c echo.Context) error {
 	}
 
 	descending := services.StrToBool(c.QueryParam("descending"))
+	includeSelf := services.StrToBool(c.QueryParam("includeSelf"))
 
-	group, err := h.groupStore.GetById(id)
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -499,7 +653,7 @@ func (h *Handler) GetGroupAdmins(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
 	}
 
-	isInGroup, err := h.groupStore.IsInGroup(group, user)
+	isInGroup, err := h.groupStore.IsInGroup(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -507,101 +661,108 @@ func (h *Handler) GetGroupAdmins(c echo.Context) error {
 		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member/admin of the group", lang))
 	}
 
-	includeSelf := services.StrToBool(c.QueryParam("includeSelf"))
-	var admins []models.User
+	var memberships []models.GroupMembership
 	if includeSelf {
-		admins, err = h.groupStore.GetAdmins(nil, c.QueryParam("search"), group, page, pageSize, descending)
+		memberships, err = h.groupStore.GetMemberships(c.Request().Context(), nil, c.QueryParam("search"), group, page, pageSize, descending)
 	} else {
-		admins, err = h.groupStore.GetAdmins(user, c.QueryParam("search"), group, page, pageSize, descending)
+		memberships, err = h.groupStore.GetMemberships(c.Request().Context(), user, c.QueryParam("search"), group, page, pageSize, descending)
 	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	count, err := h.groupStore.AdminCount(group)
+	count, err := h.groupStore.MembershipCount(c.Request().Context(), group)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	return c.JSON(http.StatusOK, responses.NewUsers(admins, count))
-}
-
-// /api/group/:id/admin (POST)
-func (h *Handler) AddGroupAdmin(c echo.Context) error {
-	lang := c.Get("lang").(string)
-	authUserId := c.Get("userId").(string)
-	authUser, err := h.userStore.GetById(authUserId)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	type dto struct {
+		Id     string `json:"id"`
+		Name   string `json:"name"`
+		Member bool   `json:"member"`
+		Admin  bool   `json:"admin"`
 	}
-	if authUser == nil {
-		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
-	}
-
-	id := c.Param("id")
-	if id == "" {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	dtos := make([]dto, len(memberships))
+	for i, m := range memberships {
+		member, err := h.userStore.GetById(c.Request().Context(), m.UserId)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		dtos[i] = dto{
+			Id:     member.Id,
+			Name:   member.Name,
+			Member: m.IsMember,
+			Admin:  m.IsAdmin,
+		}
 	}
 
-	group, err := h.groupStore.GetById(id)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
-	if group == nil {
-		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	type response struct {
+		responses.Base
+		Users []dto `json:"users"`
+		Count int64 `json:"count"`
 	}
+	return c.JSON(http.StatusOK, response{
+		Base: responses.Base{
+			Success: true,
+		},
+		Users: dtos,
+		Count: count,
+	})
+}
 
-	authIsAdmin, err := h.groupStore.IsAdmin(group, authUser)
+// /api/group/:id/membership (GET)
+// Returns the relationship of the authenticated user to the group. The balance is only included for members.
+func (h *Handler) GetOwnMembership(c echo.Context) error {
+	lang := c.Get("lang").(string)
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if !authIsAdmin {
-		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	var body bindings.Id
-	err = c.Bind(&body)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
 
-	user, err := h.userStore.GetById(body.Id)
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if user == nil {
-		return c.JSON(http.StatusOK, responses.New(false, "The user doesn't exist", lang))
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	isMember, err := h.groupStore.IsMember(group, user)
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if !isMember {
-		return c.JSON(http.StatusOK, responses.New(false, "The user is not a member of the group", lang))
-	}
 
-	isAdmin, err := h.groupStore.IsAdmin(group, user)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if isAdmin {
-		return c.JSON(http.StatusOK, responses.New(false, "The user already is an admin of the group", lang))
-	}
 
-	err = h.groupStore.AddAdmin(group, user)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	var balance *int
+	if isMember {
+		b, err := h.groupStore.GetUserBalance(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		balance = &b
 	}
 
-	return c.JSON(http.StatusOK, responses.New(true, "Successfully made user an admin", lang))
+	return c.JSON(http.StatusOK, responses.NewOwnMembership(isMember, isAdmin, balance))
 }
 
-// /api/group/:id/admin (DELETE)
-func (h *Handler) RemoveAdminRights(c echo.Context) error {
+// /api/group/:id/member (GET)
+func (h *Handler) GetGroupMembers(c echo.Context) error {
 	lang := c.Get("lang").(string)
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -614,7 +775,31 @@ func (h *Handler) RemoveAdminRights(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
 
-	group, err := h.groupStore.GetById(id)
+	page := 0
+	pageSize := 20
+
+	if c.QueryParam("page") != "" {
+		page, err = strconv.Atoi(c.QueryParam("page"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
+		}
+	}
+
+	if c.QueryParam("pageSize") != "" {
+		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+		}
+		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		}
+	}
+
+	descending := services.StrToBool(c.QueryParam("descending"))
+	includeSelf := services.StrToBool(c.QueryParam("includeSelf"))
+	includeFormer := services.StrToBool(c.QueryParam("includeFormer"))
+
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -622,54 +807,61 @@ func (h *Handler) RemoveAdminRights(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
 	}
 
-	isAdmin, err := h.groupStore.IsAdmin(group, user)
+	isInGroup, err := h.groupStore.IsInGroup(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if !isAdmin {
-		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	if !isInGroup {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member/admin of the group", lang))
 	}
 
-	userCount, err := h.groupStore.GetUserCount(group)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	if includeFormer {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+		}
 	}
 
-	isMember, err := h.groupStore.IsMember(group, user)
+	var members []models.User
+	if includeSelf {
+		members, err = h.groupStore.GetMembers(c.Request().Context(), nil, c.QueryParam("search"), group, page, pageSize, descending, includeFormer)
+	} else {
+		members, err = h.groupStore.GetMembers(c.Request().Context(), user, c.QueryParam("search"), group, page, pageSize, descending, includeFormer)
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	admins, err := h.groupStore.GetAdmins(nil, "", group, 0, 2, false)
+	count, err := h.groupStore.MemberCount(c.Request().Context(), group, includeFormer)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	if (userCount > 1 && len(admins) == 1) || (userCount == 1 && isMember) {
-		return c.JSON(http.StatusOK, responses.New(false, "Cannot remove admin rights of sole admin of group", lang))
+	if !includeFormer {
+		return c.JSON(http.StatusOK, responses.NewUsers(members, count))
 	}
 
-	if userCount == 1 {
-		err = h.groupStore.Delete(group)
+	former := make(map[string]bool)
+	for i := range members {
+		isMember, err := h.groupStore.IsMember(c.Request().Context(), group, &members[i])
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
-		return c.JSON(http.StatusOK, responses.New(true, "Successfully deleted group", lang))
-	}
-
-	err = h.groupStore.RemoveAdmin(group, user)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		former[members[i].Id] = !isMember
 	}
 
-	return c.JSON(http.StatusOK, responses.New(true, "Successfully removed admin rights", lang))
+	return c.JSON(http.StatusOK, responses.NewGroupMembers(members, former, count))
 }
 
-// /api/group/:id/picture?id=uuid (GET)
-func (h *Handler) GetGroupPicture(c echo.Context) error {
+// /api/group/:id/member/export?format=csv|vcard (GET)
+// Emails are only included for publicly visible users. The members are written page by page so large groups don't have to fit into memory.
+func (h *Handler) ExportGroupMembers(c echo.Context) error {
 	lang := c.Get("lang").(string)
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -682,56 +874,92 @@ func (h *Handler) GetGroupPicture(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
 
-	group, err := h.groupStore.GetById(id)
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "vcard" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported format", lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 	if group == nil {
-		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	isInGroup, err := h.groupStore.IsInGroup(group, user)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if !isInGroup {
-		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member/admin of the group", lang))
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
 	}
 
-	if c.QueryParam("id") != "" && c.QueryParam("id") != group.GroupPictureId {
-		return c.JSON(http.StatusNotFound, responses.New(false, "Wrong group picture id", lang))
+	var csvWriter *csv.Writer
+	if format == "csv" {
+		c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=UTF-8")
+		c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=\"members.csv\"")
+		csvWriter = csv.NewWriter(c.Response())
+	} else {
+		c.Response().Header().Set(echo.HeaderContentType, "text/vcard; charset=UTF-8")
+		c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=\"members.vcf\"")
 	}
+	c.Response().WriteHeader(http.StatusOK)
 
-	size := services.PictureSize(c.QueryParam("size"))
-	if c.QueryParam("size") != "" {
-		if !size.Validate() {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid 'size' query parameter", lang))
+	if csvWriter != nil {
+		err = csvWriter.Write([]string{"name", "email"})
+		if err != nil {
+			return err
 		}
-	} else {
-		size = services.PictureHuge
 	}
 
-	groupPicture, err := h.groupStore.GetGroupPicture(group, size)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
-	if len(groupPicture) == 0 {
-		data, err := os.ReadFile("assets/fallback-group-picture.svg")
+	// The status code is already sent, so errors can only abort the download.
+	for page := 0; ; page++ {
+		members, err := h.groupStore.GetMembers(c.Request().Context(), nil, "", group, page, config.Data.MaxPageSize, false, false)
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+			return err
+		}
+		if len(members) == 0 {
+			break
+		}
+
+		for _, m := range members {
+			email := ""
+			if m.PubliclyVisible {
+				email = m.Email
+			}
+			if csvWriter != nil {
+				err = csvWriter.Write([]string{services.EscapeCSVCell(m.Name), services.EscapeCSVCell(email)})
+			} else {
+				err = services.WriteVCard(c.Response(), m.Name, email)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if csvWriter != nil {
+			csvWriter.Flush()
+			err = csvWriter.Error()
+			if err != nil {
+				return err
+			}
 		}
-		return c.Blob(http.StatusOK, "image/svg+xml", data)
+		c.Response().Flush()
 	}
 
-	return c.Blob(http.StatusOK, "image/jpeg", groupPicture)
+	return nil
 }
 
-// /api/group/:id/picture (POST)
-func (h *Handler) SetGroupPicture(c echo.Context) error {
+// /api/group/:id/member/:userId (GET)
+// Balance and recent transactions are only included for group admins and the member themselves.
+func (h *Handler) GetGroupMember(c echo.Context) error {
 	lang := c.Get("lang").(string)
-
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -739,82 +967,76 @@ func (h *Handler) SetGroupPicture(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	groupId := c.Param("id")
-	if groupId == "" {
+	id := c.Param("id")
+	if id == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
 
-	group, err := h.groupStore.GetById(groupId)
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 	if group == nil {
-		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
 	}
 
-	isAdmin, err := h.groupStore.IsAdmin(group, user)
+	isInGroup, err := h.groupStore.IsInGroup(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if !isAdmin {
-		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	if !isInGroup {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member/admin of the group", lang))
 	}
 
-	file, err := c.FormFile("groupPicture")
+	member, err := h.userStore.GetById(c.Request().Context(), c.Param("userId"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid or missing group picture file", lang))
-	}
-
-	if file.Size > config.Data.MaxProfilePictureFileSize {
-		return c.JSON(http.StatusBadRequest, responses.New(false, fmt.Sprintf(services.Tr("File too big (max %s)", lang), services.SizeInBytesToStr(config.Data.MaxProfilePictureFileSize)), ""))
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-
-	mimeType := file.Header.Get("Content-Type")
-	if !services.SupportedPictureMimeType(mimeType) {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported file type", lang))
+	if member == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "The user is not a member of the group", lang))
 	}
 
-	src, err := file.Open()
+	membership, err := h.groupStore.GetMembership(c.Request().Context(), group, member)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	defer src.Close()
+	if membership == nil || (!membership.IsMember && !membership.IsAdmin) {
+		return c.JSON(http.StatusNotFound, responses.New(false, "The user is not a member of the group", lang))
+	}
 
-	var buf bytes.Buffer
-	_, err = buf.ReadFrom(src)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	pic, err := services.NewPicture(buf.Bytes(), mimeType)
+	var balance *int
+	var recentTransactions []models.TransactionLogEntry
+	if membership.IsMember && (isAdmin || member.Id == user.Id) {
+		b, err := h.groupStore.GetUserBalance(c.Request().Context(), group, member)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		balance = &b
+
+		recentTransactions, err = h.groupStore.GetTransactionLog(c.Request().Context(), group, member, "", models.TransactionFilter{}, 0, 5, false)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+	}
+
+	names, err := h.transactionUserNames(c.Request().Context(), group, recentTransactions...)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	group.GroupPictureId = uuid.NewString()
-	h.groupStore.UpdateGroupPicture(group, &models.GroupPicture{
-		Tiny:   pic.Tiny,
-		Small:  pic.Small,
-		Medium: pic.Medium,
-		Large:  pic.Large,
-		Huge:   pic.Huge,
-	})
-
-	return c.JSON(http.StatusOK, responses.Id{
-		Base: responses.Base{
-			Success: true,
-			Message: services.Tr("Successfully updated group picture", lang),
-		},
-		Id: group.GroupPictureId,
-	})
+	return c.JSON(http.StatusOK, responses.NewGroupMember(membership, member, balance, recentTransactions, names))
 }
 
-// /api/group/:id/picture (DELETE)
-func (h *Handler) RemoveGroupPicture(c echo.Context) error {
+// /api/group/:id/member (DELETE)
+func (h *Handler) LeaveGroup(c echo.Context) error {
 	lang := c.Get("lang").(string)
-
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -822,44 +1044,66 @@ func (h *Handler) RemoveGroupPicture(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	groupId := c.Param("id")
-	if groupId == "" {
+	id := c.Param("id")
+	if id == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 	if group == nil {
-		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
 	}
 
-	isAdmin, err := h.groupStore.IsAdmin(group, user)
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if !isAdmin {
-		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	if !isMember {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
 	}
 
-	group.GroupPictureId = uuid.NewString()
-	h.groupStore.UpdateGroupPicture(group, nil)
+	_, err = h.removeMember(c.Request().Context(), group, user, user, lang)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
 
-	return c.JSON(http.StatusOK, responses.Id{
-		Base: responses.Base{
-			Success: true,
-			Message: services.Tr("Successfully updated group picture", lang),
-		},
-		Id: group.GroupPictureId,
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully left group", lang))
+}
+
+// Settles the balance of the member according to the LeaveBalancePolicy of the group and removes the member.
+// A settlement is recorded in the audit log with actor as the user who caused it.
+// Reports whether the balance was settled.
+func (h *Handler) removeMember(ctx context.Context, group *models.Group, member, actor *models.User, lang string) (bool, error) {
+	transactions, err := h.groupStore.SettleBalanceAndRemoveMember(ctx, group, member, services.Tr("Balance settled on leaving the group", lang))
+	if err != nil {
+		return false, err
+	}
+	if len(transactions) == 0 {
+		return false, nil
+	}
+
+	action := models.AuditBalanceRedistributed
+	if transactions[0].SenderIsBank || transactions[0].ReceiverIsBank {
+		action = models.AuditBalanceSettled
+	}
+	return true, h.groupStore.AddAuditLogEntry(ctx, &models.GroupAuditLogEntry{
+		GroupId:    group.Id,
+		Action:     action,
+		ActorId:    actor.Id,
+		ActorName:  actor.Name,
+		TargetId:   member.Id,
+		TargetName: member.Name,
 	})
 }
 
-// /api/group/:id/transaction/balance (GET)
-func (h *Handler) GetBalance(c echo.Context) error {
+// /api/group/:id/member/:userId (DELETE)
+func (h *Handler) RemoveGroupMember(c echo.Context) error {
 	lang := c.Get("lang").(string)
-
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -867,28 +1111,694 @@ func (h *Handler) GetBalance(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	groupId := c.Param("id")
-	if groupId == "" {
+	id := c.Param("id")
+	if id == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 	if group == nil {
-		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
 	}
 
-	isMember, err := h.groupStore.IsMember(group, user)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
 
-	if !isMember {
-		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+	if c.Param("userId") == user.Id {
+		return c.JSON(http.StatusOK, responses.New(false, "You can't remove yourself", lang))
 	}
 
-	balance, err := h.groupStore.GetUserBalance(group, user)
+	member, err := h.userStore.GetById(c.Request().Context(), c.Param("userId"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if member == nil {
+		return c.JSON(http.StatusOK, responses.New(false, "The user doesn't exist", lang))
+	}
+
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, member)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isMember {
+		return c.JSON(http.StatusOK, responses.New(false, "The user is not a member of the group", lang))
+	}
+
+	memberIsAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, member)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if memberIsAdmin {
+		return c.JSON(http.StatusOK, responses.New(false, "Cannot remove an admin of the group", lang))
+	}
+
+	// RemoveMember also deletes the payment plans of the member.
+	_, err = h.removeMember(c.Request().Context(), group, member, user, lang)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	err = h.groupStore.AddAuditLogEntry(c.Request().Context(), &models.GroupAuditLogEntry{
+		GroupId:    group.Id,
+		Action:     models.AuditMemberRemoved,
+		ActorId:    user.Id,
+		ActorName:  user.Name,
+		TargetId:   member.Id,
+		TargetName: member.Name,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if config.Data.EmailEnabled {
+		type templateData struct {
+			Name      string
+			GroupName string
+		}
+		// The member has already been removed, so a failing email must not fail the request.
+		body, err := services.ParseEmailTemplate("removedFromGroup", member.Language, templateData{
+			Name:      member.Name,
+			GroupName: group.Name,
+		})
+		if err != nil {
+			log.Println("Error while sending removal email:", err)
+		} else {
+			go services.SendEmail([]string{member.Email}, config.Data.EmailProductName+" "+services.Tr("Removed from group", member.Language), body, false)
+		}
+	}
+
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully removed member", lang))
+}
+
+// /api/group/:id/audit?page=int&pageSize=int&oldestFirst=bool (GET)
+func (h *Handler) GetAuditLog(c echo.Context) error {
+	lang := c.Get("lang").(string)
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	page := 0
+	pageSize := 20
+
+	if c.QueryParam("page") != "" {
+		page, err = strconv.Atoi(c.QueryParam("page"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
+		}
+	}
+
+	if c.QueryParam("pageSize") != "" {
+		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+		}
+		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		}
+	}
+
+	oldestFirst := oldestFirstParam(c, user)
+
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	log, err := h.groupStore.GetAuditLog(c.Request().Context(), group, page, pageSize, oldestFirst)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	count, err := h.groupStore.AuditLogEntryCount(c.Request().Context(), group)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewAuditLog(log, count))
+}
+
+// /api/group/:id/admin (GET)
+func (h *Handler) GetGroupAdmins(c echo.Context) error {
+	lang := c.Get("lang").(string)
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	page := 0
+	pageSize := 20
+
+	if c.QueryParam("page") != "" {
+		page, err = strconv.Atoi(c.QueryParam("page"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
+		}
+	}
+
+	if c.QueryParam("pageSize") != "" {
+		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+		}
+		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		}
+	}
+
+	descending := services.StrToBool(c.QueryParam("descending"))
+
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	isInGroup, err := h.groupStore.IsInGroup(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isInGroup {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member/admin of the group", lang))
+	}
+
+	includeSelf := services.StrToBool(c.QueryParam("includeSelf"))
+	var admins []models.User
+	if includeSelf {
+		admins, err = h.groupStore.GetAdmins(c.Request().Context(), nil, c.QueryParam("search"), group, page, pageSize, descending)
+	} else {
+		admins, err = h.groupStore.GetAdmins(c.Request().Context(), user, c.QueryParam("search"), group, page, pageSize, descending)
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	count, err := h.groupStore.AdminCount(c.Request().Context(), group)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewUsers(admins, count))
+}
+
+// /api/group/:id/admin (POST)
+func (h *Handler) AddGroupAdmin(c echo.Context) error {
+	lang := c.Get("lang").(string)
+	authUserId := c.Get("userId").(string)
+	authUser, err := h.userStore.GetById(c.Request().Context(), authUserId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if authUser == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	authIsAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, authUser)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !authIsAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	var body bindings.Id
+	err = c.Bind(&body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+	}
+
+	user, err := h.userStore.GetById(c.Request().Context(), body.Id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusOK, responses.New(false, "The user doesn't exist", lang))
+	}
+
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isMember {
+		return c.JSON(http.StatusOK, responses.New(false, "The user is not a member of the group", lang))
+	}
+
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if isAdmin {
+		return c.JSON(http.StatusOK, responses.New(false, "The user already is an admin of the group", lang))
+	}
+
+	err = h.groupStore.AddAdmin(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully made user an admin", lang))
+}
+
+// /api/group/:id/admin (DELETE)
+func (h *Handler) RemoveAdminRights(c echo.Context) error {
+	lang := c.Get("lang").(string)
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	userCount, err := h.groupStore.GetUserCount(c.Request().Context(), group)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	admins, err := h.groupStore.GetAdmins(c.Request().Context(), nil, "", group, 0, 2, false)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if (userCount > 1 && len(admins) == 1) || (userCount == 1 && isMember) {
+		return c.JSON(http.StatusOK, responses.New(false, "Cannot remove admin rights of sole admin of group", lang))
+	}
+
+	if userCount == 1 {
+		err = h.groupStore.Delete(c.Request().Context(), group)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		return c.JSON(http.StatusOK, responses.New(true, "Successfully deleted group", lang))
+	}
+
+	err = h.groupStore.RemoveAdmin(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully removed admin rights", lang))
+}
+
+// /api/group/:id/picture?id=uuid&encoding=base64 (GET)
+// The picture is returned as base64 inside of a JSON object if encoding is base64 and as raw data otherwise.
+func (h *Handler) GetGroupPicture(c echo.Context) error {
+	lang := c.Get("lang").(string)
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	isInGroup, err := h.groupStore.IsInGroup(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isInGroup {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member/admin of the group", lang))
+	}
+
+	if c.QueryParam("id") != "" && c.QueryParam("id") != group.GroupPictureId {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Wrong group picture id", lang))
+	}
+
+	size := services.PictureSize(c.QueryParam("size"))
+	if c.QueryParam("size") != "" {
+		if !size.Validate() {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid 'size' query parameter", lang))
+		}
+	} else {
+		size = services.PictureHuge
+	}
+
+	encoding := c.QueryParam("encoding")
+	if encoding != "" && encoding != "base64" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported encoding", lang))
+	}
+
+	groupPicture, err := h.groupStore.GetGroupPicture(c.Request().Context(), group, size)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if len(groupPicture) == 0 {
+		data, err := os.ReadFile("assets/fallback-group-picture.svg")
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		return sendPicture(c, "image/svg+xml", data, encoding == "base64")
+	}
+
+	return sendPicture(c, "image/jpeg", groupPicture, encoding == "base64")
+}
+
+func sendPicture(c echo.Context, contentType string, data []byte, base64 bool) error {
+	if base64 {
+		return c.JSON(http.StatusOK, responses.NewPicture(contentType, data))
+	}
+	return c.Blob(http.StatusOK, contentType, data)
+}
+
+// /api/group/:id/picture (POST)
+func (h *Handler) SetGroupPicture(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	file, err := c.FormFile("groupPicture")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid or missing group picture file", lang))
+	}
+
+	if file.Size > config.Data.MaxProfilePictureFileSize {
+		return c.JSON(http.StatusBadRequest, responses.New(false, fmt.Sprintf(services.Tr("File too big (max %s)", lang), services.SizeInBytesToStr(config.Data.MaxProfilePictureFileSize)), ""))
+	}
+
+	mimeType := file.Header.Get("Content-Type")
+	if !services.SupportedPictureMimeType(mimeType) {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported file type", lang))
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	defer src.Close()
+
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(src)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if !services.SupportedPictureData(buf.Bytes()) {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported file type", lang))
+	}
+
+	pic, err := services.NewPicture(c.Request().Context(), buf.Bytes(), mimeType)
+	if errors.Is(err, services.ErrPictureProcessingBusy) {
+		c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(services.PictureQueueTimeout.Seconds())))
+		return c.JSON(http.StatusServiceUnavailable, responses.New(false, "Too many pictures are being processed, please try again later", lang))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	group.GroupPictureId = uuid.NewString()
+	h.groupStore.UpdateGroupPicture(c.Request().Context(), group, &models.GroupPicture{
+		Tiny:   pic.Tiny,
+		Small:  pic.Small,
+		Medium: pic.Medium,
+		Large:  pic.Large,
+		Huge:   pic.Huge,
+	})
+
+	return c.JSON(http.StatusOK, responses.Id{
+		Base: responses.Base{
+			Success: true,
+			Message: services.Tr("Successfully updated group picture", lang),
+		},
+		Id: group.GroupPictureId,
+	})
+}
+
+// /api/group/:id/picture (DELETE)
+func (h *Handler) RemoveGroupPicture(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	group.GroupPictureId = uuid.NewString()
+	h.groupStore.UpdateGroupPicture(c.Request().Context(), group, nil)
+
+	return c.JSON(http.StatusOK, responses.Id{
+		Base: responses.Base{
+			Success: true,
+			Message: services.Tr("Successfully updated group picture", lang),
+		},
+		Id: group.GroupPictureId,
+	})
+}
+
+// /api/group/:id/transaction/balance (GET)
+func (h *Handler) GetBalance(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if !isMember {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+	}
+
+	balance, err := h.groupStore.GetUserBalance(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.Balance{
+		Base: responses.Base{
+			Success: true,
+		},
+		Balance: balance,
+	})
+}
+
+// /api/group/:id/transaction/balanceAt?at=int&userId=string (GET)
+func (h *Handler) GetBalanceAt(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	if c.QueryParam("at") == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing 'at' query parameter", lang))
+	}
+	at, err := strconv.ParseInt(c.QueryParam("at"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "'at' query parameter not a number", lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	member := user
+	if c.QueryParam("userId") != "" && c.QueryParam("userId") != user.Id {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+		}
+
+		member = &models.User{Base: models.Base{Id: c.QueryParam("userId")}}
+		membership, err := h.groupStore.GetMembership(c.Request().Context(), group, member)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if membership == nil {
+			return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+		}
+	} else {
+		isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !isMember {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+		}
+	}
+
+	balance, err := h.groupStore.GetUserBalanceAt(c.Request().Context(), group, member, at)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -897,16 +1807,1836 @@ func (h *Handler) GetBalance(c echo.Context) error {
 		Base: responses.Base{
 			Success: true,
 		},
-		Balance: balance,
+		Balance: balance,
+	})
+}
+
+// /api/group/:id/transaction/:transactionId (GET)
+func (h *Handler) GetTransactionById(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	transactionId := c.Param("transactionId")
+	if transactionId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing transactionId parameter", lang))
+	}
+
+	transaction, err := h.groupStore.GetTransactionLogEntryById(c.Request().Context(), group, transactionId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if transaction == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	return h.transactionResponse(c, lang, user, group, transaction)
+}
+
+// /api/group/:id/transaction/:transactionId/full (GET)
+// Returns the transaction with the balances of both parties.
+func (h *Handler) GetFullTransactionById(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	transactionId := c.Param("transactionId")
+	if transactionId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing transactionId parameter", lang))
+	}
+
+	transaction, err := h.groupStore.GetTransactionLogEntryById(c.Request().Context(), group, transactionId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if transaction == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	names, err := h.transactionUserNames(c.Request().Context(), group, *transaction)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewFullTransaction(transaction, names))
+}
+
+// /api/group/:id/transaction/byReference/:ref (GET)
+func (h *Handler) GetTransactionByReference(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	reference := c.Param("ref")
+	if reference == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing ref parameter", lang))
+	}
+
+	// References are generated in upper case but are often typed in lower case.
+	transaction, err := h.groupStore.GetTransactionLogEntryByReference(c.Request().Context(), group, strings.ToUpper(reference))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if transaction == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	return h.transactionResponse(c, lang, user, group, transaction)
+}
+
+// Responds with the transaction if the user is its sender or receiver or an admin and the bank is involved.
+func (h *Handler) transactionResponse(c echo.Context, lang string, user *models.User, group *models.Group, transaction *models.TransactionLogEntry) error {
+	names, err := h.transactionUserNames(c.Request().Context(), group, *transaction)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	isSender := user.Id == transaction.SenderId
+	isReceiver := user.Id == transaction.ReceiverId
+
+	if isSender || isReceiver {
+		return c.JSON(http.StatusOK, responses.NewTransaction(transaction, user, names))
+	} else if transaction.SenderIsBank || transaction.ReceiverIsBank {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+		}
+
+		return c.JSON(http.StatusOK, responses.NewBankTransaction(transaction, names))
+	}
+
+	return c.JSON(http.StatusForbidden, responses.New(false, "User not allowed to view transaction", lang))
+}
+
+// /api/group/:id/transaction?bank=bool&search=string&counterparty=string&minAmount=int&maxAmount=int&page=int&pageSize=int&oldestFirst=bool (GET)
+func (h *Handler) GetTransactionLog(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	page := 0
+	pageSize := 20
+
+	if c.QueryParam("page") != "" {
+		page, err = strconv.Atoi(c.QueryParam("page"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
+		}
+	}
+
+	if c.QueryParam("pageSize") != "" {
+		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+		}
+		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		}
+	}
+
+	oldestFirst := oldestFirstParam(c, user)
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	bank := services.StrToBool(c.QueryParam("bank"))
+
+	if !bank {
+		isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		if !isMember {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+		}
+
+		filter := models.TransactionFilter{
+			CounterpartyId: c.QueryParam("counterparty"),
+		}
+		if c.QueryParam("minAmount") != "" {
+			filter.MinAmount, err = strconv.Atoi(c.QueryParam("minAmount"))
+			if err != nil || filter.MinAmount < 0 {
+				return c.JSON(http.StatusBadRequest, responses.New(false, "'minAmount' query parameter not a number or <0", lang))
+			}
+		}
+		if c.QueryParam("maxAmount") != "" {
+			filter.MaxAmount, err = strconv.Atoi(c.QueryParam("maxAmount"))
+			if err != nil || filter.MaxAmount < 1 {
+				return c.JSON(http.StatusBadRequest, responses.New(false, "'maxAmount' query parameter not a number or <1", lang))
+			}
+			if filter.MinAmount > filter.MaxAmount {
+				return c.JSON(http.StatusBadRequest, responses.New(false, "'minAmount' must not exceed 'maxAmount'", lang))
+			}
+		}
+
+		log, err := h.groupStore.GetTransactionLog(c.Request().Context(), group, user, c.QueryParam("search"), filter, page, pageSize, oldestFirst)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		count, err := h.groupStore.TransactionLogEntryCount(c.Request().Context(), group, user, filter)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		names, err := h.transactionUserNames(c.Request().Context(), group, log...)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		return c.JSON(http.StatusOK, responses.NewTransactionLog(log, user, names, count))
+	} else {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		if !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+		}
+
+		log, err := h.groupStore.GetBankTransactionLog(c.Request().Context(), group, c.QueryParam("search"), page, pageSize, oldestFirst)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		count, err := h.groupStore.BankTransactionLogEntryCount(c.Request().Context(), group)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		names, err := h.transactionUserNames(c.Request().Context(), group, log...)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		return c.JSON(http.StatusOK, responses.NewBankTransactionLog(log, names, count))
+	}
+}
+
+// /api/group/:id/transaction/byCounterparty?page=int&pageSize=int (GET)
+func (h *Handler) GetCounterpartyTotals(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	page := 0
+	pageSize := 20
+
+	if c.QueryParam("page") != "" {
+		page, err = strconv.Atoi(c.QueryParam("page"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
+		}
+	}
+
+	if c.QueryParam("pageSize") != "" {
+		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+		}
+		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		}
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isMember {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+	}
+
+	totals, err := h.groupStore.GetCounterpartyTotals(c.Request().Context(), group, user, page, pageSize)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	count, err := h.groupStore.CounterpartyCount(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	userIds := make([]string, 0, len(totals))
+	for _, t := range totals {
+		if t.CounterpartyId != "bank" {
+			userIds = append(userIds, t.CounterpartyId)
+		}
+	}
+	names, err := h.groupStore.GetUserNames(c.Request().Context(), group, userIds)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	names["bank"] = group.BankName
+
+	return c.JSON(http.StatusOK, responses.NewCounterpartyTotals(totals, names, count))
+}
+
+// /api/group/:id/transaction?dryRun=bool (POST)
+func (h *Handler) CreateTransaction(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	var body bindings.CreateTransaction
+	err = c.Bind(&body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+	}
+	if status, msg := validateTransaction(int(body.Amount), &body.Title, &body.Description); msg != "" {
+		return c.JSON(status, responses.New(false, msg, lang))
+	}
+
+	dryRun := services.StrToBool(c.QueryParam("dryRun"))
+	createTransaction := h.groupStore.CreateTransaction
+	if dryRun {
+		createTransaction = h.groupStore.PreviewTransaction
+	}
+
+	if !body.FromBank {
+		status, msg, err := h.checkSender(c.Request().Context(), group, user, int(body.Amount))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if msg != "" {
+			return c.JSON(status, responses.New(false, msg, lang))
+		}
+	}
+
+	var transaction *models.TransactionLogEntry
+
+	if strings.EqualFold(body.ReceiverId, "bank") {
+		if body.FromBank {
+			return c.JSON(http.StatusOK, responses.New(false, "Cannot send money from bank to bank", lang))
+		}
+		transaction, err = createTransaction(c.Request().Context(), group, false, true, user, nil, body.Title, body.Description, int(body.Amount))
+		if errors.Is(err, models.ErrInsufficientFunds) {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Not enough money", lang))
+		}
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, responses.NewUnexpectedError(err, lang))
+		}
+	} else {
+		receiver, status, msg, err := h.findReceiver(c.Request().Context(), group, body.ReceiverId, int(body.Amount))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if msg != "" {
+			return c.JSON(status, responses.New(false, msg, lang))
+		}
+
+		if body.FromBank {
+			isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+			}
+			if !isAdmin {
+				return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+			}
+			transaction, err = createTransaction(c.Request().Context(), group, true, false, nil, receiver, body.Title, body.Description, int(body.Amount))
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, responses.NewUnexpectedError(err, lang))
+			}
+		} else {
+			if user.Id == body.ReceiverId {
+				return c.JSON(http.StatusOK, responses.New(false, "Sender is the receiver", lang))
+			}
+			transaction, err = createTransaction(c.Request().Context(), group, false, false, user, receiver, body.Title, body.Description, int(body.Amount))
+			if errors.Is(err, models.ErrInsufficientFunds) {
+				return c.JSON(http.StatusBadRequest, responses.New(false, "Not enough money", lang))
+			}
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+			}
+		}
+	}
+
+	names, err := h.transactionUserNames(c.Request().Context(), group, *transaction)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if dryRun {
+		return c.JSON(http.StatusOK, responses.NewTransactionPreview(transaction, names))
+	}
+
+	if config.Data.EmailEnabled {
+		go h.sendReceiptEmails(context.WithoutCancel(c.Request().Context()), group, transaction, names)
+	}
+
+	return c.JSON(http.StatusOK, responses.NewTransaction(transaction, user, names))
+}
+
+// /api/group/:id/transfer (POST)
+// Sends money from the authenticated member to another member without having to specify the bank flags.
+func (h *Handler) Transfer(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	var body bindings.Transfer
+	err = c.Bind(&body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+	}
+	if status, msg := validateTransaction(body.Amount, &body.Title, &body.Description); msg != "" {
+		return c.JSON(status, responses.New(false, msg, lang))
+	}
+	if body.ReceiverId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing receiver", lang))
+	}
+	if body.ReceiverId == user.Id {
+		return c.JSON(http.StatusOK, responses.New(false, "Sender is the receiver", lang))
+	}
+
+	// Admins who aren't members have no balance, so both sides have to be members.
+	status, msg, err := h.checkSender(c.Request().Context(), group, user, body.Amount)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if msg != "" {
+		return c.JSON(status, responses.New(false, msg, lang))
+	}
+
+	receiver, status, msg, err := h.findReceiver(c.Request().Context(), group, body.ReceiverId, body.Amount)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if msg != "" {
+		return c.JSON(status, responses.New(false, msg, lang))
+	}
+
+	transaction, err := h.groupStore.CreateTransaction(c.Request().Context(), group, false, false, user, receiver, body.Title, body.Description, body.Amount)
+	if errors.Is(err, models.ErrInsufficientFunds) {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Not enough money", lang))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	names, err := h.transactionUserNames(c.Request().Context(), group, *transaction)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if config.Data.EmailEnabled {
+		go h.sendReceiptEmails(context.WithoutCancel(c.Request().Context()), group, transaction, names)
+	}
+
+	return c.JSON(http.StatusOK, responses.NewTransaction(transaction, user, names))
+}
+
+// Trims title and description of a new transaction and checks them and the amount.
+// Returns the status code and message of the error response, the message is empty if the transaction is valid.
+func validateTransaction(amount int, title, description *string) (int, string) {
+	if amount <= 0 {
+		return http.StatusOK, "Amount must be >0"
+	}
+
+	*title = strings.TrimSpace(*title)
+	*description = strings.TrimSpace(*description)
+
+	if utf8.RuneCountInString(*title) > config.Data.MaxNameLength {
+		return http.StatusOK, "Title too long"
+	}
+
+	if utf8.RuneCountInString(*title) < config.Data.MinNameLength {
+		return http.StatusOK, "Title too short"
+	}
+
+	if utf8.RuneCountInString(*description) > config.Data.MaxDescriptionLength {
+		return http.StatusOK, "Description too long"
+	}
+
+	if utf8.RuneCountInString(*description) < config.Data.MinDescriptionLength {
+		return http.StatusOK, "Description too short"
+	}
+
+	return 0, ""
+}
+
+// Checks that the sender is a member of the group whose balance stays above the minimum balance after sending amount
+// if the group has overdraft protection enabled.
+// Returns the status code and message of the error response, the message is empty if the sender may send the money.
+func (h *Handler) checkSender(ctx context.Context, group *models.Group, sender *models.User, amount int) (int, string, error) {
+	isMember, err := h.groupStore.IsMember(ctx, group, sender)
+	if err != nil {
+		return 0, "", err
+	}
+	if !isMember {
+		return http.StatusForbidden, "Not a member of the group", nil
+	}
+
+	if !group.OverdraftProtection {
+		return 0, "", nil
+	}
+	balance, err := h.groupStore.GetUserBalance(ctx, group, sender)
+	if err != nil {
+		return 0, "", err
+	}
+	if balance-amount < group.MinBalance {
+		return http.StatusBadRequest, "Not enough money", nil
+	}
+
+	return 0, "", nil
+}
+
+// Returns the member of the group with the given id if their balance stays below the maximum balance after receiving amount.
+// Otherwise the status code and message of the error response are returned.
+func (h *Handler) findReceiver(ctx context.Context, group *models.Group, receiverId string, amount int) (*models.User, int, string, error) {
+	receiver, err := h.userStore.GetById(ctx, receiverId)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if receiver == nil {
+		return nil, http.StatusNotFound, "Couldn't find receiver", nil
+	}
+
+	isMember, err := h.groupStore.IsMember(ctx, group, receiver)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if !isMember {
+		return nil, http.StatusForbidden, "Receiver not a member of the group", nil
+	}
+
+	if group.MaxBalance != 0 {
+		balance, err := h.groupStore.GetUserBalance(ctx, group, receiver)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		if balance+amount > group.MaxBalance {
+			return nil, http.StatusOK, "The balance of the receiver would exceed the maximum balance", nil
+		}
+	}
+
+	return receiver, 0, "", nil
+}
+
+// /api/group/:id/transaction/cash (POST)
+func (h *Handler) CreateCashTransaction(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isMember {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+	}
+
+	var body bindings.CreateCashTransaction
+	err = c.Bind(&body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+	}
+
+	// The cash log counts the coins and notes of a single currency.
+	if group.Unit != config.Data.CashUnit {
+		return c.JSON(http.StatusOK, responses.New(false, "Cash transactions are only supported in groups using the currency of the cash log", lang))
+	}
+
+	cash := newCashLogEntry(body.AddCashLogEntry)
+	if hasUnsupportedCash(&cash, config.Data.CashDenominations) {
+		return c.JSON(http.StatusOK, responses.New(false, "Unsupported coin or note", lang))
+	}
+	amount := cash.Value()
+
+	if status, msg := validateTransaction(amount, &body.Title, &body.Description); msg != "" {
+		return c.JSON(status, responses.New(false, msg, lang))
+	}
+
+	balance, err := h.groupStore.GetUserBalance(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if body.Deposit {
+		// Deposits are booked as transactions from the bank, which only admins may create.
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+		}
+		if group.MaxBalance != 0 && balance+amount > group.MaxBalance {
+			return c.JSON(http.StatusOK, responses.New(false, "The balance of the receiver would exceed the maximum balance", lang))
+		}
+	} else if group.OverdraftProtection && balance-amount < group.MinBalance {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Not enough money", lang))
+	}
+
+	transaction, cashLogEntry, err := h.groupStore.CreateCashTransaction(c.Request().Context(), group, user, body.Deposit, body.Title, body.Description, &cash)
+	if errors.Is(err, models.ErrNotEnoughCash) {
+		return c.JSON(http.StatusOK, responses.New(false, "Not enough cash", lang))
+	}
+	if errors.Is(err, models.ErrInsufficientFunds) {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Not enough money", lang))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	names, err := h.transactionUserNames(c.Request().Context(), group, *transaction)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if config.Data.EmailEnabled {
+		go h.sendReceiptEmails(context.WithoutCancel(c.Request().Context()), group, transaction, names)
+	}
+
+	return c.JSON(http.StatusOK, responses.NewCashTransaction(transaction, cashLogEntry, user, names))
+}
+
+// Sends a receipt of the transaction to all involved users who opted in.
+// Runs after the response is sent, so ctx must not be cancelled together with the request.
+func (h *Handler) sendReceiptEmails(ctx context.Context, group *models.Group, transaction *models.TransactionLogEntry, names map[string]string) {
+	type participant struct {
+		id         string
+		newBalance int
+	}
+	var participants []participant
+	if !transaction.SenderIsBank {
+		participants = append(participants, participant{id: transaction.SenderId, newBalance: transaction.NewBalanceSender})
+	}
+	if !transaction.ReceiverIsBank {
+		participants = append(participants, participant{id: transaction.ReceiverId, newBalance: transaction.NewBalanceReceiver})
+	}
+
+	for _, p := range participants {
+		user, err := h.userStore.GetById(ctx, p.id)
+		if err != nil {
+			log.Println("Error while sending receipt email:", err)
+			continue
+		}
+		if user == nil || !user.SendReceiptEmail {
+			continue
+		}
+
+		// Receipts are rendered in the language of their recipient, not of the user who created the transaction.
+		lang := user.Language
+		senderName := names[transaction.SenderId]
+		if transaction.SenderIsBank {
+			senderName = bankName(group, lang)
+		}
+		receiverName := names[transaction.ReceiverId]
+		if transaction.ReceiverIsBank {
+			receiverName = bankName(group, lang)
+		}
+
+		pdf, err := services.GenerateReceiptPDF(services.Receipt{
+			Reference:    transaction.Reference,
+			Time:         transaction.Created,
+			GroupName:    group.Name,
+			Title:        transaction.Title,
+			Description:  transaction.Description,
+			Amount:       transaction.Amount,
+			Unit:         group.Unit,
+			SenderName:   senderName,
+			ReceiverName: receiverName,
+			NewBalance:   p.newBalance,
+		}, lang)
+		if err != nil {
+			log.Println("Error while generating receipt:", err)
+			continue
+		}
+
+		type templateData struct {
+			Name       string
+			GroupName  string
+			Title      string
+			Amount     string
+			NewBalance string
+		}
+		body, err := services.ParseEmailTemplate("transactionReceipt", lang, templateData{
+			Name:       user.Name,
+			GroupName:  group.Name,
+			Title:      transaction.Title,
+			Amount:     services.FormatAmount(transaction.Amount, group.Unit),
+			NewBalance: services.FormatAmount(p.newBalance, group.Unit),
+		})
+		if err != nil {
+			log.Println("Error while sending receipt email:", err)
+			continue
+		}
+
+		services.SendEmail([]string{user.Email}, config.Data.EmailProductName+" "+services.Tr("Transaction Receipt", lang), body, false, services.EmailAttachment{
+			Filename:    fmt.Sprintf("receipt-%s.pdf", transaction.Reference),
+			ContentType: "application/pdf",
+			Data:        pdf,
+		})
+	}
+}
+
+// Returns the names of the users involved in the transactions, including users which are no longer part of the group.
+func (h *Handler) transactionUserNames(ctx context.Context, group *models.Group, transactions ...models.TransactionLogEntry) (map[string]string, error) {
+	userIds := make([]string, 0, 2*len(transactions))
+	for _, t := range transactions {
+		if !t.SenderIsBank {
+			userIds = append(userIds, t.SenderId)
+		}
+		if !t.ReceiverIsBank {
+			userIds = append(userIds, t.ReceiverId)
+		}
+	}
+	names, err := h.groupStore.GetUserNames(ctx, group, userIds)
+	if err != nil {
+		return nil, err
+	}
+	names["bank"] = group.BankName
+	return names, nil
+}
+
+// Returns the bank name of the group. The default name is translated.
+func bankName(group *models.Group, lang string) string {
+	if group.BankName == "" || group.BankName == models.DefaultBankName {
+		return services.Tr("Bank", lang)
+	}
+	return group.BankName
+}
+
+// /api/group/invitation?page=int&pageSize=int&oldestFirst=bool (GET)
+func (h *Handler) GetInvitationsByUser(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	page := 0
+	pageSize := 20
+
+	if c.QueryParam("page") != "" {
+		page, err = strconv.Atoi(c.QueryParam("page"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
+		}
+	}
+
+	if c.QueryParam("pageSize") != "" {
+		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+		}
+		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		}
+	}
+
+	oldestFirst := oldestFirstParam(c, user)
+
+	invitations, err := h.groupStore.GetInvitationsByUser(c.Request().Context(), user, page, pageSize, oldestFirst)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	count, err := h.groupStore.InvitationCountByUser(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	// The response still contains the previous seen state so that the client can highlight new invitations.
+	err = h.groupStore.MarkInvitationsAsSeen(c.Request().Context(), invitations)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewInvitations(invitations, count))
+}
+
+// /api/group/invitation/unseen (GET)
+func (h *Handler) GetUnseenInvitationCount(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	count, err := h.groupStore.UnseenInvitationCountByUser(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewUnseenInvitationCount(count))
+}
+
+// /api/group/invitation/seen (POST)
+func (h *Handler) MarkAllInvitationsAsSeen(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	count, err := h.groupStore.MarkAllInvitationsAsSeen(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewInvitationsMarkedAsSeen(count, lang))
+}
+
+// /api/group/:id/invitation?page=int&pageSize=int&oldestFirst=bool (GET)
+func (h *Handler) GetInvitationsByGroup(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	page := 0
+	pageSize := 20
+
+	if c.QueryParam("page") != "" {
+		page, err = strconv.Atoi(c.QueryParam("page"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
+		}
+	}
+
+	if c.QueryParam("pageSize") != "" {
+		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+		}
+		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		}
+	}
+
+	oldestFirst := oldestFirstParam(c, user)
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	invitations, err := h.groupStore.GetInvitationsByGroup(c.Request().Context(), group, page, pageSize, oldestFirst)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	count, err := h.groupStore.InvitationCountByGroup(c.Request().Context(), group)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewInvitations(invitations, count))
+}
+
+// /api/group/invitation/:id (GET)
+func (h *Handler) GetInvitationById(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	invitation, err := h.groupStore.GetInvitationById(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if invitation == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), invitation.GroupId)
+	if err != nil || group == nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if userId != invitation.UserId {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+		}
+	}
+
+	return c.JSON(http.StatusOK, responses.NewInvitation(invitation))
+}
+
+// /api/group/invitation/:id/preview (GET)
+// Shows the group and the inviter of an invitation of the user before it is accepted or denied.
+func (h *Handler) GetInvitationPreview(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	invitation, err := h.groupStore.GetInvitationById(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if invitation == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	if userId != invitation.UserId {
+		return c.JSON(http.StatusForbidden, responses.New(false, "User is not the receiver of the invitation", lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), invitation.GroupId)
+	if err != nil || group == nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	var inviter *models.User
+	if invitation.InviterId != "" {
+		inviter, err = h.userStore.GetById(c.Request().Context(), invitation.InviterId)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+	}
+
+	return c.JSON(http.StatusOK, responses.NewInvitationPreview(invitation, group, inviter))
+}
+
+// /api/group/:id/invitation (POST)
+func (h *Handler) CreateInvitation(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	authUserId := c.Get("userId").(string)
+	authUser, err := h.userStore.GetById(c.Request().Context(), authUserId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if authUser == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	var body bindings.CreateInvitation
+	err = c.Bind(&body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+	}
+
+	if utf8.RuneCountInString(body.Message) > config.Data.MaxDescriptionLength {
+		return c.JSON(http.StatusOK, responses.New(false, "Message too long", lang))
+	}
+
+	if utf8.RuneCountInString(body.Message) < config.Data.MinDescriptionLength {
+		return c.JSON(http.StatusOK, responses.New(false, "Message too short", lang))
+	}
+
+	if body.UserId == authUserId {
+		return c.JSON(http.StatusOK, responses.New(false, "You can't invite yourself", lang))
+	}
+
+	user, err := h.userStore.GetById(c.Request().Context(), body.UserId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusOK, responses.New(false, "The user doesn't exist", lang))
+	}
+
+	userIsInGroup, err := h.groupStore.IsInGroup(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if userIsInGroup {
+		return c.JSON(http.StatusOK, responses.New(false, "The user is already a member/an admin of the group", lang))
+	}
+
+	authUserIsAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, authUser)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !authUserIsAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	invitation, err := h.groupStore.GetInvitationByGroupAndUser(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if invitation != nil {
+		return c.JSON(http.StatusOK, responses.New(false, "The user was already invited", lang))
+	}
+
+	invitation, err = h.groupStore.CreateInvitation(c.Request().Context(), group, user, authUser, body.Message, body.OpeningBalance)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if !user.DontSendInvitationEmail && config.Data.EmailEnabled {
+		err = sendInvitationEmail(user, group, lang)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+	}
+
+	return c.JSON(http.StatusCreated, responses.NewInvitation(invitation))
+}
+
+// /api/group/:id/member/:userId/reinvite (POST)
+// Invites a user who left the group. Their old membership is reactivated on acceptance, so their balance and transactions carry over.
+func (h *Handler) ReinviteFormerMember(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	authUserId := c.Get("userId").(string)
+	authUser, err := h.userStore.GetById(c.Request().Context(), authUserId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if authUser == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	memberId := c.Param("userId")
+	if memberId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing userId parameter", lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, authUser)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	var body bindings.ReinviteMember
+	err = c.Bind(&body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+	}
+
+	if utf8.RuneCountInString(body.Message) > config.Data.MaxDescriptionLength {
+		return c.JSON(http.StatusOK, responses.New(false, "Message too long", lang))
+	}
+
+	if utf8.RuneCountInString(body.Message) < config.Data.MinDescriptionLength {
+		return c.JSON(http.StatusOK, responses.New(false, "Message too short", lang))
+	}
+
+	user, err := h.userStore.GetById(c.Request().Context(), memberId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusOK, responses.New(false, "The user doesn't exist", lang))
+	}
+
+	isInGroup, err := h.groupStore.IsInGroup(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if isInGroup {
+		return c.JSON(http.StatusOK, responses.New(false, "The user is already a member/an admin of the group", lang))
+	}
+
+	membership, err := h.groupStore.GetMembership(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if membership == nil {
+		return c.JSON(http.StatusOK, responses.New(false, "The user is not a former member of the group", lang))
+	}
+
+	invitation, err := h.groupStore.GetInvitationByGroupAndUser(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if invitation != nil {
+		return c.JSON(http.StatusOK, responses.New(false, "The user was already invited", lang))
+	}
+
+	// The opening balance is always 0 because the old balance of the member is still in effect.
+	invitation, err = h.groupStore.CreateInvitation(c.Request().Context(), group, user, authUser, body.Message, 0)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if !user.DontSendInvitationEmail && config.Data.EmailEnabled {
+		err = sendInvitationEmail(user, group, lang)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+	}
+
+	return c.JSON(http.StatusCreated, responses.NewInvitation(invitation))
+}
+
+// /api/group/:id/invitation/:invitationId/resend (POST)
+func (h *Handler) ResendInvitation(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	authUserId := c.Get("userId").(string)
+	authUser, err := h.userStore.GetById(c.Request().Context(), authUserId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if authUser == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, authUser)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	invitationId := c.Param("invitationId")
+	if invitationId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing invitationId parameter", lang))
+	}
+	invitation, err := h.groupStore.GetInvitationById(c.Request().Context(), invitationId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if invitation == nil || invitation.GroupId != group.Id {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	retryAfter := invitation.LastSent + config.Data.InvitationResendCooldown - time.Now().Unix()
+	if retryAfter > 0 {
+		c.Response().Header().Set(echo.HeaderRetryAfter, strconv.FormatInt(retryAfter, 10))
+		return c.JSON(http.StatusTooManyRequests, responses.NewInvitationResendCooldown(retryAfter, lang))
+	}
+
+	user, err := h.userStore.GetById(c.Request().Context(), invitation.UserId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	if user.DontSendInvitationEmail || !config.Data.EmailEnabled {
+		return c.JSON(http.StatusOK, responses.New(false, "The user doesn't receive invitation emails", lang))
+	}
+
+	err = sendInvitationEmail(user, group, lang)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	err = h.groupStore.UpdateInvitationLastSent(c.Request().Context(), invitation, time.Now().Unix())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewInvitation(invitation))
+}
+
+func sendInvitationEmail(user *models.User, group *models.Group, lang string) error {
+	type templateData struct {
+		Name           string
+		GroupName      string
+		InvitationsUrl string
+	}
+	body, err := services.ParseEmailTemplate("invitation", lang, templateData{
+		Name:           user.Name,
+		GroupName:      group.Name,
+		InvitationsUrl: fmt.Sprintf("%s/invitations", config.Data.BaseURL),
+	})
+	if err != nil {
+		return err
+	}
+	go services.SendEmail([]string{user.Email}, config.Data.EmailProductName+" "+services.Tr("Invitation", lang), body, false)
+	return nil
+}
+
+// /api/group/invitation/:id (POST)
+func (h *Handler) AcceptInvitation(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	invitation, err := h.groupStore.GetInvitationById(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if invitation == nil {
+		return h.acceptedInvitation(c, user, id)
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), invitation.GroupId)
+	if err != nil || group == nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if userId != invitation.UserId {
+		return c.JSON(http.StatusForbidden, responses.New(false, "User is not the receiver of the invitation", lang))
+	}
+
+	isInGroup, err := h.groupStore.IsInGroup(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if isInGroup {
+		return c.JSON(http.StatusOK, responses.New(false, "The user is already a member/an admin of the group", lang))
+	}
+
+	claimed, err := h.groupStore.AcceptInvitation(c.Request().Context(), invitation, group, user, services.Tr("Opening balance", lang))
+	if errors.Is(err, models.ErrGroupFull) {
+		return c.JSON(http.StatusConflict, responses.New(false, "The group is full", lang))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !claimed {
+		return h.acceptedInvitation(c, user, id)
+	}
+
+	return c.JSON(http.StatusOK, responses.NewGroup(group, true, false))
+}
+
+// Answers repeated requests to accept an invitation, e.g. caused by a double-tap, with the same response as the first one.
+func (h *Handler) acceptedInvitation(c echo.Context, user *models.User, id string) error {
+	lang := c.Get("lang").(string)
+
+	invitation, err := h.groupStore.GetDeletedInvitationById(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if invitation == nil || invitation.UserId != user.Id {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), invitation.GroupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	// Denied invitations and invitations of members who left the group again can't be accepted.
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isMember {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewGroup(group, true, false))
+}
+
+// /api/group/invitation/:id (DELETE)
+func (h *Handler) DenyInvitation(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	invitation, err := h.groupStore.GetInvitationById(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if invitation == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), invitation.GroupId)
+	if err != nil || group == nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	if userId != invitation.UserId {
+		return c.JSON(http.StatusForbidden, responses.New(false, "User is not the receiver of the invitation", lang))
+	}
+
+	err = h.groupStore.DeleteInvitation(c.Request().Context(), invitation)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully denied invitation", lang))
+}
+
+// /api/group/:id/paymentPlan/:paymentPlanId (GET)
+func (h *Handler) GetPaymentPlanById(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	paymentPlanId := c.Param("paymentPlanId")
+	if paymentPlanId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+
+	paymentPlan, err := h.groupStore.GetPaymentPlanById(c.Request().Context(), group, paymentPlanId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if paymentPlan == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	isSender := user.Id == paymentPlan.SenderId
+	isReceiver := user.Id == paymentPlan.ReceiverId
+
+	if isSender || isReceiver {
+		isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !isMember {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+		}
+
+		return c.JSON(http.StatusOK, responses.NewPaymentPlan(paymentPlan))
+	} else if paymentPlan.SenderIsBank || paymentPlan.ReceiverIsBank {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+		}
+
+		return c.JSON(http.StatusOK, responses.NewPaymentPlan(paymentPlan))
+	}
+
+	return c.JSON(http.StatusForbidden, responses.New(false, "User not allowed to view payment plan", lang))
+}
+
+// /api/group/:id/paymentPlan?bank=bool&search=string&direction=string&counterparty=string&page=int&pageSize=int&oldestFirst=bool (GET)
+func (h *Handler) GetPaymentPlans(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	page := 0
+	pageSize := 20
+
+	if c.QueryParam("page") != "" {
+		page, err = strconv.Atoi(c.QueryParam("page"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
+		}
+	}
+
+	if c.QueryParam("pageSize") != "" {
+		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+		}
+		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		}
+	}
+
+	oldestFirst := oldestFirstParam(c, user)
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	bank := services.StrToBool(c.QueryParam("bank"))
+
+	if !bank {
+		isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		if !isMember {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+		}
+
+		filter := models.PaymentPlanFilter{
+			Direction:      strings.ToLower(c.QueryParam("direction")),
+			CounterpartyId: c.QueryParam("counterparty"),
+		}
+		if filter.Direction != "" && filter.Direction != models.PaymentPlanDirectionSending && filter.Direction != models.PaymentPlanDirectionReceiving {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid direction", lang))
+		}
+
+		paymentPlans, err := h.groupStore.GetPaymentPlans(c.Request().Context(), group, user, c.QueryParam("search"), filter, page, pageSize, oldestFirst)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		count, err := h.groupStore.PaymentPlanCount(c.Request().Context(), group, user, filter)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		return c.JSON(http.StatusOK, responses.NewPaymentPlans(paymentPlans, count))
+	} else {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		if !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+		}
+
+		paymentPlans, err := h.groupStore.GetBankPaymentPlans(c.Request().Context(), group, c.QueryParam("search"), page, pageSize, oldestFirst)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		count, err := h.groupStore.BankPaymentPlanCount(c.Request().Context(), group)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		return c.JSON(http.StatusOK, responses.NewPaymentPlans(paymentPlans, count))
+	}
+}
+
+// /api/group/:id/paymentPlan/nextPayment?id=uuid&firstPayment=int&schedule=int&scheduleUnit=string&timeZone=string&count=int
+func (h *Handler) GetPaymentPlanNextPayments(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	count := 1
+	if c.QueryParam("count") != "" {
+		count, err = strconv.Atoi(c.QueryParam("count"))
+		if err != nil || count < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'count' query parameter not a number or <1", lang))
+		}
+		if count > config.Data.MaxPageSize {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'count' query parameter too big", lang))
+		}
+	}
+
+	schedule := -1
+	scheduleUnit := ""
+	timeZone := ""
+	firstPayment := int64(-1)
+
+	if c.QueryParam("id") != "" {
+		id := c.QueryParam("id")
+		if id == "" {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+		}
+
+		paymentPlan, err := h.groupStore.GetPaymentPlanById(c.Request().Context(), group, id)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if paymentPlan == nil {
+			return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+		}
+
+		isSender := user.Id == paymentPlan.SenderId
+		isReceiver := user.Id == paymentPlan.ReceiverId
+
+		if isSender || isReceiver {
+			isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+			}
+			if !isMember {
+				return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+			}
+		} else if paymentPlan.SenderIsBank || paymentPlan.ReceiverIsBank {
+			isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+			}
+			if !isAdmin {
+				return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+			}
+		} else {
+			return c.JSON(http.StatusForbidden, responses.New(false, "User not allowed to view payment plan", lang))
+		}
+
+		schedule = paymentPlan.Schedule
+		scheduleUnit = paymentPlan.ScheduleUnit
+		timeZone = paymentPlan.TimeZone
+		firstPayment = paymentPlan.NextExecute
+	} else {
+		if c.QueryParam("schedule") != "" {
+			schedule, err = strconv.Atoi(c.QueryParam("schedule"))
+			if err != nil || schedule < 1 {
+				return c.JSON(http.StatusBadRequest, responses.New(false, "'schedule' query parameter not a number or <1", lang))
+			}
+		} else {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Missing 'schedule' or 'id' query parameter", lang))
+		}
+
+		scheduleUnit = strings.ToLower(c.QueryParam("scheduleUnit"))
+		if scheduleUnit != models.ScheduleUnitDay && scheduleUnit != models.ScheduleUnitWeek && scheduleUnit != models.ScheduleUnitMonth && scheduleUnit != models.ScheduleUnitYear {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid schedule unit", lang))
+		}
+
+		if c.QueryParam("firstPayment") != "" {
+			firstPayment, err = strconv.ParseInt(c.QueryParam("firstPayment"), 10, 64)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, responses.New(false, "'firstPayment' query parameter not a number", lang))
+			}
+		} else {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Missing 'firstPayment' or 'id' query parameter", lang))
+		}
+
+		timeZone = c.QueryParam("timeZone")
+		if _, err := services.LoadTimeZone(timeZone); err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid time zone", lang))
+		}
+	}
+
+	executionTimes := make([]int64, count)
+	for i := 0; i < count; i++ {
+		executionTimes[i] = firstPayment
+		firstPayment = services.NextPaymentPlanExecution(firstPayment, schedule, scheduleUnit, timeZone)
+	}
+
+	return c.JSON(http.StatusOK, responses.PaymentPlanExecutionTimes{
+		Base: responses.Base{
+			Success: true,
+		},
+		ExecutionTimes: executionTimes,
 	})
 }
 
-// /api/group/:id/transaction/:transactionId (GET)
-func (h *Handler) GetTransactionById(c echo.Context) error {
+// /api/group/:id/paymentPlan/upcoming?until=int&count=int (GET)
+func (h *Handler) GetUpcomingPayments(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -918,7 +3648,7 @@ func (h *Handler) GetTransactionById(c echo.Context) error {
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -926,45 +3656,47 @@ func (h *Handler) GetTransactionById(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	transactionId := c.Param("transactionId")
-	if transactionId == "" {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing transactionId parameter", lang))
-	}
-
-	transaction, err := h.groupStore.GetTransactionLogEntryById(group, transactionId)
+	isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if transaction == nil {
-		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	if !isMember {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
 	}
 
-	isSender := user.Id == transaction.SenderId
-	isReceiver := user.Id == transaction.ReceiverId
-
-	if isSender || isReceiver {
-		return c.JSON(http.StatusOK, responses.NewTransaction(transaction, user))
-	} else if transaction.SenderIsBank || transaction.ReceiverIsBank {
-		isAdmin, err := h.groupStore.IsAdmin(group, user)
+	until := int64(-1)
+	if c.QueryParam("until") != "" {
+		until, err = strconv.ParseInt(c.QueryParam("until"), 10, 64)
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'until' query parameter not a number", lang))
 		}
-		if !isAdmin {
-			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+	}
+
+	count := 20
+	if c.QueryParam("count") != "" {
+		count, err = strconv.Atoi(c.QueryParam("count"))
+		if err != nil || count < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'count' query parameter not a number or <1", lang))
+		}
+		if count > config.Data.MaxPageSize {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'count' query parameter too big", lang))
 		}
+	}
 
-		return c.JSON(http.StatusOK, responses.NewBankTransaction(transaction))
+	paymentPlans, err := h.groupStore.GetUpcomingPaymentPlans(c.Request().Context(), group, user, until, count)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	return c.JSON(http.StatusForbidden, responses.New(false, "User not allowed to view transaction", lang))
+	return c.JSON(http.StatusOK, responses.NewUpcomingPayments(paymentPlans, user))
 }
 
-// /api/group/:id/transaction?bank=bool&search=string&page=int&pageSize=int&oldestFirst=bool (GET)
-func (h *Handler) GetTransactionLog(c echo.Context) error {
+// /api/group/:id/paymentPlan/estimate?amount=int&schedule=int&scheduleUnit=string&paymentCount=int&firstPayment=int&timeZone=string (GET)
+func (h *Handler) EstimatePaymentPlan(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -972,33 +3704,11 @@ func (h *Handler) GetTransactionLog(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	page := 0
-	pageSize := 20
-
-	if c.QueryParam("page") != "" {
-		page, err = strconv.Atoi(c.QueryParam("page"))
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
-		}
-	}
-
-	if c.QueryParam("pageSize") != "" {
-		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
-		}
-		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
-		}
-	}
-
-	oldestFirst := services.StrToBool(c.QueryParam("oldestFirst"))
-
 	groupId := c.Param("id")
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1006,59 +3716,76 @@ func (h *Handler) GetTransactionLog(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	bank := services.StrToBool(c.QueryParam("bank"))
+	isInGroup, err := h.groupStore.IsInGroup(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isInGroup {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+	}
 
-	if !bank {
-		isMember, err := h.groupStore.IsMember(group, user)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-		}
+	amount, err := strconv.Atoi(c.QueryParam("amount"))
+	if err != nil || amount < 1 {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "'amount' query parameter not a number or <1", lang))
+	}
 
-		if !isMember {
-			return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
-		}
+	schedule, err := strconv.Atoi(c.QueryParam("schedule"))
+	if err != nil || schedule < 1 {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "'schedule' query parameter not a number or <1", lang))
+	}
 
-		log, err := h.groupStore.GetTransactionLog(group, user, c.QueryParam("search"), page, pageSize, oldestFirst)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-		}
+	scheduleUnit := strings.ToLower(c.QueryParam("scheduleUnit"))
+	if scheduleUnit != models.ScheduleUnitDay && scheduleUnit != models.ScheduleUnitWeek && scheduleUnit != models.ScheduleUnitMonth && scheduleUnit != models.ScheduleUnitYear {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid schedule unit", lang))
+	}
 
-		count, err := h.groupStore.TransactionLogEntryCount(group, user)
+	paymentCount := -1
+	if c.QueryParam("paymentCount") != "" {
+		paymentCount, err = strconv.Atoi(c.QueryParam("paymentCount"))
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'paymentCount' query parameter not a number", lang))
+		}
+		if paymentCount == 0 {
+			paymentCount = -1
 		}
+	}
 
-		return c.JSON(http.StatusOK, responses.NewTransactionLog(log, user, count))
-	} else {
-		isAdmin, err := h.groupStore.IsAdmin(group, user)
+	firstPayment := time.Now().Unix()
+	if c.QueryParam("firstPayment") != "" {
+		firstPayment, err = strconv.ParseInt(c.QueryParam("firstPayment"), 10, 64)
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'firstPayment' query parameter not a number", lang))
 		}
+	}
 
-		if !isAdmin {
-			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
-		}
+	timeZone := c.QueryParam("timeZone")
+	if _, err := services.LoadTimeZone(timeZone); err != nil {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid time zone", lang))
+	}
 
-		log, err := h.groupStore.GetBankTransactionLog(group, c.QueryParam("search"), page, pageSize, oldestFirst)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-		}
+	total, finalPayment, indefinite := services.EstimatePaymentPlan(amount, paymentCount, schedule, scheduleUnit, firstPayment, timeZone)
 
-		count, err := h.groupStore.BankTransactionLogEntryCount(group)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-		}
+	return c.JSON(http.StatusOK, responses.NewPaymentPlanEstimate(amount, total, finalPayment, indefinite))
+}
 
-		return c.JSON(http.StatusOK, responses.NewBankTransactionLog(log, count))
+// Reports whether creating count more payment plans would exceed config.Data.MaxPaymentPlansPerGroup.
+func (h *Handler) paymentPlanLimitExceeded(ctx context.Context, group *models.Group, count int) (bool, error) {
+	if config.Data.MaxPaymentPlansPerGroup <= 0 {
+		return false, nil
+	}
+	active, err := h.groupStore.ActivePaymentPlanCount(ctx, group)
+	if err != nil {
+		return false, err
 	}
+	return active+int64(count) > int64(config.Data.MaxPaymentPlansPerGroup), nil
 }
 
-// /api/group/:id/transaction (POST)
-func (h *Handler) CreateTransaction(c echo.Context) error {
+// /api/group/:id/paymentPlan (POST)
+func (h *Handler) CreatePaymentPlan(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1070,7 +3797,7 @@ func (h *Handler) CreateTransaction(c echo.Context) error {
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1078,276 +3805,164 @@ func (h *Handler) CreateTransaction(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	var body bindings.CreateTransaction
+	var body bindings.CreatePaymentPlan
 	err = c.Bind(&body)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
 	}
-	if body.Amount <= 0 {
-		return c.JSON(http.StatusOK, responses.New(false, "Amount must be >0", lang))
-	}
-
-	body.Title = strings.TrimSpace(body.Title)
-	body.Description = strings.TrimSpace(body.Description)
-
-	if utf8.RuneCountInString(body.Title) > config.Data.MaxNameLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Title too long", lang))
-	}
-
-	if utf8.RuneCountInString(body.Title) < config.Data.MinNameLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Title too short", lang))
-	}
 
-	if utf8.RuneCountInString(body.Description) > config.Data.MaxDescriptionLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Description too long", lang))
-	}
-
-	if utf8.RuneCountInString(body.Description) < config.Data.MinDescriptionLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Description too short", lang))
+	firstPayment, status, msg := validatePaymentPlan(&body)
+	if msg != "" {
+		return c.JSON(status, responses.New(false, msg, lang))
 	}
 
 	if !body.FromBank {
-		isMember, err := h.groupStore.IsMember(group, user)
+		isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
 		if !isMember {
 			return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
 		}
+	}
 
-		balanceSender, err := h.groupStore.GetUserBalance(group, user)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-		}
-
-		if balanceSender-int(body.Amount) < 0 {
-			return c.JSON(http.StatusOK, responses.New(false, "Not enough money", lang))
-		}
+	exceeded, err := h.paymentPlanLimitExceeded(c.Request().Context(), group, 1)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if exceeded {
+		return c.JSON(http.StatusConflict, responses.New(false, "Too many payment plans in the group", lang))
 	}
 
-	var transaction *models.TransactionLogEntry
+	var paymentPlan *models.PaymentPlan
 
 	if strings.EqualFold(body.ReceiverId, "bank") {
 		if body.FromBank {
 			return c.JSON(http.StatusOK, responses.New(false, "Cannot send money from bank to bank", lang))
 		}
-		transaction, err = h.groupStore.CreateTransaction(group, false, true, user, nil, body.Title, body.Description, int(body.Amount))
+		paymentPlan, err = h.groupStore.CreatePaymentPlan(c.Request().Context(), group, false, true, user, nil, body.Name, body.Description, int(body.Amount), body.PaymentCount, int(body.Schedule), body.ScheduleUnit, firstPayment.Unix(), body.TimeZone)
 		if err != nil {
 			return c.JSON(http.StatusUnauthorized, responses.NewUnexpectedError(err, lang))
 		}
 	} else {
-		receiver, err := h.userStore.GetById(body.ReceiverId)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-		}
-		if receiver == nil {
-			return c.JSON(http.StatusNotFound, responses.New(false, "Couldn't find receiver", lang))
-		}
-		isReceiverMember, err := h.groupStore.IsMember(group, receiver)
+		receiver, err := h.userStore.GetById(c.Request().Context(), body.ReceiverId)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
-		if !isReceiverMember {
-			return c.JSON(http.StatusForbidden, responses.New(false, "Receiver not a member of the group", lang))
-		}
-
-		if body.FromBank {
-			isAdmin, err := h.groupStore.IsAdmin(group, user)
-			if err != nil {
-				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-			}
-			if !isAdmin {
-				return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
-			}
-			transaction, err = h.groupStore.CreateTransaction(group, true, false, nil, receiver, body.Title, body.Description, int(body.Amount))
-			if err != nil {
-				return c.JSON(http.StatusUnauthorized, responses.NewUnexpectedError(err, lang))
-			}
-		} else {
-			if user.Id == body.ReceiverId {
-				return c.JSON(http.StatusOK, responses.New(false, "Sender is the receiver", lang))
-			}
-			transaction, err = h.groupStore.CreateTransaction(group, false, false, user, receiver, body.Title, body.Description, int(body.Amount))
-			if err != nil {
-				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-			}
-		}
-	}
-
-	return c.JSON(http.StatusOK, responses.NewTransaction(transaction, user))
-}
-
-// /api/group/invitation?page=int&pageSize=int&oldestFirst=bool (GET)
-func (h *Handler) GetInvitationsByUser(c echo.Context) error {
-	lang := c.Get("lang").(string)
-
-	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
-	if user == nil {
-		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
-	}
-
-	page := 0
-	pageSize := 20
-
-	if c.QueryParam("page") != "" {
-		page, err = strconv.Atoi(c.QueryParam("page"))
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
-		}
-	}
-
-	if c.QueryParam("pageSize") != "" {
-		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
-		}
-		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
-		}
-	}
-
-	oldestFirst := services.StrToBool(c.QueryParam("oldestFirst"))
-
-	invitations, err := h.groupStore.GetInvitationsByUser(user, page, pageSize, oldestFirst)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
-
-	count, err := h.groupStore.InvitationCountByUser(user)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
-
-	return c.JSON(http.StatusOK, responses.NewInvitations(invitations, count))
-}
-
-// /api/group/:id/invitation?page=int&pageSize=int&oldestFirst=bool (GET)
-func (h *Handler) GetInvitationsByGroup(c echo.Context) error {
-	lang := c.Get("lang").(string)
-
-	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
-	if user == nil {
-		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
-	}
-
-	page := 0
-	pageSize := 20
-
-	if c.QueryParam("page") != "" {
-		page, err = strconv.Atoi(c.QueryParam("page"))
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
+		if receiver == nil {
+			return c.JSON(http.StatusNotFound, responses.New(false, "Couldn't find receiver", lang))
 		}
-	}
-
-	if c.QueryParam("pageSize") != "" {
-		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		isReceiverMember, err := h.groupStore.IsMember(c.Request().Context(), group, receiver)
 		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
-		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		if !isReceiverMember {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Receiver not a member of the group", lang))
+		}
+
+		if body.FromBank {
+			isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+			}
+			if !isAdmin {
+				return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+			}
+			paymentPlan, err = h.groupStore.CreatePaymentPlan(c.Request().Context(), group, true, false, nil, receiver, body.Name, body.Description, int(body.Amount), body.PaymentCount, int(body.Schedule), body.ScheduleUnit, firstPayment.Unix(), body.TimeZone)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, responses.NewUnexpectedError(err, lang))
+			}
+		} else {
+			if user.Id == body.ReceiverId {
+				return c.JSON(http.StatusOK, responses.New(false, "Sender is the receiver", lang))
+			}
+			paymentPlan, err = h.groupStore.CreatePaymentPlan(c.Request().Context(), group, false, false, user, receiver, body.Name, body.Description, int(body.Amount), body.PaymentCount, int(body.Schedule), body.ScheduleUnit, firstPayment.Unix(), body.TimeZone)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+			}
 		}
 	}
 
-	oldestFirst := services.StrToBool(c.QueryParam("oldestFirst"))
+	return c.JSON(http.StatusOK, responses.NewPaymentPlan(paymentPlan))
+}
 
-	groupId := c.Param("id")
-	if groupId == "" {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+// Normalizes the payment plan in body and returns the time of the first payment.
+// If the payment plan is invalid, the status code and error message of the response are returned.
+func validatePaymentPlan(body *bindings.CreatePaymentPlan) (time.Time, int, string) {
+	if body.Amount <= 0 {
+		return time.Time{}, http.StatusOK, "Amount must be >0"
 	}
 
-	group, err := h.groupStore.GetById(groupId)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
-	if group == nil {
-		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	if body.Schedule <= 0 {
+		return time.Time{}, http.StatusBadRequest, "Schedule must be >0"
 	}
 
-	isAdmin, err := h.groupStore.IsAdmin(group, user)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	body.Name = strings.TrimSpace(body.Name)
+	body.Description = strings.TrimSpace(body.Description)
+
+	if utf8.RuneCountInString(body.Name) > config.Data.MaxNameLength {
+		return time.Time{}, http.StatusOK, "Name too long"
 	}
-	if !isAdmin {
-		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+
+	if utf8.RuneCountInString(body.Name) < config.Data.MinNameLength {
+		return time.Time{}, http.StatusOK, "Name too short"
 	}
 
-	invitations, err := h.groupStore.GetInvitationsByGroup(group, page, pageSize, oldestFirst)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	if utf8.RuneCountInString(body.Description) > config.Data.MaxDescriptionLength {
+		return time.Time{}, http.StatusOK, "Description too long"
 	}
 
-	count, err := h.groupStore.InvitationCountByGroup(group)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	if utf8.RuneCountInString(body.Description) < config.Data.MinDescriptionLength {
+		return time.Time{}, http.StatusOK, "Description too short"
 	}
 
-	return c.JSON(http.StatusOK, responses.NewInvitations(invitations, count))
-}
+	body.ScheduleUnit = strings.ToLower(body.ScheduleUnit)
 
-// /api/group/invitation/:id (GET)
-func (h *Handler) GetInvitationById(c echo.Context) error {
-	lang := c.Get("lang").(string)
+	if body.ScheduleUnit != models.ScheduleUnitDay && body.ScheduleUnit != models.ScheduleUnitWeek && body.ScheduleUnit != models.ScheduleUnitMonth && body.ScheduleUnit != models.ScheduleUnitYear {
+		return time.Time{}, http.StatusBadRequest, "Invalid schedule unit"
+	}
 
-	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	loc, err := services.LoadTimeZone(body.TimeZone)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
-	if user == nil {
-		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+		return time.Time{}, http.StatusBadRequest, "Invalid time zone"
 	}
 
-	id := c.Param("id")
-	if id == "" {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	firstPayment, status, msg := parsePaymentDate(body.FirstPayment, loc, "First payment can't be in the past")
+	if msg != "" {
+		return time.Time{}, status, msg
 	}
 
-	invitation, err := h.groupStore.GetInvitationById(id)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
-	if invitation == nil {
-		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	if body.PaymentCount == 0 {
+		body.PaymentCount = -1
 	}
 
-	group, err := h.groupStore.GetById(invitation.GroupId)
-	if err != nil || group == nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
+	return firstPayment, http.StatusOK, ""
+}
 
-	if userId != invitation.UserId {
-		isAdmin, err := h.groupStore.IsAdmin(group, user)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-		}
-		if !isAdmin {
-			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
-		}
+// Parses a date with format "YYYY-MM-DD" in loc which must not be in the past. Today is allowed.
+// The returned time is the start of the date in loc. pastMsg is returned for dates in the past.
+func parsePaymentDate(date string, loc *time.Location, pastMsg string) (time.Time, int, string) {
+	payment, err := services.ParseDateIn(date, loc)
+	if err != nil {
+		return time.Time{}, http.StatusBadRequest, "Invalid date string"
 	}
-
-	return c.JSON(http.StatusOK, responses.NewInvitation(invitation))
+	now := time.Now().In(loc)
+	if payment.Before(services.StartOfDay(now.Year(), now.Month(), now.Day(), loc)) {
+		return time.Time{}, http.StatusBadRequest, pastMsg
+	}
+	return payment, http.StatusOK, ""
 }
 
-// /api/group/:id/invitation (POST)
-func (h *Handler) CreateInvitation(c echo.Context) error {
+// /api/group/:id/paymentPlan/bulk (POST)
+func (h *Handler) CreateBulkPaymentPlans(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
-	authUserId := c.Get("userId").(string)
-	authUser, err := h.userStore.GetById(authUserId)
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if authUser == nil {
+	if user == nil {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
@@ -1355,7 +3970,7 @@ func (h *Handler) CreateInvitation(c echo.Context) error {
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1363,88 +3978,107 @@ func (h *Handler) CreateInvitation(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	var body bindings.CreateInvitation
+	var body bindings.CreateBulkPaymentPlans
 	err = c.Bind(&body)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
 	}
 
-	if utf8.RuneCountInString(body.Message) > config.Data.MaxDescriptionLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Message too long", lang))
+	firstPayment, status, msg := validatePaymentPlan(&body.CreatePaymentPlan)
+	if msg != "" {
+		return c.JSON(status, responses.New(false, msg, lang))
 	}
 
-	if utf8.RuneCountInString(body.Message) < config.Data.MinDescriptionLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Message too short", lang))
+	if body.FromBank {
+		return c.JSON(http.StatusOK, responses.New(false, "Bulk payment plans can't be sent from the bank", lang))
 	}
 
-	if body.UserId == authUserId {
-		return c.JSON(http.StatusOK, responses.New(false, "You can't invite yourself", lang))
+	if len(body.SenderIds) == 0 {
+		return c.JSON(http.StatusOK, responses.New(false, "No senders", lang))
 	}
 
-	user, err := h.userStore.GetById(body.UserId)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
-	if user == nil {
-		return c.JSON(http.StatusOK, responses.New(false, "The user doesn't exist", lang))
+	if len(body.SenderIds) > config.Data.MaxPageSize {
+		return c.JSON(http.StatusOK, responses.New(false, "Too many senders", lang))
 	}
 
-	userIsInGroup, err := h.groupStore.IsInGroup(group, user)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-
-	if userIsInGroup {
-		return c.JSON(http.StatusOK, responses.New(false, "The user is already a member/an admin of the group", lang))
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
 	}
 
-	authUserIsAdmin, err := h.groupStore.IsAdmin(group, authUser)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	receiverIsBank := strings.EqualFold(body.ReceiverId, "bank")
+	var receiver *models.User
+	if !receiverIsBank {
+		receiver, err = h.userStore.GetById(c.Request().Context(), body.ReceiverId)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if receiver == nil {
+			return c.JSON(http.StatusNotFound, responses.New(false, "Couldn't find receiver", lang))
+		}
+		isReceiverMember, err := h.groupStore.IsMember(c.Request().Context(), group, receiver)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !isReceiverMember {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Receiver not a member of the group", lang))
+		}
 	}
-	if !authUserIsAdmin {
-		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+
+	senders := make([]models.User, 0, len(body.SenderIds))
+	seen := make(map[string]bool, len(body.SenderIds))
+	for _, id := range body.SenderIds {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if !receiverIsBank && id == receiver.Id {
+			return c.JSON(http.StatusOK, responses.New(false, "Sender is the receiver", lang))
+		}
+
+		sender, err := h.userStore.GetById(c.Request().Context(), id)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if sender == nil {
+			return c.JSON(http.StatusNotFound, responses.New(false, "Couldn't find sender", lang))
+		}
+		isSenderMember, err := h.groupStore.IsMember(c.Request().Context(), group, sender)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !isSenderMember {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Sender not a member of the group", lang))
+		}
+		senders = append(senders, *sender)
 	}
 
-	invitation, err := h.groupStore.GetInvitationByGroupAndUser(group, user)
+	exceeded, err := h.paymentPlanLimitExceeded(c.Request().Context(), group, len(senders))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if invitation != nil {
-		return c.JSON(http.StatusOK, responses.New(false, "The user was already invited", lang))
+	if exceeded {
+		return c.JSON(http.StatusConflict, responses.New(false, "Too many payment plans in the group", lang))
 	}
 
-	invitation, err = h.groupStore.CreateInvitation(group, user, body.Message)
+	paymentPlans, err := h.groupStore.CreatePaymentPlans(c.Request().Context(), group, senders, receiverIsBank, receiver, body.Name, body.Description, int(body.Amount), body.PaymentCount, int(body.Schedule), body.ScheduleUnit, firstPayment.Unix(), body.TimeZone)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	if !user.DontSendInvitationEmail && config.Data.EmailEnabled {
-		type templateData struct {
-			Name           string
-			GroupName      string
-			InvitationsUrl string
-		}
-		body, err := services.ParseEmailTemplate("invitation", c.Get("lang").(string), templateData{
-			Name:           user.Name,
-			GroupName:      group.Name,
-			InvitationsUrl: fmt.Sprintf("%s/invitations", config.Data.BaseURL),
-		})
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-		}
-		go services.SendEmail([]string{user.Email}, services.Tr("H-Bank Invitation", lang), body)
-	}
-
-	return c.JSON(http.StatusCreated, responses.NewInvitation(invitation))
+	return c.JSON(http.StatusOK, responses.NewPaymentPlans(paymentPlans, int64(len(paymentPlans))))
 }
 
-// /api/group/invitation/:id (POST)
-func (h *Handler) AcceptInvitation(c echo.Context) error {
+// /api/group/:id/paymentPlan/:paymentPlanId (DELETE)
+func (h *Handler) DeletePaymentPlan(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1452,56 +4086,67 @@ func (h *Handler) AcceptInvitation(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	id := c.Param("id")
-	if id == "" {
+	groupId := c.Param("id")
+	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-
-	invitation, err := h.groupStore.GetInvitationById(id)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if invitation == nil {
-		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
-	}
-
-	group, err := h.groupStore.GetById(invitation.GroupId)
-	if err != nil || group == nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	if userId != invitation.UserId {
-		return c.JSON(http.StatusForbidden, responses.New(false, "User is not the receiver of the invitation", lang))
+	paymentPlanId := c.Param("paymentPlanId")
+	if paymentPlanId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
 
-	isInGroup, err := h.groupStore.IsInGroup(group, user)
+	paymentPlan, err := h.groupStore.GetPaymentPlanById(c.Request().Context(), group, paymentPlanId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-
-	if isInGroup {
-		return c.JSON(http.StatusOK, responses.New(false, "The user is already a member/an admin of the group", lang))
+	if paymentPlan == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
 	}
 
-	err = h.groupStore.AddMember(group, user)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	isSender := user.Id == paymentPlan.SenderId
+	if !isSender {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !paymentPlan.SenderIsBank || !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "User not the sender of the payment plan", lang))
+		}
 	}
 
-	err = h.groupStore.DeleteInvitation(invitation)
+	err = h.groupStore.DeletePaymentPlan(c.Request().Context(), paymentPlan)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	return c.JSON(http.StatusOK, responses.NewGroup(group, true, false))
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully deleted payment plan", lang))
 }
 
-// /api/group/invitation/:id (DELETE)
-func (h *Handler) DenyInvitation(c echo.Context) error {
+// Maps the updatable fields of a payment plan to their database columns.
+var paymentPlanFieldColumns = map[string]string{
+	"name":         "name",
+	"description":  "description",
+	"amount":       "amount",
+	"nextPayment":  "next_execute",
+	"schedule":     "schedule",
+	"scheduleUnit": "schedule_unit",
+	"paymentCount": "payment_count",
+}
+
+// /api/group/:id/paymentPlan/:paymentPlanId (PUT)
+func (h *Handler) UpdatePaymentPlan(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1509,42 +4154,129 @@ func (h *Handler) DenyInvitation(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	id := c.Param("id")
-	if id == "" {
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
+	}
+
+	paymentPlanId := c.Param("paymentPlanId")
+	if paymentPlanId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
 
-	invitation, err := h.groupStore.GetInvitationById(id)
+	paymentPlan, err := h.groupStore.GetPaymentPlanById(c.Request().Context(), group, paymentPlanId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if invitation == nil {
+	if paymentPlan == nil {
 		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
 	}
 
-	group, err := h.groupStore.GetById(invitation.GroupId)
-	if err != nil || group == nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	isSender := user.Id == paymentPlan.SenderId
+	if !isSender {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !paymentPlan.SenderIsBank || !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "User not the sender of the payment plan", lang))
+		}
 	}
 
-	if userId != invitation.UserId {
-		return c.JSON(http.StatusForbidden, responses.New(false, "User is not the receiver of the invitation", lang))
+	var body bindings.UpdatePaymentPlan
+	err = c.Bind(&body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+	}
+
+	fields := body.Fields
+	if len(fields) == 0 {
+		fields = []string{"name", "description", "amount", "nextPayment", "schedule", "scheduleUnit"}
+	}
+	columns := make([]string, 0, len(fields))
+	for _, f := range fields {
+		column, ok := paymentPlanFieldColumns[f]
+		if !ok {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unknown field", lang))
+		}
+		columns = append(columns, column)
+		if f == "paymentCount" {
+			columns = append(columns, "total_payment_count")
+		}
+	}
+
+	for _, f := range fields {
+		switch f {
+		case "name":
+			body.Name = strings.TrimSpace(body.Name)
+			if utf8.RuneCountInString(body.Name) > config.Data.MaxNameLength {
+				return c.JSON(http.StatusOK, responses.New(false, "Name too long", lang))
+			}
+			if utf8.RuneCountInString(body.Name) < config.Data.MinNameLength {
+				return c.JSON(http.StatusOK, responses.New(false, "Name too short", lang))
+			}
+			paymentPlan.Name = body.Name
+		case "description":
+			body.Description = strings.TrimSpace(body.Description)
+			if utf8.RuneCountInString(body.Description) > config.Data.MaxDescriptionLength {
+				return c.JSON(http.StatusOK, responses.New(false, "Description too long", lang))
+			}
+			if utf8.RuneCountInString(body.Description) < config.Data.MinDescriptionLength {
+				return c.JSON(http.StatusOK, responses.New(false, "Description too short", lang))
+			}
+			paymentPlan.Description = body.Description
+		case "amount":
+			if body.Amount <= 0 {
+				return c.JSON(http.StatusOK, responses.New(false, "Amount must be >0", lang))
+			}
+			paymentPlan.Amount = int(body.Amount)
+		case "nextPayment":
+			nextPayment, status, msg := parsePaymentDate(body.NextPayment, services.PaymentPlanLocation(paymentPlan.TimeZone), "Next payment can't be in the past")
+			if msg != "" {
+				return c.JSON(status, responses.New(false, msg, lang))
+			}
+			paymentPlan.NextExecute = nextPayment.Unix()
+		case "schedule":
+			if body.Schedule <= 0 {
+				return c.JSON(http.StatusBadRequest, responses.New(false, "Schedule must be >0", lang))
+			}
+			paymentPlan.Schedule = int(body.Schedule)
+		case "scheduleUnit":
+			body.ScheduleUnit = strings.ToLower(body.ScheduleUnit)
+			if body.ScheduleUnit != models.ScheduleUnitDay && body.ScheduleUnit != models.ScheduleUnitWeek && body.ScheduleUnit != models.ScheduleUnitMonth && body.ScheduleUnit != models.ScheduleUnitYear {
+				return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid schedule unit", lang))
+			}
+			paymentPlan.ScheduleUnit = body.ScheduleUnit
+		case "paymentCount":
+			if body.PaymentCount <= 0 {
+				body.PaymentCount = -1
+			}
+			paymentPlan.PaymentCount = body.PaymentCount
+			paymentPlan.TotalPaymentCount = body.PaymentCount
+		}
 	}
 
-	err = h.groupStore.DeleteInvitation(invitation)
+	err = h.groupStore.UpdatePaymentPlan(c.Request().Context(), paymentPlan, columns...)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	return c.JSON(http.StatusOK, responses.New(true, "Successfully denied invitation", lang))
+	return c.JSON(http.StatusOK, responses.NewPaymentPlan(paymentPlan))
 }
 
-// /api/group/:id/paymentPlan/:paymentPlanId (GET)
-func (h *Handler) GetPaymentPlanById(c echo.Context) error {
+// /api/group/:id/paymentPlan/:paymentPlanId/skipNext (POST)
+func (h *Handler) SkipNextPaymentPlanExecution(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1556,7 +4288,7 @@ func (h *Handler) GetPaymentPlanById(c echo.Context) error {
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1569,7 +4301,7 @@ func (h *Handler) GetPaymentPlanById(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
 
-	paymentPlan, err := h.groupStore.GetPaymentPlanById(group, paymentPlanId)
+	paymentPlan, err := h.groupStore.GetPaymentPlanById(c.Request().Context(), group, paymentPlanId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1578,39 +4310,33 @@ func (h *Handler) GetPaymentPlanById(c echo.Context) error {
 	}
 
 	isSender := user.Id == paymentPlan.SenderId
-	isReceiver := user.Id == paymentPlan.ReceiverId
-
-	if isSender || isReceiver {
-		isMember, err := h.groupStore.IsMember(group, user)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-		}
-		if !isMember {
-			return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
-		}
-
-		return c.JSON(http.StatusOK, responses.NewPaymentPlan(paymentPlan))
-	} else if paymentPlan.SenderIsBank || paymentPlan.ReceiverIsBank {
-		isAdmin, err := h.groupStore.IsAdmin(group, user)
+	if !isSender {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
-		if !isAdmin {
-			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+		if !paymentPlan.SenderIsBank || !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "User not the sender of the payment plan", lang))
 		}
+	}
 
-		return c.JSON(http.StatusOK, responses.NewPaymentPlan(paymentPlan))
+	err = h.groupStore.SkipPaymentPlanExecution(c.Request().Context(), group, paymentPlan)
+	if errors.Is(err, models.ErrPaymentPlanChanged) {
+		return c.JSON(http.StatusConflict, responses.New(false, "The payment plan was changed in the meantime", lang))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	return c.JSON(http.StatusForbidden, responses.New(false, "User not allowed to view payment plan", lang))
+	return c.JSON(http.StatusOK, responses.NewPaymentPlan(paymentPlan))
 }
 
-// /api/group/:id/paymentPlan?bank=bool&search=string&page=int&pageSize=int&oldestFirst=bool (GET)
-func (h *Handler) GetPaymentPlans(c echo.Context) error {
+// /api/group/:id/paymentPlan/:paymentPlanId/duplicate (POST)
+func (h *Handler) DuplicatePaymentPlan(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1618,33 +4344,11 @@ func (h *Handler) GetPaymentPlans(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	page := 0
-	pageSize := 20
-
-	if c.QueryParam("page") != "" {
-		page, err = strconv.Atoi(c.QueryParam("page"))
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
-		}
-	}
-
-	if c.QueryParam("pageSize") != "" {
-		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
-		}
-		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
-		}
-	}
-
-	oldestFirst := services.StrToBool(c.QueryParam("oldestFirst"))
-
 	groupId := c.Param("id")
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1652,59 +4356,121 @@ func (h *Handler) GetPaymentPlans(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	bank := services.StrToBool(c.QueryParam("bank"))
+	paymentPlanId := c.Param("paymentPlanId")
+	if paymentPlanId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
 
-	if !bank {
-		isMember, err := h.groupStore.IsMember(group, user)
+	paymentPlan, err := h.groupStore.GetPaymentPlanById(c.Request().Context(), group, paymentPlanId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if paymentPlan == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
+
+	var body bindings.DuplicatePaymentPlan
+	err = c.Bind(&body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+	}
+
+	// Members can duplicate their own payment plans, admins the payment plans between the bank and a member.
+	isSender := !paymentPlan.SenderIsBank && user.Id == paymentPlan.SenderId
+	if !isSender {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
+		if !isAdmin || (!paymentPlan.SenderIsBank && !paymentPlan.ReceiverIsBank) {
+			return c.JSON(http.StatusForbidden, responses.New(false, "User not the sender of the payment plan", lang))
+		}
+	}
 
-		if !isMember {
-			return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
+	senderIsBank, senderId := paymentPlan.SenderIsBank, paymentPlan.SenderId
+	receiverIsBank, receiverId := paymentPlan.ReceiverIsBank, paymentPlan.ReceiverId
+	if body.CounterpartyId != "" {
+		counterpartyIsBank := strings.EqualFold(body.CounterpartyId, "bank")
+		if isSender || paymentPlan.SenderIsBank {
+			receiverIsBank, receiverId = counterpartyIsBank, body.CounterpartyId
+		} else {
+			senderIsBank, senderId = counterpartyIsBank, body.CounterpartyId
 		}
+	}
+	if senderIsBank && receiverIsBank {
+		return c.JSON(http.StatusOK, responses.New(false, "Cannot send money from bank to bank", lang))
+	}
+	if !senderIsBank && !receiverIsBank && senderId == receiverId {
+		return c.JSON(http.StatusOK, responses.New(false, "Sender is the receiver", lang))
+	}
 
-		paymentPlans, err := h.groupStore.GetPaymentPlans(group, user, c.QueryParam("search"), page, pageSize, oldestFirst)
+	var sender *models.User
+	if !senderIsBank {
+		sender, err = h.userStore.GetById(c.Request().Context(), senderId)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
-
-		count, err := h.groupStore.PaymentPlanCount(group, user)
+		if sender == nil {
+			return c.JSON(http.StatusNotFound, responses.New(false, "Couldn't find sender", lang))
+		}
+		isSenderMember, err := h.groupStore.IsMember(c.Request().Context(), group, sender)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
+		if !isSenderMember {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Sender not a member of the group", lang))
+		}
+	}
 
-		return c.JSON(http.StatusOK, responses.NewPaymentPlans(paymentPlans, count))
-	} else {
-		isAdmin, err := h.groupStore.IsAdmin(group, user)
+	var receiver *models.User
+	if !receiverIsBank {
+		receiver, err = h.userStore.GetById(c.Request().Context(), receiverId)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
-
-		if !isAdmin {
-			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
+		if receiver == nil {
+			return c.JSON(http.StatusNotFound, responses.New(false, "Couldn't find receiver", lang))
 		}
-
-		paymentPlans, err := h.groupStore.GetBankPaymentPlans(group, c.QueryParam("search"), page, pageSize, oldestFirst)
+		isReceiverMember, err := h.groupStore.IsMember(c.Request().Context(), group, receiver)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
+		if !isReceiverMember {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Receiver not a member of the group", lang))
+		}
+	}
 
-		count, err := h.groupStore.BankPaymentPlanCount(group)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	firstPayment := paymentPlan.NextExecute
+	if body.FirstPayment != "" {
+		date, status, msg := parsePaymentDate(body.FirstPayment, services.PaymentPlanLocation(paymentPlan.TimeZone), "First payment can't be in the past")
+		if msg != "" {
+			return c.JSON(status, responses.New(false, msg, lang))
 		}
+		firstPayment = date.Unix()
+	}
 
-		return c.JSON(http.StatusOK, responses.NewPaymentPlans(paymentPlans, count))
+	exceeded, err := h.paymentPlanLimitExceeded(c.Request().Context(), group, 1)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if exceeded {
+		return c.JSON(http.StatusConflict, responses.New(false, "Too many payment plans in the group", lang))
+	}
+
+	duplicate, err := h.groupStore.CreatePaymentPlan(c.Request().Context(), group, senderIsBank, receiverIsBank, sender, receiver, paymentPlan.Name, paymentPlan.Description, paymentPlan.Amount, paymentPlan.TotalPaymentCount, paymentPlan.Schedule, paymentPlan.ScheduleUnit, firstPayment, paymentPlan.TimeZone)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
+
+	return c.JSON(http.StatusOK, responses.NewPaymentPlan(duplicate))
 }
 
-// /api/group/:id/paymentPlan/nextPayment?id=uuid&firstPayment=int&schedule=int&scheduleUnit=string&count=int
-func (h *Handler) GetPaymentPlanNextPayments(c echo.Context) error {
+// /api/group/:id/paymentPlan/:paymentPlanId/history?page=int&pageSize=int&oldestFirst=bool (GET)
+func (h *Handler) GetPaymentPlanExecutions(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1716,7 +4482,30 @@ func (h *Handler) GetPaymentPlanNextPayments(c echo.Context) error {
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+
+	page := 0
+	pageSize := 20
+
+	if c.QueryParam("page") != "" {
+		page, err = strconv.Atoi(c.QueryParam("page"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'page' query parameter not a number", lang))
+		}
+	}
+
+	if c.QueryParam("pageSize") != "" {
+		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+		}
+		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		}
+	}
+
+	oldestFirst := oldestFirstParam(c, user)
+
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1724,106 +4513,61 @@ func (h *Handler) GetPaymentPlanNextPayments(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	count := 1
-	if c.QueryParam("count") != "" {
-		count, err = strconv.Atoi(c.QueryParam("count"))
-		if err != nil || count < 1 {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'count' query parameter not a number or <1", lang))
-		}
-		if count > config.Data.MaxPageSize {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "'count' query parameter too big", lang))
-		}
+	paymentPlanId := c.Param("paymentPlanId")
+	if paymentPlanId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
 
-	schedule := -1
-	scheduleUnit := ""
-	firstPayment := int64(-1)
+	paymentPlan, err := h.groupStore.GetPaymentPlanById(c.Request().Context(), group, paymentPlanId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if paymentPlan == nil {
+		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	}
 
-	if c.QueryParam("id") != "" {
-		id := c.QueryParam("id")
-		if id == "" {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
-		}
+	isSender := user.Id == paymentPlan.SenderId
+	isReceiver := user.Id == paymentPlan.ReceiverId
 
-		paymentPlan, err := h.groupStore.GetPaymentPlanById(group, id)
+	if isSender || isReceiver {
+		isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
-		if paymentPlan == nil {
-			return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
-		}
-
-		isSender := user.Id == paymentPlan.SenderId
-		isReceiver := user.Id == paymentPlan.ReceiverId
-
-		if isSender || isReceiver {
-			isMember, err := h.groupStore.IsMember(group, user)
-			if err != nil {
-				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-			}
-			if !isMember {
-				return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
-			}
-		} else if paymentPlan.SenderIsBank || paymentPlan.ReceiverIsBank {
-			isAdmin, err := h.groupStore.IsAdmin(group, user)
-			if err != nil {
-				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-			}
-			if !isAdmin {
-				return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
-			}
-		} else {
-			return c.JSON(http.StatusForbidden, responses.New(false, "User not allowed to view payment plan", lang))
+		if !isMember {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
 		}
-
-		schedule = paymentPlan.Schedule
-		scheduleUnit = paymentPlan.ScheduleUnit
-		firstPayment = paymentPlan.NextExecute
-	} else {
-		if c.QueryParam("schedule") != "" {
-			schedule, err = strconv.Atoi(c.QueryParam("schedule"))
-			if err != nil || schedule < 1 {
-				return c.JSON(http.StatusBadRequest, responses.New(false, "'schedule' query parameter not a number or <1", lang))
-			}
-		} else {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "Missing 'schedule' or 'id' query parameter", lang))
+	} else if paymentPlan.SenderIsBank || paymentPlan.ReceiverIsBank {
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
-
-		scheduleUnit = strings.ToLower(c.QueryParam("scheduleUnit"))
-		if scheduleUnit != models.ScheduleUnitDay && scheduleUnit != models.ScheduleUnitWeek && scheduleUnit != models.ScheduleUnitMonth && scheduleUnit != models.ScheduleUnitYear {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid schedule unit", lang))
+		if !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
 		}
+	} else {
+		return c.JSON(http.StatusForbidden, responses.New(false, "User not allowed to view payment plan", lang))
+	}
 
-		if c.QueryParam("firstPayment") != "" {
-			firstPayment, err = strconv.ParseInt(c.QueryParam("firstPayment"), 10, 64)
-			if err != nil {
-				return c.JSON(http.StatusBadRequest, responses.New(false, "'firstPayment' query parameter not a number", lang))
-			}
-		} else {
-			return c.JSON(http.StatusBadRequest, responses.New(false, "Missing 'firstPayment' or 'id' query parameter", lang))
-		}
+	executions, err := h.groupStore.GetPaymentPlanExecutions(c.Request().Context(), paymentPlan, page, pageSize, oldestFirst)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	executionTimes := make([]int64, count)
-	for i := 0; i < count; i++ {
-		executionTimes[i] = firstPayment
-		firstPayment = services.AddTime(firstPayment, schedule, scheduleUnit)
+	count, err := h.groupStore.PaymentPlanExecutionCount(c.Request().Context(), paymentPlan)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	return c.JSON(http.StatusOK, responses.PaymentPlanExecutionTimes{
-		Base: responses.Base{
-			Success: true,
-		},
-		ExecutionTimes: executionTimes,
-	})
+	return c.JSON(http.StatusOK, responses.NewPaymentPlanExecutions(executions, count))
 }
 
-// /api/group/:id/paymentPlan (POST)
-func (h *Handler) CreatePaymentPlan(c echo.Context) error {
+// /api/group/:id/total (GET)
+func (h *Handler) GetTotalMoney(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1835,7 +4579,7 @@ func (h *Handler) CreatePaymentPlan(c echo.Context) error {
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1843,125 +4587,113 @@ func (h *Handler) CreatePaymentPlan(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	var body bindings.CreatePaymentPlan
-	err = c.Bind(&body)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
-	}
-
-	if body.Amount <= 0 {
-		return c.JSON(http.StatusOK, responses.New(false, "Amount must be >0", lang))
-	}
-
-	if body.Schedule <= 0 {
-		return c.JSON(http.StatusOK, responses.New(false, "Schedule must be >0", lang))
-	}
-
-	body.Name = strings.TrimSpace(body.Name)
-	body.Description = strings.TrimSpace(body.Description)
-
-	if utf8.RuneCountInString(body.Name) > config.Data.MaxNameLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Name too long", lang))
-	}
-
-	if utf8.RuneCountInString(body.Name) < config.Data.MinNameLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Name too short", lang))
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-
-	if utf8.RuneCountInString(body.Description) > config.Data.MaxDescriptionLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Description too long", lang))
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
 	}
 
-	if utf8.RuneCountInString(body.Description) < config.Data.MinDescriptionLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Description too short", lang))
+	total, err := h.groupStore.GetTotalMoney(c.Request().Context(), group)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	body.ScheduleUnit = strings.ToLower(body.ScheduleUnit)
+	return c.JSON(http.StatusOK, responses.NewTotalMoney(total))
+}
 
-	if body.ScheduleUnit != models.ScheduleUnitDay && body.ScheduleUnit != models.ScheduleUnitWeek && body.ScheduleUnit != models.ScheduleUnitMonth && body.ScheduleUnit != models.ScheduleUnitYear {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid schedule unit", lang))
+// /api/group/:id/transaction/between?userA=uuid&userB=uuid (GET)
+func (h *Handler) GetTransactionsBetween(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	firstPayment, err := time.Parse("2006-01-02", body.FirstPayment)
+	groupId := c.Param("id")
+	if groupId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	}
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid date string", lang))
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if firstPayment.Before(time.Now()) {
-		return c.JSON(http.StatusOK, responses.New(false, "First payment can't be in the past", lang))
+	if group == nil {
+		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	if body.PaymentCount == 0 {
-		body.PaymentCount = -1
+	userAId := c.QueryParam("userA")
+	userBId := c.QueryParam("userB")
+	if userAId == "" || userBId == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing 'userA' or 'userB' query parameter", lang))
+	}
+	if userAId == userBId {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "'userA' and 'userB' must be different users", lang))
 	}
 
-	if !body.FromBank {
-		isMember, err := h.groupStore.IsMember(group, user)
+	// The involved members may see their shared history, everyone else needs to be an admin.
+	if user.Id == userAId || user.Id == userBId {
+		isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
 		if !isMember {
 			return c.JSON(http.StatusForbidden, responses.New(false, "Not a member of the group", lang))
 		}
-	}
-
-	var paymentPlan *models.PaymentPlan
-
-	if strings.EqualFold(body.ReceiverId, "bank") {
-		if body.FromBank {
-			return c.JSON(http.StatusOK, responses.New(false, "Cannot send money from bank to bank", lang))
-		}
-		paymentPlan, err = h.groupStore.CreatePaymentPlan(group, false, true, user, nil, body.Name, body.Description, int(body.Amount), body.PaymentCount, int(body.Schedule), body.ScheduleUnit, firstPayment.Unix())
-		if err != nil {
-			return c.JSON(http.StatusUnauthorized, responses.NewUnexpectedError(err, lang))
-		}
 	} else {
-		receiver, err := h.userStore.GetById(body.ReceiverId)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-		}
-		if receiver == nil {
-			return c.JSON(http.StatusNotFound, responses.New(false, "Couldn't find receiver", lang))
-		}
-		isReceiverMember, err := h.groupStore.IsMember(group, receiver)
+		isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
-		if !isReceiverMember {
-			return c.JSON(http.StatusForbidden, responses.New(false, "Receiver not a member of the group", lang))
+		if !isAdmin {
+			return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
 		}
+	}
 
-		if body.FromBank {
-			isAdmin, err := h.groupStore.IsAdmin(group, user)
-			if err != nil {
-				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-			}
-			if !isAdmin {
-				return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
-			}
-			paymentPlan, err = h.groupStore.CreatePaymentPlan(group, true, false, nil, receiver, body.Name, body.Description, int(body.Amount), body.PaymentCount, int(body.Schedule), body.ScheduleUnit, firstPayment.Unix())
-			if err != nil {
-				return c.JSON(http.StatusUnauthorized, responses.NewUnexpectedError(err, lang))
-			}
+	// Former members and deleted users may still appear in the transaction log, so the ids are not resolved.
+	userA := &models.User{Base: models.Base{Id: userAId}}
+	userB := &models.User{Base: models.Base{Id: userBId}}
+
+	log, err := h.groupStore.GetTransactionsBetween(c.Request().Context(), group, userA, userB)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	// Amount userA sent to userB minus the amount userB sent to userA
+	netFlow := 0
+	for _, t := range log {
+		if t.SenderId == userAId {
+			netFlow += t.Amount
 		} else {
-			if user.Id == body.ReceiverId {
-				return c.JSON(http.StatusOK, responses.New(false, "Sender is the receiver", lang))
-			}
-			paymentPlan, err = h.groupStore.CreatePaymentPlan(group, false, false, user, receiver, body.Name, body.Description, int(body.Amount), body.PaymentCount, int(body.Schedule), body.ScheduleUnit, firstPayment.Unix())
-			if err != nil {
-				return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-			}
+			netFlow -= t.Amount
 		}
 	}
 
-	return c.JSON(http.StatusOK, responses.NewPaymentPlan(paymentPlan))
+	names, err := h.transactionUserNames(c.Request().Context(), group, log...)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewTransactionsBetween(log, names, userAId, userBId, netFlow))
 }
 
-// /api/group/:id/paymentPlan/:paymentPlanId (DELETE)
-func (h *Handler) DeletePaymentPlan(c echo.Context) error {
+// Upper bound for the number of data points returned by GetTimeSeries.
+const maxTimeSeriesPoints = 1000
+
+// /api/group/:id/transaction/timeseries?interval=day|week|month&from=int&to=int (GET)
+//
+// Returns the total balance of all members at the end of every interval between from and to.
+func (h *Handler) GetTimeSeries(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1973,7 +4705,7 @@ func (h *Handler) DeletePaymentPlan(c echo.Context) error {
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -1981,44 +4713,79 @@ func (h *Handler) DeletePaymentPlan(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	paymentPlanId := c.Param("paymentPlanId")
-	if paymentPlanId == "" {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
-	}
-
-	paymentPlan, err := h.groupStore.GetPaymentPlanById(group, paymentPlanId)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if paymentPlan == nil {
-		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
 	}
 
-	isSender := user.Id == paymentPlan.SenderId
-	if !isSender {
-		isAdmin, err := h.groupStore.IsAdmin(group, user)
+	interval := strings.ToLower(c.QueryParam("interval"))
+	if interval == "" {
+		interval = models.ScheduleUnitDay
+	}
+	if interval != models.ScheduleUnitDay && interval != models.ScheduleUnitWeek && interval != models.ScheduleUnitMonth {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid interval", lang))
+	}
+
+	if c.QueryParam("from") == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing 'from' query parameter", lang))
+	}
+	from, err := strconv.ParseInt(c.QueryParam("from"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "'from' query parameter not a number", lang))
+	}
+
+	to := time.Now().Unix()
+	if c.QueryParam("to") != "" {
+		to, err = strconv.ParseInt(c.QueryParam("to"), 10, 64)
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'to' query parameter not a number", lang))
 		}
-		if !paymentPlan.SenderIsBank || !isAdmin {
-			return c.JSON(http.StatusForbidden, responses.New(false, "User not the sender of the payment plan", lang))
+	}
+	if to < from {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "'to' must not be before 'from'", lang))
+	}
+
+	var starts []int64
+	for start := services.StartOfInterval(from, interval); start <= to; start = services.AddTime(start, 1, interval) {
+		if len(starts) == maxTimeSeriesPoints {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Too many intervals", lang))
 		}
+		starts = append(starts, start)
 	}
 
-	err = h.groupStore.DeletePaymentPlan(paymentPlan)
+	changes, err := h.groupStore.GetDailyBalanceChanges(c.Request().Context(), group, services.AddTime(starts[len(starts)-1], 1, interval))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	return c.JSON(http.StatusOK, responses.New(true, "Successfully deleted payment plan", lang))
+	// Intervals without transactions carry the balance of the previous interval forward.
+	points := make([]responses.TimeSeriesPoint, len(starts))
+	balance := 0
+	next := 0
+	for i, start := range starts {
+		end := services.AddTime(start, 1, interval)
+		for next < len(changes) && changes[next].Day*86400 < end {
+			balance += changes[next].Change
+			next++
+		}
+		points[i] = responses.TimeSeriesPoint{
+			Time:    start,
+			Balance: balance,
+		}
+	}
+
+	return c.JSON(http.StatusOK, responses.NewTimeSeries(interval, points))
 }
 
-// /api/group/:id/paymentPlan/:paymentPlanId (PUT)
-func (h *Handler) UpdatePaymentPlan(c echo.Context) error {
+// /api/group/:id/statement?month=YYYY-MM&format=json|pdf (GET)
+func (h *Handler) GetStatement(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -2030,7 +4797,22 @@ func (h *Handler) UpdatePaymentPlan(c echo.Context) error {
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+
+	start := time.Now().UTC()
+	start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if c.QueryParam("month") != "" {
+		start, err = time.Parse("2006-01", c.QueryParam("month"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid month", lang))
+		}
+	}
+
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != "pdf" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported format", lang))
+	}
+
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -2038,98 +4820,124 @@ func (h *Handler) UpdatePaymentPlan(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	paymentPlanId := c.Param("paymentPlanId")
-	if paymentPlanId == "" {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if !isAdmin {
+		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
 	}
 
-	paymentPlan, err := h.groupStore.GetPaymentPlanById(group, paymentPlanId)
+	statement, err := h.buildStatement(c.Request().Context(), group, start)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
-	if paymentPlan == nil {
-		return c.JSON(http.StatusNotFound, responses.NewNotFound(lang))
-	}
 
-	isSender := user.Id == paymentPlan.SenderId
-	if !isSender {
-		isAdmin, err := h.groupStore.IsAdmin(group, user)
+	if format == "pdf" {
+		pdf, err := services.GenerateStatementPDF(statement, lang)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 		}
-		if !paymentPlan.SenderIsBank || !isAdmin {
-			return c.JSON(http.StatusForbidden, responses.New(false, "User not the sender of the payment plan", lang))
-		}
-	}
-
-	var body bindings.UpdatePaymentPlan
-	err = c.Bind(&body)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"statement-%s.pdf\"", statement.Month))
+		return c.Blob(http.StatusOK, "application/pdf", pdf)
 	}
 
-	if body.Amount <= 0 {
-		return c.JSON(http.StatusOK, responses.New(false, "Amount must be >0", lang))
-	}
+	return c.JSON(http.StatusOK, responses.NewStatement(statement))
+}
 
-	if body.Schedule <= 0 {
-		return c.JSON(http.StatusOK, responses.New(false, "Schedule must be >0", lang))
+// Aggregates the transactions of the month beginning at start for every current member
+// and every former member who took part in a transaction of that month.
+func (h *Handler) buildStatement(ctx context.Context, group *models.Group, start time.Time) (services.Statement, error) {
+	statement := services.Statement{
+		GroupName: group.Name,
+		Month:     start.Format("2006-01"),
+		From:      start.Unix(),
+		To:        start.AddDate(0, 1, 0).Unix(),
+		Unit:      group.Unit,
 	}
 
-	body.Name = strings.TrimSpace(body.Name)
-	body.Description = strings.TrimSpace(body.Description)
-
-	if utf8.RuneCountInString(body.Name) > config.Data.MaxNameLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Name too long", lang))
+	transactions, err := h.groupStore.GetTransactionsInPeriod(ctx, group, statement.From, statement.To)
+	if err != nil {
+		return statement, err
 	}
 
-	if utf8.RuneCountInString(body.Name) < config.Data.MinNameLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Name too short", lang))
+	members, err := h.groupStore.GetMembers(ctx, nil, "", group, -1, -1, false, false)
+	if err != nil {
+		return statement, err
 	}
 
-	if utf8.RuneCountInString(body.Description) > config.Data.MaxDescriptionLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Description too long", lang))
+	names, err := h.transactionUserNames(ctx, group, transactions...)
+	if err != nil {
+		return statement, err
 	}
 
-	if utf8.RuneCountInString(body.Description) < config.Data.MinDescriptionLength {
-		return c.JSON(http.StatusOK, responses.New(false, "Description too short", lang))
+	entries := make(map[string]*services.StatementMember)
+	var order []string
+	addEntry := func(id, name string) *services.StatementMember {
+		entry, ok := entries[id]
+		if !ok {
+			entry = &services.StatementMember{UserId: id, Name: name}
+			entries[id] = entry
+			order = append(order, id)
+		}
+		return entry
 	}
-
-	body.ScheduleUnit = strings.ToLower(body.ScheduleUnit)
-
-	if body.ScheduleUnit != models.ScheduleUnitDay && body.ScheduleUnit != models.ScheduleUnitWeek && body.ScheduleUnit != models.ScheduleUnitMonth && body.ScheduleUnit != models.ScheduleUnitYear {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid schedule unit", lang))
+	for _, m := range members {
+		addEntry(m.Id, m.Name)
 	}
 
-	nextPayment, err := time.Parse("2006-01-02", body.NextPayment)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid date string", lang))
+	apply := func(id string, difference, newBalance int) {
+		entry := addEntry(id, names[id])
+		if entry.TransactionCount == 0 {
+			entry.OpeningBalance = newBalance - difference
+		}
+		entry.TransactionCount++
+		if difference > 0 {
+			entry.TotalIn += difference
+		} else {
+			entry.TotalOut -= difference
+		}
+		entry.ClosingBalance = newBalance
 	}
-	if nextPayment.Before(time.Now()) {
-		return c.JSON(http.StatusOK, responses.New(false, "Next payment can't be in the past", lang))
+	for _, t := range transactions {
+		if !t.SenderIsBank {
+			apply(t.SenderId, t.BalanceDifferenceSender, t.NewBalanceSender)
+		}
+		if !t.ReceiverIsBank {
+			apply(t.ReceiverId, t.BalanceDifferenceReceiver, t.NewBalanceReceiver)
+		}
 	}
 
-	paymentPlan.Amount = int(body.Amount)
-	paymentPlan.Name = body.Name
-	paymentPlan.Description = body.Description
-	paymentPlan.NextExecute = nextPayment.Unix()
-	paymentPlan.Schedule = int(body.Schedule)
-	paymentPlan.ScheduleUnit = body.ScheduleUnit
+	for _, m := range members {
+		entry := entries[m.Id]
+		if entry.TransactionCount > 0 {
+			continue
+		}
+		entry.OpeningBalance, err = h.groupStore.GetUserBalanceAt(ctx, group, &m, statement.From-1)
+		if err != nil {
+			return statement, err
+		}
+		entry.ClosingBalance = entry.OpeningBalance
+	}
 
-	err = h.groupStore.UpdatePaymentPlan(paymentPlan)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	statement.Members = make([]services.StatementMember, len(order))
+	for i, id := range order {
+		statement.Members[i] = *entries[id]
 	}
 
-	return c.JSON(http.StatusOK, responses.NewPaymentPlan(paymentPlan))
+	return statement, nil
 }
 
-// /api/group/:id/total (GET)
-func (h *Handler) GetTotalMoney(c echo.Context) error {
+// /api/group/:id/transaction/import?dryRun=bool (POST)
+//
+// Expects a CSV file with the columns date (YYYY-MM-DD), sender email, receiver email, amount and title.
+// "bank" can be used instead of an email address. Dates must not be in the future.
+// The minimum and maximum balance of the group aren't enforced, even with overdraft protection, because the imported transactions already happened.
+func (h *Handler) ImportTransactions(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -2141,7 +4949,7 @@ func (h *Handler) GetTotalMoney(c echo.Context) error {
 	if groupId == "" {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
-	group, err := h.groupStore.GetById(groupId)
+	group, err := h.groupStore.GetById(c.Request().Context(), groupId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -2149,7 +4957,7 @@ func (h *Handler) GetTotalMoney(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, responses.New(false, "Group not found", lang))
 	}
 
-	isAdmin, err := h.groupStore.IsAdmin(group, user)
+	isAdmin, err := h.groupStore.IsAdmin(c.Request().Context(), group, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -2157,10 +4965,182 @@ func (h *Handler) GetTotalMoney(c echo.Context) error {
 		return c.JSON(http.StatusForbidden, responses.New(false, "Not an admin of the group", lang))
 	}
 
-	total, err := h.groupStore.GetTotalMoney(group)
+	dryRun := services.StrToBool(c.QueryParam("dryRun"))
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid or missing CSV file", lang))
+	}
+
+	if file.Size > config.Data.MaxImportFileSize {
+		return c.JSON(http.StatusBadRequest, responses.New(false, fmt.Sprintf(services.Tr("File too big (max %s)", lang), services.SizeInBytesToStr(config.Data.MaxImportFileSize)), ""))
+	}
+
+	src, err := file.Open()
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
+	defer src.Close()
 
-	return c.JSON(http.StatusOK, responses.NewTotalMoney(total))
+	reader := csv.NewReader(src)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid or missing CSV file", lang))
+	}
+
+	var importErrors []responses.ImportError
+	transactions := make([]models.TransactionLogEntry, 0, len(records))
+	users := make(map[string]*models.User)
+	for i, record := range records {
+		row := i + 1
+		if i == 0 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "date") {
+			continue
+		}
+
+		fail := func(message string) {
+			importErrors = append(importErrors, responses.ImportError{Row: row, Message: services.Tr(message, lang)})
+		}
+
+		if len(record) != 5 {
+			fail("Invalid number of columns")
+			continue
+		}
+
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			fail("Invalid date string")
+			continue
+		}
+		if date.Unix() > time.Now().Unix() {
+			fail("Date can't be in the future")
+			continue
+		}
+
+		sender, senderMsg, err := h.importUser(c.Request().Context(), group, record[1], users)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if senderMsg != "" {
+			fail(senderMsg)
+			continue
+		}
+
+		receiver, receiverMsg, err := h.importUser(c.Request().Context(), group, record[2], users)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if receiverMsg != "" {
+			fail(receiverMsg)
+			continue
+		}
+
+		if sender == nil && receiver == nil {
+			fail("Cannot send money from bank to bank")
+			continue
+		}
+		if sender != nil && receiver != nil && sender.Id == receiver.Id {
+			fail("Sender is the receiver")
+			continue
+		}
+
+		amount, err := strconv.Atoi(strings.TrimSpace(record[3]))
+		if err != nil || amount <= 0 {
+			fail("Amount must be >0")
+			continue
+		}
+
+		title := strings.TrimSpace(record[4])
+		if utf8.RuneCountInString(title) > config.Data.MaxNameLength {
+			fail("Title too long")
+			continue
+		}
+		if utf8.RuneCountInString(title) < config.Data.MinNameLength {
+			fail("Title too short")
+			continue
+		}
+
+		transaction := models.TransactionLogEntry{
+			Base: models.Base{
+				Created: date.Unix(),
+			},
+			Title:          title,
+			Amount:         amount,
+			SenderIsBank:   sender == nil,
+			ReceiverIsBank: receiver == nil,
+		}
+		if sender != nil {
+			transaction.SenderId = sender.Id
+		}
+		if receiver != nil {
+			transaction.ReceiverId = receiver.Id
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if len(importErrors) > 0 {
+		return c.JSON(http.StatusOK, responses.NewTransactionImport(0, dryRun, importErrors))
+	}
+
+	if len(transactions) == 0 {
+		return c.JSON(http.StatusOK, responses.New(false, "No transactions to import", lang))
+	}
+
+	// Balances are derived from the latest transaction, so the imported transactions are created in chronological order.
+	// Transactions on the same day keep the order of the file.
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Created < transactions[j].Created
+	})
+
+	if dryRun {
+		newer, err := h.groupStore.GetTransactionsInPeriod(c.Request().Context(), group, transactions[0].Created, math.MaxInt64)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if len(newer) > 0 {
+			return c.JSON(http.StatusOK, responses.New(false, "Imported transactions must be newer than the existing transactions of the group", lang))
+		}
+	} else {
+		err = h.groupStore.ImportTransactions(c.Request().Context(), group, transactions)
+		if errors.Is(err, models.ErrImportNotNewer) {
+			return c.JSON(http.StatusOK, responses.New(false, "Imported transactions must be newer than the existing transactions of the group", lang))
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+	}
+
+	return c.JSON(http.StatusOK, responses.NewTransactionImport(len(transactions), dryRun, nil))
+}
+
+// Resolves the user with the email address of an imported transaction. A nil user without a message stands for the bank.
+func (h *Handler) importUser(ctx context.Context, group *models.Group, email string, cache map[string]*models.User) (*models.User, string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "bank" {
+		return nil, "", nil
+	}
+
+	if user, ok := cache[email]; ok {
+		return user, "", nil
+	}
+
+	user, err := h.userStore.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, "", err
+	}
+	if user == nil {
+		return nil, "Unknown email address", nil
+	}
+
+	isMember, err := h.groupStore.IsMember(ctx, group, user)
+	if err != nil {
+		return nil, "", err
+	}
+	if !isMember {
+		return nil, "The user is not a member of the group", nil
+	}
+
+	cache[email] = user
+	return user, "", nil
 }
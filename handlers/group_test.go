@@ -0,0 +1,3107 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/juho05/h-bank/bindings"
+	"github.com/juho05/h-bank/config"
+	"github.com/juho05/h-bank/db"
+	"github.com/juho05/h-bank/models"
+	"github.com/juho05/h-bank/responses"
+	"github.com/juho05/h-bank/router"
+)
+
+func TestHandler_GetTransactionLog_RemovedMember(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user1 := &models.User{
+		Name:  "bob",
+		Email: "bob@gmail.com",
+	}
+	us.Create(context.Background(), user1)
+
+	user2 := &models.User{
+		Name:  "peter",
+		Email: "peter@gmail.com",
+	}
+	us.Create(context.Background(), user2)
+
+	group := &models.Group{
+		Name: "group",
+	}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+
+	_, err = gs.CreateTransaction(context.Background(), group, true, false, nil, user1, "Pocket money", "", 100)
+	if err != nil {
+		t.Fatalf("Couldn't create transaction")
+	}
+	_, err = gs.CreateTransaction(context.Background(), group, false, false, user1, user2, "Gift", "", 50)
+	if err != nil {
+		t.Fatalf("Couldn't create transaction")
+	}
+
+	err = gs.RemoveMember(context.Background(), group, user2)
+	if err != nil {
+		t.Fatalf("Couldn't remove member")
+	}
+
+	handler := New(us, gs, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := r.NewContext(req, rec)
+	c.Set("lang", "en")
+	c.Set("userId", user1.Id)
+	c.SetParamNames("id")
+	c.SetParamValues(group.Id)
+
+	err = handler.GetTransactionLog(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	type transactionsResp struct {
+		responses.Base
+		Transactions []struct {
+			Title        string `json:"title"`
+			SenderId     string `json:"senderId"`
+			SenderName   string `json:"senderName"`
+			ReceiverId   string `json:"receiverId"`
+			ReceiverName string `json:"receiverName"`
+		} `json:"transactions"`
+	}
+	var resp transactionsResp
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+
+	if assert.Len(t, resp.Transactions, 2) {
+		for _, transaction := range resp.Transactions {
+			switch transaction.Title {
+			case "Gift":
+				assert.Equal(t, user1.Name, transaction.SenderName)
+				assert.Equal(t, user2.Id, transaction.ReceiverId)
+				assert.Equal(t, user2.Name, transaction.ReceiverName)
+			case "Pocket money":
+				assert.Equal(t, "bank", transaction.SenderId)
+				assert.Equal(t, models.DefaultBankName, transaction.SenderName)
+				assert.Equal(t, user1.Name, transaction.ReceiverName)
+			}
+		}
+	}
+
+	isInGroup, err := gs.IsInGroup(context.Background(), group, user2)
+	assert.NoError(t, err)
+	assert.False(t, isInGroup)
+
+	count, err := gs.MembershipCount(context.Background(), group)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestHandler_GetStatement(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user1 := &models.User{
+		Name:  "bob",
+		Email: "bob@gmail.com",
+	}
+	us.Create(context.Background(), user1)
+
+	user2 := &models.User{
+		Name:  "peter",
+		Email: "peter@gmail.com",
+	}
+	us.Create(context.Background(), user2)
+
+	group := &models.Group{
+		Name: "group",
+	}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+	gs.AddAdmin(context.Background(), group, user1)
+
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	previous, err := gs.CreateTransaction(context.Background(), group, true, false, nil, user1, "Pocket money", "", 100)
+	if err != nil {
+		t.Fatalf("Couldn't create transaction")
+	}
+	database.Model(previous).Update("created", month.AddDate(0, 0, -1).Unix())
+	_, err = gs.CreateTransaction(context.Background(), group, false, false, user1, user2, "Gift", "", 30)
+	if err != nil {
+		t.Fatalf("Couldn't create transaction")
+	}
+
+	handler := New(us, gs, nil)
+
+	type statementResp struct {
+		responses.Base
+		Month   string `json:"month"`
+		Members []struct {
+			UserId         string `json:"userId"`
+			OpeningBalance int    `json:"openingBalance"`
+			TotalIn        int    `json:"totalIn"`
+			TotalOut       int    `json:"totalOut"`
+			ClosingBalance int    `json:"closingBalance"`
+		} `json:"members"`
+	}
+
+	tests := []struct {
+		name        string
+		userId      string
+		month       string
+		wantCode    int
+		wantBalance map[string][4]int
+	}{
+		{name: "Current month", userId: user1.Id, month: month.Format("2006-01"), wantCode: http.StatusOK, wantBalance: map[string][4]int{user1.Id: {100, 0, 30, 70}, user2.Id: {0, 30, 0, 30}}},
+		{name: "Previous month", userId: user1.Id, month: month.AddDate(0, -1, 0).Format("2006-01"), wantCode: http.StatusOK, wantBalance: map[string][4]int{user1.Id: {0, 100, 0, 100}, user2.Id: {0, 0, 0, 0}}},
+		{name: "Invalid month", userId: user1.Id, month: "2024-13", wantCode: http.StatusBadRequest},
+		{name: "Not an admin", userId: user2.Id, month: month.Format("2006-01"), wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?month="+tt.month, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.GetStatement(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantBalance == nil {
+				return
+			}
+
+			var resp statementResp
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.Equal(t, tt.month, resp.Month)
+			if assert.Len(t, resp.Members, len(tt.wantBalance)) {
+				for _, m := range resp.Members {
+					assert.Equal(t, tt.wantBalance[m.UserId], [4]int{m.OpeningBalance, m.TotalIn, m.TotalOut, m.ClosingBalance})
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_AcceptInvitation_OpeningBalance(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name                string
+		openingBalance      int
+		overdraftProtection bool
+		wantCount           int64
+	}{
+		{name: "Credit", openingBalance: 250, wantCount: 1},
+		{name: "Debt", openingBalance: -80, wantCount: 1},
+		{name: "Zero", openingBalance: 0, wantCount: 0},
+		// The opening balance is set by an admin, so it isn't limited by the minimum balance.
+		{name: "Debt with overdraft protection", openingBalance: -80, overdraftProtection: true, wantCount: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &models.User{
+				Name:  tt.name,
+				Email: strings.ReplaceAll(tt.name, " ", "") + "@gmail.com",
+			}
+			us.Create(context.Background(), user)
+
+			group := &models.Group{
+				Name: tt.name,
+			}
+			group.OverdraftProtection = tt.overdraftProtection
+			gs.Create(context.Background(), group)
+
+			invitation, err := gs.CreateInvitation(context.Background(), group, user, nil, "", tt.openingBalance)
+			if err != nil {
+				t.Fatalf("Couldn't create invitation")
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user.Id)
+			c.SetParamNames("id")
+			c.SetParamValues(invitation.Id)
+
+			err = handler.AcceptInvitation(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			balance, err := gs.GetUserBalance(context.Background(), group, user)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.openingBalance, balance)
+
+			count, err := gs.TransactionLogEntryCount(context.Background(), group, user, models.TransactionFilter{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCount, count)
+
+			isMember, err := gs.IsMember(context.Background(), group, user)
+			assert.NoError(t, err)
+			assert.True(t, isMember)
+		})
+	}
+}
+
+func TestHandler_AcceptInvitation_Twice(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	handler := New(us, gs, nil)
+
+	user := &models.User{Name: "user", Email: "user@gmail.com"}
+	us.Create(context.Background(), user)
+	other := &models.User{Name: "other", Email: "other@gmail.com"}
+	us.Create(context.Background(), other)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+
+	invitation, err := gs.CreateInvitation(context.Background(), group, user, nil, "", 100)
+	if err != nil {
+		t.Fatalf("Couldn't create invitation")
+	}
+
+	accept := func(userId string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		rec := httptest.NewRecorder()
+		c := r.NewContext(req, rec)
+		c.Set("lang", "en")
+		c.Set("userId", userId)
+		c.SetParamNames("id")
+		c.SetParamValues(invitation.Id)
+		return rec, handler.AcceptInvitation(c)
+	}
+
+	for i := 0; i < 2; i++ {
+		rec, err := accept(user.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"success":true`)
+	}
+
+	rec, err := accept(other.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	isMember, err := gs.IsMember(context.Background(), group, user)
+	assert.NoError(t, err)
+	assert.True(t, isMember)
+
+	count, err := gs.TransactionLogEntryCount(context.Background(), group, user, models.TransactionFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	balance, err := gs.GetUserBalance(context.Background(), group, user)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, balance)
+}
+
+func TestHandler_ResendInvitation(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	handler := New(us, gs, nil)
+
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+	user := &models.User{Name: "user", Email: "user@gmail.com"}
+	us.Create(context.Background(), user)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddAdmin(context.Background(), group, admin)
+
+	otherGroup := &models.Group{Name: "other"}
+	gs.Create(context.Background(), otherGroup)
+	gs.AddAdmin(context.Background(), otherGroup, admin)
+
+	invitation, err := gs.CreateInvitation(context.Background(), group, user, nil, "", 0)
+	if err != nil {
+		t.Fatalf("Couldn't create invitation")
+	}
+	expired, err := gs.CreateInvitation(context.Background(), otherGroup, user, nil, "", 0)
+	if err != nil {
+		t.Fatalf("Couldn't create invitation")
+	}
+	gs.UpdateInvitationLastSent(context.Background(), expired, 0)
+
+	tests := []struct {
+		name           string
+		userId         string
+		groupId        string
+		invitationId   string
+		wantCode       int
+		wantSuccess    bool
+		wantRetryAfter bool
+	}{
+		{name: "Cooldown", userId: admin.Id, groupId: group.Id, invitationId: invitation.Id, wantCode: http.StatusTooManyRequests, wantRetryAfter: true},
+		{name: "Cooldown expired", userId: admin.Id, groupId: otherGroup.Id, invitationId: expired.Id, wantCode: http.StatusOK},
+		{name: "Not an admin", userId: user.Id, groupId: group.Id, invitationId: invitation.Id, wantCode: http.StatusForbidden},
+		{name: "Wrong group", userId: admin.Id, groupId: otherGroup.Id, invitationId: invitation.Id, wantCode: http.StatusNotFound},
+		{name: "Unknown invitation", userId: admin.Id, groupId: group.Id, invitationId: "abc", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id", "invitationId")
+			c.SetParamValues(tt.groupId, tt.invitationId)
+
+			err := handler.ResendInvitation(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			var resp struct {
+				Success    bool  `json:"success"`
+				RetryAfter int64 `json:"retryAfter"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.Equal(t, tt.wantSuccess, resp.Success)
+			if tt.wantRetryAfter {
+				assert.Greater(t, resp.RetryAfter, int64(0))
+				assert.NotEmpty(t, rec.Header().Get(echo.HeaderRetryAfter))
+			} else {
+				assert.Empty(t, rec.Header().Get(echo.HeaderRetryAfter))
+			}
+		})
+	}
+}
+
+func TestHandler_GetInvitationPreview(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	handler := New(us, gs, nil)
+
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+	user := &models.User{Name: "user", Email: "user@gmail.com"}
+	us.Create(context.Background(), user)
+
+	group := &models.Group{Name: "group", Description: "Pocket money"}
+	gs.Create(context.Background(), group)
+	gs.AddAdmin(context.Background(), group, admin)
+
+	otherGroup := &models.Group{Name: "other"}
+	gs.Create(context.Background(), otherGroup)
+
+	invitation, err := gs.CreateInvitation(context.Background(), group, user, admin, "Welcome", 100)
+	if err != nil {
+		t.Fatalf("Couldn't create invitation")
+	}
+	withoutInviter, err := gs.CreateInvitation(context.Background(), otherGroup, user, nil, "", 0)
+	if err != nil {
+		t.Fatalf("Couldn't create invitation")
+	}
+
+	tests := []struct {
+		name         string
+		userId       string
+		invitationId string
+		wantCode     int
+		wantGroup    string
+		wantInviter  string
+	}{
+		{name: "Success", userId: user.Id, invitationId: invitation.Id, wantCode: http.StatusOK, wantGroup: group.Name, wantInviter: admin.Name},
+		{name: "Without inviter", userId: user.Id, invitationId: withoutInviter.Id, wantCode: http.StatusOK, wantGroup: otherGroup.Name},
+		{name: "Not the receiver", userId: admin.Id, invitationId: invitation.Id, wantCode: http.StatusForbidden},
+		{name: "Unknown invitation", userId: user.Id, invitationId: "abc", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.invitationId)
+
+			err := handler.GetInvitationPreview(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			if tt.wantCode == http.StatusOK {
+				var resp struct {
+					Group struct {
+						Name string `json:"name"`
+					} `json:"group"`
+					Inviter *struct {
+						Name string `json:"name"`
+					} `json:"inviter"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, tt.wantGroup, resp.Group.Name)
+				if tt.wantInviter != "" && assert.NotNil(t, resp.Inviter) {
+					assert.Equal(t, tt.wantInviter, resp.Inviter.Name)
+				} else if tt.wantInviter == "" {
+					assert.Nil(t, resp.Inviter)
+				}
+			}
+		})
+	}
+
+	seen, err := gs.GetInvitationById(context.Background(), invitation.Id)
+	assert.NoError(t, err)
+	assert.False(t, seen.Seen, "the preview doesn't change the invitation")
+}
+
+func TestHandler_InvitationSeen(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+
+	groups := make([]*models.Group, 3)
+	for i := range groups {
+		groups[i] = &models.Group{Name: fmt.Sprintf("group%d", i)}
+		gs.Create(context.Background(), groups[i])
+	}
+	gs.CreateInvitation(context.Background(), groups[0], bob, nil, "", 0)
+	gs.CreateInvitation(context.Background(), groups[1], bob, nil, "", 0)
+
+	handler := New(us, gs, nil)
+
+	call := func(method string, handle echo.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		rec := httptest.NewRecorder()
+		c := r.NewContext(req, rec)
+		c.Set("lang", "en")
+		c.Set("userId", bob.Id)
+		assert.NoError(t, handle(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		return rec
+	}
+	unseenCount := func() int64 {
+		var resp struct {
+			Count int64 `json:"count"`
+		}
+		json.Unmarshal(call(http.MethodGet, handler.GetUnseenInvitationCount).Body.Bytes(), &resp)
+		return resp.Count
+	}
+	getInvitations := func() []bool {
+		var resp struct {
+			Invitations []struct {
+				Seen bool `json:"seen"`
+			} `json:"invitations"`
+		}
+		json.Unmarshal(call(http.MethodGet, handler.GetInvitationsByUser).Body.Bytes(), &resp)
+		seen := make([]bool, len(resp.Invitations))
+		for i, in := range resp.Invitations {
+			seen[i] = in.Seen
+		}
+		return seen
+	}
+
+	assert.Equal(t, int64(2), unseenCount())
+
+	// Listing the invitations marks them as seen but still reports the previous state.
+	assert.Equal(t, []bool{false, false}, getInvitations())
+	assert.Equal(t, int64(0), unseenCount())
+	assert.Equal(t, []bool{true, true}, getInvitations())
+
+	gs.CreateInvitation(context.Background(), groups[2], bob, nil, "", 0)
+	assert.Equal(t, int64(1), unseenCount())
+
+	call(http.MethodPost, handler.MarkAllInvitationsAsSeen)
+	assert.Equal(t, int64(0), unseenCount())
+}
+
+func TestHandler_CreateBulkPaymentPlans(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+	user1 := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "peter", Email: "peter@gmail.com"}
+	us.Create(context.Background(), user2)
+	outsider := &models.User{Name: "eve", Email: "eve@gmail.com"}
+	us.Create(context.Background(), outsider)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddAdmin(context.Background(), group, admin)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+
+	handler := New(us, gs, nil)
+
+	firstPayment := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+
+	tests := []struct {
+		name      string
+		userId    string
+		senderIds []string
+		wantCode  int
+		wantCount int64
+	}{
+		{name: "Not an admin", userId: user1.Id, senderIds: []string{user1.Id, user2.Id}, wantCode: http.StatusForbidden, wantCount: 0},
+		{name: "Sender not a member", userId: admin.Id, senderIds: []string{user1.Id, outsider.Id}, wantCode: http.StatusForbidden, wantCount: 0},
+		{name: "Success", userId: admin.Id, senderIds: []string{user1.Id, user2.Id, user1.Id}, wantCode: http.StatusOK, wantCount: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"name":         "Dues",
+				"amount":       500,
+				"receiverId":   "bank",
+				"schedule":     1,
+				"scheduleUnit": "month",
+				"firstPayment": firstPayment,
+				"senderIds":    tt.senderIds,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.CreateBulkPaymentPlans(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			count, err := gs.BankPaymentPlanCount(context.Background(), group)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCount, count)
+		})
+	}
+}
+
+func TestHandler_UpdatePaymentPlan_Fields(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user)
+
+	nextExecute := time.Now().AddDate(0, 0, 2).Unix()
+	paymentPlan, err := gs.CreatePaymentPlan(context.Background(), group, false, true, user, nil, "Dues", "Monthly dues", 500, 3, 1, models.ScheduleUnitMonth, nextExecute, "")
+	if err != nil {
+		t.Fatalf("Couldn't create payment plan")
+	}
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name     string
+		body     map[string]interface{}
+		wantCode int
+		want     models.PaymentPlan
+		// Zero if the next execution must not change
+		wantNextExecute int64
+	}{
+		{name: "Unknown field", body: map[string]interface{}{"fields": []string{"senderId"}}, wantCode: http.StatusBadRequest, want: *paymentPlan},
+		{name: "Clear description", body: map[string]interface{}{"fields": []string{"description"}, "description": ""}, wantCode: http.StatusOK, want: models.PaymentPlan{Name: "Dues", Description: "", Amount: 500, PaymentCount: 3}},
+		{name: "Unlimited payment count", body: map[string]interface{}{"fields": []string{"paymentCount"}, "paymentCount": 0}, wantCode: http.StatusOK, want: models.PaymentPlan{Name: "Dues", Description: "", Amount: 500, PaymentCount: -1}},
+		{name: "Invalid amount", body: map[string]interface{}{"fields": []string{"amount"}, "amount": 0}, wantCode: http.StatusOK, want: models.PaymentPlan{Name: "Dues", Description: "", Amount: 500, PaymentCount: -1}},
+		{name: "Next payment in the past", body: map[string]interface{}{"fields": []string{"nextPayment"}, "nextPayment": time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")}, wantCode: http.StatusBadRequest, want: models.PaymentPlan{Name: "Dues", Description: "", Amount: 500, PaymentCount: -1}},
+		{name: "Next payment today", body: map[string]interface{}{"fields": []string{"nextPayment"}, "nextPayment": time.Now().UTC().Format("2006-01-02")}, wantCode: http.StatusOK, want: models.PaymentPlan{Name: "Dues", Description: "", Amount: 500, PaymentCount: -1}, wantNextExecute: time.Now().UTC().Truncate(24 * time.Hour).Unix()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user.Id)
+			c.SetParamNames("id", "paymentPlanId")
+			c.SetParamValues(group.Id, paymentPlan.Id)
+
+			err := handler.UpdatePaymentPlan(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			updated, err := gs.GetPaymentPlanById(context.Background(), group, paymentPlan.Id)
+			if assert.NoError(t, err) && assert.NotNil(t, updated) {
+				assert.Equal(t, tt.want.Name, updated.Name)
+				assert.Equal(t, tt.want.Description, updated.Description)
+				assert.Equal(t, tt.want.Amount, updated.Amount)
+				assert.Equal(t, tt.want.PaymentCount, updated.PaymentCount)
+				if tt.wantNextExecute != 0 {
+					assert.Equal(t, tt.wantNextExecute, updated.NextExecute)
+				} else {
+					assert.Equal(t, nextExecute, updated.NextExecute)
+				}
+			}
+		})
+	}
+}
+
+func Test_validatePaymentPlan(t *testing.T) {
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+	today := time.Now().UTC().Format("2006-01-02")
+
+	tests := []struct {
+		name         string
+		schedule     uint
+		scheduleUnit string
+		firstPayment string
+		wantCode     int
+		wantMsg      string
+	}{
+		{name: "Day", schedule: 1, scheduleUnit: models.ScheduleUnitDay, firstPayment: tomorrow, wantCode: http.StatusOK},
+		{name: "Week", schedule: 2, scheduleUnit: models.ScheduleUnitWeek, firstPayment: tomorrow, wantCode: http.StatusOK},
+		{name: "Month", schedule: 1, scheduleUnit: models.ScheduleUnitMonth, firstPayment: tomorrow, wantCode: http.StatusOK},
+		{name: "Year", schedule: 1, scheduleUnit: models.ScheduleUnitYear, firstPayment: tomorrow, wantCode: http.StatusOK},
+		{name: "Upper case unit", schedule: 1, scheduleUnit: "MONTH", firstPayment: tomorrow, wantCode: http.StatusOK},
+		{name: "Today", schedule: 1, scheduleUnit: models.ScheduleUnitDay, firstPayment: today, wantCode: http.StatusOK},
+		{name: "Zero schedule", schedule: 0, scheduleUnit: models.ScheduleUnitDay, firstPayment: tomorrow, wantCode: http.StatusBadRequest, wantMsg: "Schedule must be >0"},
+		{name: "Invalid unit", schedule: 1, scheduleUnit: "hour", firstPayment: tomorrow, wantCode: http.StatusBadRequest, wantMsg: "Invalid schedule unit"},
+		{name: "Missing unit", schedule: 1, scheduleUnit: "", firstPayment: tomorrow, wantCode: http.StatusBadRequest, wantMsg: "Invalid schedule unit"},
+		{name: "Invalid date", schedule: 1, scheduleUnit: models.ScheduleUnitDay, firstPayment: "01.01.2030", wantCode: http.StatusBadRequest, wantMsg: "Invalid date string"},
+		{name: "Past date", schedule: 1, scheduleUnit: models.ScheduleUnitDay, firstPayment: "1970-01-01", wantCode: http.StatusBadRequest, wantMsg: "First payment can't be in the past"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bindings.CreatePaymentPlan{
+				Name:         "Dues",
+				Amount:       100,
+				Schedule:     tt.schedule,
+				ScheduleUnit: tt.scheduleUnit,
+				FirstPayment: tt.firstPayment,
+			}
+			_, code, msg := validatePaymentPlan(&body)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantMsg, msg)
+		})
+	}
+}
+
+func TestHandler_SkipNextPaymentPlanExecution(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user1 := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "peter", Email: "peter@gmail.com"}
+	us.Create(context.Background(), user2)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+
+	nextExecute := time.Date(2030, time.January, 15, 0, 0, 0, 0, time.UTC).Unix()
+	paymentPlan, err := gs.CreatePaymentPlan(context.Background(), group, false, false, user1, user2, "Rent", "", 500, 3, 1, models.ScheduleUnitMonth, nextExecute, "")
+	if err != nil {
+		t.Fatalf("Couldn't create payment plan")
+	}
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name            string
+		userId          string
+		wantCode        int
+		wantNextExecute int64
+		wantSkips       int64
+	}{
+		{name: "Not the sender", userId: user2.Id, wantCode: http.StatusForbidden, wantNextExecute: nextExecute, wantSkips: 0},
+		{name: "Success", userId: user1.Id, wantCode: http.StatusOK, wantNextExecute: time.Date(2030, time.February, 15, 0, 0, 0, 0, time.UTC).Unix(), wantSkips: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id", "paymentPlanId")
+			c.SetParamValues(group.Id, paymentPlan.Id)
+
+			err := handler.SkipNextPaymentPlanExecution(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			updated, err := gs.GetPaymentPlanById(context.Background(), group, paymentPlan.Id)
+			if assert.NoError(t, err) && assert.NotNil(t, updated) {
+				assert.Equal(t, tt.wantNextExecute, updated.NextExecute)
+				assert.Equal(t, 3, updated.PaymentCount)
+			}
+
+			count, err := gs.PaymentPlanExecutionCount(context.Background(), paymentPlan)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSkips, count)
+
+			transactionCount, err := gs.TransactionLogEntryCount(context.Background(), group, user1, models.TransactionFilter{})
+			assert.NoError(t, err)
+			assert.Zero(t, transactionCount)
+		})
+	}
+
+	executions, err := gs.GetPaymentPlanExecutions(context.Background(), paymentPlan, -1, -1, false)
+	if assert.NoError(t, err) && assert.Len(t, executions, 1) {
+		assert.True(t, executions[0].Skipped)
+		assert.Equal(t, nextExecute, executions[0].ScheduledFor)
+	}
+}
+
+func TestHandler_UpdateGroup(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddAdmin(context.Background(), group, admin)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name            string
+		body            string
+		wantDescription string
+		wantUnit        string
+	}{
+		{name: "Unit", body: `{"description":"Chores","unit":" pts "}`, wantDescription: "Chores", wantUnit: "pts"},
+		{name: "Keep unit", body: `{"description":"Weekly chores"}`, wantDescription: "Weekly chores", wantUnit: "pts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", admin.Id)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.UpdateGroup(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			updated, err := gs.GetById(context.Background(), group.Id)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.wantDescription, updated.Description)
+				assert.Equal(t, tt.wantUnit, updated.Unit)
+			}
+		})
+	}
+}
+
+func TestHandler_UpdateGroupSettings(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+	member := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), member)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddAdmin(context.Background(), group, admin)
+	gs.AddMember(context.Background(), group, member)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name        string
+		userId      string
+		body        string
+		wantCode    int
+		wantSuccess bool
+		want        models.GroupSettings
+	}{
+		{name: "Not an admin", userId: member.Id, body: `{"unit":"pts"}`, wantCode: http.StatusForbidden, want: models.GroupSettings{Unit: models.DefaultGroupUnit, LeaveBalancePolicy: models.LeaveBalanceKeep, BankName: models.DefaultBankName}},
+		{name: "Positive minimum", userId: admin.Id, body: `{"minBalance":10}`, wantCode: http.StatusOK, want: models.GroupSettings{Unit: models.DefaultGroupUnit, LeaveBalancePolicy: models.LeaveBalanceKeep, BankName: models.DefaultBankName}},
+		{name: "Minimum above maximum", userId: admin.Id, body: `{"minBalance":-10,"maxBalance":-20}`, wantCode: http.StatusOK, want: models.GroupSettings{Unit: models.DefaultGroupUnit, LeaveBalancePolicy: models.LeaveBalanceKeep, BankName: models.DefaultBankName}},
+		{name: "Success", userId: admin.Id, body: `{"unit":"pts","minBalance":-500,"maxBalance":1000}`, wantCode: http.StatusOK, wantSuccess: true, want: models.GroupSettings{Unit: "pts", MinBalance: -500, MaxBalance: 1000, LeaveBalancePolicy: models.LeaveBalanceKeep, BankName: models.DefaultBankName}},
+		{name: "Keep unit", userId: admin.Id, body: `{"minBalance":-500,"maxBalance":1000}`, wantCode: http.StatusOK, wantSuccess: true, want: models.GroupSettings{Unit: "pts", MinBalance: -500, MaxBalance: 1000, LeaveBalancePolicy: models.LeaveBalanceKeep, BankName: models.DefaultBankName}},
+		{name: "Overdraft protection", userId: admin.Id, body: `{"minBalance":-100,"overdraftProtection":true}`, wantCode: http.StatusOK, wantSuccess: true, want: models.GroupSettings{Unit: "pts", MinBalance: -100, OverdraftProtection: true, LeaveBalancePolicy: models.LeaveBalanceKeep, BankName: models.DefaultBankName}},
+		{name: "Leave balance policy", userId: admin.Id, body: `{"leaveBalancePolicy":"redistribute"}`, wantCode: http.StatusOK, wantSuccess: true, want: models.GroupSettings{Unit: "pts", LeaveBalancePolicy: models.LeaveBalanceRedistribute, BankName: models.DefaultBankName}},
+		{name: "Invalid leave balance policy", userId: admin.Id, body: `{"leaveBalancePolicy":"burn"}`, wantCode: http.StatusBadRequest, want: models.GroupSettings{Unit: "pts", LeaveBalancePolicy: models.LeaveBalanceRedistribute, BankName: models.DefaultBankName}},
+		{name: "Bank name", userId: admin.Id, body: `{"bankName":" Membership Fund "}`, wantCode: http.StatusOK, wantSuccess: true, want: models.GroupSettings{Unit: "pts", LeaveBalancePolicy: models.LeaveBalanceKeep, BankName: "Membership Fund"}},
+		{name: "Bank name too long", userId: admin.Id, body: `{"bankName":"` + strings.Repeat("a", config.Data.MaxNameLength+1) + `"}`, wantCode: http.StatusOK, want: models.GroupSettings{Unit: "pts", LeaveBalancePolicy: models.LeaveBalanceKeep, BankName: "Membership Fund"}},
+		{name: "Reset", userId: admin.Id, body: `{}`, wantCode: http.StatusOK, wantSuccess: true, want: models.GroupSettings{Unit: "pts", LeaveBalancePolicy: models.LeaveBalanceKeep, BankName: models.DefaultBankName}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.UpdateGroupSettings(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			var resp responses.Base
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.Equal(t, tt.wantSuccess, resp.Success)
+
+			updated, err := gs.GetById(context.Background(), group.Id)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, updated.GroupSettings)
+			}
+		})
+	}
+}
+
+func TestHandler_GetBalanceAt(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+	user := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddAdmin(context.Background(), group, admin)
+	gs.AddMember(context.Background(), group, user)
+
+	first, _ := gs.CreateTransaction(context.Background(), group, true, false, nil, user, "Pocket money", "", 100)
+	database.Model(first).Update("created", 1000)
+	second, _ := gs.CreateTransaction(context.Background(), group, false, true, user, nil, "Snacks", "", 30)
+	database.Model(second).Update("created", 2000)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name        string
+		userId      string
+		query       string
+		wantCode    int
+		wantBalance int
+	}{
+		{name: "Before first transaction", userId: user.Id, query: "at=999", wantCode: http.StatusOK, wantBalance: 0},
+		{name: "At first transaction", userId: user.Id, query: "at=1000", wantCode: http.StatusOK, wantBalance: 100},
+		{name: "Between transactions", userId: user.Id, query: "at=1999", wantCode: http.StatusOK, wantBalance: 100},
+		{name: "After last transaction", userId: user.Id, query: "at=5000", wantCode: http.StatusOK, wantBalance: 70},
+		{name: "Admin querying member", userId: admin.Id, query: "at=5000&userId=" + user.Id, wantCode: http.StatusOK, wantBalance: 70},
+		{name: "Member querying other member", userId: user.Id, query: "at=5000&userId=" + admin.Id, wantCode: http.StatusForbidden},
+		{name: "Missing time", userId: user.Id, query: "", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.GetBalanceAt(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode == http.StatusOK {
+				var resp responses.Balance
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, tt.wantBalance, resp.Balance)
+			}
+		})
+	}
+}
+
+func TestHandler_ImportTransactions(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+	user := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user)
+	outsider := &models.User{Name: "eve", Email: "eve@gmail.com"}
+	us.Create(context.Background(), outsider)
+
+	// The imported balances exceed the limits of the group, which don't apply to imports.
+	group := &models.Group{Name: "group"}
+	group.MinBalance = 0
+	group.MaxBalance = 50
+	group.OverdraftProtection = true
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, admin)
+	gs.AddAdmin(context.Background(), group, admin)
+	gs.AddMember(context.Background(), group, user)
+
+	handler := New(us, gs, nil)
+
+	validCSV := "date,sender,receiver,amount,title\n" +
+		"2020-01-02,bob@gmail.com,bank,30,Snacks\n" +
+		"2020-01-01,bank,bob@gmail.com,100,Pocket money\n" +
+		"2020-01-02,admin@gmail.com,bob@gmail.com,5,Gift\n"
+
+	tests := []struct {
+		name          string
+		userId        string
+		query         string
+		csv           string
+		wantCode      int
+		wantSuccess   bool
+		wantErrorRows []int
+		wantCount     int
+	}{
+		{name: "Not an admin", userId: user.Id, csv: validCSV, wantCode: http.StatusForbidden},
+		{name: "Row errors", userId: admin.Id, csv: "2020-01-01,bank,eve@gmail.com,100,Pocket money\n2020-13-01,bank,bob@gmail.com,100,Pocket money\n2020-01-01,bank,bob@gmail.com,0,Pocket money\n2020-01-01,bank,bob@gmail.com,100,Pocket money\n2020-01-01,bank,bank,100,Pocket money\n" + time.Now().AddDate(0, 0, 2).Format("2006-01-02") + ",bank,bob@gmail.com,100,Pocket money\n", wantCode: http.StatusOK, wantErrorRows: []int{1, 2, 3, 5, 6}},
+		{name: "Dry run", userId: admin.Id, query: "dryRun=true", csv: validCSV, wantCode: http.StatusOK, wantSuccess: true, wantCount: 0},
+		{name: "Import", userId: admin.Id, csv: validCSV, wantCode: http.StatusOK, wantSuccess: true, wantCount: 3},
+		{name: "Older than existing transactions", userId: admin.Id, csv: validCSV, wantCode: http.StatusOK, wantCount: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := new(bytes.Buffer)
+			writer := multipart.NewWriter(body)
+			part, _ := writer.CreateFormFile("file", "transactions.csv")
+			part.Write([]byte(tt.csv))
+			writer.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/?"+tt.query, body)
+			req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.ImportTransactions(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Success bool                    `json:"success"`
+				Errors  []responses.ImportError `json:"errors"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.Equal(t, tt.wantSuccess, resp.Success)
+
+			rows := make([]int, 0, len(resp.Errors))
+			for _, e := range resp.Errors {
+				rows = append(rows, e.Row)
+			}
+			if tt.wantErrorRows != nil {
+				assert.Equal(t, tt.wantErrorRows, rows)
+			}
+
+			transactions, _ := gs.GetTransactionsInPeriod(context.Background(), group, 0, math.MaxInt64)
+			assert.Len(t, transactions, tt.wantCount)
+		})
+	}
+
+	balance, _ := gs.GetUserBalance(context.Background(), group, user)
+	assert.Equal(t, 75, balance)
+	balance, _ = gs.GetUserBalance(context.Background(), group, admin)
+	assert.Equal(t, -5, balance)
+}
+
+func TestHandler_CreateTransaction_NotEnoughMoney(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user1 := &models.User{Name: "bob", Email: "bob@notenough.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "alice", Email: "alice@notenough.com"}
+	us.Create(context.Background(), user2)
+
+	group := &models.Group{Name: "group"}
+	group.OverdraftProtection = true
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+
+	gs.CreateTransaction(context.Background(), group, true, false, nil, user1, "Pocket money", "", 100)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name       string
+		receiverId string
+	}{
+		{name: "Member", receiverId: user2.Id},
+		{name: "Bank", receiverId: "bank"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(bindings.CreateTransaction{
+				Title:      "Gift",
+				Amount:     1000,
+				ReceiverId: tt.receiverId,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user1.Id)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.CreateTransaction(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"message":"Not enough money"`)
+
+			balance, err := gs.GetUserBalance(context.Background(), group, user1)
+			assert.NoError(t, err)
+			assert.Equal(t, 100, balance)
+		})
+	}
+
+	// Without overdraft protection the minimum balance isn't enforced.
+	group.OverdraftProtection = false
+	gs.UpdateSettings(context.Background(), group)
+
+	body, _ := json.Marshal(bindings.CreateTransaction{
+		Title:      "Gift",
+		Amount:     1000,
+		ReceiverId: user2.Id,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := r.NewContext(req, rec)
+	c.Set("lang", "en")
+	c.Set("userId", user1.Id)
+	c.SetParamNames("id")
+	c.SetParamValues(group.Id)
+
+	err = handler.CreateTransaction(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	balance, err := gs.GetUserBalance(context.Background(), group, user1)
+	assert.NoError(t, err)
+	assert.Equal(t, -900, balance)
+}
+
+func TestHandler_CreateTransaction_DryRun(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user1 := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), user2)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+
+	gs.CreateTransaction(context.Background(), group, true, false, nil, user1, "Pocket money", "", 100)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name                   string
+		query                  string
+		wantTransactions       int
+		wantNewBalanceSender   int
+		wantNewBalanceReceiver int
+	}{
+		{name: "Dry run", query: "dryRun=true", wantTransactions: 1, wantNewBalanceSender: 70, wantNewBalanceReceiver: 30},
+		{name: "Create", query: "", wantTransactions: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(bindings.CreateTransaction{
+				Title:      "Gift",
+				Amount:     30,
+				ReceiverId: user2.Id,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/?"+tt.query, bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user1.Id)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.CreateTransaction(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var resp struct {
+				Success            bool `json:"success"`
+				DryRun             bool `json:"dryRun"`
+				NewBalanceSender   int  `json:"newBalanceSender"`
+				NewBalanceReceiver int  `json:"newBalanceReceiver"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.True(t, resp.Success)
+			assert.Equal(t, tt.query != "", resp.DryRun)
+			if resp.DryRun {
+				assert.Equal(t, tt.wantNewBalanceSender, resp.NewBalanceSender)
+				assert.Equal(t, tt.wantNewBalanceReceiver, resp.NewBalanceReceiver)
+			}
+
+			transactions, _ := gs.GetTransactionsInPeriod(context.Background(), group, 0, math.MaxInt64)
+			assert.Len(t, transactions, tt.wantTransactions)
+		})
+	}
+}
+
+func TestHandler_Transfer(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user1 := &models.User{Name: "bob", Email: "bob@transfer.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "alice", Email: "alice@transfer.com"}
+	us.Create(context.Background(), user2)
+	user3 := &models.User{Name: "eve", Email: "eve@transfer.com"}
+	us.Create(context.Background(), user3)
+
+	group := &models.Group{Name: "group"}
+	group.OverdraftProtection = true
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+
+	gs.CreateTransaction(context.Background(), group, true, false, nil, user1, "Pocket money", "", 100)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name        string
+		receiverId  string
+		amount      int
+		wantCode    int
+		wantSuccess bool
+		wantMessage string
+	}{
+		{name: "Success", receiverId: user2.Id, amount: 30, wantCode: http.StatusOK, wantSuccess: true},
+		{name: "Self", receiverId: user1.Id, amount: 30, wantCode: http.StatusOK, wantSuccess: false, wantMessage: "Sender is the receiver"},
+		{name: "Zero amount", receiverId: user2.Id, amount: 0, wantCode: http.StatusOK, wantSuccess: false, wantMessage: "Amount must be >0"},
+		{name: "Negative amount", receiverId: user2.Id, amount: -10, wantCode: http.StatusOK, wantSuccess: false, wantMessage: "Amount must be >0"},
+		{name: "Receiver not a member", receiverId: user3.Id, amount: 30, wantCode: http.StatusForbidden, wantSuccess: false, wantMessage: "Receiver not a member of the group"},
+		{name: "Unknown receiver", receiverId: "unknown", amount: 30, wantCode: http.StatusNotFound, wantSuccess: false, wantMessage: "Couldn't find receiver"},
+		{name: "Not enough money", receiverId: user2.Id, amount: 1000, wantCode: http.StatusBadRequest, wantSuccess: false, wantMessage: "Not enough money"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(bindings.Transfer{
+				Title:      "Gift",
+				Amount:     tt.amount,
+				ReceiverId: tt.receiverId,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user1.Id)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.Transfer(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			var resp struct {
+				Success bool   `json:"success"`
+				Message string `json:"message"`
+				Id      string `json:"id"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.Equal(t, tt.wantSuccess, resp.Success)
+			if tt.wantSuccess {
+				transaction, err := gs.GetTransactionLogEntryById(context.Background(), group, resp.Id)
+				if assert.NoError(t, err) && assert.NotNil(t, transaction) {
+					assert.Equal(t, user1.Id, transaction.SenderId)
+					assert.Equal(t, user2.Id, transaction.ReceiverId)
+					assert.Equal(t, tt.amount, transaction.Amount)
+				}
+			} else {
+				assert.Equal(t, tt.wantMessage, resp.Message)
+			}
+		})
+	}
+}
+
+func TestHandler_CreateCashTransaction(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	admin := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), admin)
+	member := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), member)
+
+	group := &models.Group{Name: "group"}
+	group.OverdraftProtection = true
+	gs.Create(context.Background(), group)
+	gs.AddAdmin(context.Background(), group, admin)
+	gs.AddMember(context.Background(), group, admin)
+	gs.AddMember(context.Background(), group, member)
+
+	gs.CreateTransaction(context.Background(), group, true, false, nil, admin, "Pocket money", "", 1000)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name             string
+		userId           string
+		body             bindings.CreateCashTransaction
+		wantCode         int
+		wantSuccess      bool
+		wantBalance      int
+		wantCash         int
+		wantTransactions int
+	}{
+		{name: "Withdrawal", userId: admin.Id, body: bindings.CreateCashTransaction{AddCashLogEntry: bindings.AddCashLogEntry{Title: "ATM", Eur5: 1}}, wantCode: http.StatusOK, wantSuccess: true, wantBalance: 500, wantCash: 500, wantTransactions: 2},
+		{name: "Not enough money", userId: admin.Id, body: bindings.CreateCashTransaction{AddCashLogEntry: bindings.AddCashLogEntry{Title: "ATM", Eur10: 1}}, wantCode: http.StatusBadRequest, wantBalance: 500, wantCash: 500, wantTransactions: 2},
+		{name: "Not enough cash", userId: admin.Id, body: bindings.CreateCashTransaction{AddCashLogEntry: bindings.AddCashLogEntry{Title: "Deposit", Eur10: 1}, Deposit: true}, wantCode: http.StatusOK, wantBalance: 500, wantCash: 500, wantTransactions: 2},
+		{name: "Deposit", userId: admin.Id, body: bindings.CreateCashTransaction{AddCashLogEntry: bindings.AddCashLogEntry{Title: "Deposit", Eur5: 1}, Deposit: true}, wantCode: http.StatusOK, wantSuccess: true, wantBalance: 1000, wantCash: 0, wantTransactions: 3},
+		{name: "Deposit by member", userId: member.Id, body: bindings.CreateCashTransaction{AddCashLogEntry: bindings.AddCashLogEntry{Title: "Deposit", Eur5: 1}, Deposit: true}, wantCode: http.StatusForbidden, wantBalance: 1000, wantCash: 0, wantTransactions: 3},
+		{name: "No cash", userId: admin.Id, body: bindings.CreateCashTransaction{AddCashLogEntry: bindings.AddCashLogEntry{Title: "ATM"}}, wantCode: http.StatusOK, wantBalance: 1000, wantCash: 0, wantTransactions: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.CreateCashTransaction(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			var resp struct {
+				Success      bool `json:"success"`
+				CashLogEntry struct {
+					Amount int `json:"amount"`
+				} `json:"cashLogEntry"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.Equal(t, tt.wantSuccess, resp.Success)
+			if tt.wantSuccess {
+				assert.Equal(t, tt.wantCash, resp.CashLogEntry.Amount)
+			}
+
+			balance, err := gs.GetUserBalance(context.Background(), group, admin)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBalance, balance)
+
+			cash, err := us.GetLastCashLogEntry(context.Background(), admin)
+			assert.NoError(t, err)
+			if assert.NotNil(t, cash) {
+				assert.Equal(t, tt.wantCash, cash.TotalAmount)
+			}
+
+			transactions, _ := gs.GetTransactionsInPeriod(context.Background(), group, 0, math.MaxInt64)
+			assert.Len(t, transactions, tt.wantTransactions)
+		})
+	}
+}
+
+func TestHandler_ConfirmGroupDeletion(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	admin1 := &models.User{Name: "admin1", Email: "admin1@gmail.com"}
+	us.Create(context.Background(), admin1)
+	admin2 := &models.User{Name: "admin2", Email: "admin2@gmail.com"}
+	us.Create(context.Background(), admin2)
+	member := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), member)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddAdmin(context.Background(), group, admin1)
+	gs.AddAdmin(context.Background(), group, admin2)
+	gs.AddMember(context.Background(), group, member)
+
+	handler := New(us, gs, nil)
+
+	call := func(h echo.HandlerFunc, userId string) responses.Base {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		rec := httptest.NewRecorder()
+		c := r.NewContext(req, rec)
+		c.Set("lang", "en")
+		c.Set("userId", userId)
+		c.SetParamNames("id")
+		c.SetParamValues(group.Id)
+
+		err := h(c)
+		assert.NoError(t, err)
+
+		var resp responses.Base
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+
+	assert.False(t, call(handler.ConfirmGroupDeletion, admin2.Id).Success, "confirm without request")
+	assert.False(t, call(handler.RequestGroupDeletion, member.Id).Success, "request by member")
+	assert.True(t, call(handler.RequestGroupDeletion, admin1.Id).Success, "request")
+	assert.False(t, call(handler.RequestGroupDeletion, admin2.Id).Success, "second request")
+	assert.False(t, call(handler.ConfirmGroupDeletion, admin1.Id).Success, "confirm own request")
+
+	g, _ := gs.GetById(context.Background(), group.Id)
+	assert.NotNil(t, g)
+
+	assert.True(t, call(handler.CancelGroupDeletion, admin2.Id).Success, "cancel")
+	assert.False(t, call(handler.ConfirmGroupDeletion, admin2.Id).Success, "confirm cancelled request")
+
+	g, _ = gs.GetById(context.Background(), group.Id)
+	g.DeletionRequestedBy = admin1.Id
+	g.DeletionExpires = time.Now().Unix() - 1
+	gs.UpdateDeletionRequest(context.Background(), g)
+	assert.False(t, call(handler.ConfirmGroupDeletion, admin2.Id).Success, "confirm expired request")
+
+	assert.True(t, call(handler.RequestGroupDeletion, admin1.Id).Success, "request again")
+	assert.True(t, call(handler.ConfirmGroupDeletion, admin2.Id).Success, "confirm")
+
+	g, _ = gs.GetById(context.Background(), group.Id)
+	assert.Nil(t, g)
+}
+
+func TestHandler_GetUpcomingPayments(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user1 := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), user2)
+	user3 := &models.User{Name: "peter", Email: "peter@gmail.com"}
+	us.Create(context.Background(), user3)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+	gs.AddMember(context.Background(), group, user3)
+
+	rent, _ := gs.CreatePaymentPlan(context.Background(), group, false, false, user1, user2, "Rent", "", 500, -1, 1, models.ScheduleUnitMonth, 3000, "")
+	pocketMoney, _ := gs.CreatePaymentPlan(context.Background(), group, true, false, nil, user1, "Pocket money", "", 100, -1, 1, models.ScheduleUnitWeek, 1000, "")
+	fee, _ := gs.CreatePaymentPlan(context.Background(), group, false, true, user1, nil, "Fee", "", 5, 3, 1, models.ScheduleUnitMonth, 2000, "")
+	gs.CreatePaymentPlan(context.Background(), group, false, false, user2, user3, "Other", "", 10, -1, 1, models.ScheduleUnitDay, 500, "")
+
+	handler := New(us, gs, nil)
+
+	type payment struct {
+		PaymentPlanId  string `json:"paymentPlanId"`
+		Direction      string `json:"direction"`
+		CounterpartyId string `json:"counterpartyId"`
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		want     []payment
+	}{
+		{name: "All", wantCode: http.StatusOK, want: []payment{
+			{PaymentPlanId: pocketMoney.Id, Direction: "incoming", CounterpartyId: "bank"},
+			{PaymentPlanId: fee.Id, Direction: "outgoing", CounterpartyId: "bank"},
+			{PaymentPlanId: rent.Id, Direction: "outgoing", CounterpartyId: user2.Id},
+		}},
+		{name: "Until", query: "until=2000", wantCode: http.StatusOK, want: []payment{
+			{PaymentPlanId: pocketMoney.Id, Direction: "incoming", CounterpartyId: "bank"},
+			{PaymentPlanId: fee.Id, Direction: "outgoing", CounterpartyId: "bank"},
+		}},
+		{name: "Count", query: "count=1", wantCode: http.StatusOK, want: []payment{
+			{PaymentPlanId: pocketMoney.Id, Direction: "incoming", CounterpartyId: "bank"},
+		}},
+		{name: "Invalid until", query: "until=abc", wantCode: http.StatusBadRequest},
+		{name: "Invalid count", query: "count=0", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user1.Id)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.GetUpcomingPayments(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode == http.StatusOK {
+				var resp struct {
+					Payments []payment `json:"payments"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, tt.want, resp.Payments)
+			}
+		})
+	}
+}
+
+func TestHandler_GetPaymentPlans_Filter(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user1 := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), user2)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+
+	rent, _ := gs.CreatePaymentPlan(context.Background(), group, false, false, user1, user2, "Rent", "", 500, -1, 1, models.ScheduleUnitMonth, 3000, "")
+	pocketMoney, _ := gs.CreatePaymentPlan(context.Background(), group, true, false, nil, user1, "Pocket money", "", 100, -1, 1, models.ScheduleUnitWeek, 1000, "")
+	fee, _ := gs.CreatePaymentPlan(context.Background(), group, false, true, user1, nil, "Fee", "", 5, 3, 1, models.ScheduleUnitMonth, 2000, "")
+	refund, _ := gs.CreatePaymentPlan(context.Background(), group, false, false, user2, user1, "Refund", "", 10, -1, 1, models.ScheduleUnitDay, 500, "")
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantIds   []string
+		wantCount int64
+	}{
+		{name: "All", wantCode: http.StatusOK, wantIds: []string{refund.Id, pocketMoney.Id, fee.Id, rent.Id}, wantCount: 4},
+		{name: "Sending", query: "direction=sending", wantCode: http.StatusOK, wantIds: []string{fee.Id, rent.Id}, wantCount: 2},
+		{name: "Receiving", query: "direction=receiving", wantCode: http.StatusOK, wantIds: []string{refund.Id, pocketMoney.Id}, wantCount: 2},
+		{name: "Counterparty", query: "counterparty=" + user2.Id, wantCode: http.StatusOK, wantIds: []string{refund.Id, rent.Id}, wantCount: 2},
+		{name: "Bank", query: "counterparty=bank", wantCode: http.StatusOK, wantIds: []string{pocketMoney.Id, fee.Id}, wantCount: 2},
+		{name: "Sending to bank", query: "direction=sending&counterparty=bank", wantCode: http.StatusOK, wantIds: []string{fee.Id}, wantCount: 1},
+		{name: "Search", query: "direction=receiving&search=Ref", wantCode: http.StatusOK, wantIds: []string{refund.Id}, wantCount: 2},
+		{name: "Invalid direction", query: "direction=sideways", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user1.Id)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.GetPaymentPlans(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode == http.StatusOK {
+				var resp struct {
+					Count        int64 `json:"count"`
+					PaymentPlans []struct {
+						Id string `json:"id"`
+					} `json:"paymentPlans"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				ids := make([]string, 0, len(resp.PaymentPlans))
+				for _, p := range resp.PaymentPlans {
+					ids = append(ids, p.Id)
+				}
+				assert.Equal(t, tt.wantIds, ids)
+				assert.Equal(t, tt.wantCount, resp.Count)
+			}
+		})
+	}
+}
+
+func TestHandler_GetTimeSeries(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+	user1 := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), user2)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddAdmin(context.Background(), group, admin)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+
+	date := func(month time.Month, day int) int64 {
+		return time.Date(2024, month, day, 12, 0, 0, 0, time.UTC).Unix()
+	}
+	transfer := func(senderIsBank, receiverIsBank bool, sender, receiver *models.User, amount int, created int64) {
+		transaction, _ := gs.CreateTransaction(context.Background(), group, senderIsBank, receiverIsBank, sender, receiver, "Transfer", "", amount)
+		database.Model(transaction).Update("created", created)
+	}
+	transfer(true, false, nil, user1, 100, date(time.February, 20))
+	transfer(true, false, nil, user2, 50, date(time.March, 4))
+	transfer(false, false, user1, user2, 30, date(time.March, 5))
+	transfer(false, true, user2, nil, 20, date(time.March, 13))
+
+	handler := New(us, gs, nil)
+
+	day := func(month time.Month, day int) int64 {
+		return time.Date(2024, month, day, 0, 0, 0, 0, time.UTC).Unix()
+	}
+
+	tests := []struct {
+		name       string
+		userId     string
+		query      string
+		wantCode   int
+		wantPoints []responses.TimeSeriesPoint
+	}{
+		{name: "Weeks", userId: admin.Id, query: fmt.Sprintf("interval=week&from=%d&to=%d", day(time.March, 1), day(time.March, 20)), wantCode: http.StatusOK, wantPoints: []responses.TimeSeriesPoint{
+			{Time: day(time.February, 26), Balance: 100},
+			{Time: day(time.March, 4), Balance: 150},
+			{Time: day(time.March, 11), Balance: 130},
+			{Time: day(time.March, 18), Balance: 130},
+		}},
+		{name: "Days", userId: admin.Id, query: fmt.Sprintf("interval=day&from=%d&to=%d", day(time.March, 3), day(time.March, 5)), wantCode: http.StatusOK, wantPoints: []responses.TimeSeriesPoint{
+			{Time: day(time.March, 3), Balance: 100},
+			{Time: day(time.March, 4), Balance: 150},
+			{Time: day(time.March, 5), Balance: 150},
+		}},
+		{name: "Months", userId: admin.Id, query: fmt.Sprintf("interval=month&from=%d&to=%d", day(time.January, 15), day(time.March, 1)), wantCode: http.StatusOK, wantPoints: []responses.TimeSeriesPoint{
+			{Time: day(time.January, 1), Balance: 0},
+			{Time: day(time.February, 1), Balance: 100},
+			{Time: day(time.March, 1), Balance: 130},
+		}},
+		{name: "Not an admin", userId: user1.Id, query: fmt.Sprintf("from=%d", day(time.March, 1)), wantCode: http.StatusForbidden},
+		{name: "Missing from", userId: admin.Id, query: "", wantCode: http.StatusBadRequest},
+		{name: "Invalid interval", userId: admin.Id, query: fmt.Sprintf("interval=year&from=%d", day(time.March, 1)), wantCode: http.StatusBadRequest},
+		{name: "To before from", userId: admin.Id, query: fmt.Sprintf("from=%d&to=%d", day(time.March, 2), day(time.March, 1)), wantCode: http.StatusBadRequest},
+		{name: "Too many intervals", userId: admin.Id, query: "interval=day&from=0", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.GetTimeSeries(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode == http.StatusOK {
+				var resp struct {
+					Points []responses.TimeSeriesPoint `json:"points"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, tt.wantPoints, resp.Points)
+			}
+		})
+	}
+}
+
+func TestHandler_GetTransactionsBetween(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+	user1 := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), user2)
+	user3 := &models.User{Name: "peter", Email: "peter@gmail.com"}
+	us.Create(context.Background(), user3)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddAdmin(context.Background(), group, admin)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+	gs.AddMember(context.Background(), group, user3)
+
+	gs.CreateTransaction(context.Background(), group, true, false, nil, user1, "Pocket money", "", 100)
+	gs.CreateTransaction(context.Background(), group, false, false, user1, user2, "Gift", "", 50)
+	gs.CreateTransaction(context.Background(), group, false, false, user2, user1, "Refund", "", 20)
+	gs.CreateTransaction(context.Background(), group, false, false, user1, user3, "Other", "", 10)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name        string
+		userId      string
+		query       string
+		wantCode    int
+		wantCount   int
+		wantNetFlow int
+	}{
+		{name: "Involved member", userId: user1.Id, query: "userA=" + user1.Id + "&userB=" + user2.Id, wantCode: http.StatusOK, wantCount: 2, wantNetFlow: 30},
+		{name: "Reversed", userId: user2.Id, query: "userA=" + user2.Id + "&userB=" + user1.Id, wantCode: http.StatusOK, wantCount: 2, wantNetFlow: -30},
+		{name: "Admin", userId: admin.Id, query: "userA=" + user1.Id + "&userB=" + user3.Id, wantCode: http.StatusOK, wantCount: 1, wantNetFlow: 10},
+		{name: "Uninvolved member", userId: user3.Id, query: "userA=" + user1.Id + "&userB=" + user2.Id, wantCode: http.StatusForbidden},
+		{name: "Missing user", userId: user1.Id, query: "userA=" + user1.Id, wantCode: http.StatusBadRequest},
+		{name: "Same user", userId: user1.Id, query: "userA=" + user1.Id + "&userB=" + user1.Id, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.GetTransactionsBetween(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode == http.StatusOK {
+				var resp struct {
+					NetFlow      int `json:"netFlow"`
+					Transactions []struct {
+						Id string `json:"id"`
+					} `json:"transactions"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Len(t, resp.Transactions, tt.wantCount)
+				assert.Equal(t, tt.wantNetFlow, resp.NetFlow)
+			}
+		})
+	}
+}
+
+func TestHandler_GetTransactionByReference(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	alice := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), alice)
+	carol := &models.User{Name: "carol", Email: "carol@gmail.com"}
+	us.Create(context.Background(), carol)
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+	gs.AddMember(context.Background(), group, alice)
+	gs.AddMember(context.Background(), group, carol)
+	gs.AddAdmin(context.Background(), group, admin)
+
+	deposit, _ := gs.CreateTransaction(context.Background(), group, true, false, nil, bob, "Pocket money", "", 500)
+	gift, _ := gs.CreateTransaction(context.Background(), group, false, false, bob, alice, "Gift", "", 200)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		tName       string
+		userId      string
+		reference   string
+		wantCode    int
+		wantSuccess bool
+		wantId      string
+	}{
+		{tName: "Receiver", userId: alice.Id, reference: gift.Reference, wantCode: http.StatusOK, wantSuccess: true, wantId: gift.Id},
+		{tName: "Lower case", userId: bob.Id, reference: strings.ToLower(gift.Reference), wantCode: http.StatusOK, wantSuccess: true, wantId: gift.Id},
+		{tName: "Admin bank transaction", userId: admin.Id, reference: deposit.Reference, wantCode: http.StatusOK, wantSuccess: true, wantId: deposit.Id},
+		{tName: "Not a participant", userId: carol.Id, reference: gift.Reference, wantCode: http.StatusForbidden, wantSuccess: false},
+		{tName: "Admin without bank", userId: admin.Id, reference: gift.Reference, wantCode: http.StatusForbidden, wantSuccess: false},
+		{tName: "Unknown reference", userId: bob.Id, reference: models.TransactionReference(group.Id, 99), wantCode: http.StatusNotFound, wantSuccess: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id", "ref")
+			c.SetParamValues(group.Id, tt.reference)
+
+			err := handler.GetTransactionByReference(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"success":%t`, tt.wantSuccess))
+
+			if tt.wantSuccess {
+				assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"id":"%s"`, tt.wantId))
+			}
+		})
+	}
+}
+
+// Not parallel because the limit is changed in the global config.
+func TestHandler_CreatePaymentPlan_Limit(t *testing.T) {
+	maxPaymentPlans := config.Data.MaxPaymentPlansPerGroup
+	t.Cleanup(func() {
+		config.Data.MaxPaymentPlansPerGroup = maxPaymentPlans
+	})
+	config.Data.MaxPaymentPlansPerGroup = 5
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user)
+
+	for i := 0; i < config.Data.MaxPaymentPlansPerGroup-1; i++ {
+		gs.CreatePaymentPlan(context.Background(), group, false, true, user, nil, "Dues", "", 500, -1, 1, models.ScheduleUnitMonth, time.Now().AddDate(0, 0, 2).Unix(), "")
+	}
+
+	handler := New(us, gs, nil)
+
+	firstPayment := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+
+	tests := []struct {
+		name     string
+		wantCode int
+	}{
+		{name: "Last payment plan", wantCode: http.StatusOK},
+		{name: "Limit reached", wantCode: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"name":         "Dues",
+				"amount":       500,
+				"receiverId":   "bank",
+				"schedule":     1,
+				"scheduleUnit": "month",
+				"firstPayment": firstPayment,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user.Id)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.CreatePaymentPlan(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+
+	count, err := gs.ActivePaymentPlanCount(context.Background(), group)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(config.Data.MaxPaymentPlansPerGroup), count)
+}
+
+func TestHandler_CreatePaymentPlan_TimeZone(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user)
+
+	handler := New(us, gs, nil)
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	date := time.Now().AddDate(0, 0, 2)
+	firstPayment := date.Format("2006-01-02")
+
+	tests := []struct {
+		name        string
+		timeZone    string
+		wantCode    int
+		wantExecute int64
+	}{
+		{name: "UTC", timeZone: "", wantCode: http.StatusOK, wantExecute: time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC).Unix()},
+		{name: "Local midnight", timeZone: "Europe/Berlin", wantCode: http.StatusOK, wantExecute: time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, berlin).Unix()},
+		{name: "Invalid time zone", timeZone: "Mars/Olympus", wantCode: http.StatusBadRequest},
+		{name: "Server time zone", timeZone: "Local", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"name":         "Dues",
+				"amount":       500,
+				"receiverId":   "bank",
+				"schedule":     1,
+				"scheduleUnit": "month",
+				"firstPayment": firstPayment,
+				"timeZone":     tt.timeZone,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user.Id)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.CreatePaymentPlan(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			if tt.wantCode == http.StatusOK {
+				var resp struct {
+					NextExecute int64  `json:"nextExecute"`
+					TimeZone    string `json:"timeZone"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, tt.wantExecute, resp.NextExecute)
+				assert.Equal(t, tt.timeZone, resp.TimeZone)
+			}
+		})
+	}
+}
+
+func TestHandler_DuplicatePaymentPlan(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	alice := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), alice)
+	carol := &models.User{Name: "carol", Email: "carol@gmail.com"}
+	us.Create(context.Background(), carol)
+	outsider := &models.User{Name: "eve", Email: "eve@gmail.com"}
+	us.Create(context.Background(), outsider)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddAdmin(context.Background(), group, admin)
+	gs.AddMember(context.Background(), group, bob)
+	gs.AddMember(context.Background(), group, alice)
+	gs.AddMember(context.Background(), group, carol)
+
+	nextExecute := time.Now().UTC().AddDate(0, 0, 3).Truncate(24 * time.Hour).Unix()
+	gift, _ := gs.CreatePaymentPlan(context.Background(), group, false, false, bob, alice, "Gift", "", 100, 5, 1, models.ScheduleUnitWeek, nextExecute, "")
+	gs.CreateTransaction(context.Background(), group, true, false, nil, bob, "Pocket money", "", 1000)
+	gs.CreateTransactionFromPaymentPlan(context.Background(), group, false, false, bob, alice, "Gift", "", 100, gift.Id)
+	gs.CreateTransactionFromPaymentPlan(context.Background(), group, false, false, bob, alice, "Gift", "", 100, gift.Id)
+	gift.PaymentCount = 3
+	gs.UpdatePaymentPlan(context.Background(), gift, "payment_count")
+
+	dues, _ := gs.CreatePaymentPlan(context.Background(), group, false, true, bob, nil, "Dues", "", 500, -1, 1, models.ScheduleUnitMonth, nextExecute, "")
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name             string
+		userId           string
+		paymentPlanId    string
+		body             map[string]string
+		wantCode         int
+		wantSuccess      bool
+		wantSenderId     string
+		wantReceiverId   string
+		wantPaymentCount int
+		wantNextExecute  int64
+	}{
+		{name: "Sender", userId: bob.Id, paymentPlanId: gift.Id, body: map[string]string{"counterpartyId": carol.Id}, wantCode: http.StatusOK, wantSuccess: true, wantSenderId: bob.Id, wantReceiverId: carol.Id, wantPaymentCount: 5, wantNextExecute: nextExecute},
+		{name: "Keep counterparty", userId: bob.Id, paymentPlanId: gift.Id, body: map[string]string{"firstPayment": time.Unix(nextExecute, 0).UTC().AddDate(0, 0, 7).Format("2006-01-02")}, wantCode: http.StatusOK, wantSuccess: true, wantSenderId: bob.Id, wantReceiverId: alice.Id, wantPaymentCount: 5, wantNextExecute: time.Unix(nextExecute, 0).UTC().AddDate(0, 0, 7).Unix()},
+		{name: "Admin bank payment plan", userId: admin.Id, paymentPlanId: dues.Id, body: map[string]string{"counterpartyId": alice.Id}, wantCode: http.StatusOK, wantSuccess: true, wantSenderId: alice.Id, wantReceiverId: "bank", wantPaymentCount: -1, wantNextExecute: nextExecute},
+		{name: "Receiver", userId: alice.Id, paymentPlanId: gift.Id, body: map[string]string{}, wantCode: http.StatusForbidden, wantSuccess: false},
+		{name: "Admin without bank", userId: admin.Id, paymentPlanId: gift.Id, body: map[string]string{}, wantCode: http.StatusForbidden, wantSuccess: false},
+		{name: "Sender is receiver", userId: bob.Id, paymentPlanId: gift.Id, body: map[string]string{"counterpartyId": bob.Id}, wantCode: http.StatusOK, wantSuccess: false},
+		{name: "Bank to bank", userId: admin.Id, paymentPlanId: dues.Id, body: map[string]string{"counterpartyId": "bank"}, wantCode: http.StatusOK, wantSuccess: false},
+		{name: "Counterparty not a member", userId: bob.Id, paymentPlanId: gift.Id, body: map[string]string{"counterpartyId": outsider.Id}, wantCode: http.StatusForbidden, wantSuccess: false},
+		{name: "First payment in the past", userId: bob.Id, paymentPlanId: gift.Id, body: map[string]string{"firstPayment": "2000-01-01"}, wantCode: http.StatusBadRequest, wantSuccess: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id", "paymentPlanId")
+			c.SetParamValues(group.Id, tt.paymentPlanId)
+
+			err := handler.DuplicatePaymentPlan(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"success":%t`, tt.wantSuccess))
+
+			if tt.wantSuccess {
+				var resp struct {
+					Id         string `json:"id"`
+					SenderId   string `json:"senderId"`
+					ReceiverId string `json:"receiverId"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NotEqual(t, tt.paymentPlanId, resp.Id)
+				assert.Equal(t, tt.wantSenderId, resp.SenderId)
+				assert.Equal(t, tt.wantReceiverId, resp.ReceiverId)
+
+				duplicate, err := gs.GetPaymentPlanById(context.Background(), group, resp.Id)
+				assert.NoError(t, err)
+				if assert.NotNil(t, duplicate) {
+					assert.Equal(t, tt.wantPaymentCount, duplicate.PaymentCount)
+					assert.Equal(t, tt.wantNextExecute, duplicate.NextExecute)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_GetTransactionLog_Filter(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user1 := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), user2)
+	user3 := &models.User{Name: "carol", Email: "carol@gmail.com"}
+	us.Create(context.Background(), user3)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, user1)
+	gs.AddMember(context.Background(), group, user2)
+	gs.AddMember(context.Background(), group, user3)
+
+	pocketMoney, _ := gs.CreateTransaction(context.Background(), group, true, false, nil, user1, "Pocket money", "", 1000)
+	gift, _ := gs.CreateTransaction(context.Background(), group, false, false, user1, user2, "Gift", "", 100)
+	refund, _ := gs.CreateTransaction(context.Background(), group, false, false, user2, user1, "Refund", "", 10)
+	gs.CreateTransaction(context.Background(), group, false, false, user2, user3, "Lunch", "", 20)
+	dinner, _ := gs.CreateTransaction(context.Background(), group, false, false, user3, user1, "Dinner", "", 30)
+	fee, _ := gs.CreateTransaction(context.Background(), group, false, true, user1, nil, "Fee", "", 5)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantIds   []string
+		wantCount int64
+	}{
+		{name: "All", wantCode: http.StatusOK, wantIds: []string{fee.Id, dinner.Id, refund.Id, gift.Id, pocketMoney.Id}, wantCount: 5},
+		{name: "Counterparty", query: "counterparty=" + user2.Id, wantCode: http.StatusOK, wantIds: []string{refund.Id, gift.Id}, wantCount: 2},
+		{name: "Bank", query: "counterparty=bank", wantCode: http.StatusOK, wantIds: []string{fee.Id, pocketMoney.Id}, wantCount: 2},
+		{name: "Search", query: "counterparty=" + user2.Id + "&search=Ref", wantCode: http.StatusOK, wantIds: []string{refund.Id}, wantCount: 2},
+		{name: "No transactions", query: "counterparty=" + user1.Id, wantCode: http.StatusOK, wantIds: []string{}, wantCount: 0},
+		{name: "Amount range", query: "minAmount=10&maxAmount=100", wantCode: http.StatusOK, wantIds: []string{dinner.Id, refund.Id, gift.Id}, wantCount: 3},
+		{name: "Min amount", query: "minAmount=100", wantCode: http.StatusOK, wantIds: []string{gift.Id, pocketMoney.Id}, wantCount: 2},
+		{name: "Max amount with counterparty", query: "maxAmount=50&counterparty=" + user2.Id, wantCode: http.StatusOK, wantIds: []string{refund.Id}, wantCount: 1},
+		{name: "Negative min amount", query: "minAmount=-1", wantCode: http.StatusBadRequest},
+		{name: "Invalid max amount", query: "maxAmount=abc", wantCode: http.StatusBadRequest},
+		{name: "Min above max", query: "minAmount=100&maxAmount=10", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user1.Id)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.GetTransactionLog(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Count        int64 `json:"count"`
+				Transactions []struct {
+					Id string `json:"id"`
+				} `json:"transactions"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+
+			ids := make([]string, 0, len(resp.Transactions))
+			for _, entry := range resp.Transactions {
+				ids = append(ids, entry.Id)
+			}
+			assert.Equal(t, tt.wantIds, ids)
+			assert.Equal(t, tt.wantCount, resp.Count)
+		})
+	}
+}
+
+func TestHandler_GetGroupMembers_IncludeFormer(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	alice := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), alice)
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+	gs.AddMember(context.Background(), group, alice)
+	gs.AddMember(context.Background(), group, admin)
+	gs.AddAdmin(context.Background(), group, admin)
+	gs.RemoveMember(context.Background(), group, alice)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		tName         string
+		userId        string
+		includeFormer string
+		wantCode      int
+		wantSuccess   bool
+		wantCount     int
+		wantFormer    bool
+	}{
+		{tName: "Without former", userId: admin.Id, includeFormer: "false", wantCode: http.StatusOK, wantSuccess: true, wantCount: 2},
+		{tName: "With former", userId: admin.Id, includeFormer: "true", wantCode: http.StatusOK, wantSuccess: true, wantCount: 3, wantFormer: true},
+		{tName: "Not an admin", userId: bob.Id, includeFormer: "true", wantCode: http.StatusForbidden, wantSuccess: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?includeSelf=true&includeFormer="+tt.includeFormer, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.GetGroupMembers(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"success":%t`, tt.wantSuccess))
+
+			if tt.wantSuccess {
+				assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"count":%d`, tt.wantCount))
+				if tt.wantFormer {
+					assert.Contains(t, rec.Body.String(), fmt.Sprintf(`{"id":"%s","name":"alice","former":true}`, alice.Id))
+					assert.Contains(t, rec.Body.String(), fmt.Sprintf(`{"id":"%s","name":"bob","former":false}`, bob.Id))
+				} else {
+					assert.NotContains(t, rec.Body.String(), alice.Id)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_ReinviteFormerMember(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	alice := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), alice)
+	carol := &models.User{Name: "carol", Email: "carol@gmail.com"}
+	us.Create(context.Background(), carol)
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+	gs.AddMember(context.Background(), group, alice)
+	gs.AddAdmin(context.Background(), group, admin)
+
+	gs.CreateTransaction(context.Background(), group, true, false, nil, bob, "Pocket money", "", 500)
+	gs.RemoveMember(context.Background(), group, bob)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		tName       string
+		userId      string
+		memberId    string
+		wantCode    int
+		wantSuccess bool
+		wantMessage string
+	}{
+		{tName: "Not an admin", userId: alice.Id, memberId: bob.Id, wantCode: http.StatusForbidden, wantSuccess: false},
+		{tName: "Current member", userId: admin.Id, memberId: alice.Id, wantCode: http.StatusOK, wantSuccess: false, wantMessage: "The user is already a member/an admin of the group"},
+		{tName: "Never a member", userId: admin.Id, memberId: carol.Id, wantCode: http.StatusOK, wantSuccess: false, wantMessage: "The user is not a former member of the group"},
+		{tName: "Former member", userId: admin.Id, memberId: bob.Id, wantCode: http.StatusCreated, wantSuccess: true},
+		{tName: "Already invited", userId: admin.Id, memberId: bob.Id, wantCode: http.StatusOK, wantSuccess: false, wantMessage: "The user was already invited"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"message":"Welcome back"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id", "userId")
+			c.SetParamValues(group.Id, tt.memberId)
+
+			err := handler.ReinviteFormerMember(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"success":%t`, tt.wantSuccess))
+			if tt.wantMessage != "" {
+				assert.Contains(t, rec.Body.String(), tt.wantMessage)
+			}
+		})
+	}
+
+	bob.Name = "robert"
+	us.Update(context.Background(), bob)
+
+	invitation, err := gs.GetInvitationByGroupAndUser(context.Background(), group, bob)
+	assert.NoError(t, err)
+	if assert.NotNil(t, invitation) {
+		assert.Equal(t, 0, invitation.OpeningBalance)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := r.NewContext(req, rec)
+	c.Set("lang", "en")
+	c.Set("userId", bob.Id)
+	c.SetParamNames("id")
+	c.SetParamValues(invitation.Id)
+	err = handler.AcceptInvitation(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var memberships []models.GroupMembership
+	database.Find(&memberships, "group_id = ? AND user_id = ?", group.Id, bob.Id)
+	if assert.Len(t, memberships, 1) {
+		assert.True(t, memberships[0].IsMember)
+		assert.Equal(t, "robert", memberships[0].UserName)
+	}
+
+	balance, err := gs.GetUserBalance(context.Background(), group, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, 500, balance)
+}
+
+func TestHandler_ExportGroupMembers(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	alice := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), alice)
+	alice.PubliclyVisible = false
+	us.Update(context.Background(), alice)
+	mallory := &models.User{Name: "=1+1", Email: "mallory@gmail.com"}
+	us.Create(context.Background(), mallory)
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+	gs.AddMember(context.Background(), group, alice)
+	gs.AddMember(context.Background(), group, mallory)
+	gs.AddAdmin(context.Background(), group, admin)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		tName      string
+		userId     string
+		format     string
+		wantCode   int
+		wantBody   string
+		wantHeader string
+	}{
+		{tName: "CSV", userId: admin.Id, format: "csv", wantCode: http.StatusOK, wantBody: "name,email\n'=1+1,mallory@gmail.com\nalice,\nbob,bob@gmail.com\n", wantHeader: "text/csv; charset=UTF-8"},
+		{tName: "vCard", userId: admin.Id, format: "vcard", wantCode: http.StatusOK, wantBody: "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:=1+1\r\nEMAIL:mallory@gmail.com\r\nEND:VCARD\r\nBEGIN:VCARD\r\nVERSION:4.0\r\nFN:alice\r\nEND:VCARD\r\nBEGIN:VCARD\r\nVERSION:4.0\r\nFN:bob\r\nEMAIL:bob@gmail.com\r\nEND:VCARD\r\n", wantHeader: "text/vcard; charset=UTF-8"},
+		{tName: "Unsupported format", userId: admin.Id, format: "xml", wantCode: http.StatusBadRequest},
+		{tName: "Not an admin", userId: bob.Id, format: "csv", wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?format="+tt.format, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.ExportGroupMembers(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode == http.StatusOK {
+				assert.Equal(t, tt.wantBody, rec.Body.String())
+				assert.Equal(t, tt.wantHeader, rec.Header().Get(echo.HeaderContentType))
+			} else {
+				assert.Contains(t, rec.Body.String(), `"success":false`)
+			}
+		})
+	}
+}
+
+func TestHandler_GetGroupPicture_Base64(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+	gs.UpdateGroupPicture(context.Background(), group, &models.GroupPicture{Tiny: []byte("tiny"), Huge: []byte("huge")})
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		tName    string
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{tName: "Raw", query: "size=tiny", wantCode: http.StatusOK, wantBody: "tiny"},
+		{tName: "Base64", query: "size=tiny&encoding=base64", wantCode: http.StatusOK, wantBody: `"contentType":"image/jpeg","data":"` + base64.StdEncoding.EncodeToString([]byte("tiny")) + `"`},
+		{tName: "Base64 default size", query: "encoding=base64", wantCode: http.StatusOK, wantBody: `"data":"` + base64.StdEncoding.EncodeToString([]byte("huge")) + `"`},
+		{tName: "Unsupported encoding", query: "encoding=hex", wantCode: http.StatusBadRequest, wantBody: `"success":false`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", bob.Id)
+			c.SetParamNames("id")
+			c.SetParamValues(group.Id)
+
+			err := handler.GetGroupPicture(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+func TestHandler_GetOwnMembership(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+	carol := &models.User{Name: "carol", Email: "carol@gmail.com"}
+	us.Create(context.Background(), carol)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+	gs.AddAdmin(context.Background(), group, admin)
+
+	gs.CreateTransaction(context.Background(), group, true, false, nil, bob, "Pocket money", "", 500)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		tName    string
+		userId   string
+		groupId  string
+		wantCode int
+		wantBody string
+	}{
+		{tName: "Member", userId: bob.Id, groupId: group.Id, wantCode: http.StatusOK, wantBody: `"isMember":true,"isAdmin":false,"role":"member","balance":500}`},
+		{tName: "Admin", userId: admin.Id, groupId: group.Id, wantCode: http.StatusOK, wantBody: `"isMember":false,"isAdmin":true,"role":"admin"}`},
+		{tName: "Outsider", userId: carol.Id, groupId: group.Id, wantCode: http.StatusOK, wantBody: `"isMember":false,"isAdmin":false,"role":"none"}`},
+		{tName: "Unknown group", userId: bob.Id, groupId: "unknown", wantCode: http.StatusNotFound, wantBody: `"success":false`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.groupId)
+
+			err := handler.GetOwnMembership(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+func TestHandler_GetFullTransactionById(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	alice := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), alice)
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+
+	group := &models.Group{Name: "group"}
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+	gs.AddMember(context.Background(), group, alice)
+	gs.AddAdmin(context.Background(), group, admin)
+
+	gs.CreateTransaction(context.Background(), group, true, false, nil, bob, "Pocket money", "", 500)
+	transaction, _ := gs.CreateTransaction(context.Background(), group, false, false, bob, alice, "Gift", "", 200)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name          string
+		userId        string
+		transactionId string
+		wantCode      int
+	}{
+		{name: "Sender", userId: bob.Id, transactionId: transaction.Id, wantCode: http.StatusForbidden},
+		{name: "Receiver", userId: alice.Id, transactionId: transaction.Id, wantCode: http.StatusForbidden},
+		{name: "Admin", userId: admin.Id, transactionId: transaction.Id, wantCode: http.StatusOK},
+		{name: "Unknown transaction", userId: admin.Id, transactionId: "unknown", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id", "transactionId")
+			c.SetParamValues(group.Id, tt.transactionId)
+
+			err := handler.GetFullTransactionById(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			if tt.wantCode == http.StatusOK {
+				var resp struct {
+					Id                        string `json:"id"`
+					SenderId                  string `json:"senderId"`
+					NewBalanceSender          int    `json:"newBalanceSender"`
+					BalanceDifferenceSender   int    `json:"balanceDifferenceSender"`
+					NewBalanceReceiver        int    `json:"newBalanceReceiver"`
+					BalanceDifferenceReceiver int    `json:"balanceDifferenceReceiver"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.Equal(t, transaction.Id, resp.Id)
+				assert.Equal(t, bob.Id, resp.SenderId)
+				assert.Equal(t, 300, resp.NewBalanceSender)
+				assert.Equal(t, -200, resp.BalanceDifferenceSender)
+				assert.Equal(t, 200, resp.NewBalanceReceiver)
+				assert.Equal(t, 200, resp.BalanceDifferenceReceiver)
+			}
+		})
+	}
+}
+
+func TestHandler_GetTransactionById_BankFlags(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	alice := &models.User{Name: "alice", Email: "alice@gmail.com"}
+	us.Create(context.Background(), alice)
+	admin := &models.User{Name: "admin", Email: "admin@gmail.com"}
+	us.Create(context.Background(), admin)
+
+	group := &models.Group{Name: "group"}
+	group.BankName = "Membership Fund"
+	gs.Create(context.Background(), group)
+	gs.AddMember(context.Background(), group, bob)
+	gs.AddMember(context.Background(), group, alice)
+	gs.AddAdmin(context.Background(), group, admin)
+
+	bankToMember, _ := gs.CreateTransaction(context.Background(), group, true, false, nil, bob, "Pocket money", "", 500)
+	memberToMember, _ := gs.CreateTransaction(context.Background(), group, false, false, bob, alice, "Gift", "", 200)
+	memberToBank, _ := gs.CreateTransaction(context.Background(), group, false, true, bob, nil, "Fee", "", 50)
+	// The store rejects bank to bank transactions, but older data may still contain them.
+	bankToBank := &models.TransactionLogEntry{GroupId: group.Id, Title: "Correction", Amount: 10, SenderIsBank: true, ReceiverIsBank: true}
+	database.Create(bankToBank)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		tName       string
+		userId      string
+		transaction *models.TransactionLogEntry
+		wantParties string
+	}{
+		{tName: "Member to member", userId: bob.Id, transaction: memberToMember, wantParties: fmt.Sprintf(`"senderId":"%s","receiverId":"%s","senderName":"bob","receiverName":"alice","senderIsBank":false,"receiverIsBank":false`, bob.Id, alice.Id)},
+		{tName: "Bank to member", userId: bob.Id, transaction: bankToMember, wantParties: fmt.Sprintf(`"senderId":"bank","receiverId":"%s","senderName":"Membership Fund","receiverName":"bob","senderIsBank":true,"receiverIsBank":false`, bob.Id)},
+		{tName: "Member to bank", userId: admin.Id, transaction: memberToBank, wantParties: fmt.Sprintf(`"senderId":"%s","receiverId":"bank","senderName":"bob","receiverName":"Membership Fund","senderIsBank":false,"receiverIsBank":true`, bob.Id)},
+		{tName: "Bank to bank", userId: admin.Id, transaction: bankToBank, wantParties: `"senderId":"bank","receiverId":"bank","senderName":"Membership Fund","receiverName":"Membership Fund","senderIsBank":true,"receiverIsBank":true`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+			c.SetParamNames("id", "transactionId")
+			c.SetParamValues(group.Id, tt.transaction.Id)
+
+			err := handler.GetTransactionById(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantParties)
+		})
+	}
+}
+
+func TestHandler_GetGroups_Role(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user)
+
+	adminOnly := &models.Group{Name: "a"}
+	gs.Create(context.Background(), adminOnly)
+	gs.AddAdmin(context.Background(), adminOnly, user)
+
+	memberOnly := &models.Group{Name: "b"}
+	gs.Create(context.Background(), memberOnly)
+	gs.AddMember(context.Background(), memberOnly, user)
+
+	both := &models.Group{Name: "c"}
+	gs.Create(context.Background(), both)
+	gs.AddMember(context.Background(), both, user)
+	gs.AddAdmin(context.Background(), both, user)
+
+	other := &models.Group{Name: "d"}
+	gs.Create(context.Background(), other)
+
+	handler := New(us, gs, nil)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantIds   []string
+		wantCount int64
+	}{
+		{name: "All", wantCode: http.StatusOK, wantIds: []string{adminOnly.Id, memberOnly.Id, both.Id}, wantCount: 3},
+		{name: "Admin", query: "role=admin", wantCode: http.StatusOK, wantIds: []string{adminOnly.Id, both.Id}, wantCount: 2},
+		{name: "Member", query: "role=Member", wantCode: http.StatusOK, wantIds: []string{memberOnly.Id}, wantCount: 1},
+		{name: "Paginated", query: "role=admin&descending=true&pageSize=1", wantCode: http.StatusOK, wantIds: []string{both.Id}, wantCount: 2},
+		{name: "Invalid role", query: "role=owner", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user.Id)
+
+			err := handler.GetGroups(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode == http.StatusOK {
+				var resp struct {
+					Count  int64 `json:"count"`
+					Groups []struct {
+						Id string `json:"id"`
+					} `json:"groups"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+
+				ids := make([]string, 0, len(resp.Groups))
+				for _, g := range resp.Groups {
+					ids = append(ids, g.Id)
+				}
+				assert.Equal(t, tt.wantIds, ids)
+				assert.Equal(t, tt.wantCount, resp.Count)
+			}
+		})
+	}
+}
@@ -3,9 +3,12 @@ package handlers
 import (
 	"mime"
 
+	"github.com/labstack/echo/v4"
+
 	"github.com/juho05/oidc-client/oidc"
 
 	"github.com/juho05/h-bank/models"
+	"github.com/juho05/h-bank/services"
 )
 
 func init() {
@@ -27,3 +30,11 @@ func New(userStore models.UserStore, groupStore models.GroupStore, oidcClient *o
 		oidcClient: oidcClient,
 	}
 }
+
+// Reads the oldestFirst query parameter. If it is missing, the sort preference of the user is used.
+func oldestFirstParam(c echo.Context, user *models.User) bool {
+	if c.QueryParam("oldestFirst") == "" {
+		return !user.DefaultSortDescending
+	}
+	return services.StrToBool(c.QueryParam("oldestFirst"))
+}
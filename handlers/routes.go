@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"time"
+
 	"github.com/labstack/echo/v4"
 
+	"github.com/juho05/h-bank/config"
 	"github.com/juho05/h-bank/router/middlewares"
 )
 
@@ -10,9 +13,12 @@ func (h *Handler) RegisterAPI(api *echo.Group) {
 	api.GET("/status", h.Status)
 
 	jwt := middlewares.Auth(h.oidcClient, h.userStore)
+	loginLimit := middlewares.RateLimit(config.Data.LoginRateLimit, time.Minute)
+	transactionLimit := middlewares.RateLimit(config.Data.TransactionRateLimit, time.Minute)
+	emailLimit := middlewares.RateLimit(config.Data.EmailRateLimit, time.Minute)
 
 	auth := api.Group("/auth")
-	auth.GET("/login", h.Login)
+	auth.GET("/login", h.Login, loginLimit)
 	auth.GET("/callback", h.LoginCallback)
 	auth.GET("/refresh", func(c echo.Context) error {
 		return nil
@@ -20,16 +26,24 @@ func (h *Handler) RegisterAPI(api *echo.Group) {
 	auth.POST("/logout", h.Logout)
 
 	api.GET("/user", h.GetUsers, jwt)
+	api.GET("/user/me", h.GetAuthUser, jwt)
 	api.GET("/user/:id", h.GetUser, jwt)
 	api.PUT("/user", h.UpdateUser, jwt)
 	api.POST("/user/delete", h.DeleteUser, jwt)
 
 	user := api.Group("/user")
 
+	user.GET("/activity", h.GetActivity, jwt)
+	user.GET("/netWorth", h.GetNetWorth, jwt)
+	user.GET("/overview", h.GetOverview, jwt)
+	user.POST("/leaveGroups", h.LeaveAllGroups, jwt)
+
 	user.GET("/cash/current", h.GetCurrentCash, jwt)
+	user.GET("/cash/makeChange", h.MakeChange, jwt)
 	user.GET("/cash/:id", h.GetCashLogEntryById, jwt)
 	user.GET("/cash", h.GetCashLog, jwt)
 	user.POST("/cash", h.AddCashLogEntry, jwt)
+	user.DELETE("/cash", h.DeleteCashLog, jwt)
 
 	api.GET("/group", h.GetGroups, jwt)
 	api.GET("/group/:id", h.GetGroupById, jwt)
@@ -37,7 +51,17 @@ func (h *Handler) RegisterAPI(api *echo.Group) {
 	api.PUT("/group/:id", h.UpdateGroup, jwt)
 
 	group := api.Group("/group")
+	group.GET("/:id/settings", h.GetGroupSettings, jwt)
+	group.PUT("/:id/settings", h.UpdateGroupSettings, jwt)
+	group.POST("/:id/delete", h.RequestGroupDeletion, jwt)
+	group.POST("/:id/delete/confirm", h.ConfirmGroupDeletion, jwt)
+	group.DELETE("/:id/delete", h.CancelGroupDeletion, jwt)
+	group.GET("/:id/membership", h.GetOwnMembership, jwt)
 	group.GET("/:id/member", h.GetGroupMembers, jwt)
+	group.GET("/:id/member/export", h.ExportGroupMembers, jwt)
+	group.GET("/:id/member/:userId", h.GetGroupMember, jwt)
+	group.DELETE("/:id/member/:userId", h.RemoveGroupMember, jwt)
+	group.POST("/:id/member/:userId/reinvite", h.ReinviteFormerMember, jwt, emailLimit)
 	group.DELETE("/:id/member", h.LeaveGroup, jwt)
 	group.GET("/:id/admin", h.GetGroupAdmins, jwt)
 	group.POST("/:id/admin", h.AddGroupAdmin, jwt)
@@ -48,23 +72,52 @@ func (h *Handler) RegisterAPI(api *echo.Group) {
 	group.DELETE("/:id/picture", h.RemoveGroupPicture, jwt)
 
 	group.GET("/:id/transaction/balance", h.GetBalance, jwt)
+	group.GET("/:id/transaction/balanceAt", h.GetBalanceAt, jwt)
+	group.GET("/:id/transaction/timeseries", h.GetTimeSeries, jwt)
+	group.GET("/:id/transaction/between", h.GetTransactionsBetween, jwt)
+	group.GET("/:id/transaction/byCounterparty", h.GetCounterpartyTotals, jwt)
+	group.GET("/:id/transaction/byReference/:ref", h.GetTransactionByReference, jwt)
+	group.GET("/:id/transaction/:transactionId/full", h.GetFullTransactionById, jwt)
 	group.GET("/:id/transaction/:transactionId", h.GetTransactionById, jwt)
 	group.GET("/:id/transaction", h.GetTransactionLog, jwt)
-	group.POST("/:id/transaction", h.CreateTransaction, jwt)
+	group.POST("/:id/transaction", h.CreateTransaction, jwt, transactionLimit)
+	group.POST("/:id/transaction/import", h.ImportTransactions, jwt, transactionLimit)
+	group.POST("/:id/transaction/cash", h.CreateCashTransaction, jwt, transactionLimit)
+	group.POST("/:id/transfer", h.Transfer, jwt, transactionLimit)
 
 	group.GET("/:id/invitation", h.GetInvitationsByGroup, jwt)
 	group.GET("/invitation", h.GetInvitationsByUser, jwt)
+	group.GET("/invitation/unseen", h.GetUnseenInvitationCount, jwt)
+	group.POST("/invitation/seen", h.MarkAllInvitationsAsSeen, jwt)
 	group.GET("/invitation/:id", h.GetInvitationById, jwt)
-	group.POST("/:id/invitation", h.CreateInvitation, jwt)
+	group.GET("/invitation/:id/preview", h.GetInvitationPreview, jwt)
+	group.POST("/:id/invitation", h.CreateInvitation, jwt, emailLimit)
+	group.POST("/:id/invitation/:invitationId/resend", h.ResendInvitation, jwt, emailLimit)
 	group.POST("/invitation/:id", h.AcceptInvitation, jwt)
 	group.DELETE("/invitation/:id", h.DenyInvitation, jwt)
 
 	group.GET("/:id/paymentPlan/:paymentPlanId", h.GetPaymentPlanById, jwt)
 	group.GET("/:id/paymentPlan", h.GetPaymentPlans, jwt)
 	group.GET("/:id/paymentPlan/nextPayment", h.GetPaymentPlanNextPayments, jwt)
+	group.GET("/:id/paymentPlan/upcoming", h.GetUpcomingPayments, jwt)
+	group.GET("/:id/paymentPlan/estimate", h.EstimatePaymentPlan, jwt)
 	group.POST("/:id/paymentPlan", h.CreatePaymentPlan, jwt)
+	group.POST("/:id/paymentPlan/bulk", h.CreateBulkPaymentPlans, jwt)
 	group.PUT("/:id/paymentPlan/:paymentPlanId", h.UpdatePaymentPlan, jwt)
 	group.DELETE("/:id/paymentPlan/:paymentPlanId", h.DeletePaymentPlan, jwt)
+	group.POST("/:id/paymentPlan/:paymentPlanId/skipNext", h.SkipNextPaymentPlanExecution, jwt)
+	group.POST("/:id/paymentPlan/:paymentPlanId/duplicate", h.DuplicatePaymentPlan, jwt)
+	group.GET("/:id/paymentPlan/:paymentPlanId/history", h.GetPaymentPlanExecutions, jwt)
 
 	group.GET("/:id/total", h.GetTotalMoney, jwt)
+
+	group.GET("/:id/audit", h.GetAuditLog, jwt)
+	group.GET("/:id/statement", h.GetStatement, jwt)
+
+	admin := api.Group("/admin")
+	admin.GET("/stats", h.GetStats, jwt)
+	admin.GET("/failedEmail", h.GetFailedEmails, jwt)
+	admin.POST("/failedEmail/:id", h.ResendFailedEmail, jwt)
+	admin.DELETE("/failedEmail/:id", h.DeleteFailedEmail, jwt)
+	admin.GET("/emailPreview", h.PreviewEmail, jwt)
 }
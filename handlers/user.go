@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -19,7 +21,7 @@ import (
 func (h *Handler) GetUsers(c echo.Context) error {
 	lang := c.Get("lang").(string)
 	authUserId := c.Get("userId").(string)
-	authUser, err := h.userStore.GetById(authUserId)
+	authUser, err := h.userStore.GetById(c.Request().Context(), authUserId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -56,12 +58,12 @@ func (h *Handler) GetUsers(c echo.Context) error {
 		}
 	}
 
-	users, err := h.userStore.GetAll(ids, c.QueryParam("search"), page, pageSize, descending)
+	users, err := h.userStore.GetAll(c.Request().Context(), ids, c.QueryParam("search"), page, pageSize, descending)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	count, err := h.userStore.Count()
+	count, err := h.userStore.Count(c.Request().Context())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -73,7 +75,7 @@ func (h *Handler) GetUsers(c echo.Context) error {
 func (h *Handler) GetUser(c echo.Context) error {
 	lang := c.Get("lang").(string)
 	authUserId := c.Get("userId").(string)
-	authUser, err := h.userStore.GetById(authUserId)
+	authUser, err := h.userStore.GetById(c.Request().Context(), authUserId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -86,7 +88,7 @@ func (h *Handler) GetUser(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
 
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -100,6 +102,74 @@ func (h *Handler) GetUser(c echo.Context) error {
 	return c.JSON(http.StatusOK, responses.NewUser(user))
 }
 
+// /api/user/me (GET)
+func (h *Handler) GetAuthUser(c echo.Context) error {
+	lang := c.Get("lang").(string)
+	authUserId := c.Get("userId").(string)
+	authUser, err := h.userStore.GetById(c.Request().Context(), authUserId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if authUser == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.NewAuthUser(authUser))
+}
+
+// /api/user/activity?cursor=string&pageSize=int (GET)
+func (h *Handler) GetActivity(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	pageSize := 20
+	if c.QueryParam("pageSize") != "" {
+		pageSize, err = strconv.Atoi(c.QueryParam("pageSize"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "'pageSize' query parameter not a number", lang))
+		}
+		if pageSize > config.Data.MaxPageSize || pageSize < 1 {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Unsupported page size", lang))
+		}
+	}
+
+	// The cursor has the format "<unix time>_<id>" and points to the last entry of the previous page.
+	beforeTime := int64(math.MaxInt64)
+	beforeId := ""
+	if c.QueryParam("cursor") != "" {
+		timeStr, id, found := strings.Cut(c.QueryParam("cursor"), "_")
+		if !found {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid 'cursor' query parameter", lang))
+		}
+		beforeTime, err = strconv.ParseInt(timeStr, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid 'cursor' query parameter", lang))
+		}
+		beforeId = id
+	}
+
+	activities, err := h.groupStore.GetActivity(c.Request().Context(), user, beforeTime, beforeId, pageSize)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	cursor := ""
+	if len(activities) == pageSize {
+		last := activities[len(activities)-1]
+		cursor = fmt.Sprintf("%d_%s", last.Time, last.Id)
+	}
+
+	return c.JSON(http.StatusOK, responses.NewActivity(activities, user, cursor))
+}
+
 // /api/user/delete (POST)
 func (h *Handler) DeleteUser(c echo.Context) error {
 	// TODO
@@ -111,7 +181,7 @@ func (h *Handler) UpdateUser(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -126,8 +196,12 @@ func (h *Handler) UpdateUser(c echo.Context) error {
 	}
 
 	user.DontSendInvitationEmail = body.DontSendInvitationEmail
+	user.SendReceiptEmail = body.SendReceiptEmail
 	user.PubliclyVisible = body.PubliclyVisible
-	h.userStore.Update(user)
+	if body.DefaultSortDescending != nil {
+		user.DefaultSortDescending = *body.DefaultSortDescending
+	}
+	h.userStore.Update(c.Request().Context(), user)
 
 	return c.JSON(http.StatusOK, responses.NewAuthUser(user))
 }
@@ -137,7 +211,7 @@ func (h *Handler) GetCurrentCash(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -145,7 +219,7 @@ func (h *Handler) GetCurrentCash(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
 	}
 
-	entry, err := h.userStore.GetLastCashLogEntry(user)
+	entry, err := h.userStore.GetLastCashLogEntry(c.Request().Context(), user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -160,12 +234,205 @@ func (h *Handler) GetCurrentCash(c echo.Context) error {
 	return c.JSON(http.StatusOK, responses.NewCashLogEntry(entry))
 }
 
+// /api/user/cash/makeChange (GET)
+func (h *Handler) MakeChange(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	if c.QueryParam("amount") == "" {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing 'amount' query parameter", lang))
+	}
+	amount, err := strconv.Atoi(c.QueryParam("amount"))
+	if err != nil || amount < 0 {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "Invalid 'amount' query parameter", lang))
+	}
+
+	entry, err := h.userStore.GetLastCashLogEntry(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if entry == nil {
+		entry = &models.CashLogEntry{}
+	}
+
+	values, counts := cashDenominations(entry)
+	available := make([]int, len(counts))
+	for i, count := range counts {
+		available[i] = *count
+	}
+
+	picked, ok := services.MakeChange(amount, values, available)
+	if !ok {
+		return c.JSON(http.StatusOK, responses.New(false, "The amount can't be made exactly with the available cash", lang))
+	}
+
+	change := &models.CashLogEntry{}
+	_, changeCounts := cashDenominations(change)
+	for i, count := range changeCounts {
+		*count = picked[i]
+	}
+
+	return c.JSON(http.StatusOK, responses.NewChange(change))
+}
+
+// Returns the values of the configured coins and notes from the highest to the lowest together with pointers to their counts in entry.
+func cashDenominations(entry *models.CashLogEntry) ([]int, []*int) {
+	values := config.Data.CashDenominations
+	return values, entry.Counts()[:len(values)]
+}
+
+// Reports whether entry contains coins or notes in fields without a denomination in values.
+func hasUnsupportedCash(entry *models.CashLogEntry, values []int) bool {
+	for _, count := range entry.Counts()[len(values):] {
+		if *count != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// /api/user/netWorth (GET)
+func (h *Handler) GetNetWorth(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	balances, err := h.groupStore.GetBalancesByUser(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	cash := 0
+	entry, err := h.userStore.GetLastCashLogEntry(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if entry != nil {
+		cash = entry.TotalAmount
+	}
+
+	return c.JSON(http.StatusOK, responses.NewNetWorth(balances, cash))
+}
+
+// /api/user/overview (GET)
+func (h *Handler) GetOverview(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groupCount, err := h.groupStore.Count(c.Request().Context(), user, "")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	invitationCount, err := h.groupStore.InvitationCountByUser(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	unseenInvitationCount, err := h.groupStore.UnseenInvitationCountByUser(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	balance, err := h.groupStore.GetTotalBalanceByUser(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	cash := 0
+	entry, err := h.userStore.GetLastCashLogEntry(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if entry != nil {
+		cash = entry.TotalAmount
+	}
+
+	return c.JSON(http.StatusOK, responses.NewOverview(groupCount, invitationCount, unseenInvitationCount, balance+cash))
+}
+
+// /api/user/leaveGroups (POST)
+// Leaves all groups the user is a member of and settles the balances according to the policies of the groups.
+// Admin rights are kept and have to be given up separately.
+func (h *Handler) LeaveAllGroups(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	groups, err := h.groupStore.GetAllByUser(c.Request().Context(), user, "", -1, -1, false)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	left := make([]models.LeftGroup, 0, len(groups))
+	for i := range groups {
+		group := &groups[i]
+
+		isMember, err := h.groupStore.IsMember(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+		if !isMember {
+			continue
+		}
+
+		balance, err := h.groupStore.GetUserBalance(c.Request().Context(), group, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		settled, err := h.removeMember(c.Request().Context(), group, user, user, lang)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+		}
+
+		left = append(left, models.LeftGroup{
+			GroupId:   group.Id,
+			GroupName: group.Name,
+			Balance:   balance,
+			Settled:   settled,
+		})
+	}
+
+	return c.JSON(http.StatusOK, responses.NewLeftGroups(left))
+}
+
 // /api/user/cash/:id (GET)
 func (h *Handler) GetCashLogEntryById(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -178,7 +445,7 @@ func (h *Handler) GetCashLogEntryById(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, responses.New(false, "Missing id parameter", lang))
 	}
 
-	entry, err := h.userStore.GetCashLogEntryById(user, id)
+	entry, err := h.userStore.GetCashLogEntryById(c.Request().Context(), user, id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -194,7 +461,7 @@ func (h *Handler) GetCashLog(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -222,14 +489,14 @@ func (h *Handler) GetCashLog(c echo.Context) error {
 		}
 	}
 
-	oldestFirst := services.StrToBool(c.QueryParam("oldestFirst"))
+	oldestFirst := oldestFirstParam(c, user)
 
-	entries, err := h.userStore.GetCashLog(user, c.QueryParam("search"), page, pageSize, oldestFirst)
+	entries, err := h.userStore.GetCashLog(c.Request().Context(), user, c.QueryParam("search"), page, pageSize, oldestFirst)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
 
-	count, err := h.userStore.CashLogEntryCount(user)
+	count, err := h.userStore.CashLogEntryCount(c.Request().Context(), user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -238,11 +505,12 @@ func (h *Handler) GetCashLog(c echo.Context) error {
 }
 
 // /api/user/cash (POST)
+// The body contains the counted coins and notes, not the change since the last entry.
 func (h *Handler) AddCashLogEntry(c echo.Context) error {
 	lang := c.Get("lang").(string)
 
 	userId := c.Get("userId").(string)
-	user, err := h.userStore.GetById(userId)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 	}
@@ -275,7 +543,53 @@ func (h *Handler) AddCashLogEntry(c echo.Context) error {
 		return c.JSON(http.StatusOK, responses.New(false, "Description too short", lang))
 	}
 
-	cashLogEntry := models.CashLogEntry{
+	cashLogEntry := newCashLogEntry(body)
+	if hasUnsupportedCash(&cashLogEntry, config.Data.CashDenominations) {
+		return c.JSON(http.StatusOK, responses.New(false, "Unsupported coin or note", lang))
+	}
+
+	err = h.userStore.AddCashLogEntry(c.Request().Context(), user, &cashLogEntry)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusCreated, responses.New(true, "Successfully added new cash log entry", lang))
+}
+
+// /api/user/cash (DELETE)
+// Clears the cash log history of the user without deleting the account.
+// The entries are deleted permanently, so the request body has to confirm the deletion with {"confirm": true}.
+func (h *Handler) DeleteCashLog(c echo.Context) error {
+	lang := c.Get("lang").(string)
+
+	userId := c.Get("userId").(string)
+	user, err := h.userStore.GetById(c.Request().Context(), userId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, responses.NewUserNoLongerExists(lang))
+	}
+
+	var body bindings.DeleteCashLog
+	err = c.Bind(&body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, responses.NewInvalidRequestBody(lang))
+	}
+	if !body.Confirm {
+		return c.JSON(http.StatusBadRequest, responses.New(false, "The deletion of the cash log has to be confirmed", lang))
+	}
+
+	err = h.userStore.DeleteCashLog(c.Request().Context(), user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
+	}
+
+	return c.JSON(http.StatusOK, responses.New(true, "Successfully deleted cash log", lang))
+}
+
+func newCashLogEntry(body bindings.AddCashLogEntry) models.CashLogEntry {
+	return models.CashLogEntry{
 		ChangeTitle:       body.Title,
 		ChangeDescription: body.Description,
 		Ct1:               int(body.Ct1),
@@ -294,11 +608,4 @@ func (h *Handler) AddCashLogEntry(c echo.Context) error {
 		Eur200:            int(body.Eur200),
 		Eur500:            int(body.Eur500),
 	}
-
-	err = h.userStore.AddCashLogEntry(user, &cashLogEntry)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
-	}
-
-	return c.JSON(http.StatusCreated, responses.New(true, "Successfully added new cash log entry", lang))
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -42,13 +43,13 @@ func TestHandler_GetUsers(t *testing.T) {
 		Name:  "bob",
 		Email: "bob@gmail.com",
 	}
-	us.Create(user1)
+	us.Create(context.Background(), user1)
 
 	user2 := &models.User{
 		Name:  "peter",
 		Email: "peter@gmail.com",
 	}
-	us.Create(user2)
+	us.Create(context.Background(), user2)
 
 	handler := New(us, nil, nil)
 
@@ -114,13 +115,13 @@ func TestHandler_GetUser(t *testing.T) {
 		Name:  "bob",
 		Email: "bob@gmail.com",
 	}
-	us.Create(user1)
+	us.Create(context.Background(), user1)
 
 	user2 := &models.User{
 		Name:  "peter",
 		Email: "peter@gmail.com",
 	}
-	us.Create(user2)
+	us.Create(context.Background(), user2)
 
 	handler := New(us, nil, nil)
 
@@ -172,6 +173,62 @@ func TestHandler_GetUser(t *testing.T) {
 	}
 }
 
+func TestHandler_GetAuthUser(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+
+	user := &models.User{
+		Name:  "bob",
+		Email: "bob@gmail.com",
+	}
+	us.Create(context.Background(), user)
+
+	handler := New(us, nil, nil)
+
+	tests := []struct {
+		tName       string
+		userId      string
+		wantCode    int
+		wantSuccess bool
+	}{
+		{tName: "Auth user", userId: user.Id, wantCode: http.StatusOK, wantSuccess: true},
+		{tName: "Deleted user", userId: uuid.NewString(), wantCode: http.StatusUnauthorized, wantSuccess: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", tt.userId)
+
+			err := handler.GetAuthUser(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"success":%t`, tt.wantSuccess))
+
+			if tt.wantSuccess {
+				assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"id":"%s"`, user.Id))
+				assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"email":"%s"`, user.Email))
+			}
+		})
+	}
+}
+
 func TestHandler_UpdateUser(t *testing.T) {
 	t.Parallel()
 	config.Data.Debug = true
@@ -194,14 +251,14 @@ func TestHandler_UpdateUser(t *testing.T) {
 		Email:                   "bob@gmail.com",
 		DontSendInvitationEmail: true,
 	}
-	us.Create(user1)
+	us.Create(context.Background(), user1)
 
 	user2 := &models.User{
 		Name:                    "bob2",
 		Email:                   "bob2@gmail.com",
 		DontSendInvitationEmail: true,
 	}
-	us.Create(user2)
+	us.Create(context.Background(), user2)
 
 	handler := New(us, nil, nil)
 
@@ -233,7 +290,7 @@ func TestHandler_UpdateUser(t *testing.T) {
 			assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"success":%t`, tt.wantSuccess))
 			assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"message":"%s"`, tt.wantMessage))
 
-			user, _ := us.GetById(tt.user.Id)
+			user, _ := us.GetById(context.Background(), tt.user.Id)
 			if tt.wantSuccess {
 				assert.Equal(t, tt.dontSendInvitationEmail, user.DontSendInvitationEmail)
 			} else {
@@ -271,13 +328,13 @@ func TestHandler_GetCurrentCash(t *testing.T) {
 			{ChangeTitle: "Change3", Base: models.Base{Created: time.Now().Unix()}},
 		},
 	}
-	us.Create(user1)
+	us.Create(context.Background(), user1)
 
 	user2 := &models.User{
 		Name:  "peter",
 		Email: "peter@gmail.com",
 	}
-	us.Create(user2)
+	us.Create(context.Background(), user2)
 
 	handler := New(us, nil, nil)
 
@@ -340,7 +397,7 @@ func TestHandler_GetCashLogEntryById(t *testing.T) {
 			{ChangeTitle: "Change3", Base: models.Base{Created: time.Now().Unix()}},
 		},
 	}
-	us.Create(user)
+	us.Create(context.Background(), user)
 
 	handler := New(us, nil, nil)
 
@@ -405,7 +462,7 @@ func TestHandler_GetCashLog(t *testing.T) {
 			{ChangeTitle: "Change3", Base: models.Base{Created: time.Now().Unix()}},
 		},
 	}
-	us.Create(user)
+	us.Create(context.Background(), user)
 
 	handler := New(us, nil, nil)
 
@@ -471,13 +528,13 @@ func TestHandler_AddCashLogEntry(t *testing.T) {
 		Name:  "bob",
 		Email: "bob@gmail.com",
 	}
-	us.Create(user1)
+	us.Create(context.Background(), user1)
 
 	user2 := &models.User{
 		Name:  "bob2",
 		Email: "bob2@gmail.com",
 	}
-	us.Create(user2)
+	us.Create(context.Background(), user2)
 
 	handler := New(us, nil, nil)
 
@@ -512,7 +569,7 @@ func TestHandler_AddCashLogEntry(t *testing.T) {
 			assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"success":%t`, tt.wantSuccess))
 			assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"message":"%s"`, tt.wantMessage))
 
-			log, _ := us.GetCashLog(tt.user, "", 0, 10, false)
+			log, _ := us.GetCashLog(context.Background(), tt.user, "", 0, 10, false)
 			if tt.wantSuccess {
 				assert.Equal(t, 1, len(log))
 			} else {
@@ -521,3 +578,545 @@ func TestHandler_AddCashLogEntry(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_AddCashLogEntry_Recount(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+
+	user := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user)
+
+	handler := New(us, nil, nil)
+
+	tests := []struct {
+		name           string
+		entry          bindings.AddCashLogEntry
+		wantAmount     int
+		wantDifference int
+	}{
+		{name: "First count", entry: bindings.AddCashLogEntry{Title: "Count", Eur10: 2, Ct50: 1}, wantAmount: 2050, wantDifference: 2050},
+		{name: "Spent money", entry: bindings.AddCashLogEntry{Title: "Count", Eur5: 1, Ct50: 1}, wantAmount: 550, wantDifference: -1500},
+		{name: "Same count", entry: bindings.AddCashLogEntry{Title: "Count", Eur5: 1, Ct50: 1}, wantAmount: 550, wantDifference: 0},
+		{name: "Received money", entry: bindings.AddCashLogEntry{Title: "Count", Eur50: 1}, wantAmount: 5000, wantDifference: 4450},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonBody, _ := json.Marshal(tt.entry)
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(jsonBody)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user.Id)
+
+			err := handler.AddCashLogEntry(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+
+			entry, err := us.GetLastCashLogEntry(context.Background(), user)
+			assert.NoError(t, err)
+			if assert.NotNil(t, entry) {
+				assert.Equal(t, tt.wantAmount, entry.TotalAmount)
+				assert.Equal(t, tt.wantDifference, entry.ChangeDifference)
+			}
+		})
+	}
+}
+
+func TestHandler_DeleteCashLog(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+
+	user1 := &models.User{
+		Name:  "bob",
+		Email: "bob@gmail.com",
+		CashLog: []models.CashLogEntry{
+			{ChangeTitle: "Change1"},
+			{ChangeTitle: "Change2"},
+		},
+	}
+	us.Create(context.Background(), user1)
+
+	user2 := &models.User{
+		Name:    "peter",
+		Email:   "peter@gmail.com",
+		CashLog: []models.CashLogEntry{{ChangeTitle: "Change1"}},
+	}
+	us.Create(context.Background(), user2)
+
+	handler := New(us, nil, nil)
+
+	deleteCashLog := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := r.NewContext(req, rec)
+		c.Set("lang", "en")
+		c.Set("userId", user1.Id)
+		assert.NoError(t, handler.DeleteCashLog(c))
+		return rec
+	}
+
+	// The deletion has to be confirmed.
+	rec := deleteCashLog("")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = deleteCashLog(`{"confirm":false}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	count, err := us.CashLogEntryCount(context.Background(), user1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	rec = deleteCashLog(`{"confirm":true}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"success":true`)
+
+	count, err = us.CashLogEntryCount(context.Background(), user1)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+
+	count, err = us.CashLogEntryCount(context.Background(), user2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	user, err := us.GetById(context.Background(), user1.Id)
+	assert.NoError(t, err)
+	assert.NotNil(t, user)
+}
+
+func TestHandler_MakeChange(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+
+	user := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), user)
+	us.AddCashLogEntry(context.Background(), user, &models.CashLogEntry{ChangeTitle: "Counted", Ct1: 3, Ct50: 1, Eur5: 2, Eur20: 1})
+
+	handler := New(us, nil, nil)
+
+	type change struct {
+		Success bool `json:"success"`
+		Amount  int  `json:"amount"`
+		Ct1     int  `json:"ct1"`
+		Ct50    int  `json:"ct50"`
+		Eur5    int  `json:"eur5"`
+		Eur20   int  `json:"eur20"`
+	}
+
+	tests := []struct {
+		name     string
+		amount   string
+		wantCode int
+		want     change
+	}{
+		{name: "Exact", amount: "3052", wantCode: http.StatusOK, want: change{Success: true, Amount: 3052, Ct1: 2, Ct50: 1, Eur5: 2, Eur20: 1}},
+		{name: "Prefer notes", amount: "2000", wantCode: http.StatusOK, want: change{Success: true, Amount: 2000, Eur20: 1}},
+		{name: "Not possible", amount: "400", wantCode: http.StatusOK, want: change{}},
+		{name: "Too much", amount: "5000", wantCode: http.StatusOK, want: change{}},
+		{name: "Missing amount", amount: "", wantCode: http.StatusBadRequest, want: change{}},
+		{name: "Invalid amount", amount: "-5", wantCode: http.StatusBadRequest, want: change{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?amount="+tt.amount, nil)
+			rec := httptest.NewRecorder()
+			c := r.NewContext(req, rec)
+			c.Set("lang", "en")
+			c.Set("userId", user.Id)
+
+			err := handler.MakeChange(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			var got change
+			json.Unmarshal(rec.Body.Bytes(), &got)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHandler_GetNetWorth(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user1 := &models.User{
+		Name:  "bob",
+		Email: "bob@gmail.com",
+		CashLog: []models.CashLogEntry{
+			{ChangeTitle: "Change1", TotalAmount: 500, Base: models.Base{Created: time.Now().Unix()}},
+		},
+	}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "peter", Email: "peter@gmail.com"}
+	us.Create(context.Background(), user2)
+
+	group1 := &models.Group{Name: "a group"}
+	gs.Create(context.Background(), group1)
+	gs.AddMember(context.Background(), group1, user1)
+	gs.AddMember(context.Background(), group1, user2)
+	group2 := &models.Group{Name: "b group"}
+	gs.Create(context.Background(), group2)
+	gs.AddMember(context.Background(), group2, user1)
+	group3 := &models.Group{Name: "c group"}
+	gs.Create(context.Background(), group3)
+	gs.AddMember(context.Background(), group3, user2)
+
+	first, _ := gs.CreateTransaction(context.Background(), group1, true, false, nil, user1, "Pocket money", "", 100)
+	database.Model(first).Update("created", 1000)
+	second, _ := gs.CreateTransaction(context.Background(), group1, false, false, user1, user2, "Gift", "", 30)
+	database.Model(second).Update("created", 2000)
+	gs.CreateTransaction(context.Background(), group2, false, true, user1, nil, "Snacks", "", 20)
+	gs.CreateTransaction(context.Background(), group3, true, false, nil, user2, "Pocket money", "", 1000)
+
+	handler := New(us, gs, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := r.NewContext(req, rec)
+	c.Set("lang", "en")
+	c.Set("userId", user1.Id)
+
+	err = handler.GetNetWorth(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Success bool `json:"success"`
+		Groups  []struct {
+			GroupId string `json:"groupId"`
+			Balance int    `json:"balance"`
+		} `json:"groups"`
+		Cash  int `json:"cash"`
+		Total int `json:"total"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+
+	assert.True(t, resp.Success)
+	if assert.Len(t, resp.Groups, 2) {
+		assert.Equal(t, group1.Id, resp.Groups[0].GroupId)
+		assert.Equal(t, 70, resp.Groups[0].Balance)
+		assert.Equal(t, group2.Id, resp.Groups[1].GroupId)
+		assert.Equal(t, -20, resp.Groups[1].Balance)
+	}
+	assert.Equal(t, 500, resp.Cash)
+	assert.Equal(t, 550, resp.Total)
+}
+
+func TestHandler_GetOverview(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	user1 := &models.User{
+		Name:  "bob",
+		Email: "bob@gmail.com",
+		CashLog: []models.CashLogEntry{
+			{ChangeTitle: "Change1", TotalAmount: 500, Base: models.Base{Created: time.Now().Unix()}},
+		},
+	}
+	us.Create(context.Background(), user1)
+	user2 := &models.User{Name: "peter", Email: "peter@gmail.com"}
+	us.Create(context.Background(), user2)
+
+	group1 := &models.Group{Name: "a group"}
+	gs.Create(context.Background(), group1)
+	gs.AddMember(context.Background(), group1, user1)
+	gs.AddMember(context.Background(), group1, user2)
+	group2 := &models.Group{Name: "b group"}
+	gs.Create(context.Background(), group2)
+	gs.AddMember(context.Background(), group2, user1)
+	group3 := &models.Group{Name: "c group"}
+	gs.Create(context.Background(), group3)
+	gs.AddMember(context.Background(), group3, user2)
+	group4 := &models.Group{Name: "d group"}
+	gs.Create(context.Background(), group4)
+
+	gs.CreateTransaction(context.Background(), group1, true, false, nil, user1, "Pocket money", "", 100)
+	gs.CreateTransaction(context.Background(), group1, false, false, user1, user2, "Gift", "", 30)
+	gs.CreateTransaction(context.Background(), group2, false, true, user1, nil, "Snacks", "", 20)
+	gs.CreateTransaction(context.Background(), group3, true, false, nil, user2, "Pocket money", "", 1000)
+
+	gs.CreateInvitation(context.Background(), group3, user1, nil, "", 0)
+	invitation, _ := gs.CreateInvitation(context.Background(), group4, user1, nil, "", 0)
+	gs.MarkInvitationsAsSeen(context.Background(), []models.GroupInvitation{*invitation})
+
+	handler := New(us, gs, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := r.NewContext(req, rec)
+	c.Set("lang", "en")
+	c.Set("userId", user1.Id)
+
+	err = handler.GetOverview(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Success               bool  `json:"success"`
+		GroupCount            int64 `json:"groupCount"`
+		InvitationCount       int64 `json:"invitationCount"`
+		UnseenInvitationCount int64 `json:"unseenInvitationCount"`
+		NetWorth              int   `json:"netWorth"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+
+	assert.True(t, resp.Success)
+	assert.EqualValues(t, 2, resp.GroupCount)
+	assert.EqualValues(t, 2, resp.InvitationCount)
+	assert.EqualValues(t, 1, resp.UnseenInvitationCount)
+	assert.Equal(t, 550, resp.NetWorth)
+}
+
+func TestHandler_LeaveAllGroups(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+	gs := db.NewGroupStore(database)
+
+	bob := &models.User{Name: "bob", Email: "bob@gmail.com"}
+	us.Create(context.Background(), bob)
+	peter := &models.User{Name: "peter", Email: "peter@gmail.com"}
+	us.Create(context.Background(), peter)
+
+	keep := &models.Group{Name: "a group"}
+	gs.Create(context.Background(), keep)
+	gs.AddMember(context.Background(), keep, bob)
+	bank := &models.Group{Name: "b group"}
+	bank.LeaveBalancePolicy = models.LeaveBalanceBank
+	gs.Create(context.Background(), bank)
+	gs.AddMember(context.Background(), bank, bob)
+	adminOnly := &models.Group{Name: "c group"}
+	gs.Create(context.Background(), adminOnly)
+	gs.AddAdmin(context.Background(), adminOnly, bob)
+	other := &models.Group{Name: "d group"}
+	gs.Create(context.Background(), other)
+	gs.AddMember(context.Background(), other, peter)
+
+	gs.CreateTransaction(context.Background(), keep, true, false, nil, bob, "Pocket money", "", 100)
+	gs.CreateTransaction(context.Background(), bank, true, false, nil, bob, "Pocket money", "", 50)
+
+	handler := New(us, gs, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := r.NewContext(req, rec)
+	c.Set("lang", "en")
+	c.Set("userId", bob.Id)
+
+	err = handler.LeaveAllGroups(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Success bool `json:"success"`
+		Groups  []struct {
+			GroupId string `json:"groupId"`
+			Balance int    `json:"balance"`
+			Settled bool   `json:"settled"`
+		} `json:"groups"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+
+	assert.True(t, resp.Success)
+	if assert.Len(t, resp.Groups, 2) {
+		assert.Equal(t, keep.Id, resp.Groups[0].GroupId)
+		assert.Equal(t, 100, resp.Groups[0].Balance)
+		assert.False(t, resp.Groups[0].Settled)
+		assert.Equal(t, bank.Id, resp.Groups[1].GroupId)
+		assert.Equal(t, 50, resp.Groups[1].Balance)
+		assert.True(t, resp.Groups[1].Settled)
+	}
+
+	for _, g := range []*models.Group{keep, bank} {
+		isMember, err := gs.IsMember(context.Background(), g, bob)
+		assert.NoError(t, err)
+		assert.False(t, isMember)
+	}
+
+	balance, err := gs.GetUserBalance(context.Background(), bank, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, balance)
+
+	isAdmin, err := gs.IsAdmin(context.Background(), adminOnly, bob)
+	assert.NoError(t, err)
+	assert.True(t, isAdmin)
+}
+
+func TestHandler_DefaultSortDescending(t *testing.T) {
+	t.Parallel()
+	config.Data.Debug = true
+	r := router.New()
+
+	database, dbId, err := db.NewTestDB()
+	if err != nil {
+		t.Fatalf("Couldn't create test database")
+	}
+	defer db.DeleteTestDB(dbId)
+	err = db.AutoMigrate(database)
+	if err != nil {
+		t.Fatalf("Couldn't auto migrate database")
+	}
+
+	us := db.NewUserStore(database)
+
+	user := &models.User{
+		Name:  "bob",
+		Email: "bob@gmail.com",
+		CashLog: []models.CashLogEntry{
+			{ChangeTitle: "Old", Base: models.Base{Created: 1000}},
+			{ChangeTitle: "New", Base: models.Base{Created: 2000}},
+		},
+	}
+	us.Create(context.Background(), user)
+
+	handler := New(us, nil, nil)
+
+	updateUser := func(body string) {
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := r.NewContext(req, rec)
+		c.Set("lang", "en")
+		c.Set("userId", user.Id)
+		err := handler.UpdateUser(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	firstTitle := func(query string) string {
+		req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := r.NewContext(req, rec)
+		c.Set("lang", "en")
+		c.Set("userId", user.Id)
+		err := handler.GetCashLog(c)
+		assert.NoError(t, err)
+
+		var resp struct {
+			Log []struct {
+				Title string `json:"title"`
+			} `json:"log"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if !assert.Len(t, resp.Log, 2) {
+			return ""
+		}
+		return resp.Log[0].Title
+	}
+
+	assert.Equal(t, "New", firstTitle(""))
+
+	updateUser(`{"defaultSortDescending": false}`)
+	assert.Equal(t, "Old", firstTitle(""))
+	assert.Equal(t, "New", firstTitle("oldestFirst=false"))
+
+	updateUser(`{"publiclyVisible": true}`)
+	assert.Equal(t, "Old", firstTitle(""))
+
+	updateUser(`{"defaultSortDescending": true}`)
+	assert.Equal(t, "New", firstTitle(""))
+	assert.Equal(t, "Old", firstTitle("oldestFirst=true"))
+}
+
+func TestHandler_hasUnsupportedCash(t *testing.T) {
+	dollars := []int{10000, 5000, 2000, 1000, 500, 200, 100, 50, 25, 10, 5, 1}
+
+	tests := []struct {
+		name      string
+		entry     models.CashLogEntry
+		values    []int
+		want      bool
+		wantValue int
+	}{
+		{name: "Euro", entry: models.CashLogEntry{Eur500: 1, Ct1: 3}, values: config.Data.CashDenominations, want: false, wantValue: 50003},
+		{name: "Dollar", entry: models.CashLogEntry{Eur500: 1, Eur5: 2, Ct10: 4}, values: dollars, want: false, wantValue: 10000 + 2*100 + 4*1},
+		{name: "Unused field", entry: models.CashLogEntry{Eur1: 1, Ct1: 3}, values: dollars, want: true, wantValue: 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, hasUnsupportedCash(&tt.entry, tt.values))
+			assert.Equal(t, tt.wantValue, tt.entry.ValueIn(tt.values))
+		})
+	}
+}
@@ -34,7 +34,7 @@ func Initialize() {
 			log.Println("Forwarding frontend requests to", config.Data.DevFrontend)
 			return
 		} else {
-			log.Println("WARNING: Dev frontend at %s is not reachable", config.Data.DevFrontend)
+			log.Printf("WARNING: Dev frontend at %s is not reachable", config.Data.DevFrontend)
 		}
 	}
 
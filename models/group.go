@@ -1,84 +1,195 @@
 package models
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
 	"github.com/juho05/h-bank/services"
 )
 
+// Returned when a side of a transaction is both or neither the bank and a user or when the bank would send money to itself.
+var ErrInvalidTransactionParties = errors.New("invalid transaction parties")
+
+// Returned when a transaction would push the balance of a sending member below the minimum balance of the group.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// Returned when adding a member would exceed the maximum number of users of a group.
+var ErrGroupFull = errors.New("group full")
+
+// Returned when imported transactions aren't newer than the existing transactions of the group.
+var ErrImportNotNewer = errors.New("imported transactions not newer than existing transactions")
+
+// Returned when the next execution of a payment plan changed since it was loaded, e.g. because it was skipped.
+var ErrPaymentPlanChanged = errors.New("payment plan changed")
+
 type GroupStore interface {
-	GetAllByUser(user *User, page, pageSize int, descending bool) ([]Group, error)
-	Count(user *User) (int64, error)
-	GetById(id string) (*Group, error)
-	Create(group *Group) error
-	Update(group *Group) error
-	Delete(group *Group) error
-	DeleteById(id string) error
-
-	GetGroupPicture(group *Group, size services.PictureSize) ([]byte, error)
-	UpdateGroupPicture(group *Group, pic *GroupPicture) error
-
-	GetMembers(except *User, searchInput string, group *Group, page, pageSize int, descending bool) ([]User, error)
-	MemberCount(group *Group) (int64, error)
-	IsMember(group *Group, user *User) (bool, error)
-	AddMember(group *Group, user *User) error
-	RemoveMember(group *Group, user *User) error
-
-	GetAdmins(except *User, searchInput string, group *Group, page, pageSize int, descending bool) ([]User, error)
-	AdminCount(group *Group) (int64, error)
-	IsAdmin(group *Group, user *User) (bool, error)
-	AddAdmin(group *Group, user *User) error
-	RemoveAdmin(group *Group, user *User) error
-
-	GetMemberships(except *User, searchInput string, group *Group, page, pageSize int, descending bool) ([]GroupMembership, error)
-	MembershipCount(group *Group) (int64, error)
-
-	IsInGroup(group *Group, user *User) (bool, error)
-	GetUserCount(group *Group) (int64, error)
-
-	GetTransactionLog(group *Group, user *User, searchInput string, page, pageSize int, oldestFirst bool) ([]TransactionLogEntry, error)
-	TransactionLogEntryCount(group *Group, user *User) (int64, error)
-	GetBankTransactionLog(group *Group, searchInput string, page, pageSize int, oldestFirst bool) ([]TransactionLogEntry, error)
-	BankTransactionLogEntryCount(group *Group) (int64, error)
-	GetTransactionLogEntryById(group *Group, id string) (*TransactionLogEntry, error)
-	GetLastTransactionLogEntry(group *Group, user *User) (*TransactionLogEntry, error)
-	GetUserBalance(group *Group, user *User) (int, error)
-	CreateTransaction(group *Group, senderIsBank, receiverIsBank bool, sender *User, receiver *User, title, description string, amount int) (*TransactionLogEntry, error)
-	CreateTransactionFromPaymentPlan(group *Group, senderIsBank, receiverIsBank bool, sender *User, receiver *User, title, description string, amount int, paymentPlanId string) (*TransactionLogEntry, error)
-
-	CreateInvitation(group *Group, user *User, message string) (*GroupInvitation, error)
-	GetInvitationById(id string) (*GroupInvitation, error)
-	GetInvitationsByGroup(group *Group, page, pageSize int, oldestFirst bool) ([]GroupInvitation, error)
-	InvitationCountByGroup(group *Group) (int64, error)
-	GetInvitationsByUser(user *User, page, pageSize int, oldestFirst bool) ([]GroupInvitation, error)
-	InvitationCountByUser(user *User) (int64, error)
-	GetInvitationByGroupAndUser(group *Group, user *User) (*GroupInvitation, error)
-	DeleteInvitation(invitation *GroupInvitation) error
-
-	GetPaymentPlans(group *Group, user *User, searchInput string, page, pageSize int, descending bool) ([]PaymentPlan, error)
-	PaymentPlanCount(group *Group, user *User) (int64, error)
-	GetBankPaymentPlans(group *Group, searchInput string, page, pageSize int, descending bool) ([]PaymentPlan, error)
-	BankPaymentPlanCount(group *Group) (int64, error)
-	GetPaymentPlansThatNeedToBeExecuted() ([]PaymentPlan, error)
-	GetPaymentPlanById(group *Group, id string) (*PaymentPlan, error)
-	CreatePaymentPlan(group *Group, senderIsBank, receiverIsBank bool, sender *User, receiver *User, name, description string, amount, repeats, schedule int, scheduleUnit string, firstPayment int64) (*PaymentPlan, error)
-	UpdatePaymentPlan(paymentPlan *PaymentPlan) error
-	DeletePaymentPlan(paymentPlan *PaymentPlan) error
-
-	GetTotalMoney(group *Group) (int, error)
-
-	AreInSameGroup(userId1, userId2 string) (bool, error)
+	GetAllByUser(ctx context.Context, user *User, role string, page, pageSize int, descending bool) ([]Group, error)
+	Count(ctx context.Context, user *User, role string) (int64, error)
+	GetById(ctx context.Context, id string) (*Group, error)
+	GetByIds(ctx context.Context, ids []string, user *User) ([]Group, error)
+	Create(ctx context.Context, group *Group) error
+	Update(ctx context.Context, group *Group) error
+	UpdateSettings(ctx context.Context, group *Group) error
+	UpdateDeletionRequest(ctx context.Context, group *Group) error
+	Delete(ctx context.Context, group *Group) error
+	DeleteById(ctx context.Context, id string) error
+
+	GetGroupPicture(ctx context.Context, group *Group, size services.PictureSize) ([]byte, error)
+	UpdateGroupPicture(ctx context.Context, group *Group, pic *GroupPicture) error
+
+	GetMembers(ctx context.Context, except *User, searchInput string, group *Group, page, pageSize int, descending, includeFormer bool) ([]User, error)
+	MemberCount(ctx context.Context, group *Group, includeFormer bool) (int64, error)
+	IsMember(ctx context.Context, group *Group, user *User) (bool, error)
+	AddMember(ctx context.Context, group *Group, user *User) error
+	RemoveMember(ctx context.Context, group *Group, user *User) error
+	SettleBalanceAndRemoveMember(ctx context.Context, group *Group, user *User, title string) ([]TransactionLogEntry, error)
+
+	GetAdmins(ctx context.Context, except *User, searchInput string, group *Group, page, pageSize int, descending bool) ([]User, error)
+	AdminCount(ctx context.Context, group *Group) (int64, error)
+	IsAdmin(ctx context.Context, group *Group, user *User) (bool, error)
+	AddAdmin(ctx context.Context, group *Group, user *User) error
+	RemoveAdmin(ctx context.Context, group *Group, user *User) error
+
+	GetMemberships(ctx context.Context, except *User, searchInput string, group *Group, page, pageSize int, descending bool) ([]GroupMembership, error)
+	GetMembership(ctx context.Context, group *Group, user *User) (*GroupMembership, error)
+	GetUserNames(ctx context.Context, group *Group, userIds []string) (map[string]string, error)
+	MembershipCount(ctx context.Context, group *Group) (int64, error)
+
+	IsInGroup(ctx context.Context, group *Group, user *User) (bool, error)
+	GetUserCount(ctx context.Context, group *Group) (int64, error)
+
+	GetTransactionLog(ctx context.Context, group *Group, user *User, searchInput string, filter TransactionFilter, page, pageSize int, oldestFirst bool) ([]TransactionLogEntry, error)
+	TransactionLogEntryCount(ctx context.Context, group *Group, user *User, filter TransactionFilter) (int64, error)
+	GetCounterpartyTotals(ctx context.Context, group *Group, user *User, page, pageSize int) ([]CounterpartyTotals, error)
+	CounterpartyCount(ctx context.Context, group *Group, user *User) (int64, error)
+	GetBankTransactionLog(ctx context.Context, group *Group, searchInput string, page, pageSize int, oldestFirst bool) ([]TransactionLogEntry, error)
+	BankTransactionLogEntryCount(ctx context.Context, group *Group) (int64, error)
+	GetTransactionLogEntryById(ctx context.Context, group *Group, id string) (*TransactionLogEntry, error)
+	GetTransactionLogEntryByReference(ctx context.Context, group *Group, reference string) (*TransactionLogEntry, error)
+	GetLastTransactionLogEntry(ctx context.Context, group *Group, user *User) (*TransactionLogEntry, error)
+	GetUserBalance(ctx context.Context, group *Group, user *User) (int, error)
+	GetUserBalanceAt(ctx context.Context, group *Group, user *User, time int64) (int, error)
+	GetTransactionsInPeriod(ctx context.Context, group *Group, from, to int64) ([]TransactionLogEntry, error)
+	GetTransactionsBetween(ctx context.Context, group *Group, userA, userB *User) ([]TransactionLogEntry, error)
+	GetDailyBalanceChanges(ctx context.Context, group *Group, before int64) ([]DailyBalanceChange, error)
+	CreateTransaction(ctx context.Context, group *Group, senderIsBank, receiverIsBank bool, sender *User, receiver *User, title, description string, amount int) (*TransactionLogEntry, error)
+	ImportTransactions(ctx context.Context, group *Group, transactions []TransactionLogEntry) error
+	PreviewTransaction(ctx context.Context, group *Group, senderIsBank, receiverIsBank bool, sender *User, receiver *User, title, description string, amount int) (*TransactionLogEntry, error)
+	CreateCashTransaction(ctx context.Context, group *Group, user *User, deposit bool, title, description string, cash *CashLogEntry) (*TransactionLogEntry, *CashLogEntry, error)
+	CreateTransactionFromPaymentPlan(ctx context.Context, group *Group, senderIsBank, receiverIsBank bool, sender *User, receiver *User, title, description string, amount int, paymentPlanId string) (*TransactionLogEntry, error)
+
+	CreateInvitation(ctx context.Context, group *Group, user, inviter *User, message string, openingBalance int) (*GroupInvitation, error)
+	GetInvitationById(ctx context.Context, id string) (*GroupInvitation, error)
+	GetInvitationsByGroup(ctx context.Context, group *Group, page, pageSize int, oldestFirst bool) ([]GroupInvitation, error)
+	InvitationCountByGroup(ctx context.Context, group *Group) (int64, error)
+	GetInvitationsByUser(ctx context.Context, user *User, page, pageSize int, oldestFirst bool) ([]GroupInvitation, error)
+	InvitationCountByUser(ctx context.Context, user *User) (int64, error)
+	UnseenInvitationCountByUser(ctx context.Context, user *User) (int64, error)
+	MarkInvitationsAsSeen(ctx context.Context, invitations []GroupInvitation) error
+	MarkAllInvitationsAsSeen(ctx context.Context, user *User) (int64, error)
+	GetInvitationByGroupAndUser(ctx context.Context, group *Group, user *User) (*GroupInvitation, error)
+	DeleteInvitation(ctx context.Context, invitation *GroupInvitation) error
+	AcceptInvitation(ctx context.Context, invitation *GroupInvitation, group *Group, user *User, openingBalanceTitle string) (bool, error)
+	GetDeletedInvitationById(ctx context.Context, id string) (*GroupInvitation, error)
+	UpdateInvitationLastSent(ctx context.Context, invitation *GroupInvitation, lastSent int64) error
+
+	GetPaymentPlans(ctx context.Context, group *Group, user *User, searchInput string, filter PaymentPlanFilter, page, pageSize int, descending bool) ([]PaymentPlan, error)
+	PaymentPlanCount(ctx context.Context, group *Group, user *User, filter PaymentPlanFilter) (int64, error)
+	GetBankPaymentPlans(ctx context.Context, group *Group, searchInput string, page, pageSize int, descending bool) ([]PaymentPlan, error)
+	BankPaymentPlanCount(ctx context.Context, group *Group) (int64, error)
+	ActivePaymentPlanCount(ctx context.Context, group *Group) (int64, error)
+	GetPaymentPlansThatNeedToBeExecuted(ctx context.Context) ([]PaymentPlan, error)
+	GetUpcomingPaymentPlans(ctx context.Context, group *Group, user *User, until int64, limit int) ([]PaymentPlan, error)
+	GetPaymentPlanById(ctx context.Context, group *Group, id string) (*PaymentPlan, error)
+	CreatePaymentPlan(ctx context.Context, group *Group, senderIsBank, receiverIsBank bool, sender *User, receiver *User, name, description string, amount, repeats, schedule int, scheduleUnit string, firstPayment int64, timeZone string) (*PaymentPlan, error)
+	CreatePaymentPlans(ctx context.Context, group *Group, senders []User, receiverIsBank bool, receiver *User, name, description string, amount, repeats, schedule int, scheduleUnit string, firstPayment int64, timeZone string) ([]PaymentPlan, error)
+	UpdatePaymentPlan(ctx context.Context, paymentPlan *PaymentPlan, fields ...string) error
+	DeletePaymentPlan(ctx context.Context, paymentPlan *PaymentPlan) error
+	AddPaymentPlanExecution(ctx context.Context, execution *PaymentPlanExecution) error
+	ExecutePaymentPlan(ctx context.Context, group *Group, paymentPlan *PaymentPlan, sender, receiver *User, title, description string) (*TransactionLogEntry, error)
+	SkipPaymentPlanExecution(ctx context.Context, group *Group, paymentPlan *PaymentPlan) error
+	GetPaymentPlanExecutions(ctx context.Context, paymentPlan *PaymentPlan, page, pageSize int, oldestFirst bool) ([]PaymentPlanExecution, error)
+	PaymentPlanExecutionCount(ctx context.Context, paymentPlan *PaymentPlan) (int64, error)
+	PaymentPlanTransactionCount(ctx context.Context, paymentPlan *PaymentPlan) (int64, error)
+
+	GetTotalMoney(ctx context.Context, group *Group) (int, error)
+	GetBalancesByUser(ctx context.Context, user *User) ([]GroupBalance, error)
+	GetTotalBalanceByUser(ctx context.Context, user *User) (int, error)
+	CountAll(ctx context.Context) (int64, error)
+	GetTotalBalance(ctx context.Context) (int, error)
+
+	AreInSameGroup(ctx context.Context, userId1, userId2 string) (bool, error)
+
+	GetActivity(ctx context.Context, user *User, beforeTime int64, beforeId string, limit int) ([]Activity, error)
+
+	AddAuditLogEntry(ctx context.Context, entry *GroupAuditLogEntry) error
+	GetAuditLog(ctx context.Context, group *Group, page, pageSize int, oldestFirst bool) ([]GroupAuditLogEntry, error)
+	AuditLogEntryCount(ctx context.Context, group *Group) (int64, error)
 }
 
+// Default display label of group amounts. Units are purely cosmetic, amounts are always integers.
+const DefaultGroupUnit = "€"
+
+// Roles of users in a group. Admins have the admin role even if they are members as well.
+const (
+	GroupRoleAdmin  = "admin"
+	GroupRoleMember = "member"
+)
+
+// Default name of the bank side of transactions, translated where it is shown to users.
+const DefaultBankName = "Bank"
+
 type Group struct {
 	Base
-	Name           string
-	Description    string
+	Name        string
+	Description string
+	GroupSettings
 	GroupPicture   *GroupPicture `gorm:"constraint:OnDelete:CASCADE"`
 	GroupPictureId string
 
+	// Set while a deletion requested by an admin waits for the confirmation of another admin
+	DeletionRequestedBy string
+	DeletionExpires     int64
+
+	// Number of transactions which received a reference, see TransactionReference
+	TransactionCount int
+
 	Memberships []GroupMembership
 	Invitations []GroupInvitation
 }
 
+// Options of a group which are read and updated together.
+type GroupSettings struct {
+	Unit string `gorm:"default:€"`
+	// Lowest balance members can reach by sending money
+	MinBalance int
+	// Highest balance members can reach by receiving money, 0 for no limit
+	MaxBalance int
+	// Reject transactions which would push a sending member below MinBalance. Without it MinBalance isn't enforced.
+	// The balance is checked while the sender is locked, so concurrent transactions can't overdraw it together.
+	OverdraftProtection bool
+	// Notify members when their balance drops below LowBalanceThreshold
+	LowBalanceAlert     bool
+	LowBalanceThreshold int
+	// What happens to the balance of members leaving the group, one of the LeaveBalance constants
+	LeaveBalancePolicy string `gorm:"default:keep"`
+	// Shown as the name of the bank side of transactions
+	BankName string `gorm:"default:Bank"`
+}
+
+const (
+	// The balance of the member stays in the transaction log
+	LeaveBalanceKeep = "keep"
+	// The balance is settled with the bank
+	LeaveBalanceBank = "bank"
+	// The balance is split between the remaining members, or settled with the bank if there are none
+	LeaveBalanceRedistribute = "redistribute"
+)
+
 type GroupPicture struct {
 	Base
 
@@ -107,6 +218,56 @@ type GroupInvitation struct {
 	Message   string
 	GroupId   string
 	UserId    string
+	// Admin who created the invitation, empty for older invitations
+	InviterId string
+	Seen      bool
+	// Booked as a transaction between the bank and the user when the invitation is accepted
+	OpeningBalance int
+	// Time of the last invitation email, used to limit how often it can be resent
+	LastSent int64
+	// Accepted and denied invitations are only soft deleted to recognize repeated requests
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+type AdminChange struct {
+	Base
+	GroupId   string
+	GroupName string
+	UserId    string
+	Granted   bool
+}
+
+// Change of the total balance of all members of a group on one day, caused by transactions with the bank.
+type DailyBalanceChange struct {
+	// Days since the unix epoch (UTC)
+	Day    int64
+	Change int
+}
+
+// Sums of the amounts a user sent to and received from another party of a group.
+type CounterpartyTotals struct {
+	// Id of the other party, "bank" for the bank
+	CounterpartyId string
+	Sent           int
+	Received       int
+}
+
+// Outcome of a user leaving one of their groups.
+type LeftGroup struct {
+	GroupId   string
+	GroupName string
+	// Balance of the user when leaving
+	Balance int
+	// Whether the balance was transferred according to Group.LeaveBalancePolicy
+	Settled bool
+}
+
+// Balance of a user in one of their groups.
+type GroupBalance struct {
+	GroupId   string
+	GroupName string
+	Unit      string
+	Balance   int
 }
 
 type TransactionLogEntry struct {
@@ -116,6 +277,11 @@ type TransactionLogEntry struct {
 	Amount      int
 
 	GroupId string
+	// Human-friendly sequential reference unique within the group, e.g. 3F2A9C1B-0001
+	Reference string
+	// Position of the transaction in the group, the number of the reference. The balances of a user are the ones of their
+	// transaction with the highest sequence number, creation times aren't unique.
+	Sequence int
 
 	SenderIsBank            bool
 	SenderId                string
@@ -130,6 +296,19 @@ type TransactionLogEntry struct {
 	PaymentPlanId string
 }
 
+// Builds the reference of the n-th transaction of the group from the first characters of the group id.
+func TransactionReference(groupId string, n int) string {
+	return fmt.Sprintf("%s-%04d", strings.ToUpper(groupId[:min(8, len(groupId))]), n)
+}
+
+// Restricts the transactions of a user. Empty fields match all transactions.
+type TransactionFilter struct {
+	// Id of the other party of the transaction, "bank" for the bank
+	CounterpartyId string
+	MinAmount      int
+	MaxAmount      int
+}
+
 const (
 	ScheduleUnitDay   = "day"
 	ScheduleUnitWeek  = "week"
@@ -146,10 +325,15 @@ type PaymentPlan struct {
 
 	// negative payment count for unlimited payments
 	PaymentCount int
+	// Number of payments the payment plan was configured with, negative for unlimited payments.
+	// Unlike PaymentCount it isn't decreased by executions.
+	TotalPaymentCount int
 
 	NextExecute  int64
 	Schedule     int
 	ScheduleUnit string
+	// IANA time zone, e.g. "Europe/Berlin", in whose midnight the payments are executed. UTC if empty.
+	TimeZone string
 
 	SenderIsBank bool
 	SenderId     string
@@ -159,3 +343,66 @@ type PaymentPlan struct {
 
 	GroupId string
 }
+
+const (
+	PaymentPlanDirectionSending   = "sending"
+	PaymentPlanDirectionReceiving = "receiving"
+)
+
+// Restricts the payment plans of a user. Empty fields match all payment plans.
+type PaymentPlanFilter struct {
+	// PaymentPlanDirectionSending or PaymentPlanDirectionReceiving
+	Direction string
+	// Id of the other party of the payment plan, "bank" for the bank
+	CounterpartyId string
+}
+
+// An executed or skipped payment of a payment plan.
+type PaymentPlanExecution struct {
+	Base
+	PaymentPlanId string
+	GroupId       string
+	// Time the payment was scheduled for
+	ScheduledFor int64
+	Skipped      bool
+	// Empty if the payment was skipped
+	TransactionId string
+}
+
+const (
+	ActivityTransaction = "transaction"
+	ActivityInvitation  = "invitation"
+	ActivityAdminChange = "adminChange"
+)
+
+// An entry in a user's activity feed. Exactly one of Transaction, Invitation and AdminChange is set depending on Type.
+type Activity struct {
+	Type string
+	Time int64
+	Id   string
+
+	Transaction *TransactionLogEntry
+	Invitation  *GroupInvitation
+	AdminChange *AdminChange
+}
+
+const (
+	AuditMemberRemoved     = "memberRemoved"
+	AuditDeletionRequested = "deletionRequested"
+	AuditDeletionCancelled = "deletionCancelled"
+
+	// The balance of a leaving or removed member was settled according to Group.LeaveBalancePolicy
+	AuditBalanceSettled       = "balanceSettled"
+	AuditBalanceRedistributed = "balanceRedistributed"
+)
+
+// Records administrative actions in a group.
+type GroupAuditLogEntry struct {
+	Base
+	GroupId    string
+	Action     string
+	ActorId    string
+	ActorName  string
+	TargetId   string
+	TargetName string
+}
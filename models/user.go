@@ -1,21 +1,45 @@
 package models
 
+import (
+	"context"
+	"errors"
+
+	"github.com/juho05/h-bank/config"
+)
+
+// Returned when more coins or notes of a kind are deposited than the user owns according to their cash log.
+var ErrNotEnoughCash = errors.New("not enough cash")
+
 type UserStore interface {
-	GetAll(exclude []string, searchInput string, page, pageSize int, descending bool) ([]User, error)
-	Count() (int64, error)
-	GetById(id string) (*User, error)
-	GetByEmail(email string) (*User, error)
-	Create(user *User) error
-	Update(user *User) error
-	Delete(user *User) error
-	DeleteById(id string) error
-	DeleteByEmail(email string) error
-
-	GetCashLog(user *User, searchInput string, page, pageSize int, oldestFirst bool) ([]CashLogEntry, error)
-	CashLogEntryCount(user *User) (int64, error)
-	GetLastCashLogEntry(user *User) (*CashLogEntry, error)
-	GetCashLogEntryById(user *User, id string) (*CashLogEntry, error)
-	AddCashLogEntry(user *User, entry *CashLogEntry) error
+	GetAll(ctx context.Context, exclude []string, searchInput string, page, pageSize int, descending bool) ([]User, error)
+	Count(ctx context.Context) (int64, error)
+	CountAll(ctx context.Context) (int64, error)
+	GetById(ctx context.Context, id string) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	Create(ctx context.Context, user *User) error
+	Update(ctx context.Context, user *User) error
+	UpdateProfile(ctx context.Context, user *User, name, email string) error
+	Delete(ctx context.Context, user *User) error
+	DeleteById(ctx context.Context, id string) error
+	DeleteByEmail(ctx context.Context, email string) error
+	UpdateLastLogin(ctx context.Context, user *User, lastLogin int64, lang string) error
+	InitLastLogin(ctx context.Context, lastLogin int64) error
+	GetInactiveUsers(ctx context.Context, lastLoginBefore int64) ([]User, error)
+	UpdateInactivityWarningSent(ctx context.Context, user *User, warningSent int64) error
+
+	GetCashLog(ctx context.Context, user *User, searchInput string, page, pageSize int, oldestFirst bool) ([]CashLogEntry, error)
+	CashLogEntryCount(ctx context.Context, user *User) (int64, error)
+	GetLastCashLogEntry(ctx context.Context, user *User) (*CashLogEntry, error)
+	GetCashLogEntryById(ctx context.Context, user *User, id string) (*CashLogEntry, error)
+	AddCashLogEntry(ctx context.Context, user *User, entry *CashLogEntry) error
+	DeleteCashLog(ctx context.Context, user *User) error
+	GetTotalCash(ctx context.Context) (int, error)
+
+	CreateFailedEmail(ctx context.Context, failedEmail *FailedEmail) error
+	GetFailedEmails(ctx context.Context, page, pageSize int, oldestFirst bool) ([]FailedEmail, error)
+	FailedEmailCount(ctx context.Context) (int64, error)
+	GetFailedEmailById(ctx context.Context, id string) (*FailedEmail, error)
+	DeleteFailedEmail(ctx context.Context, failedEmail *FailedEmail) error
 }
 
 type User struct {
@@ -24,9 +48,19 @@ type User struct {
 	Email                   string `gorm:"unique"`
 	PubliclyVisible         bool   `gorm:"default:true"`
 	DontSendInvitationEmail bool
-	CashLog                 []CashLogEntry
-	GroupMemberships        []GroupMembership
-	GroupInvitations        []GroupInvitation
+	SendReceiptEmail        bool
+	// Whether chronological lists are sorted newest first if the request doesn't specify the order
+	DefaultSortDescending bool `gorm:"default:true"`
+	CashLog               []CashLogEntry
+	GroupMemberships      []GroupMembership
+	GroupInvitations      []GroupInvitation
+
+	// Unix time of the last login, 0 if the user didn't log in since logins are tracked
+	LastLogin int64
+	// Language of the last login, used for emails which aren't sent in response to a request of the user
+	Language string `gorm:"default:en"`
+	// Time of the warning that the account will be deleted because of inactivity, 0 if the user wasn't warned
+	InactivityWarningSent int64
 }
 
 type CashLogEntry struct {
@@ -54,4 +88,44 @@ type CashLogEntry struct {
 	Eur500 int
 
 	UserId string
+	// Position of the entry in the cash log of the user. The current cash is the entry with the highest sequence number,
+	// entries created before sequence numbers were introduced have 0 and are ordered by their creation time.
+	Sequence int
+}
+
+// Keys of the coin and note fields from the highest to the lowest value of the default (euro) denominations.
+// The configured denominations are assigned to the fields in this order.
+var CashDenominationKeys = []string{"eur500", "eur200", "eur100", "eur50", "eur20", "eur10", "eur5", "eur2", "eur1", "ct50", "ct20", "ct10", "ct5", "ct2", "ct1"}
+
+// Returns pointers to the counts of the coins and notes in the order of CashDenominationKeys.
+func (entry *CashLogEntry) Counts() []*int {
+	return []*int{
+		&entry.Eur500, &entry.Eur200, &entry.Eur100, &entry.Eur50, &entry.Eur20, &entry.Eur10, &entry.Eur5, &entry.Eur2, &entry.Eur1,
+		&entry.Ct50, &entry.Ct20, &entry.Ct10, &entry.Ct5, &entry.Ct2, &entry.Ct1,
+	}
+}
+
+// Returns the value of the coins and notes in the minor unit of the configured currency.
+func (entry *CashLogEntry) Value() int {
+	return entry.ValueIn(config.Data.CashDenominations)
+}
+
+// Returns the value of the coins and notes with the given denomination values in the order of CashDenominationKeys.
+func (entry *CashLogEntry) ValueIn(values []int) int {
+	totalAmount := 0
+	for i, count := range entry.Counts() {
+		if i < len(values) {
+			totalAmount += values[i] * *count
+		}
+	}
+	return totalAmount
+}
+
+// A critical email which could not be delivered after all retry attempts.
+type FailedEmail struct {
+	Base
+	Addresses string // comma separated
+	Subject   string
+	Message   []byte
+	Reason    string
 }
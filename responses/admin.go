@@ -0,0 +1,60 @@
+package responses
+
+import (
+	"strings"
+
+	"github.com/juho05/h-bank/models"
+)
+
+type failedEmail struct {
+	Id        string   `json:"id"`
+	Created   int64    `json:"created"`
+	Addresses []string `json:"addresses"`
+	Subject   string   `json:"subject"`
+	Reason    string   `json:"reason"`
+}
+
+func NewFailedEmails(failedEmails []models.FailedEmail, count int64) interface{} {
+	dtos := make([]failedEmail, len(failedEmails))
+	for i, f := range failedEmails {
+		dtos[i].Id = f.Id
+		dtos[i].Created = f.Created
+		dtos[i].Addresses = strings.Split(f.Addresses, ",")
+		dtos[i].Subject = f.Subject
+		dtos[i].Reason = f.Reason
+	}
+
+	type failedEmailsResp struct {
+		Base
+		Count        int64         `json:"count"`
+		FailedEmails []failedEmail `json:"failedEmails"`
+	}
+
+	return failedEmailsResp{
+		Base: Base{
+			Success: true,
+		},
+		Count:        count,
+		FailedEmails: dtos,
+	}
+}
+
+func NewStats(userCount, groupCount int64, cash, balance int) interface{} {
+	type statsResp struct {
+		Base
+		UserCount    int64 `json:"userCount"`
+		GroupCount   int64 `json:"groupCount"`
+		TotalCash    int   `json:"totalCash"`
+		TotalBalance int   `json:"totalBalance"`
+	}
+
+	return statsResp{
+		Base: Base{
+			Success: true,
+		},
+		UserCount:    userCount,
+		GroupCount:   groupCount,
+		TotalCash:    cash,
+		TotalBalance: balance,
+	}
+}
@@ -1,6 +1,9 @@
 package responses
 
 import (
+	"encoding/base64"
+	"time"
+
 	"github.com/google/uuid"
 
 	"github.com/juho05/h-bank/models"
@@ -22,10 +25,79 @@ type PaymentPlanExecutionTimes struct {
 	ExecutionTimes []int64 `json:"executionTimes"`
 }
 
+type ImportError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+func NewTransactionImport(count int, dryRun bool, errors []ImportError) interface{} {
+	type transactionImportResp struct {
+		Base
+		Count  int           `json:"count"`
+		DryRun bool          `json:"dryRun"`
+		Errors []ImportError `json:"errors,omitempty"`
+	}
+
+	return transactionImportResp{
+		Base: Base{
+			Success: len(errors) == 0,
+		},
+		Count:  count,
+		DryRun: dryRun,
+		Errors: errors,
+	}
+}
+
+type TimeSeriesPoint struct {
+	// Start of the interval
+	Time int64 `json:"time"`
+	// Total balance of all members at the end of the interval
+	Balance int `json:"balance"`
+}
+
+func NewTimeSeries(interval string, points []TimeSeriesPoint) interface{} {
+	type timeSeriesResp struct {
+		Base
+		Interval string            `json:"interval"`
+		Points   []TimeSeriesPoint `json:"points"`
+	}
+
+	return timeSeriesResp{
+		Base: Base{
+			Success: true,
+		},
+		Interval: interval,
+		Points:   points,
+	}
+}
+
+type PaymentPlanEstimate struct {
+	Base
+	// Amount per execution
+	Amount int `json:"amount"`
+	// Total amount of all executions, equal to amount for indefinite payment plans
+	Total        int   `json:"total"`
+	FinalPayment int64 `json:"finalPayment,omitempty"`
+	Indefinite   bool  `json:"indefinite"`
+}
+
+func NewPaymentPlanEstimate(amount, total int, finalPayment int64, indefinite bool) PaymentPlanEstimate {
+	return PaymentPlanEstimate{
+		Base: Base{
+			Success: true,
+		},
+		Amount:       amount,
+		Total:        total,
+		FinalPayment: finalPayment,
+		Indefinite:   indefinite,
+	}
+}
+
 type group struct {
 	Id             string `json:"id"`
 	Name           string `json:"name"`
 	Description    string `json:"description"`
+	Unit           string `json:"unit"`
 	GroupPictureId string `json:"groupPictureId"`
 }
 
@@ -33,9 +105,13 @@ type groupDetailed struct {
 	Id             string `json:"id"`
 	Name           string `json:"name"`
 	Description    string `json:"description"`
+	Unit           string `json:"unit"`
 	GroupPictureId string `json:"groupPictureId"`
 	Member         bool   `json:"member"`
 	Admin          bool   `json:"admin"`
+
+	DeletionRequestedBy string `json:"deletionRequestedBy,omitempty"`
+	DeletionExpires     int64  `json:"deletionExpires,omitempty"`
 }
 
 type transaction struct {
@@ -44,13 +120,18 @@ type transaction struct {
 	Title       string `json:"title"`
 	Description string `json:"description,omitempty"`
 
-	GroupId string `json:"groupId"`
+	GroupId   string `json:"groupId"`
+	Reference string `json:"reference"`
 
 	Amount     int `json:"amount"`
 	NewBalance int `json:"newBalance"`
 
-	SenderId   string `json:"senderId"`
-	ReceiverId string `json:"receiverId"`
+	SenderId       string `json:"senderId"`
+	ReceiverId     string `json:"receiverId"`
+	SenderName     string `json:"senderName,omitempty"`
+	ReceiverName   string `json:"receiverName,omitempty"`
+	SenderIsBank   bool   `json:"senderIsBank"`
+	ReceiverIsBank bool   `json:"receiverIsBank"`
 
 	PaymentPlanId string `json:"paymentPlanId,omitempty"`
 }
@@ -62,10 +143,15 @@ type bankTransaction struct {
 	Description string `json:"description,omitempty"`
 	Amount      int    `json:"amount"`
 
-	GroupId string `json:"groupId"`
+	GroupId   string `json:"groupId"`
+	Reference string `json:"reference"`
 
-	SenderId   string `json:"senderId"`
-	ReceiverId string `json:"receiverId"`
+	SenderId       string `json:"senderId"`
+	ReceiverId     string `json:"receiverId"`
+	SenderName     string `json:"senderName,omitempty"`
+	ReceiverName   string `json:"receiverName,omitempty"`
+	SenderIsBank   bool   `json:"senderIsBank"`
+	ReceiverIsBank bool   `json:"receiverIsBank"`
 
 	PaymentPlanId string `json:"paymentPlanId,omitempty"`
 }
@@ -80,6 +166,8 @@ type paymentPlan struct {
 
 	Schedule     int    `json:"schedule"`
 	ScheduleUnit string `json:"scheduleUnit"`
+	// Empty for UTC
+	TimeZone string `json:"timeZone"`
 
 	GroupId string `json:"groupId"`
 
@@ -96,6 +184,9 @@ type invitation struct {
 	GroupName         string `json:"groupName,omitempty"`
 	GroupId           string `json:"groupId,omitempty"`
 	UserId            string `json:"userId,omitempty"`
+	Seen              bool   `json:"seen"`
+	OpeningBalance    int    `json:"openingBalance"`
+	LastSent          int64  `json:"lastSent,omitempty"`
 }
 
 type groupUser struct {
@@ -115,6 +206,8 @@ func NewInvitations(invitations []models.GroupInvitation, count int64) interface
 		dtos[i].UserId = in.UserId
 		dtos[i].GroupName = in.GroupName
 		dtos[i].GroupId = in.GroupId
+		dtos[i].Seen = in.Seen
+		dtos[i].OpeningBalance = in.OpeningBalance
 	}
 
 	type invitationsResp struct {
@@ -132,6 +225,30 @@ func NewInvitations(invitations []models.GroupInvitation, count int64) interface
 	}
 }
 
+func NewUnseenInvitationCount(count int64) interface{} {
+	type unseenCount struct {
+		Base
+		Count int64 `json:"count"`
+	}
+	return unseenCount{
+		Base: Base{
+			Success: true,
+		},
+		Count: count,
+	}
+}
+
+func NewInvitationsMarkedAsSeen(count int64, lang string) interface{} {
+	type markedCount struct {
+		Base
+		Count int64 `json:"count"`
+	}
+	return markedCount{
+		Base:  New(true, "Successfully marked invitations as seen", lang),
+		Count: count,
+	}
+}
+
 func NewInvitation(invitationModel *models.GroupInvitation) interface{} {
 	type invitationResp struct {
 		Base
@@ -149,7 +266,63 @@ func NewInvitation(invitationModel *models.GroupInvitation) interface{} {
 			GroupName:         invitationModel.GroupName,
 			GroupId:           invitationModel.GroupId,
 			UserId:            invitationModel.UserId,
+			Seen:              invitationModel.Seen,
+			OpeningBalance:    invitationModel.OpeningBalance,
+			LastSent:          invitationModel.LastSent,
+		},
+	}
+}
+
+// inviter is nil if the invitation wasn't created by an admin or the admin no longer exists.
+func NewInvitationPreview(invitationModel *models.GroupInvitation, groupModel *models.Group, inviter *models.User) interface{} {
+	type inviterDTO struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	type previewResp struct {
+		Base
+		Id                string      `json:"id"`
+		InvitationMessage string      `json:"invitationMessage"`
+		OpeningBalance    int         `json:"openingBalance"`
+		Group             group       `json:"group"`
+		Inviter           *inviterDTO `json:"inviter,omitempty"`
+	}
+
+	resp := previewResp{
+		Base: Base{
+			Success: true,
 		},
+		Id:                invitationModel.Id,
+		InvitationMessage: invitationModel.Message,
+		OpeningBalance:    invitationModel.OpeningBalance,
+		Group: group{
+			Id:             groupModel.Id,
+			Name:           groupModel.Name,
+			Description:    groupModel.Description,
+			Unit:           groupModel.Unit,
+			GroupPictureId: groupModel.GroupPictureId,
+		},
+	}
+	if inviter != nil {
+		resp.Inviter = &inviterDTO{
+			Id:   inviter.Id,
+			Name: inviter.Name,
+		}
+	}
+
+	return resp
+}
+
+func NewInvitationResendCooldown(retryAfter int64, lang string) interface{} {
+	type cooldownResp struct {
+		Base
+		RetryAfter int64 `json:"retryAfter"`
+	}
+
+	return cooldownResp{
+		Base:       New(false, "The invitation was sent too recently", lang),
+		RetryAfter: retryAfter,
 	}
 }
 
@@ -159,6 +332,7 @@ func NewGroups(groups []models.Group, count int64) interface{} {
 		groupDTOs[i].Id = g.Id
 		groupDTOs[i].Name = g.Name
 		groupDTOs[i].Description = g.Description
+		groupDTOs[i].Unit = g.Unit
 		groupDTOs[i].GroupPictureId = g.GroupPictureId
 	}
 
@@ -187,23 +361,220 @@ func NewGroup(group *models.Group, isMember, isAdmin bool) interface{} {
 		Base: Base{
 			Success: true,
 		},
-		groupDetailed: groupDetailed{
-			Id:             group.Id,
-			Name:           group.Name,
-			Description:    group.Description,
-			GroupPictureId: group.GroupPictureId,
-			Member:         isMember,
-			Admin:          isAdmin,
+		groupDetailed: newGroupDetailed(group, isMember, isAdmin),
+	}
+}
+
+// Like NewGroup but includes the number of members and admins. maxUsers is 0 if there is no limit.
+func NewGroupDetails(group *models.Group, isMember, isAdmin bool, userCount int64, maxUsers int) interface{} {
+	type groupResp struct {
+		Base
+		groupDetailed
+		UserCount int64 `json:"userCount"`
+		MaxUsers  int   `json:"maxUsers"`
+	}
+
+	return groupResp{
+		Base: Base{
+			Success: true,
+		},
+		groupDetailed: newGroupDetailed(group, isMember, isAdmin),
+		UserCount:     userCount,
+		MaxUsers:      maxUsers,
+	}
+}
+
+func newGroupDetailed(group *models.Group, isMember, isAdmin bool) groupDetailed {
+	groupDTO := groupDetailed{
+		Id:             group.Id,
+		Name:           group.Name,
+		Description:    group.Description,
+		Unit:           group.Unit,
+		GroupPictureId: group.GroupPictureId,
+		Member:         isMember,
+		Admin:          isAdmin,
+	}
+
+	if group.DeletionRequestedBy != "" && group.DeletionExpires > time.Now().Unix() {
+		groupDTO.DeletionRequestedBy = group.DeletionRequestedBy
+		groupDTO.DeletionExpires = group.DeletionExpires
+	}
+
+	return groupDTO
+}
+
+func NewGroupSettings(settings models.GroupSettings) interface{} {
+	type groupSettingsResp struct {
+		Base
+		Unit       string `json:"unit"`
+		MinBalance int    `json:"minBalance"`
+		MaxBalance int    `json:"maxBalance"`
+
+		OverdraftProtection bool `json:"overdraftProtection"`
+
+		LowBalanceAlert     bool `json:"lowBalanceAlert"`
+		LowBalanceThreshold int  `json:"lowBalanceThreshold"`
+
+		LeaveBalancePolicy string `json:"leaveBalancePolicy"`
+
+		BankName string `json:"bankName"`
+	}
+
+	return groupSettingsResp{
+		Base: Base{
+			Success: true,
 		},
+		Unit:                settings.Unit,
+		MinBalance:          settings.MinBalance,
+		MaxBalance:          settings.MaxBalance,
+		OverdraftProtection: settings.OverdraftProtection,
+		LowBalanceAlert:     settings.LowBalanceAlert,
+		LowBalanceThreshold: settings.LowBalanceThreshold,
+		LeaveBalancePolicy:  settings.LeaveBalancePolicy,
+		BankName:            settings.BankName,
 	}
 }
 
-func NewTransaction(transactionModel *models.TransactionLogEntry, user *models.User) interface{} {
+// The role is "admin" for admins (even if they are members as well), "member" for members and "none" otherwise.
+func NewOwnMembership(isMember, isAdmin bool, balance *int) interface{} {
+	type membershipResp struct {
+		Base
+		IsMember bool   `json:"isMember"`
+		IsAdmin  bool   `json:"isAdmin"`
+		Role     string `json:"role"`
+		Balance  *int   `json:"balance,omitempty"`
+	}
+
+	role := "none"
+	if isAdmin {
+		role = "admin"
+	} else if isMember {
+		role = "member"
+	}
+
+	return membershipResp{
+		Base: Base{
+			Success: true,
+		},
+		IsMember: isMember,
+		IsAdmin:  isAdmin,
+		Role:     role,
+		Balance:  balance,
+	}
+}
+
+func NewPicture(contentType string, data []byte) interface{} {
+	type pictureResp struct {
+		Base
+		ContentType string `json:"contentType"`
+		Data        string `json:"data"`
+	}
+
+	return pictureResp{
+		Base: Base{
+			Success: true,
+		},
+		ContentType: contentType,
+		Data:        base64.StdEncoding.EncodeToString(data),
+	}
+}
+
+// former maps user ids to whether the user is no longer a member of the group.
+func NewGroupMembers(members []models.User, former map[string]bool, count int64) interface{} {
+	type member struct {
+		Id     string `json:"id"`
+		Name   string `json:"name"`
+		Former bool   `json:"former"`
+	}
+
+	memberDTOs := make([]member, len(members))
+	for i, m := range members {
+		memberDTOs[i] = member{
+			Id:     m.Id,
+			Name:   m.Name,
+			Former: former[m.Id],
+		}
+	}
+
+	type membersResp struct {
+		Base
+		Count int64    `json:"count"`
+		Users []member `json:"users"`
+	}
+
+	return membersResp{
+		Base: Base{
+			Success: true,
+		},
+		Count: count,
+		Users: memberDTOs,
+	}
+}
+
+// balance and recentTransactions are omitted if they are nil.
+func NewGroupMember(membership *models.GroupMembership, member *models.User, balance *int, recentTransactions []models.TransactionLogEntry, names map[string]string) interface{} {
+	type groupMemberResp struct {
+		Base
+		Id                 string        `json:"id"`
+		Name               string        `json:"name"`
+		Member             bool          `json:"member"`
+		Admin              bool          `json:"admin"`
+		Joined             int64         `json:"joined"`
+		Balance            *int          `json:"balance,omitempty"`
+		RecentTransactions []transaction `json:"recentTransactions,omitempty"`
+	}
+
+	var transactionDTOs []transaction
+	if recentTransactions != nil {
+		transactionDTOs = newTransactionDTOs(recentTransactions, member, names)
+	}
+
+	return groupMemberResp{
+		Base: Base{
+			Success: true,
+		},
+		Id:                 member.Id,
+		Name:               member.Name,
+		Member:             membership.IsMember,
+		Admin:              membership.IsAdmin,
+		Joined:             membership.Created,
+		Balance:            balance,
+		RecentTransactions: transactionDTOs,
+	}
+}
+
+// names maps user ids to the names of the sender and receiver (see GroupStore.GetUserNames).
+func NewTransaction(transactionModel *models.TransactionLogEntry, user *models.User, names map[string]string) interface{} {
 	type transactionResp struct {
 		Base
 		transaction
 	}
 
+	return transactionResp{
+		Base: Base{
+			Success: true,
+		},
+		transaction: newTransactionDTO(transactionModel, user, names),
+	}
+}
+
+func NewCashTransaction(transactionModel *models.TransactionLogEntry, cashLogEntry *models.CashLogEntry, user *models.User, names map[string]string) interface{} {
+	type cashTransactionResp struct {
+		Base
+		Transaction  transaction          `json:"transaction"`
+		CashLogEntry CashLogEntryDetailed `json:"cashLogEntry"`
+	}
+
+	return cashTransactionResp{
+		Base: Base{
+			Success: true,
+		},
+		Transaction:  newTransactionDTO(transactionModel, user, names),
+		CashLogEntry: newCashLogEntryDetailed(cashLogEntry),
+	}
+}
+
+func newTransactionDTO(transactionModel *models.TransactionLogEntry, user *models.User, names map[string]string) transaction {
 	isSender := user.Id == transactionModel.SenderId
 
 	newBalance := transactionModel.NewBalanceReceiver
@@ -219,36 +590,117 @@ func NewTransaction(transactionModel *models.TransactionLogEntry, user *models.U
 		Amount:      transactionModel.Amount,
 		NewBalance:  newBalance,
 		GroupId:     transactionModel.GroupId,
+		Reference:   transactionModel.Reference,
 	}
 
 	if transactionModel.ReceiverIsBank {
 		transactionDTO.ReceiverId = "bank"
+		transactionDTO.ReceiverIsBank = true
 	} else {
 		transactionDTO.ReceiverId = transactionModel.ReceiverId
 	}
 
 	if transactionModel.SenderIsBank {
 		transactionDTO.SenderId = "bank"
+		transactionDTO.SenderIsBank = true
 	} else {
 		transactionDTO.SenderId = transactionModel.SenderId
 	}
 
 	transactionDTO.PaymentPlanId = transactionModel.PaymentPlanId
+	transactionDTO.SenderName = names[transactionDTO.SenderId]
+	transactionDTO.ReceiverName = names[transactionDTO.ReceiverId]
 
-	return transactionResp{
+	return transactionDTO
+}
+
+func NewTransactionPreview(transactionModel *models.TransactionLogEntry, names map[string]string) interface{} {
+	type transactionPreviewResp struct {
+		Base
+		DryRun bool `json:"dryRun"`
+
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+		Amount      int    `json:"amount"`
+		GroupId     string `json:"groupId"`
+
+		SenderId           string `json:"senderId"`
+		ReceiverId         string `json:"receiverId"`
+		SenderName         string `json:"senderName,omitempty"`
+		ReceiverName       string `json:"receiverName,omitempty"`
+		SenderIsBank       bool   `json:"senderIsBank"`
+		ReceiverIsBank     bool   `json:"receiverIsBank"`
+		NewBalanceSender   int    `json:"newBalanceSender"`
+		NewBalanceReceiver int    `json:"newBalanceReceiver"`
+	}
+
+	senderId := transactionModel.SenderId
+	if transactionModel.SenderIsBank {
+		senderId = "bank"
+	}
+	receiverId := transactionModel.ReceiverId
+	if transactionModel.ReceiverIsBank {
+		receiverId = "bank"
+	}
+
+	return transactionPreviewResp{
 		Base: Base{
 			Success: true,
 		},
-		transaction: transactionDTO,
+		DryRun:             true,
+		Title:              transactionModel.Title,
+		Description:        transactionModel.Description,
+		Amount:             transactionModel.Amount,
+		GroupId:            transactionModel.GroupId,
+		SenderId:           senderId,
+		ReceiverId:         receiverId,
+		SenderName:         names[senderId],
+		ReceiverName:       names[receiverId],
+		SenderIsBank:       transactionModel.SenderIsBank,
+		ReceiverIsBank:     transactionModel.ReceiverIsBank,
+		NewBalanceSender:   transactionModel.NewBalanceSender,
+		NewBalanceReceiver: transactionModel.NewBalanceReceiver,
 	}
 }
 
-func NewBankTransaction(transactionModel *models.TransactionLogEntry) interface{} {
+func NewBankTransaction(transactionModel *models.TransactionLogEntry, names map[string]string) interface{} {
 	type transactionResp struct {
 		Base
 		bankTransaction
 	}
 
+	return transactionResp{
+		Base: Base{
+			Success: true,
+		},
+		bankTransaction: newBankTransactionDTO(transactionModel, names),
+	}
+}
+
+// Contains the balances of both parties, which are only visible to admins.
+func NewFullTransaction(transactionModel *models.TransactionLogEntry, names map[string]string) interface{} {
+	type fullTransactionResp struct {
+		Base
+		bankTransaction
+		NewBalanceSender          int `json:"newBalanceSender"`
+		BalanceDifferenceSender   int `json:"balanceDifferenceSender"`
+		NewBalanceReceiver        int `json:"newBalanceReceiver"`
+		BalanceDifferenceReceiver int `json:"balanceDifferenceReceiver"`
+	}
+
+	return fullTransactionResp{
+		Base: Base{
+			Success: true,
+		},
+		bankTransaction:           newBankTransactionDTO(transactionModel, names),
+		NewBalanceSender:          transactionModel.NewBalanceSender,
+		BalanceDifferenceSender:   transactionModel.BalanceDifferenceSender,
+		NewBalanceReceiver:        transactionModel.NewBalanceReceiver,
+		BalanceDifferenceReceiver: transactionModel.BalanceDifferenceReceiver,
+	}
+}
+
+func newBankTransactionDTO(transactionModel *models.TransactionLogEntry, names map[string]string) bankTransaction {
 	transactionDTO := bankTransaction{
 		Id:          transactionModel.Id,
 		Time:        transactionModel.Created,
@@ -256,37 +708,31 @@ func NewBankTransaction(transactionModel *models.TransactionLogEntry) interface{
 		Description: transactionModel.Description,
 		Amount:      transactionModel.Amount,
 		GroupId:     transactionModel.GroupId,
+		Reference:   transactionModel.Reference,
 	}
 
 	if transactionModel.ReceiverIsBank {
 		transactionDTO.ReceiverId = "bank"
+		transactionDTO.ReceiverIsBank = true
 	} else {
 		transactionDTO.ReceiverId = transactionModel.ReceiverId
 	}
 
 	if transactionModel.SenderIsBank {
 		transactionDTO.SenderId = "bank"
+		transactionDTO.SenderIsBank = true
 	} else {
 		transactionDTO.SenderId = transactionModel.SenderId
 	}
 
 	transactionDTO.PaymentPlanId = transactionModel.PaymentPlanId
+	transactionDTO.SenderName = names[transactionDTO.SenderId]
+	transactionDTO.ReceiverName = names[transactionDTO.ReceiverId]
 
-	return transactionResp{
-		Base: Base{
-			Success: true,
-		},
-		bankTransaction: transactionDTO,
-	}
+	return transactionDTO
 }
 
-func NewTransactionLog(log []models.TransactionLogEntry, user *models.User, count int64) interface{} {
-	type transactionsResp struct {
-		Base
-		Count        int64         `json:"count"`
-		Transactions []transaction `json:"transactions"`
-	}
-
+func newTransactionDTOs(log []models.TransactionLogEntry, user *models.User, names map[string]string) []transaction {
 	transactionDTOs := make([]transaction, len(log))
 
 	for i, entry := range log {
@@ -304,41 +750,122 @@ func NewTransactionLog(log []models.TransactionLogEntry, user *models.User, coun
 			Amount:     entry.Amount,
 			NewBalance: newBalance,
 			GroupId:    entry.GroupId,
+			Reference:  entry.Reference,
 		}
 
 		if entry.ReceiverIsBank {
 			transactionDTO.ReceiverId = "bank"
+			transactionDTO.ReceiverIsBank = true
 		} else {
 			transactionDTO.ReceiverId = entry.ReceiverId
 		}
 
 		if entry.SenderIsBank {
 			transactionDTO.SenderId = "bank"
+			transactionDTO.SenderIsBank = true
 		} else {
 			transactionDTO.SenderId = entry.SenderId
 		}
 
 		transactionDTO.PaymentPlanId = entry.PaymentPlanId
+		transactionDTO.SenderName = names[transactionDTO.SenderId]
+		transactionDTO.ReceiverName = names[transactionDTO.ReceiverId]
 
 		transactionDTOs[i] = transactionDTO
 	}
 
+	return transactionDTOs
+}
+
+func NewTransactionLog(log []models.TransactionLogEntry, user *models.User, names map[string]string, count int64) interface{} {
+	type transactionsResp struct {
+		Base
+		Count        int64         `json:"count"`
+		Transactions []transaction `json:"transactions"`
+	}
+
 	return transactionsResp{
 		Base: Base{
 			Success: true,
 		},
 		Count:        count,
-		Transactions: transactionDTOs,
+		Transactions: newTransactionDTOs(log, user, names),
 	}
 }
 
-func NewBankTransactionLog(log []models.TransactionLogEntry, count int64) interface{} {
+func NewBankTransactionLog(log []models.TransactionLogEntry, names map[string]string, count int64) interface{} {
 	type transactionsResp struct {
 		Base
 		Count        int64             `json:"count"`
 		Transactions []bankTransaction `json:"transactions"`
 	}
 
+	return transactionsResp{
+		Base: Base{
+			Success: true,
+		},
+		Count:        count,
+		Transactions: newBankTransactionDTOs(log, names),
+	}
+}
+
+// names maps user ids to the names of the counterparties (see GroupStore.GetUserNames).
+func NewCounterpartyTotals(totals []models.CounterpartyTotals, names map[string]string, count int64) interface{} {
+	type counterparty struct {
+		Id       string `json:"id"`
+		Name     string `json:"name,omitempty"`
+		Sent     int    `json:"sent"`
+		Received int    `json:"received"`
+		Net      int    `json:"net"`
+	}
+
+	type counterpartiesResp struct {
+		Base
+		Count          int64          `json:"count"`
+		Counterparties []counterparty `json:"counterparties"`
+	}
+
+	counterparties := make([]counterparty, len(totals))
+	for i, t := range totals {
+		counterparties[i] = counterparty{
+			Id:       t.CounterpartyId,
+			Name:     names[t.CounterpartyId],
+			Sent:     t.Sent,
+			Received: t.Received,
+			Net:      t.Received - t.Sent,
+		}
+	}
+
+	return counterpartiesResp{
+		Base: Base{
+			Success: true,
+		},
+		Count:          count,
+		Counterparties: counterparties,
+	}
+}
+
+func NewTransactionsBetween(log []models.TransactionLogEntry, names map[string]string, userAId, userBId string, netFlow int) interface{} {
+	type transactionsBetweenResp struct {
+		Base
+		UserAId      string            `json:"userAId"`
+		UserBId      string            `json:"userBId"`
+		NetFlow      int               `json:"netFlow"`
+		Transactions []bankTransaction `json:"transactions"`
+	}
+
+	return transactionsBetweenResp{
+		Base: Base{
+			Success: true,
+		},
+		UserAId:      userAId,
+		UserBId:      userBId,
+		NetFlow:      netFlow,
+		Transactions: newBankTransactionDTOs(log, names),
+	}
+}
+
+func newBankTransactionDTOs(log []models.TransactionLogEntry, names map[string]string) []bankTransaction {
 	transactionDTOs := make([]bankTransaction, len(log))
 
 	for i, entry := range log {
@@ -352,28 +879,26 @@ func NewBankTransactionLog(log []models.TransactionLogEntry, count int64) interf
 
 		if entry.ReceiverIsBank {
 			transactionDTO.ReceiverId = "bank"
+			transactionDTO.ReceiverIsBank = true
 		} else {
 			transactionDTO.ReceiverId = entry.ReceiverId
 		}
 
 		if entry.SenderIsBank {
 			transactionDTO.SenderId = "bank"
+			transactionDTO.SenderIsBank = true
 		} else {
 			transactionDTO.SenderId = entry.SenderId
 		}
 
 		transactionDTO.PaymentPlanId = entry.PaymentPlanId
+		transactionDTO.SenderName = names[transactionDTO.SenderId]
+		transactionDTO.ReceiverName = names[transactionDTO.ReceiverId]
 
 		transactionDTOs[i] = transactionDTO
 	}
 
-	return transactionsResp{
-		Base: Base{
-			Success: true,
-		},
-		Count:        count,
-		Transactions: transactionDTOs,
-	}
+	return transactionDTOs
 }
 
 func NewDeleteFailedBecauseOfSoleGroupAdmin(groupIds []uuid.UUID, lang string) interface{} {
@@ -404,6 +929,7 @@ func NewPaymentPlan(paymentPlanModel *models.PaymentPlan) interface{} {
 		Description:  paymentPlanModel.Description,
 		Schedule:     paymentPlanModel.Schedule,
 		ScheduleUnit: paymentPlanModel.ScheduleUnit,
+		TimeZone:     paymentPlanModel.TimeZone,
 		Amount:       paymentPlanModel.Amount,
 		GroupId:      paymentPlanModel.GroupId,
 	}
@@ -428,6 +954,56 @@ func NewPaymentPlan(paymentPlanModel *models.PaymentPlan) interface{} {
 	}
 }
 
+func NewUpcomingPayments(paymentPlans []models.PaymentPlan, user *models.User) interface{} {
+	type upcomingPayment struct {
+		PaymentPlanId string `json:"paymentPlanId"`
+		Name          string `json:"name"`
+		NextExecute   int64  `json:"nextExecute"`
+		Amount        int    `json:"amount"`
+		// "outgoing" if the user pays, "incoming" if the user receives the amount
+		Direction      string `json:"direction"`
+		CounterpartyId string `json:"counterpartyId"`
+	}
+
+	type upcomingPaymentsResp struct {
+		Base
+		Payments []upcomingPayment `json:"payments"`
+	}
+
+	payments := make([]upcomingPayment, len(paymentPlans))
+	for i, plan := range paymentPlans {
+		payment := upcomingPayment{
+			PaymentPlanId: plan.Id,
+			Name:          plan.Name,
+			NextExecute:   plan.NextExecute,
+			Amount:        plan.Amount,
+		}
+
+		if !plan.SenderIsBank && plan.SenderId == user.Id {
+			payment.Direction = "outgoing"
+			payment.CounterpartyId = plan.ReceiverId
+			if plan.ReceiverIsBank {
+				payment.CounterpartyId = "bank"
+			}
+		} else {
+			payment.Direction = "incoming"
+			payment.CounterpartyId = plan.SenderId
+			if plan.SenderIsBank {
+				payment.CounterpartyId = "bank"
+			}
+		}
+
+		payments[i] = payment
+	}
+
+	return upcomingPaymentsResp{
+		Base: Base{
+			Success: true,
+		},
+		Payments: payments,
+	}
+}
+
 func NewPaymentPlans(paymentPlans []models.PaymentPlan, count int64) interface{} {
 	type paymentPlansResp struct {
 		Base
@@ -445,6 +1021,7 @@ func NewPaymentPlans(paymentPlans []models.PaymentPlan, count int64) interface{}
 			Name:         plan.Name,
 			Schedule:     plan.Schedule,
 			ScheduleUnit: plan.ScheduleUnit,
+			TimeZone:     plan.TimeZone,
 			Amount:       plan.Amount,
 			GroupId:      plan.GroupId,
 		}
@@ -473,6 +1050,39 @@ func NewPaymentPlans(paymentPlans []models.PaymentPlan, count int64) interface{}
 	}
 }
 
+type paymentPlanExecution struct {
+	Id            string `json:"id"`
+	ScheduledFor  int64  `json:"scheduledFor"`
+	Skipped       bool   `json:"skipped"`
+	TransactionId string `json:"transactionId,omitempty"`
+}
+
+func NewPaymentPlanExecutions(executions []models.PaymentPlanExecution, count int64) interface{} {
+	dtos := make([]paymentPlanExecution, len(executions))
+	for i, e := range executions {
+		dtos[i] = paymentPlanExecution{
+			Id:            e.Id,
+			ScheduledFor:  e.ScheduledFor,
+			Skipped:       e.Skipped,
+			TransactionId: e.TransactionId,
+		}
+	}
+
+	type paymentPlanExecutionsResp struct {
+		Base
+		Count      int64                  `json:"count"`
+		Executions []paymentPlanExecution `json:"executions"`
+	}
+
+	return paymentPlanExecutionsResp{
+		Base: Base{
+			Success: true,
+		},
+		Count:      count,
+		Executions: dtos,
+	}
+}
+
 func NewTotalMoney(total int) interface{} {
 	type totalMoney struct {
 		Base
@@ -485,3 +1095,79 @@ func NewTotalMoney(total int) interface{} {
 		Total: total,
 	}
 }
+
+type auditLogEntry struct {
+	Id         string `json:"id"`
+	Time       int64  `json:"time"`
+	Action     string `json:"action"`
+	ActorId    string `json:"actorId"`
+	ActorName  string `json:"actorName"`
+	TargetId   string `json:"targetId,omitempty"`
+	TargetName string `json:"targetName,omitempty"`
+}
+
+func NewAuditLog(log []models.GroupAuditLogEntry, count int64) interface{} {
+	dtos := make([]auditLogEntry, len(log))
+	for i, e := range log {
+		dtos[i] = auditLogEntry{
+			Id:         e.Id,
+			Time:       e.Created,
+			Action:     e.Action,
+			ActorId:    e.ActorId,
+			ActorName:  e.ActorName,
+			TargetId:   e.TargetId,
+			TargetName: e.TargetName,
+		}
+	}
+
+	type auditLogResp struct {
+		Base
+		Count   int64           `json:"count"`
+		Entries []auditLogEntry `json:"entries"`
+	}
+
+	return auditLogResp{
+		Base: Base{
+			Success: true,
+		},
+		Count:   count,
+		Entries: dtos,
+	}
+}
+
+func NewStatement(statement services.Statement) interface{} {
+	type statementMember struct {
+		UserId           string `json:"userId"`
+		Name             string `json:"name"`
+		OpeningBalance   int    `json:"openingBalance"`
+		TotalIn          int    `json:"totalIn"`
+		TotalOut         int    `json:"totalOut"`
+		ClosingBalance   int    `json:"closingBalance"`
+		TransactionCount int    `json:"transactionCount"`
+	}
+
+	members := make([]statementMember, len(statement.Members))
+	for i, m := range statement.Members {
+		members[i] = statementMember(m)
+	}
+
+	type statementResp struct {
+		Base
+		Month   string            `json:"month"`
+		From    int64             `json:"from"`
+		To      int64             `json:"to"`
+		Unit    string            `json:"unit"`
+		Members []statementMember `json:"members"`
+	}
+
+	return statementResp{
+		Base: Base{
+			Success: true,
+		},
+		Month:   statement.Month,
+		From:    statement.From,
+		To:      statement.To,
+		Unit:    statement.Unit,
+		Members: members,
+	}
+}
@@ -1,6 +1,10 @@
 package responses
 
-import "github.com/juho05/h-bank/config"
+import (
+	"github.com/juho05/h-bank/config"
+	"github.com/juho05/h-bank/models"
+	"github.com/juho05/h-bank/services"
+)
 
 type Config struct {
 	EmailEnabled              bool   `json:"emailEnabled"`
@@ -11,6 +15,18 @@ type Config struct {
 	MaxProfilePictureFileSize int64  `json:"maxProfilePictureFileSize"`
 	MaxPageSize               int    `json:"maxPageSize"`
 	IDProvider                string `json:"idProvider"`
+	// Valid values of the size query parameter of picture endpoints mapped to their edge length in pixels.
+	PictureSizes map[services.PictureSize]int `json:"pictureSizes"`
+	CashUnit     string                       `json:"cashUnit"`
+	// Coins and notes of the cash log from the highest to the lowest value. Other fields of cash log entries are unused.
+	CashDenominations []CashDenomination `json:"cashDenominations"`
+}
+
+type CashDenomination struct {
+	// Name of the field in cash log entries
+	Key string `json:"key"`
+	// Value in the minor unit of the currency
+	Value int `json:"value"`
 }
 
 type Status struct {
@@ -19,6 +35,14 @@ type Status struct {
 }
 
 func NewStatus() interface{} {
+	cashDenominations := make([]CashDenomination, len(config.Data.CashDenominations))
+	for i, value := range config.Data.CashDenominations {
+		cashDenominations[i] = CashDenomination{
+			Key:   models.CashDenominationKeys[i],
+			Value: value,
+		}
+	}
+
 	return Status{
 		Base: Base{
 			Success: true,
@@ -33,6 +57,9 @@ func NewStatus() interface{} {
 			MaxProfilePictureFileSize: config.Data.MaxProfilePictureFileSize,
 			MaxPageSize:               config.Data.MaxPageSize,
 			IDProvider:                config.Data.IDProvider,
+			PictureSizes:              services.PictureDimensions,
+			CashUnit:                  config.Data.CashUnit,
+			CashDenominations:         cashDenominations,
 		},
 	}
 }
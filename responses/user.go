@@ -8,6 +8,9 @@ type AuthUser struct {
 	Email                   string `json:"email"`
 	PubliclyVisible         bool   `json:"publiclyVisible"`
 	DontSendInvitationEmail bool   `json:"dontSendInvitationEmail"`
+	SendReceiptEmail        bool   `json:"sendReceiptEmail"`
+	DefaultSortDescending   bool   `json:"defaultSortDescending"`
+	LastLogin               int64  `json:"lastLogin"`
 }
 
 type User struct {
@@ -49,6 +52,93 @@ type CashLogEntry struct {
 	Difference int    `json:"difference"`
 }
 
+func NewLeftGroups(left []models.LeftGroup) interface{} {
+	type leftGroup struct {
+		GroupId   string `json:"groupId"`
+		GroupName string `json:"groupName"`
+		Balance   int    `json:"balance"`
+		Settled   bool   `json:"settled"`
+	}
+
+	type leftGroupsResp struct {
+		Base
+		Groups []leftGroup `json:"groups"`
+	}
+
+	groupDTOs := make([]leftGroup, len(left))
+	for i, l := range left {
+		groupDTOs[i] = leftGroup{
+			GroupId:   l.GroupId,
+			GroupName: l.GroupName,
+			Balance:   l.Balance,
+			Settled:   l.Settled,
+		}
+	}
+
+	return leftGroupsResp{
+		Base: Base{
+			Success: true,
+		},
+		Groups: groupDTOs,
+	}
+}
+
+func NewNetWorth(balances []models.GroupBalance, cash int) interface{} {
+	type groupBalance struct {
+		GroupId   string `json:"groupId"`
+		GroupName string `json:"groupName"`
+		Unit      string `json:"unit"`
+		Balance   int    `json:"balance"`
+	}
+
+	type netWorthResp struct {
+		Base
+		Groups []groupBalance `json:"groups"`
+		Cash   int            `json:"cash"`
+		Total  int            `json:"total"`
+	}
+
+	total := cash
+	groupDTOs := make([]groupBalance, len(balances))
+	for i, b := range balances {
+		groupDTOs[i] = groupBalance{
+			GroupId:   b.GroupId,
+			GroupName: b.GroupName,
+			Unit:      b.Unit,
+			Balance:   b.Balance,
+		}
+		total += b.Balance
+	}
+
+	return netWorthResp{
+		Base: Base{
+			Success: true,
+		},
+		Groups: groupDTOs,
+		Cash:   cash,
+		Total:  total,
+	}
+}
+
+func NewOverview(groupCount, invitationCount, unseenInvitationCount int64, netWorth int) interface{} {
+	type overviewResp struct {
+		Base
+		GroupCount            int64 `json:"groupCount"`
+		InvitationCount       int64 `json:"invitationCount"`
+		UnseenInvitationCount int64 `json:"unseenInvitationCount"`
+		NetWorth              int   `json:"netWorth"`
+	}
+	return overviewResp{
+		Base: Base{
+			Success: true,
+		},
+		GroupCount:            groupCount,
+		InvitationCount:       invitationCount,
+		UnseenInvitationCount: unseenInvitationCount,
+		NetWorth:              netWorth,
+	}
+}
+
 func NewCashLogEntry(entry *models.CashLogEntry) interface{} {
 	type cashLogEntryResp struct {
 		Base
@@ -58,31 +148,81 @@ func NewCashLogEntry(entry *models.CashLogEntry) interface{} {
 		Base: Base{
 			Success: true,
 		},
-		CashLogEntryDetailed: CashLogEntryDetailed{
-			Id:          entry.Id,
-			Time:        entry.Created,
-			Title:       entry.ChangeTitle,
-			Description: entry.ChangeDescription,
-
-			Ct1:    entry.Ct1,
-			Ct2:    entry.Ct2,
-			Ct5:    entry.Ct5,
-			Ct10:   entry.Ct10,
-			Ct20:   entry.Ct20,
-			Ct50:   entry.Ct50,
-			Eur1:   entry.Eur1,
-			Eur2:   entry.Eur2,
-			Eur5:   entry.Eur5,
-			Eur10:  entry.Eur10,
-			Eur20:  entry.Eur20,
-			Eur50:  entry.Eur50,
-			Eur100: entry.Eur100,
-			Eur200: entry.Eur200,
-			Eur500: entry.Eur500,
+		CashLogEntryDetailed: newCashLogEntryDetailed(entry),
+	}
+}
 
-			Amount:     entry.TotalAmount,
-			Difference: entry.ChangeDifference,
+func newCashLogEntryDetailed(entry *models.CashLogEntry) CashLogEntryDetailed {
+	return CashLogEntryDetailed{
+		Id:          entry.Id,
+		Time:        entry.Created,
+		Title:       entry.ChangeTitle,
+		Description: entry.ChangeDescription,
+
+		Ct1:    entry.Ct1,
+		Ct2:    entry.Ct2,
+		Ct5:    entry.Ct5,
+		Ct10:   entry.Ct10,
+		Ct20:   entry.Ct20,
+		Ct50:   entry.Ct50,
+		Eur1:   entry.Eur1,
+		Eur2:   entry.Eur2,
+		Eur5:   entry.Eur5,
+		Eur10:  entry.Eur10,
+		Eur20:  entry.Eur20,
+		Eur50:  entry.Eur50,
+		Eur100: entry.Eur100,
+		Eur200: entry.Eur200,
+		Eur500: entry.Eur500,
+
+		Amount:     entry.TotalAmount,
+		Difference: entry.ChangeDifference,
+	}
+}
+
+func NewChange(change *models.CashLogEntry) interface{} {
+	type changeResp struct {
+		Base
+		Amount int `json:"amount"`
+
+		Ct1    int `json:"ct1"`
+		Ct2    int `json:"ct2"`
+		Ct5    int `json:"ct5"`
+		Ct10   int `json:"ct10"`
+		Ct20   int `json:"ct20"`
+		Ct50   int `json:"ct50"`
+		Eur1   int `json:"eur1"`
+		Eur2   int `json:"eur2"`
+		Eur5   int `json:"eur5"`
+		Eur10  int `json:"eur10"`
+		Eur20  int `json:"eur20"`
+		Eur50  int `json:"eur50"`
+		Eur100 int `json:"eur100"`
+		Eur200 int `json:"eur200"`
+		Eur500 int `json:"eur500"`
+	}
+
+	return changeResp{
+		Base: Base{
+			Success: true,
 		},
+		Amount: change.Value(),
+
+		Ct1:    change.Ct1,
+		Ct2:    change.Ct2,
+		Ct5:    change.Ct5,
+		Ct10:   change.Ct10,
+		Ct20:   change.Ct20,
+		Ct50:   change.Ct50,
+		Eur1:   change.Eur1,
+		Eur2:   change.Eur2,
+		Eur5:   change.Eur5,
+		Eur10:  change.Eur10,
+		Eur20:  change.Eur20,
+		Eur50:  change.Eur50,
+		Eur100: change.Eur100,
+		Eur200: change.Eur200,
+		Eur500: change.Eur500,
 	}
 }
 
@@ -130,6 +270,9 @@ func NewAuthUser(user *models.User) interface{} {
 			Email:                   user.Email,
 			PubliclyVisible:         user.PubliclyVisible,
 			DontSendInvitationEmail: user.DontSendInvitationEmail,
+			SendReceiptEmail:        user.SendReceiptEmail,
+			DefaultSortDescending:   user.DefaultSortDescending,
+			LastLogin:               user.LastLogin,
 		},
 	}
 }
@@ -171,3 +314,90 @@ func NewUsers(users []models.User, count int64) interface{} {
 		Users: userDTOs,
 	}
 }
+
+type adminChange struct {
+	Id        string `json:"id"`
+	Time      int64  `json:"time"`
+	GroupId   string `json:"groupId"`
+	GroupName string `json:"groupName"`
+	Granted   bool   `json:"granted"`
+}
+
+type activity struct {
+	Type string `json:"type"`
+	Time int64  `json:"time"`
+
+	Transaction *transaction `json:"transaction,omitempty"`
+	Invitation  *invitation  `json:"invitation,omitempty"`
+	AdminChange *adminChange `json:"adminChange,omitempty"`
+}
+
+func NewActivity(activities []models.Activity, user *models.User, cursor string) interface{} {
+	type activityResp struct {
+		Base
+		Activity []activity `json:"activity"`
+		Cursor   string     `json:"cursor,omitempty"`
+	}
+
+	dtos := make([]activity, len(activities))
+	for i, a := range activities {
+		dtos[i] = activity{
+			Type: a.Type,
+			Time: a.Time,
+		}
+
+		switch a.Type {
+		case models.ActivityTransaction:
+			isSender := user.Id == a.Transaction.SenderId
+			newBalance := a.Transaction.NewBalanceReceiver
+			if isSender {
+				newBalance = a.Transaction.NewBalanceSender
+			}
+
+			transactionDTO := transaction{
+				Id:            a.Transaction.Id,
+				Time:          a.Transaction.Created,
+				Title:         a.Transaction.Title,
+				Amount:        a.Transaction.Amount,
+				NewBalance:    newBalance,
+				GroupId:       a.Transaction.GroupId,
+				SenderId:      a.Transaction.SenderId,
+				ReceiverId:    a.Transaction.ReceiverId,
+				PaymentPlanId: a.Transaction.PaymentPlanId,
+			}
+			if a.Transaction.SenderIsBank {
+				transactionDTO.SenderId = "bank"
+				transactionDTO.SenderIsBank = true
+			}
+			if a.Transaction.ReceiverIsBank {
+				transactionDTO.ReceiverId = "bank"
+				transactionDTO.ReceiverIsBank = true
+			}
+			dtos[i].Transaction = &transactionDTO
+		case models.ActivityInvitation:
+			dtos[i].Invitation = &invitation{
+				Id:                a.Invitation.Id,
+				Created:           a.Invitation.Created,
+				InvitationMessage: a.Invitation.Message,
+				GroupName:         a.Invitation.GroupName,
+				GroupId:           a.Invitation.GroupId,
+			}
+		case models.ActivityAdminChange:
+			dtos[i].AdminChange = &adminChange{
+				Id:        a.AdminChange.Id,
+				Time:      a.AdminChange.Created,
+				GroupId:   a.AdminChange.GroupId,
+				GroupName: a.AdminChange.GroupName,
+				Granted:   a.AdminChange.Granted,
+			}
+		}
+	}
+
+	return activityResp{
+		Base: Base{
+			Success: true,
+		},
+		Activity: dtos,
+		Cursor:   cursor,
+	}
+}
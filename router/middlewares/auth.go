@@ -3,10 +3,13 @@ package middlewares
 import (
 	"errors"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/lestrrat-go/jwx/v2/jwt"
 
 	"github.com/juho05/oidc-client/oidc"
 
@@ -46,66 +49,32 @@ func Auth(oidcClient *oidc.Client, userStore models.UserStore) func(next echo.Ha
 						return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 					}
 
-					user, err := userStore.GetById(userID)
+					user, err := userStore.GetById(c.Request().Context(), userID)
 					if err != nil {
 						return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 					}
 					if user == nil {
 						return c.JSON(http.StatusUnauthorized, responses.New(false, "The user does not longer exist", lang))
 					}
-					user.Name = info.Name
-					user.Email = info.Email
-					err = userStore.Update(user)
+					err = userStore.UpdateProfile(c.Request().Context(), user, info.Name, info.Email)
 					if err != nil {
 						return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 					}
-
-					sameSite := http.SameSiteStrictMode
-
-					if config.Data.Debug {
-						sameSite = http.SameSiteNoneMode
+					err = userStore.UpdateLastLogin(c.Request().Context(), user, time.Now().Unix(), lang)
+					if err != nil {
+						return c.JSON(http.StatusInternalServerError, responses.NewUnexpectedError(err, lang))
 					}
 
-					c.SetCookie(&http.Cookie{
-						Name:     "Refresh-Token",
-						Value:    refresh,
-						MaxAge:   int((12 * 7 * 24 * time.Hour).Seconds()),
-						Secure:   true,
-						HttpOnly: true,
-						SameSite: sameSite,
-						Domain:   config.Data.DomainName,
-						Path:     "/",
-					})
-
-					idTokenParts := strings.Split(id, ".")
-
-					c.SetCookie(&http.Cookie{
-						Name:     "ID-Token",
-						Value:    strings.Join(idTokenParts[:2], "."),
-						MaxAge:   int((30 * time.Minute).Seconds()),
-						Secure:   true,
-						HttpOnly: false,
-						SameSite: sameSite,
-						Domain:   config.Data.DomainName,
-						Path:     "/",
-					})
-
-					c.SetCookie(&http.Cookie{
-						Name:     "ID-Token-Signature",
-						Value:    idTokenParts[2],
-						MaxAge:   int((30 * time.Minute).Seconds()),
-						Secure:   true,
-						HttpOnly: true,
-						SameSite: sameSite,
-						Domain:   config.Data.DomainName,
-						Path:     "/",
-					})
+					SetAuthCookies(c, refresh, id, rememberMe(c))
 
 					c.Set("userId", userID)
 				} else {
 					return c.JSON(http.StatusUnauthorized, responses.New(false, "Invalid JWT", lang))
 				}
 			} else {
+				if !verifyClaims(token, config.Data.TokenIssuer, config.Data.TokenAudience) {
+					return c.JSON(http.StatusUnauthorized, responses.New(false, "Invalid JWT", lang))
+				}
 				c.Set("userId", token.Subject())
 			}
 
@@ -113,3 +82,87 @@ func Auth(oidcClient *oidc.Client, userStore models.UserStore) func(next echo.Ha
 		}
 	}
 }
+
+// Rejects tokens minted for a different deployment. Empty values disable the respective check.
+func verifyClaims(token jwt.Token, issuer, audience string) bool {
+	if issuer != "" && token.Issuer() != issuer {
+		return false
+	}
+	if audience != "" && !slices.Contains(token.Audience(), audience) {
+		return false
+	}
+	return true
+}
+
+// Reports whether the session was started with remember me.
+// Sessions started before the option existed don't have the cookie and keep the remember me lifetime they were created with.
+func rememberMe(c echo.Context) bool {
+	cookie, err := c.Cookie("Remember-Me")
+	if err != nil {
+		return true
+	}
+	return cookie.Value == "true"
+}
+
+// Sets the cookies containing the tokens of a session.
+// The refresh token is kept for the longer remember me lifetime if rememberMe is set.
+// The choice is stored in a cookie as well, so refreshing the tokens keeps the lifetime.
+// Only the lifetime of the cookies is controlled here, the tokens themselves expire as configured at the identity provider.
+func SetAuthCookies(c echo.Context, refresh, id string, rememberMe bool) {
+	sameSite := http.SameSiteStrictMode
+
+	if config.Data.Debug {
+		sameSite = http.SameSiteNoneMode
+	}
+
+	refreshTokenLifetime := config.Data.RefreshTokenLifetime
+	if rememberMe {
+		refreshTokenLifetime = config.Data.RememberMeTokenLifetime
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     "Refresh-Token",
+		Value:    refresh,
+		MaxAge:   int(refreshTokenLifetime),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: sameSite,
+		Domain:   config.Data.DomainName,
+		Path:     "/",
+	})
+
+	c.SetCookie(&http.Cookie{
+		Name:     "Remember-Me",
+		Value:    strconv.FormatBool(rememberMe),
+		MaxAge:   int(refreshTokenLifetime),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: sameSite,
+		Domain:   config.Data.DomainName,
+		Path:     "/",
+	})
+
+	idTokenParts := strings.Split(id, ".")
+
+	c.SetCookie(&http.Cookie{
+		Name:     "ID-Token",
+		Value:    strings.Join(idTokenParts[:2], "."),
+		MaxAge:   int((30 * time.Minute).Seconds()),
+		Secure:   true,
+		HttpOnly: false,
+		SameSite: sameSite,
+		Domain:   config.Data.DomainName,
+		Path:     "/",
+	})
+
+	c.SetCookie(&http.Cookie{
+		Name:     "ID-Token-Signature",
+		Value:    idTokenParts[2],
+		MaxAge:   int((30 * time.Minute).Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: sameSite,
+		Domain:   config.Data.DomainName,
+		Path:     "/",
+	})
+}
@@ -0,0 +1,102 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/juho05/h-bank/config"
+)
+
+func TestSetAuthCookies(t *testing.T) {
+	tests := []struct {
+		name       string
+		rememberMe bool
+		wantMaxAge int64
+	}{
+		{name: "Default", rememberMe: false, wantMaxAge: config.Data.RefreshTokenLifetime},
+		{name: "Remember me", rememberMe: true, wantMaxAge: config.Data.RememberMeTokenLifetime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			SetAuthCookies(c, "refresh", "header.payload.signature", tt.rememberMe)
+
+			cookies := make(map[string]*http.Cookie)
+			for _, cookie := range rec.Result().Cookies() {
+				cookies[cookie.Name] = cookie
+			}
+
+			if assert.Contains(t, cookies, "Refresh-Token") {
+				assert.Equal(t, "refresh", cookies["Refresh-Token"].Value)
+				assert.Equal(t, int(tt.wantMaxAge), cookies["Refresh-Token"].MaxAge)
+			}
+			if assert.Contains(t, cookies, "Remember-Me") {
+				assert.Equal(t, int(tt.wantMaxAge), cookies["Remember-Me"].MaxAge)
+				assert.Equal(t, tt.rememberMe, cookies["Remember-Me"].Value == "true")
+			}
+			if assert.Contains(t, cookies, "ID-Token") {
+				assert.Equal(t, "header.payload", cookies["ID-Token"].Value)
+			}
+			if assert.Contains(t, cookies, "ID-Token-Signature") {
+				assert.Equal(t, "signature", cookies["ID-Token-Signature"].Value)
+			}
+		})
+	}
+}
+
+func TestRememberMe(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie string
+		want   bool
+	}{
+		{name: "Remember me", cookie: "true", want: true},
+		{name: "Default", cookie: "false", want: false},
+		{name: "Legacy session", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "Remember-Me", Value: tt.cookie})
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			assert.Equal(t, tt.want, rememberMe(c))
+		})
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	token := jwt.New()
+	token.Set(jwt.IssuerKey, "https://id.example.com")
+	token.Set(jwt.AudienceKey, []string{"h-bank", "other"})
+
+	tests := []struct {
+		name     string
+		issuer   string
+		audience string
+		want     bool
+	}{
+		{name: "No checks", want: true},
+		{name: "Matching", issuer: "https://id.example.com", audience: "h-bank", want: true},
+		{name: "Second audience", audience: "other", want: true},
+		{name: "Mismatched issuer", issuer: "https://id.example.org", audience: "h-bank", want: false},
+		{name: "Mismatched audience", issuer: "https://id.example.com", audience: "h-bank-staging", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, verifyClaims(token, tt.issuer, tt.audience))
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/juho05/h-bank/responses"
+)
+
+// Number of tracked clients after which expired windows are removed.
+const rateLimitCleanupThreshold = 10000
+
+type rateLimitWindow struct {
+	start time.Time
+	count int
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	clients map[string]*rateLimitWindow
+	now     func() time.Time
+}
+
+// Returns whether the client may make another request and otherwise the time until the next request is allowed.
+func (r *rateLimiter) allow(key string) (bool, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+
+	if len(r.clients) >= rateLimitCleanupThreshold {
+		for k, w := range r.clients {
+			if now.Sub(w.start) >= r.window {
+				delete(r.clients, k)
+			}
+		}
+	}
+
+	w, ok := r.clients[key]
+	if !ok || now.Sub(w.start) >= r.window {
+		w = &rateLimitWindow{start: now}
+		r.clients[key] = w
+	}
+
+	if w.count >= r.limit {
+		return false, w.start.Add(r.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// Allows limit requests per window for every client. Clients are identified by their user id if the request
+// is authenticated (requires the Auth middleware to run first) and by their IP address otherwise.
+// Rejected requests receive a 429 response with a Retry-After header containing the seconds until the next allowed request.
+// A limit <= 0 disables rate limiting.
+func RateLimit(limit int, window time.Duration) echo.MiddlewareFunc {
+	limiter := &rateLimiter{
+		limit:   limit,
+		window:  window,
+		clients: make(map[string]*rateLimitWindow),
+		now:     time.Now,
+	}
+	return limiter.middleware
+}
+
+func (r *rateLimiter) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if r.limit <= 0 {
+			return next(c)
+		}
+
+		key := c.RealIP()
+		if userId, ok := c.Get("userId").(string); ok && userId != "" {
+			key = "user:" + userId
+		}
+
+		allowed, retryAfter := r.allow(key)
+		if !allowed {
+			lang := c.Get("lang").(string)
+			c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			return c.JSON(http.StatusTooManyRequests, responses.New(false, "Too many requests", lang))
+		}
+
+		return next(c)
+	}
+}
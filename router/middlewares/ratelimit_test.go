@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := &rateLimiter{
+		limit:   2,
+		window:  time.Minute,
+		clients: make(map[string]*rateLimitWindow),
+		now:     func() time.Time { return now },
+	}
+
+	e := echo.New()
+	handler := limiter.middleware(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	request := func(ip, userId string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("lang", "en")
+		if userId != "" {
+			c.Set("userId", userId)
+		}
+		assert.NoError(t, handler(c))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, request("10.0.0.1", "").Code)
+	assert.Equal(t, http.StatusOK, request("10.0.0.1", "").Code)
+
+	now = now.Add(20500 * time.Millisecond)
+	rec := request("10.0.0.1", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "40", rec.Header().Get(echo.HeaderRetryAfter))
+
+	assert.Equal(t, http.StatusOK, request("10.0.0.2", "").Code, "other clients are not affected")
+	assert.Equal(t, http.StatusOK, request("10.0.0.1", "user").Code, "users are limited independently of their IP")
+
+	now = now.Add(40 * time.Second)
+	assert.Equal(t, http.StatusOK, request("10.0.0.1", "").Code, "new window")
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	e := echo.New()
+	handler := RateLimit(0, time.Minute)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("lang", "en")
+		assert.NoError(t, handler(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+}
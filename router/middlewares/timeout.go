@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/juho05/h-bank/responses"
+)
+
+// Replaces internal server errors with a 504 response once the deadline of the request has passed.
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	lang     string
+	timedOut bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code != http.StatusInternalServerError || !errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	w.timedOut = true
+	body, _ := json.Marshal(responses.New(false, "Request timed out", w.lang))
+	w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	w.Header().Del(echo.HeaderContentLength)
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	w.ResponseWriter.Write(body)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if w.timedOut {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Cancels the context of the request (and all database queries using it) when the client disconnects or the timeout passes.
+// Requests failing because of the timeout receive a 504 response. A timeout <= 0 disables the deadline.
+func Timeout(timeout time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if timeout <= 0 {
+				return next(c)
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			lang, _ := c.Get("lang").(string)
+			c.Response().Writer = &timeoutWriter{
+				ResponseWriter: c.Response().Writer,
+				ctx:            ctx,
+				lang:           lang,
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		handler  echo.HandlerFunc
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:    "Success",
+			timeout: time.Second,
+			handler: func(c echo.Context) error {
+				return c.JSON(http.StatusOK, map[string]bool{"success": true})
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"success":true}`,
+		},
+		{
+			name:    "Error before deadline",
+			timeout: time.Second,
+			handler: func(c echo.Context) error {
+				return c.JSON(http.StatusInternalServerError, map[string]bool{"success": false})
+			},
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"success":false}`,
+		},
+		{
+			name:    "Timed out",
+			timeout: time.Millisecond,
+			handler: func(c echo.Context) error {
+				<-c.Request().Context().Done()
+				return c.JSON(http.StatusInternalServerError, map[string]string{"message": c.Request().Context().Err().Error()})
+			},
+			wantCode: http.StatusGatewayTimeout,
+			wantBody: `{"success":false,"message":"Request timed out"}`,
+		},
+		{
+			name:    "Disabled",
+			timeout: 0,
+			handler: func(c echo.Context) error {
+				if _, ok := c.Request().Context().Deadline(); ok {
+					return errors.New("unexpected deadline")
+				}
+				return c.NoContent(http.StatusOK)
+			},
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set("lang", "en")
+
+			err := Timeout(tt.timeout)(tt.handler)(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -2,9 +2,12 @@ package router
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/juho05/h-bank/config"
 	"github.com/juho05/h-bank/responses"
 	"github.com/juho05/h-bank/router/middlewares"
+	"github.com/juho05/h-bank/services"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
@@ -14,6 +17,7 @@ func New() *echo.Echo {
 	e.HideBanner = true
 
 	e.HTTPErrorHandler = responses.HandleHTTPError
+	e.IPExtractor = services.NewIPExtractor(config.Data.TrustedProxyNets)
 
 	e.Pre(middleware.RemoveTrailingSlash())
 
@@ -26,6 +30,7 @@ func New() *echo.Echo {
 	}))
 
 	e.Use(middlewares.Lang)
+	e.Use(middlewares.Timeout(time.Duration(config.Data.RequestTimeout) * time.Second))
 
 	return e
 }
@@ -2,10 +2,23 @@ package services
 
 import (
 	"bytes"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"html"
 	"html/template"
+	"io"
 	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
 	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/juho05/h-bank/config"
 )
@@ -16,10 +29,30 @@ func EmailAuthenticate() {
 	emailAuth = smtp.PlainAuth("", config.Data.EmailUsername, config.Data.EmailPassword, config.Data.EmailHost)
 }
 
+var emailTemplateFuncs = template.FuncMap{
+	"productName":  func() string { return config.Data.EmailProductName },
+	"logoURL":      func() string { return config.Data.EmailLogoURL },
+	"primaryColor": func() template.CSS { return template.CSS(config.Data.EmailPrimaryColor) },
+	"borderColor":  func() template.CSS { return template.CSS(config.Data.EmailBorderColor) },
+	"baseURL":      func() string { return config.Data.BaseURL },
+}
+
+// Returns the path of the template file to use for the email template name.
+// Templates in the configured email template directory take precedence over the built-in ones.
+func emailTemplatePath(name string, lang string) string {
+	if config.Data.EmailTemplateDir != "" {
+		path := filepath.Join(config.Data.EmailTemplateDir, lang, name+".html")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return fmt.Sprintf("templates/email/%s/%s.html", lang, name)
+}
+
 func ParseEmailTemplate(name string, lang string, data interface{}) (string, error) {
-	filepath := fmt.Sprintf("templates/email/%s/%s.html", lang, name)
+	path := emailTemplatePath(name, lang)
 
-	t, err := template.ParseFiles(filepath)
+	t, err := template.New(filepath.Base(path)).Funcs(emailTemplateFuncs).ParseFiles(path)
 	if err != nil {
 		return "", err
 	}
@@ -33,19 +66,249 @@ func ParseEmailTemplate(name string, lang string, data interface{}) (string, err
 	return body, nil
 }
 
-func SendEmail(address []string, subject string, body string) error {
+var ErrUnknownEmailType = errors.New("unknown email type")
+
+// Sample template data for each previewable email type.
+var emailPreviews = map[string]struct {
+	template string
+	data     map[string]string
+}{
+	"confirm":            {template: "confirmEmail", data: map[string]string{"Name": "Bob", "Code": "123456", "DeleteUrl": "https://example.com/delete"}},
+	"changeEmail":        {template: "changeEmail", data: map[string]string{"Name": "Bob", "Url": "https://example.com/changeEmail"}},
+	"forgotPassword":     {template: "forgotPassword", data: map[string]string{"Name": "Bob", "Url": "https://example.com/resetPassword"}},
+	"inactiveAccount":    {template: "inactiveAccount", data: map[string]string{"Name": "Bob", "DeletionDate": "2006-01-02", "LoginURL": "https://example.com"}},
+	"invitation":         {template: "invitation", data: map[string]string{"Name": "Bob", "GroupName": "Example group", "InvitationsUrl": "https://example.com/invitations"}},
+	"lowBalance":         {template: "lowBalance", data: map[string]string{"Name": "Bob", "GroupName": "Example group", "Balance": "-12.50 €", "Threshold": "-10.00 €"}},
+	"removedFromGroup":   {template: "removedFromGroup", data: map[string]string{"Name": "Bob", "GroupName": "Example group"}},
+	"transactionReceipt": {template: "transactionReceipt", data: map[string]string{"Name": "Bob", "GroupName": "Example group", "Title": "Example transaction", "Amount": "5.00 €", "NewBalance": "15.00 €"}},
+}
+
+// Renders the configured template of the email type with sample data.
+func PreviewEmail(emailType string, lang string) (string, error) {
+	preview, ok := emailPreviews[emailType]
+	if !ok {
+		return "", ErrUnknownEmailType
+	}
+	return ParseEmailTemplate(preview.template, lang, preview.data)
+}
+
+var (
+	headRegex       = regexp.MustCompile(`(?is)<head.*?</head>`)
+	lineBreakRegex  = regexp.MustCompile(`(?i)<br\s*/?>`)
+	paragraphRegex  = regexp.MustCompile(`(?i)</(p|div|tr|h[1-6])>`)
+	linkRegex       = regexp.MustCompile(`(?is)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	tagRegex        = regexp.MustCompile(`(?s)<[^>]*>`)
+	whitespaceRegex = regexp.MustCompile(`[ \t]+`)
+)
+
+// Derives a plaintext version of an HTML email body.
+func htmlToText(body string) string {
+	body = headRegex.ReplaceAllString(body, "")
+	body = strings.NewReplacer("\r", "", "\n", " ").Replace(body)
+	body = lineBreakRegex.ReplaceAllString(body, "\n")
+	body = paragraphRegex.ReplaceAllString(body, "\n\n")
+	body = linkRegex.ReplaceAllStringFunc(body, func(link string) string {
+		match := linkRegex.FindStringSubmatch(link)
+		text := strings.TrimSpace(tagRegex.ReplaceAllString(match[2], ""))
+		if text == "" || text == match[1] {
+			return match[1]
+		}
+		return fmt.Sprintf("%s (%s)", text, match[1])
+	})
+	body = tagRegex.ReplaceAllString(body, "")
+	body = html.UnescapeString(body)
+
+	lines := strings.Split(body, "\n")
+	text := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(whitespaceRegex.ReplaceAllString(line, " "))
+		if line == "" && (len(text) == 0 || text[len(text)-1] == "") {
+			continue
+		}
+		text = append(text, line)
+	}
+
+	return strings.TrimSpace(strings.Join(text, "\n")) + "\n"
+}
+
+// Returns the sender and the (optional) reply-to address for critical (authentication) or notification emails.
+func emailIdentity(critical bool) (from *mail.Address, replyTo *mail.Address, err error) {
+	fromStr, replyToStr := config.Data.EmailFrom, config.Data.EmailReplyTo
+	if critical {
+		if config.Data.EmailAuthFrom != "" {
+			fromStr = config.Data.EmailAuthFrom
+		}
+		if config.Data.EmailAuthReplyTo != "" {
+			replyToStr = config.Data.EmailAuthReplyTo
+		}
+	}
+
+	from, err = mail.ParseAddress(fromStr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid from address: %w", err)
+	}
+	if replyToStr != "" {
+		replyTo, err = mail.ParseAddress(replyToStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid reply-to address: %w", err)
+		}
+	}
+	return from, replyTo, nil
+}
+
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Builds a multipart/alternative message with a plaintext and an HTML part.
+// If there are attachments, the message is wrapped in a multipart/mixed message.
+func buildEmailMessage(from, replyTo *mail.Address, subject string, body string, attachments ...EmailAttachment) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	writer := multipart.NewWriter(buf)
+
+	fmt.Fprintf(buf, "From: %s\r\n", from)
+	if replyTo != nil {
+		fmt.Fprintf(buf, "Reply-To: %s\r\n", replyTo)
+	}
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+
+	if len(attachments) == 0 {
+		fmt.Fprintf(buf, "Content-Type: multipart/alternative; boundary=\"%s\"\r\n\r\n", writer.Boundary())
+		if err := writeAlternativeParts(writer, body); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	fmt.Fprintf(buf, "Content-Type: multipart/mixed; boundary=\"%s\"\r\n\r\n", writer.Boundary())
+
+	// The boundary of the nested part is needed for its header before the part can be written.
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", fmt.Sprintf("multipart/alternative; boundary=\"%s\"", boundary))
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	alternativeWriter := multipart.NewWriter(part)
+	if err = alternativeWriter.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+	if err = writeAlternativeParts(alternativeWriter, body); err != nil {
+		return nil, err
+	}
+	if err = alternativeWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, a := range attachments {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", a.ContentType)
+		header.Set("Content-Transfer-Encoding", "base64")
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			fmt.Fprintf(part, "%s\r\n", encoded[:76])
+			encoded = encoded[76:]
+		}
+		fmt.Fprintf(part, "%s\r\n", encoded)
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeAlternativeParts(writer *multipart.Writer, body string) error {
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain", content: htmlToText(body)},
+		{contentType: "text/html", content: body},
+	}
+	for _, p := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", p.contentType+"; charset=\"UTF-8\"")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		qp := quotedprintable.NewWriter(part)
+		if _, err = qp.Write([]byte(p.content)); err != nil {
+			return err
+		}
+		if err = qp.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Queues an email for delivery. Failed sends are retried in the background.
+// Critical emails which still fail are passed to FailedEmailHandler.
+func SendEmail(address []string, subject string, body string, critical bool, attachments ...EmailAttachment) error {
 	if !config.Data.EmailEnabled {
 		return nil
 	}
-	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
 
-	subject = "Subject: " + subject + "\n"
-	msg := []byte(subject + mime + "\n" + body)
-	addr := fmt.Sprintf("%s:%d", config.Data.EmailHost, config.Data.EmailPort)
+	from, replyTo, err := emailIdentity(critical)
+	if err != nil {
+		log.Println("Error while building email:", err)
+		return err
+	}
+
+	msg, err := buildEmailMessage(from, replyTo, subject, body, attachments...)
+	if err != nil {
+		log.Println("Error while building email:", err)
+		return err
+	}
 
-	err := smtp.SendMail(addr, emailAuth, config.Data.EmailUsername, address, msg)
+	err = queueEmail(emailJob{
+		from:      from.Address,
+		addresses: address,
+		subject:   subject,
+		msg:       msg,
+		critical:  critical,
+	})
 	if err != nil {
-		log.Println("Error while sending email:", err)
+		log.Printf("Error while sending email '%s' to %v: %s", subject, address, err)
+		if critical && FailedEmailHandler != nil {
+			FailedEmailHandler(address, subject, msg, err.Error())
+		}
 	}
 	return err
 }
+
+// Queues an already built message, e.g. to resend a failed email.
+func SendRawEmail(address []string, subject string, msg []byte, critical bool) error {
+	if !config.Data.EmailEnabled {
+		return ErrEmailDisabled
+	}
+
+	from, _, err := emailIdentity(critical)
+	if err != nil {
+		return err
+	}
+
+	return queueEmail(emailJob{
+		from:      from.Address,
+		addresses: address,
+		subject:   subject,
+		msg:       msg,
+		critical:  critical,
+	})
+}
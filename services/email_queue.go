@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net/smtp"
+	"sync"
+	"time"
+
+	"github.com/juho05/h-bank/config"
+)
+
+var (
+	ErrEmailDisabled        = errors.New("emails are disabled")
+	ErrEmailQueueFull       = errors.New("email queue is full")
+	ErrEmailQueueNotStarted = errors.New("email workers are not running")
+)
+
+// Called with critical emails (e.g. authentication related ones) which could not be delivered.
+// Best-effort emails like notifications are only logged.
+var FailedEmailHandler func(addresses []string, subject string, msg []byte, reason string)
+
+type emailJob struct {
+	from      string
+	addresses []string
+	subject   string
+	msg       []byte
+	critical  bool
+}
+
+type emailSender interface {
+	send(from string, to []string, msg []byte) error
+	close()
+}
+
+var (
+	emailQueue         chan emailJob
+	emailQueueLock     sync.RWMutex
+	emailWorkersGroup  *sync.WaitGroup
+	emailWorkersCancel context.CancelFunc
+
+	emailRetryBackoff = 2 * time.Second
+	newEmailSender    = func() emailSender { return &smtpSender{} }
+)
+
+// Starts the workers which deliver queued emails.
+func StartEmailWorkers() {
+	emailQueueLock.Lock()
+	defer emailQueueLock.Unlock()
+	if emailQueue != nil {
+		return
+	}
+
+	emailQueue = make(chan emailJob, config.Data.EmailQueueSize)
+	emailWorkersGroup = &sync.WaitGroup{}
+	var ctx context.Context
+	ctx, emailWorkersCancel = context.WithCancel(context.Background())
+	for i := 0; i < config.Data.EmailWorkers; i++ {
+		emailWorkersGroup.Add(1)
+		go emailWorker(ctx, emailQueue, emailWorkersGroup)
+	}
+}
+
+// Stops accepting new emails and waits until all queued emails are delivered or have failed.
+// When ctx expires first, the workers stop retrying, the remaining emails are treated as failed and StopEmailWorkers returns
+// without waiting for sends which are still in progress.
+func StopEmailWorkers(ctx context.Context) {
+	emailQueueLock.Lock()
+	if emailQueue == nil {
+		emailQueueLock.Unlock()
+		return
+	}
+	close(emailQueue)
+	emailQueue = nil
+	group, cancel := emailWorkersGroup, emailWorkersCancel
+	emailQueueLock.Unlock()
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		group.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Println("[email] ERROR: Stopped waiting for queued emails:", ctx.Err())
+	}
+}
+
+func queueEmail(job emailJob) error {
+	emailQueueLock.RLock()
+	defer emailQueueLock.RUnlock()
+	if emailQueue == nil {
+		return ErrEmailQueueNotStarted
+	}
+
+	select {
+	case emailQueue <- job:
+		return nil
+	default:
+		return ErrEmailQueueFull
+	}
+}
+
+func emailWorker(ctx context.Context, queue <-chan emailJob, group *sync.WaitGroup) {
+	defer group.Done()
+
+	sender := newEmailSender()
+	defer sender.close()
+
+	for job := range queue {
+		// Emails which are still queued after the shutdown deadline aren't sent at all.
+		err := ctx.Err()
+		for attempt := 1; ctx.Err() == nil && attempt <= config.Data.EmailMaxAttempts; attempt++ {
+			err = sender.send(job.from, job.addresses, job.msg)
+			if err == nil {
+				break
+			}
+			// The connection might be broken, so a new one is used for the next attempt.
+			sender.close()
+			if attempt < config.Data.EmailMaxAttempts {
+				backoff := emailRetryBackoff * time.Duration(1<<(attempt-1))
+				log.Printf("[email] Failed to send email '%s' (attempt %d/%d), retrying in %s: %s", job.subject, attempt, config.Data.EmailMaxAttempts, backoff, err)
+				// When shutting down, the email is treated as failed instead of being retried.
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+				}
+			}
+		}
+		if err != nil {
+			if job.critical {
+				log.Printf("[email] CRITICAL: Permanently failed to send email '%s' to %v: %s", job.subject, job.addresses, err)
+				if FailedEmailHandler != nil {
+					FailedEmailHandler(job.addresses, job.subject, job.msg, err.Error())
+				}
+			} else {
+				log.Printf("[email] ERROR: Permanently failed to send email '%s' to %v: %s", job.subject, job.addresses, err)
+			}
+		}
+	}
+}
+
+// Sends emails over a single SMTP connection which is reused between sends.
+type smtpSender struct {
+	client *smtp.Client
+}
+
+func (s *smtpSender) connect() error {
+	if s.client != nil {
+		if s.client.Noop() == nil {
+			return nil
+		}
+		s.close()
+	}
+
+	client, err := smtp.Dial(fmt.Sprintf("%s:%d", config.Data.EmailHost, config.Data.EmailPort))
+	if err != nil {
+		return err
+	}
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: config.Data.EmailHost}); err != nil {
+			client.Close()
+			return err
+		}
+	}
+
+	if ok, _ := client.Extension("AUTH"); ok && emailAuth != nil {
+		if err = client.Auth(emailAuth); err != nil {
+			client.Close()
+			return err
+		}
+	}
+
+	s.client = client
+	return nil
+}
+
+func (s *smtpSender) send(from string, to []string, msg []byte) error {
+	err := s.connect()
+	if err != nil {
+		return err
+	}
+
+	if err = s.client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err = s.client.Rcpt(addr); err != nil {
+			s.client.Reset()
+			return err
+		}
+	}
+	w, err := s.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (s *smtpSender) close() {
+	if s.client != nil {
+		s.client.Quit()
+		s.client = nil
+	}
+}
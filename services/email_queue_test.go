@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/juho05/h-bank/config"
+)
+
+type fakeEmailSender struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     [][]string
+	closed   int
+}
+
+func (f *fakeEmailSender) send(from string, to []string, msg []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.attempts <= f.failures {
+		return errors.New("temporary failure")
+	}
+	f.sent = append(f.sent, to)
+	return nil
+}
+
+func (f *fakeEmailSender) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed++
+}
+
+func TestEmailWorkers(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		maxAttempts  int
+		critical     bool
+		wantAttempts int
+		wantSent     bool
+		wantFailed   bool
+	}{
+		{name: "Success", failures: 0, maxAttempts: 3, wantAttempts: 1, wantSent: true},
+		{name: "Retry", failures: 2, maxAttempts: 3, wantAttempts: 3, wantSent: true},
+		{name: "Permanent failure", failures: 5, maxAttempts: 3, wantAttempts: 3, wantSent: false},
+		{name: "Permanent failure critical", failures: 5, maxAttempts: 3, critical: true, wantAttempts: 3, wantSent: false, wantFailed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeEmailSender{failures: tt.failures}
+			newEmailSender = func() emailSender { return sender }
+			emailRetryBackoff = time.Millisecond
+			config.Data.EmailWorkers = 1
+			config.Data.EmailQueueSize = 1
+			config.Data.EmailMaxAttempts = tt.maxAttempts
+
+			failed := false
+			FailedEmailHandler = func(addresses []string, subject string, msg []byte, reason string) {
+				failed = true
+			}
+			defer func() { FailedEmailHandler = nil }()
+
+			StartEmailWorkers()
+			err := queueEmail(emailJob{addresses: []string{"bob@example.com"}, subject: "Test", critical: tt.critical})
+			assert.NoError(t, err)
+			StopEmailWorkers(context.Background())
+
+			assert.Equal(t, tt.wantAttempts, sender.attempts)
+			if tt.wantSent {
+				assert.Equal(t, [][]string{{"bob@example.com"}}, sender.sent)
+			} else {
+				assert.Empty(t, sender.sent)
+			}
+			assert.Equal(t, tt.wantFailed, failed)
+		})
+	}
+}
+
+func TestStopEmailWorkers_Deadline(t *testing.T) {
+	sender := &fakeEmailSender{failures: 5}
+	newEmailSender = func() emailSender { return sender }
+	emailRetryBackoff = time.Hour
+	config.Data.EmailWorkers = 1
+	config.Data.EmailQueueSize = 1
+	config.Data.EmailMaxAttempts = 3
+
+	failed := make(chan string, 1)
+	FailedEmailHandler = func(addresses []string, subject string, msg []byte, reason string) {
+		failed <- subject
+	}
+	defer func() { FailedEmailHandler = nil }()
+
+	StartEmailWorkers()
+	err := queueEmail(emailJob{addresses: []string{"bob@example.com"}, subject: "Test", critical: true})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	StopEmailWorkers(ctx)
+	assert.Less(t, time.Since(start), time.Minute)
+
+	// The worker stops retrying after the deadline and hands the critical email to the failure handler.
+	select {
+	case subject := <-failed:
+		assert.Equal(t, "Test", subject)
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed email wasn't handled after the deadline")
+	}
+	assert.Equal(t, 1, sender.attempts)
+}
+
+func TestQueueEmail_NotStarted(t *testing.T) {
+	assert.ErrorIs(t, queueEmail(emailJob{}), ErrEmailQueueNotStarted)
+}
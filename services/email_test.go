@@ -0,0 +1,218 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/juho05/h-bank/config"
+)
+
+func Test_emailTemplatePath(t *testing.T) {
+	templateDir := config.Data.EmailTemplateDir
+	t.Cleanup(func() {
+		config.Data.EmailTemplateDir = templateDir
+	})
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "de"), 0755)
+	os.WriteFile(filepath.Join(dir, "de", "invitation.html"), []byte("custom"), 0644)
+
+	tests := []struct {
+		name        string
+		templateDir string
+		template    string
+		lang        string
+		want        string
+	}{
+		{name: "No template dir", templateDir: "", template: "invitation", lang: "de", want: "templates/email/de/invitation.html"},
+		{name: "Custom template", templateDir: dir, template: "invitation", lang: "de", want: filepath.Join(dir, "de", "invitation.html")},
+		{name: "Missing language", templateDir: dir, template: "invitation", lang: "en", want: "templates/email/en/invitation.html"},
+		{name: "Missing template", templateDir: dir, template: "confirmEmail", lang: "de", want: "templates/email/de/confirmEmail.html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Data.EmailTemplateDir = tt.templateDir
+			assert.Equal(t, tt.want, emailTemplatePath(tt.template, tt.lang))
+		})
+	}
+}
+
+func TestParseEmailTemplate_Branding(t *testing.T) {
+	templateDir := config.Data.EmailTemplateDir
+	productName := config.Data.EmailProductName
+	t.Cleanup(func() {
+		config.Data.EmailTemplateDir = templateDir
+		config.Data.EmailProductName = productName
+	})
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "en"), 0755)
+	os.WriteFile(filepath.Join(dir, "en", "invitation.html"), []byte("<p>{{productName}}: {{.Name}}</p>"), 0644)
+	config.Data.EmailTemplateDir = dir
+	config.Data.EmailProductName = "Family Bank"
+
+	body, err := ParseEmailTemplate("invitation", "en", map[string]string{"Name": "Bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Family Bank: Bob</p>", body)
+}
+
+func Test_htmlToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{name: "Line breaks", html: "<p>Dear Bob,<br><br>Hello!</p>", want: "Dear Bob,\n\nHello!\n"},
+		{name: "Head", html: "<html><head><title>H-Bank</title></head><body><p>Body</p></body></html>", want: "Body\n"},
+		{name: "Link", html: `<p>Click <a href="https://example.com">here</a>.</p>`, want: "Click here (https://example.com).\n"},
+		{name: "Link without text", html: `<a href="https://example.com"><img src="logo.png"></a>`, want: "https://example.com\n"},
+		{name: "Entities", html: "<p>&quot;Group&quot; &amp; more</p>", want: "\"Group\" & more\n"},
+		{name: "Whitespace", html: "<p>\n\t\tA   lot of\n\t\tspace\n</p>\n\n\n<p>Next</p>", want: "A lot of space\n\nNext\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, htmlToText(tt.html))
+		})
+	}
+}
+
+func Test_buildEmailMessage(t *testing.T) {
+	from := &mail.Address{Name: "H-Bank", Address: "noreply@hbank.example"}
+	replyTo := &mail.Address{Address: "support@hbank.example"}
+	msg, err := buildEmailMessage(from, replyTo, "Invitation", "<p>Dear Bob,<br>you were invited.</p>")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Invitation", m.Header.Get("Subject"))
+	assert.Equal(t, `"H-Bank" <noreply@hbank.example>`, m.Header.Get("From"))
+	assert.Equal(t, "<support@hbank.example>", m.Header.Get("Reply-To"))
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		content, err := io.ReadAll(part)
+		assert.NoError(t, err)
+		parts[contentType] = string(content)
+	}
+
+	assert.Equal(t, "Dear Bob,\r\nyou were invited.\r\n", parts["text/plain"])
+	assert.Equal(t, "<p>Dear Bob,<br>you were invited.</p>", parts["text/html"])
+}
+
+func Test_buildEmailMessage_Attachment(t *testing.T) {
+	from := &mail.Address{Address: "noreply@hbank.example"}
+	data := []byte("%PDF-1.3 receipt")
+	msg, err := buildEmailMessage(from, nil, "Receipt", "<p>Receipt</p>", EmailAttachment{
+		Filename:    "receipt.pdf",
+		ContentType: "application/pdf",
+		Data:        data,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if !assert.NoError(t, err) {
+		return
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	reader := multipart.NewReader(m.Body, params["boundary"])
+	part, err := reader.NextPart()
+	if !assert.NoError(t, err) {
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	assert.Equal(t, "multipart/alternative", contentType)
+
+	part, err = reader.NextPart()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "application/pdf", part.Header.Get("Content-Type"))
+	assert.Equal(t, "receipt.pdf", part.FileName())
+	content, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+	if assert.NoError(t, err) {
+		assert.Equal(t, data, content)
+	}
+}
+
+func Test_emailIdentity(t *testing.T) {
+	config.Data.EmailFrom = "H-Bank <noreply@hbank.example>"
+	config.Data.EmailReplyTo = "support@hbank.example"
+	config.Data.EmailAuthFrom = "auth@hbank.example"
+	config.Data.EmailAuthReplyTo = ""
+
+	tests := []struct {
+		name        string
+		critical    bool
+		wantFrom    string
+		wantReplyTo string
+	}{
+		{name: "Notification", critical: false, wantFrom: "noreply@hbank.example", wantReplyTo: "support@hbank.example"},
+		{name: "Authentication", critical: true, wantFrom: "auth@hbank.example", wantReplyTo: "support@hbank.example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, replyTo, err := emailIdentity(tt.critical)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.wantFrom, from.Address)
+				assert.Equal(t, tt.wantReplyTo, replyTo.Address)
+			}
+		})
+	}
+}
+
+func TestPreviewEmail(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "en"), 0755)
+	os.WriteFile(filepath.Join(dir, "en", "confirmEmail.html"), []byte("<p>{{.Name}}: {{.Code}}</p>"), 0644)
+	config.Data.EmailTemplateDir = dir
+	defer func() { config.Data.EmailTemplateDir = "" }()
+
+	body, err := PreviewEmail("confirm", "en")
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Bob: 123456</p>", body)
+
+	_, err = PreviewEmail("unknown", "en")
+	assert.ErrorIs(t, err, ErrUnknownEmailType)
+}
+
+func Test_emailPreviews(t *testing.T) {
+	for emailType, preview := range emailPreviews {
+		_, err := os.Stat(filepath.Join("..", "templates", "email", "en", preview.template+".html"))
+		assert.NoError(t, err, "template of email type %s", emailType)
+	}
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"net"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Returns an IP extractor which only honors the X-Forwarded-For header of requests coming from a trusted proxy.
+// Use c.RealIP() to get the client IP in handlers and middlewares.
+func NewIPExtractor(trustedProxies []*net.IPNet) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, ipNet := range trustedProxies {
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...)
+}
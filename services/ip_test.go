@@ -0,0 +1,39 @@
+package services
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIPExtractor(t *testing.T) {
+	_, proxyNet, _ := net.ParseCIDR("10.0.0.0/24")
+
+	tests := []struct {
+		name           string
+		trustedProxies []*net.IPNet
+		remoteAddr     string
+		xff            string
+		want           string
+	}{
+		{name: "No trusted proxies", trustedProxies: nil, remoteAddr: "10.0.0.1:1234", xff: "1.2.3.4", want: "10.0.0.1"},
+		{name: "Trusted proxy", trustedProxies: []*net.IPNet{proxyNet}, remoteAddr: "10.0.0.1:1234", xff: "1.2.3.4", want: "1.2.3.4"},
+		{name: "Untrusted proxy", trustedProxies: []*net.IPNet{proxyNet}, remoteAddr: "192.168.0.1:1234", xff: "1.2.3.4", want: "192.168.0.1"},
+		{name: "Spoofed header", trustedProxies: []*net.IPNet{proxyNet}, remoteAddr: "10.0.0.1:1234", xff: "6.6.6.6, 1.2.3.4", want: "1.2.3.4"},
+		{name: "Chained trusted proxies", trustedProxies: []*net.IPNet{proxyNet}, remoteAddr: "10.0.0.1:1234", xff: "1.2.3.4, 10.0.0.2", want: "1.2.3.4"},
+		{name: "No header", trustedProxies: []*net.IPNet{proxyNet}, remoteAddr: "10.0.0.1:1234", xff: "", want: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			assert.Equal(t, tt.want, NewIPExtractor(tt.trustedProxies)(req))
+		})
+	}
+}
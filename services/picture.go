@@ -2,12 +2,19 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/disintegration/imaging"
+
+	"github.com/juho05/h-bank/config"
 )
 
 type Picture struct {
@@ -28,23 +35,114 @@ const (
 	PictureHuge   PictureSize = "huge"
 )
 
+// Edge lengths in pixels of the stored picture sizes.
+var PictureDimensions = map[PictureSize]int{
+	PictureTiny:   64,
+	PictureSmall:  128,
+	PictureMedium: 256,
+	PictureLarge:  512,
+	PictureHuge:   1024,
+}
+
 func (ps PictureSize) Validate() bool {
 	return ps == PictureTiny || ps == PictureSmall || ps == PictureMedium || ps == PictureLarge || ps == PictureHuge
 }
 
+// Maps the picture formats which can be decoded to their mime types.
+var pictureMimeTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+}
+
+func pictureFormatAllowed(format string) bool {
+	return slices.Contains(config.Data.AllowedPictureFormats, format)
+}
+
 func SupportedPictureMimeType(mimeType string) bool {
-	return mimeType == "image/jpeg" || mimeType == "image/png" || mimeType == "image/gif"
+	for format, m := range pictureMimeTypes {
+		if m == mimeType {
+			return pictureFormatAllowed(format)
+		}
+	}
+	return false
+}
+
+// Checks the actual format of the picture data without decoding the whole image.
+func SupportedPictureData(data []byte) bool {
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	return pictureFormatAllowed(format)
+}
+
+// Returned by NewPicture if all picture processing slots stay busy for PictureQueueTimeout.
+var ErrPictureProcessingBusy = errors.New("too many pictures are being processed")
+
+// Max time NewPicture waits for a free picture processing slot.
+const PictureQueueTimeout = 5 * time.Second
+
+// Limits the number of concurrent operations. A nil semaphore doesn't limit anything.
+type semaphore chan struct{}
+
+// Waits until a slot is free, the timeout passes or ctx is cancelled.
+func (s semaphore) acquire(ctx context.Context, timeout time.Duration) error {
+	if s == nil {
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrPictureProcessingBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+var (
+	pictureSlots     semaphore
+	pictureSlotsOnce sync.Once
+)
+
+func pictureSemaphore() semaphore {
+	pictureSlotsOnce.Do(func() {
+		if config.Data.PictureProcessingLimit > 0 {
+			pictureSlots = make(semaphore, config.Data.PictureProcessingLimit)
+		}
+	})
+	return pictureSlots
 }
 
-func NewPicture(data []byte, mimeType string) (*Picture, error) {
+// Waits for a free picture processing slot if config.Data.PictureProcessingLimit pictures are already being processed.
+func NewPicture(ctx context.Context, data []byte, mimeType string) (*Picture, error) {
 	var img image.Image
 	var err error
 
-	if mimeType == "image/jpeg" || mimeType == "image/png" || mimeType == "image/gif" {
-		img, err = loadStdImage(data)
-		if err != nil {
-			return nil, err
-		}
+	if !SupportedPictureMimeType(mimeType) {
+		return nil, errors.New("unsupported picture format")
+	}
+
+	slots := pictureSemaphore()
+	err = slots.acquire(ctx, PictureQueueTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer slots.release()
+
+	img, err = loadStdImage(data)
+	if err != nil {
+		return nil, err
 	}
 
 	if img.Bounds().Dx() > img.Bounds().Dy() {
@@ -58,37 +156,38 @@ func NewPicture(data []byte, mimeType string) (*Picture, error) {
 
 func createPictureFromImage(img image.Image) (*Picture, error) {
 	var picture Picture
+	quality := imaging.JPEGQuality(config.Data.PictureQuality)
 
 	huge := bytes.Buffer{}
-	err := imaging.Encode(&huge, imaging.Resize(img, 1024, 1024, imaging.Linear), imaging.JPEG)
+	err := imaging.Encode(&huge, imaging.Resize(img, PictureDimensions[PictureHuge], PictureDimensions[PictureHuge], imaging.Linear), imaging.JPEG, quality)
 	if err != nil {
 		return nil, err
 	}
 	picture.Huge = huge.Bytes()
 
 	large := bytes.Buffer{}
-	err = imaging.Encode(&large, imaging.Resize(img, 512, 512, imaging.Linear), imaging.JPEG)
+	err = imaging.Encode(&large, imaging.Resize(img, PictureDimensions[PictureLarge], PictureDimensions[PictureLarge], imaging.Linear), imaging.JPEG, quality)
 	if err != nil {
 		return nil, err
 	}
 	picture.Large = large.Bytes()
 
 	medium := bytes.Buffer{}
-	err = imaging.Encode(&medium, imaging.Resize(img, 256, 256, imaging.Linear), imaging.JPEG)
+	err = imaging.Encode(&medium, imaging.Resize(img, PictureDimensions[PictureMedium], PictureDimensions[PictureMedium], imaging.Linear), imaging.JPEG, quality)
 	if err != nil {
 		return nil, err
 	}
 	picture.Medium = medium.Bytes()
 
 	small := bytes.Buffer{}
-	err = imaging.Encode(&small, imaging.Resize(img, 128, 128, imaging.Linear), imaging.JPEG)
+	err = imaging.Encode(&small, imaging.Resize(img, PictureDimensions[PictureSmall], PictureDimensions[PictureSmall], imaging.Linear), imaging.JPEG, quality)
 	if err != nil {
 		return nil, err
 	}
 	picture.Small = small.Bytes()
 
 	tiny := bytes.Buffer{}
-	err = imaging.Encode(&tiny, imaging.Resize(img, 64, 64, imaging.Linear), imaging.JPEG)
+	err = imaging.Encode(&tiny, imaging.Resize(img, PictureDimensions[PictureTiny], PictureDimensions[PictureTiny], imaging.Linear), imaging.JPEG, quality)
 	if err != nil {
 		return nil, err
 	}
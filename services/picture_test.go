@@ -0,0 +1,105 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/juho05/h-bank/config"
+)
+
+func TestSupportedPicture(t *testing.T) {
+	var pngData bytes.Buffer
+	png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+
+	allowedFormats := config.Data.AllowedPictureFormats
+	t.Cleanup(func() { config.Data.AllowedPictureFormats = allowedFormats })
+
+	tests := []struct {
+		name           string
+		allowed        []string
+		mimeType       string
+		data           []byte
+		wantMimeType   bool
+		wantDataResult bool
+	}{
+		{name: "Allowed", allowed: []string{"jpeg", "png"}, mimeType: "image/png", data: pngData.Bytes(), wantMimeType: true, wantDataResult: true},
+		{name: "Disallowed", allowed: []string{"jpeg"}, mimeType: "image/png", data: pngData.Bytes(), wantMimeType: false, wantDataResult: false},
+		{name: "Unknown mime type", allowed: []string{"jpeg", "png"}, mimeType: "image/heic", data: pngData.Bytes(), wantMimeType: false, wantDataResult: true},
+		{name: "Invalid data", allowed: []string{"jpeg", "png"}, mimeType: "image/png", data: []byte("not an image"), wantMimeType: true, wantDataResult: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Data.AllowedPictureFormats = tt.allowed
+			assert.Equal(t, tt.wantMimeType, SupportedPictureMimeType(tt.mimeType))
+			assert.Equal(t, tt.wantDataResult, SupportedPictureData(tt.data))
+		})
+	}
+}
+
+func TestPictureDimensions(t *testing.T) {
+	for _, size := range []PictureSize{PictureTiny, PictureSmall, PictureMedium, PictureLarge, PictureHuge} {
+		assert.Contains(t, PictureDimensions, size)
+	}
+	for size := range PictureDimensions {
+		assert.True(t, size.Validate(), "size %s", size)
+	}
+}
+
+func TestNewPicture_Quality(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: uint8((x * y) % 256), A: 255})
+		}
+	}
+	var pngData bytes.Buffer
+	png.Encode(&pngData, img)
+
+	allowedFormats, quality := config.Data.AllowedPictureFormats, config.Data.PictureQuality
+	t.Cleanup(func() {
+		config.Data.AllowedPictureFormats, config.Data.PictureQuality = allowedFormats, quality
+	})
+
+	config.Data.AllowedPictureFormats = []string{"png"}
+
+	config.Data.PictureQuality = 100
+	high, err := NewPicture(context.Background(), pngData.Bytes(), "image/png")
+	assert.NoError(t, err)
+
+	config.Data.PictureQuality = 10
+	low, err := NewPicture(context.Background(), pngData.Bytes(), "image/png")
+	assert.NoError(t, err)
+
+	assert.Less(t, len(low.Huge), len(high.Huge))
+	assert.Less(t, len(low.Tiny), len(high.Tiny))
+}
+
+func TestSemaphore(t *testing.T) {
+	s := make(semaphore, 1)
+
+	assert.NoError(t, s.acquire(context.Background(), time.Second))
+	assert.ErrorIs(t, s.acquire(context.Background(), 10*time.Millisecond), ErrPictureProcessingBusy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, s.acquire(ctx, time.Second), context.Canceled)
+
+	done := make(chan error)
+	go func() {
+		done <- s.acquire(context.Background(), time.Second)
+	}()
+	s.release()
+	assert.NoError(t, <-done, "waiting requests get the released slot")
+	s.release()
+
+	var unlimited semaphore
+	assert.NoError(t, unlimited.acquire(context.Background(), 0))
+	unlimited.release()
+}
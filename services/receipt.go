@@ -0,0 +1,63 @@
+package services
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+type Receipt struct {
+	// Reference of the transaction, see models.TransactionReference
+	Reference    string
+	Time         int64
+	GroupName    string
+	Title        string
+	Description  string
+	Amount       int
+	Unit         string
+	SenderName   string
+	ReceiverName string
+	// Balance of the recipient of the receipt after the transaction
+	NewBalance int
+}
+
+func GenerateReceiptPDF(receipt Receipt, lang string) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(tr(Tr("Transaction receipt", lang)), false)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.CellFormat(0, 12, tr(Tr("Transaction receipt", lang)), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.CellFormat(0, 8, tr(receipt.GroupName), "", 1, "L", false, 0, "")
+	pdf.Ln(6)
+
+	rows := [][2]string{
+		{Tr("Title", lang), receipt.Title},
+		{Tr("Amount", lang), FormatAmount(receipt.Amount, receipt.Unit)},
+		{Tr("Sender", lang), receipt.SenderName},
+		{Tr("Receiver", lang), receipt.ReceiverName},
+		{Tr("Time", lang), time.Unix(receipt.Time, 0).UTC().Format("2006-01-02 15:04:05 MST")},
+		{Tr("New balance", lang), FormatAmount(receipt.NewBalance, receipt.Unit)},
+		{Tr("Reference", lang), receipt.Reference},
+	}
+	if receipt.Description != "" {
+		rows = append(rows[:1], append([][2]string{{Tr("Description", lang), receipt.Description}}, rows[1:]...)...)
+	}
+
+	for _, row := range rows {
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(45, 8, tr(row[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.MultiCell(0, 8, tr(row[1]), "", "L", false)
+	}
+
+	buf := new(bytes.Buffer)
+	err := pdf.Output(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateReceiptPDF(t *testing.T) {
+	pdf, err := GenerateReceiptPDF(Receipt{
+		Reference:    "ref",
+		Time:         1700000000,
+		GroupName:    "Größe",
+		Title:        "Pocket money",
+		Amount:       1050,
+		Unit:         "€",
+		SenderName:   "Bank",
+		ReceiverName: "bob",
+		NewBalance:   1050,
+	}, "de")
+	if assert.NoError(t, err) {
+		assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF")))
+	}
+}
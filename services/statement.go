@@ -0,0 +1,74 @@
+package services
+
+import (
+	"bytes"
+
+	"github.com/go-pdf/fpdf"
+)
+
+type StatementMember struct {
+	UserId           string
+	Name             string
+	OpeningBalance   int
+	TotalIn          int
+	TotalOut         int
+	ClosingBalance   int
+	TransactionCount int
+}
+
+// Aggregated transactions of a group between From (inclusive) and To (exclusive).
+type Statement struct {
+	GroupName string
+	Month     string
+	From      int64
+	To        int64
+	Unit      string
+	Members   []StatementMember
+}
+
+func GenerateStatementPDF(statement Statement, lang string) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(tr(Tr("Monthly statement", lang)), false)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.CellFormat(0, 12, tr(Tr("Monthly statement", lang)), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.CellFormat(0, 8, tr(statement.GroupName+" - "+statement.Month), "", 1, "L", false, 0, "")
+	pdf.Ln(6)
+
+	widths := []float64{54, 34, 34, 34, 34}
+	header := []string{Tr("Member", lang), Tr("Opening balance", lang), Tr("In", lang), Tr("Out", lang), Tr("Closing balance", lang)}
+	pdf.SetFont("Helvetica", "B", 10)
+	for i, h := range header {
+		pdf.CellFormat(widths[i], 8, tr(h), "B", 0, "L", false, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, m := range statement.Members {
+		row := []string{
+			m.Name,
+			FormatAmount(m.OpeningBalance, statement.Unit),
+			FormatAmount(m.TotalIn, statement.Unit),
+			FormatAmount(m.TotalOut, statement.Unit),
+			FormatAmount(m.ClosingBalance, statement.Unit),
+		}
+		for i, value := range row {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(widths[i], 7, tr(value), "", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	buf := new(bytes.Buffer)
+	err := pdf.Output(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
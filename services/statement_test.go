@@ -0,0 +1,22 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateStatementPDF(t *testing.T) {
+	pdf, err := GenerateStatementPDF(Statement{
+		GroupName: "Größe",
+		Month:     "2024-05",
+		Unit:      "€",
+		Members: []StatementMember{
+			{UserId: "1", Name: "bob", OpeningBalance: 100, TotalIn: 50, TotalOut: 20, ClosingBalance: 130, TransactionCount: 2},
+		},
+	}, "de")
+	if assert.NoError(t, err) {
+		assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF")))
+	}
+}
@@ -1,12 +1,22 @@
 package services
 
 import (
+	"errors"
 	"log"
 	"time"
+	// Time zones of payment plans must also be available in minimal containers.
+	_ "time/tzdata"
 )
 
+var ErrInvalidTimeZone = errors.New("invalid time zone")
+
 func AddTime(unixTime int64, value int, unit string) int64 {
-	t := time.Unix(unixTime, 0).UTC()
+	return AddTimeIn(unixTime, value, unit, time.UTC)
+}
+
+// Like AddTime but the calendar arithmetic happens in loc, so the local time of day is kept across DST changes.
+func AddTimeIn(unixTime int64, value int, unit string, loc *time.Location) int64 {
+	t := time.Unix(unixTime, 0).In(loc)
 	switch unit {
 	case "day":
 		return t.AddDate(0, 0, value).Unix()
@@ -21,3 +31,94 @@ func AddTime(unixTime int64, value int, unit string) int64 {
 		return 0
 	}
 }
+
+// Returns the location of an IANA time zone name like "Europe/Berlin". An empty name means UTC.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	// "Local" depends on the server and is therefore not accepted.
+	if name == "Local" {
+		return nil, ErrInvalidTimeZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimeZone
+	}
+	return loc, nil
+}
+
+// Returns the location of the time zone of a payment plan. Unknown time zones fall back to UTC.
+func PaymentPlanLocation(timeZone string) *time.Location {
+	loc, err := LoadTimeZone(timeZone)
+	if err != nil {
+		log.Printf("Error: unknown time zone of payment plan '%s', falling back to UTC", timeZone)
+		return time.UTC
+	}
+	return loc
+}
+
+// Returns the execution of a payment plan following unixTime. Payment plans are executed at the start of the day in their time zone.
+// The schedule is advanced on the calendar date, so executions don't drift across DST changes or days without a midnight.
+func NextPaymentPlanExecution(unixTime int64, schedule int, scheduleUnit string, timeZone string) int64 {
+	loc := PaymentPlanLocation(timeZone)
+	t := time.Unix(unixTime, 0).In(loc)
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	next := time.Unix(AddTime(date.Unix(), schedule, scheduleUnit), 0).UTC()
+	return StartOfDay(next.Year(), next.Month(), next.Day(), loc).Unix()
+}
+
+// Returns the first instant of the date in loc. If a DST change skips midnight, the day starts at the end of the transition.
+func StartOfDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	// Normalizes out of range values like the 32nd of January.
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	t := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	// time.Date normalizes a skipped midnight to the previous day.
+	if t.Day() != date.Day() {
+		_, t = t.ZoneBounds()
+	}
+	return t
+}
+
+// Parses a date with format "YYYY-MM-DD" and returns the start of that day in loc.
+func ParseDateIn(date string, loc *time.Location) (time.Time, error) {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return StartOfDay(d.Year(), d.Month(), d.Day(), loc), nil
+}
+
+// Returns the start of the day, week (Monday) or month containing unixTime in UTC.
+func StartOfInterval(unixTime int64, unit string) int64 {
+	t := time.Unix(unixTime, 0).UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch unit {
+	case "day":
+		return day.Unix()
+	case "week":
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7).Unix()
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).Unix()
+	default:
+		log.Println("Error: unknown time unit:", unit)
+		return 0
+	}
+}
+
+// Returns the total amount moved by a payment plan and the time of its final execution in the time zone of the plan.
+// Payment plans with a negative payment count are unlimited and only the amount per period is returned.
+func EstimatePaymentPlan(amount, paymentCount, schedule int, scheduleUnit string, firstPayment int64, timeZone string) (total int, finalPayment int64, indefinite bool) {
+	if paymentCount < 0 {
+		return amount, 0, true
+	}
+	if paymentCount == 0 {
+		return 0, 0, false
+	}
+	// Executions are scheduled relative to the previous one like in the payment plan executor, so the months can't simply be added up.
+	finalPayment = firstPayment
+	for i := 1; i < paymentCount; i++ {
+		finalPayment = NextPaymentPlanExecution(finalPayment, schedule, scheduleUnit, timeZone)
+	}
+	return amount * paymentCount, finalPayment, false
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimatePaymentPlan(t *testing.T) {
+	first := time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC).Unix()
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	tests := []struct {
+		name             string
+		amount           int
+		paymentCount     int
+		schedule         int
+		scheduleUnit     string
+		firstPayment     int64
+		timeZone         string
+		wantTotal        int
+		wantFinalPayment int64
+		wantIndefinite   bool
+	}{
+		{name: "Single payment", amount: 500, paymentCount: 1, schedule: 1, scheduleUnit: "week", firstPayment: first, wantTotal: 500, wantFinalPayment: first},
+		{name: "Weekly", amount: 500, paymentCount: 3, schedule: 2, scheduleUnit: "week", firstPayment: first, wantTotal: 1500, wantFinalPayment: time.Date(2024, time.February, 28, 0, 0, 0, 0, time.UTC).Unix()},
+		{name: "Monthly", amount: 100, paymentCount: 3, schedule: 1, scheduleUnit: "month", firstPayment: first, wantTotal: 300, wantFinalPayment: time.Date(2024, time.April, 2, 0, 0, 0, 0, time.UTC).Unix()},
+		{name: "Across DST change", amount: 100, paymentCount: 3, schedule: 1, scheduleUnit: "day", firstPayment: time.Date(2024, time.March, 30, 0, 0, 0, 0, berlin).Unix(), timeZone: "Europe/Berlin", wantTotal: 300, wantFinalPayment: time.Date(2024, time.April, 1, 0, 0, 0, 0, berlin).Unix()},
+		{name: "Unlimited", amount: 250, paymentCount: -1, schedule: 1, scheduleUnit: "day", firstPayment: first, wantTotal: 250, wantIndefinite: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, finalPayment, indefinite := EstimatePaymentPlan(tt.amount, tt.paymentCount, tt.schedule, tt.scheduleUnit, tt.firstPayment, tt.timeZone)
+			assert.Equal(t, tt.wantTotal, total)
+			assert.Equal(t, tt.wantFinalPayment, finalPayment)
+			assert.Equal(t, tt.wantIndefinite, indefinite)
+		})
+	}
+}
+
+func TestStartOfInterval(t *testing.T) {
+	date := func(year int, month time.Month, day, hour int) int64 {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.UTC).Unix()
+	}
+	tests := []struct {
+		name string
+		time int64
+		unit string
+		want int64
+	}{
+		{name: "Day", time: date(2024, time.March, 13, 15), unit: "day", want: date(2024, time.March, 13, 0)},
+		{name: "Week on Wednesday", time: date(2024, time.March, 13, 15), unit: "week", want: date(2024, time.March, 11, 0)},
+		{name: "Week on Monday", time: date(2024, time.March, 11, 0), unit: "week", want: date(2024, time.March, 11, 0)},
+		{name: "Week on Sunday", time: date(2024, time.March, 17, 23), unit: "week", want: date(2024, time.March, 11, 0)},
+		{name: "Week across months", time: date(2024, time.March, 2, 12), unit: "week", want: date(2024, time.February, 26, 0)},
+		{name: "Month", time: date(2024, time.March, 31, 23), unit: "month", want: date(2024, time.March, 1, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, StartOfInterval(tt.time, tt.unit))
+		})
+	}
+}
+
+func TestNextPaymentPlanExecution(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Couldn't load time zone: %s", err)
+	}
+	local := func(year int, month time.Month, day int) int64 {
+		return time.Date(year, month, day, 0, 0, 0, 0, berlin).Unix()
+	}
+	chile, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Fatalf("Couldn't load time zone: %s", err)
+	}
+	// DST starts at midnight in Chile, so some days start at 01:00.
+	santiago := func(year int, month time.Month, day int) int64 {
+		return StartOfDay(year, month, day, chile).Unix()
+	}
+	utc := func(year int, month time.Month, day int) int64 {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix()
+	}
+	tests := []struct {
+		name         string
+		time         int64
+		schedule     int
+		scheduleUnit string
+		timeZone     string
+		want         int64
+	}{
+		{name: "UTC", time: utc(2024, time.March, 1), schedule: 1, scheduleUnit: "month", want: utc(2024, time.April, 1)},
+		{name: "Into DST", time: local(2024, time.March, 1), schedule: 1, scheduleUnit: "month", timeZone: "Europe/Berlin", want: local(2024, time.April, 1)},
+		{name: "Out of DST", time: local(2024, time.October, 1), schedule: 1, scheduleUnit: "month", timeZone: "Europe/Berlin", want: local(2024, time.November, 1)},
+		{name: "Day of DST change", time: local(2024, time.March, 30), schedule: 1, scheduleUnit: "day", timeZone: "Europe/Berlin", want: local(2024, time.March, 31)},
+		{name: "Day after DST change", time: local(2024, time.March, 31), schedule: 1, scheduleUnit: "day", timeZone: "Europe/Berlin", want: local(2024, time.April, 1)},
+		{name: "Week across DST change", time: local(2024, time.October, 24), schedule: 1, scheduleUnit: "week", timeZone: "Europe/Berlin", want: local(2024, time.October, 31)},
+		{name: "Unknown time zone", time: utc(2024, time.March, 1), schedule: 1, scheduleUnit: "day", timeZone: "Mars/Olympus", want: utc(2024, time.March, 2)},
+		{name: "Day across fall back", time: local(2024, time.October, 26), schedule: 2, scheduleUnit: "day", timeZone: "Europe/Berlin", want: local(2024, time.October, 28)},
+		{name: "Year across DST", time: local(2024, time.January, 15), schedule: 1, scheduleUnit: "year", timeZone: "Europe/Berlin", want: local(2025, time.January, 15)},
+		{name: "Into skipped midnight", time: santiago(2024, time.September, 7), schedule: 1, scheduleUnit: "day", timeZone: "America/Santiago", want: santiago(2024, time.September, 8)},
+		{name: "Out of skipped midnight", time: santiago(2024, time.September, 8), schedule: 1, scheduleUnit: "day", timeZone: "America/Santiago", want: santiago(2024, time.September, 9)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NextPaymentPlanExecution(tt.time, tt.schedule, tt.scheduleUnit, tt.timeZone))
+		})
+	}
+
+	// The day of the DST change only has 23 hours.
+	assert.Equal(t, int64(23*60*60), local(2024, time.April, 1)-local(2024, time.March, 31))
+}
+
+func TestNextPaymentPlanExecution_Stable(t *testing.T) {
+	for _, timeZone := range []string{"Europe/Berlin", "America/New_York", "America/Santiago", "Australia/Lord_Howe"} {
+		t.Run(timeZone, func(t *testing.T) {
+			loc, err := time.LoadLocation(timeZone)
+			if err != nil {
+				t.Fatalf("Couldn't load time zone: %s", err)
+			}
+			for _, unit := range []string{"day", "week", "month"} {
+				execute := StartOfDay(2024, time.January, 1, loc).Unix()
+				for i := 0; i < 800; i++ {
+					next := NextPaymentPlanExecution(execute, 1, unit, timeZone)
+					prev := time.Unix(execute, 0).In(loc)
+					want := StartOfDay(prev.Year(), prev.Month(), prev.Day(), loc)
+					switch unit {
+					case "day":
+						want = StartOfDay(prev.Year(), prev.Month(), prev.Day()+1, loc)
+					case "week":
+						want = StartOfDay(prev.Year(), prev.Month(), prev.Day()+7, loc)
+					case "month":
+						want = StartOfDay(prev.Year(), prev.Month()+1, prev.Day(), loc)
+					}
+					if !assert.Equal(t, want.Unix(), next, "%s after %s", unit, prev) {
+						return
+					}
+					execute = next
+				}
+			}
+		})
+	}
+}
+
+func TestStartOfDay(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	santiago, _ := time.LoadLocation("America/Santiago")
+
+	day := StartOfDay(2024, time.March, 31, berlin)
+	assert.Equal(t, time.Date(2024, time.March, 30, 23, 0, 0, 0, time.UTC), day.UTC())
+
+	// Midnight doesn't exist on 2024-09-08 in Chile.
+	day = StartOfDay(2024, time.September, 8, santiago)
+	assert.Equal(t, 8, day.Day())
+	assert.Equal(t, 1, day.Hour())
+	assert.Equal(t, time.Date(2024, time.September, 8, 4, 0, 0, 0, time.UTC), day.UTC())
+
+	date, err := ParseDateIn("2024-09-08", santiago)
+	assert.NoError(t, err)
+	assert.Equal(t, day, date)
+
+	_, err = ParseDateIn("08.09.2024", santiago)
+	assert.Error(t, err)
+}
+
+func TestLoadTimeZone(t *testing.T) {
+	loc, err := LoadTimeZone("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	loc, err = LoadTimeZone("Europe/Berlin")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Europe/Berlin", loc.String())
+	}
+
+	_, err = LoadTimeZone("Local")
+	assert.ErrorIs(t, err, ErrInvalidTimeZone)
+
+	_, err = LoadTimeZone("Mars/Olympus")
+	assert.ErrorIs(t, err, ErrInvalidTimeZone)
+}
@@ -2,9 +2,18 @@ package services
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/juho05/h-bank/config"
 )
 
+func IsSiteAdmin(userId string) bool {
+	return slices.Contains(config.Data.SiteAdmins, userId)
+}
+
 func StrToBool(value string) bool {
 	return strings.EqualFold(value, "true") || strings.EqualFold(value, "t") ||
 		strings.EqualFold(value, "yes") || strings.EqualFold(value, "y") ||
@@ -22,3 +31,77 @@ func SizeInBytesToStr(size int64) string {
 		return fmt.Sprintf("%d B", size)
 	}
 }
+
+// Formats an amount for display. Amounts of groups using the currency of the cash log (config.Data.CashUnit) are stored in cents.
+func FormatAmount(amount int, unit string) string {
+	if unit != config.Data.CashUnit {
+		return fmt.Sprintf("%d %s", amount, unit)
+	}
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d %s", sign, amount/100, amount%100, unit)
+}
+
+// Prefixes cells starting with =, +, - or @ with ' so spreadsheet applications don't evaluate them as formulas.
+func EscapeCSVCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// Expands the placeholders {date}, {count} and {remaining} in the name or description of a payment plan.
+// A negative remaining count stands for an unlimited payment plan. The date is formatted in loc.
+func ExpandPaymentPlanTemplate(text string, date int64, loc *time.Location, count, remaining int) string {
+	remainingStr := "∞"
+	if remaining >= 0 {
+		remainingStr = strconv.Itoa(remaining)
+	}
+	return strings.NewReplacer(
+		"{date}", time.Unix(date, 0).In(loc).Format("2006-01-02"),
+		"{count}", strconv.Itoa(count),
+		"{remaining}", remainingStr,
+	).Replace(text)
+}
+
+// Splits total into the given number of parts which differ by at most 1 and sum up to exactly total.
+// The remainder is distributed to the first parts, so the result only depends on the arguments.
+// Returns nil if shares is not positive.
+func DistributeAmount(total int, shares int) []int {
+	if shares <= 0 {
+		return nil
+	}
+
+	parts := make([]int, shares)
+	base := total / shares
+	remainder := total % shares
+
+	step := 1
+	if remainder < 0 {
+		step = -1
+		remainder = -remainder
+	}
+
+	for i := range parts {
+		parts[i] = base
+		if i < remainder {
+			parts[i] += step
+		}
+	}
+	return parts
+}
+
+// Greedily picks coins and notes which sum up to exactly amount, starting with the highest value.
+// values must be sorted in descending order and available contains the number of coins and notes of each value.
+// Returns the number of picked coins and notes per value and whether the amount could be reached.
+func MakeChange(amount int, values []int, available []int) ([]int, bool) {
+	picked := make([]int, len(values))
+	for i, value := range values {
+		picked[i] = min(available[i], amount/value)
+		amount -= picked[i] * value
+	}
+	return picked, amount == 0
+}
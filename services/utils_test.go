@@ -3,8 +3,11 @@ package services
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/juho05/h-bank/config"
 )
 
 func TestStrToBool(t *testing.T) {
@@ -35,3 +38,147 @@ func TestStrToBool(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatAmount(t *testing.T) {
+	cashUnit := config.Data.CashUnit
+	t.Cleanup(func() { config.Data.CashUnit = cashUnit })
+
+	tests := []struct {
+		amount   int
+		unit     string
+		cashUnit string
+		want     string
+	}{
+		{amount: 1234, unit: "€", cashUnit: "€", want: "12.34 €"},
+		{amount: 5, unit: "€", cashUnit: "€", want: "0.05 €"},
+		{amount: -250, unit: "€", cashUnit: "€", want: "-2.50 €"},
+		{amount: 42, unit: "points", cashUnit: "€", want: "42 points"},
+		{amount: 1234, unit: "$", cashUnit: "$", want: "12.34 $"},
+		{amount: 1234, unit: "€", cashUnit: "$", want: "1234 €"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			config.Data.CashUnit = tt.cashUnit
+			assert.Equal(t, tt.want, FormatAmount(tt.amount, tt.unit))
+		})
+	}
+}
+
+func TestEscapeCSVCell(t *testing.T) {
+	tests := []struct {
+		cell string
+		want string
+	}{
+		{cell: "bob", want: "bob"},
+		{cell: "", want: ""},
+		{cell: "a=b", want: "a=b"},
+		{cell: "=HYPERLINK(\"http://example.com\")", want: "'=HYPERLINK(\"http://example.com\")"},
+		{cell: "+1", want: "'+1"},
+		{cell: "-1", want: "'-1"},
+		{cell: "@SUM(A1)", want: "'@SUM(A1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cell, func(t *testing.T) {
+			assert.Equal(t, tt.want, EscapeCSVCell(tt.cell))
+		})
+	}
+}
+
+func TestExpandPaymentPlanTemplate(t *testing.T) {
+	date := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC).Unix()
+	tests := []struct {
+		name      string
+		text      string
+		count     int
+		remaining int
+		want      string
+	}{
+		{name: "Literal", text: "Monthly dues", count: 3, remaining: 9, want: "Monthly dues"},
+		{name: "Count", text: "Dues {count}/12", count: 3, remaining: 9, want: "Dues 3/12"},
+		{name: "Date", text: "Rent {date}", count: 1, remaining: 0, want: "Rent 2024-03-01"},
+		{name: "Remaining", text: "{remaining} payments left", count: 3, remaining: 9, want: "9 payments left"},
+		{name: "Unlimited", text: "{count} of {remaining}", count: 5, remaining: -1, want: "5 of ∞"},
+		{name: "Unknown placeholder", text: "{name} {count}", count: 2, remaining: 1, want: "{name} 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExpandPaymentPlanTemplate(tt.text, date, time.UTC, tt.count, tt.remaining))
+		})
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Couldn't load time zone: %s", err)
+	}
+	midnight := time.Date(2024, time.March, 1, 0, 0, 0, 0, berlin).Unix()
+	assert.Equal(t, "Rent 2024-03-01", ExpandPaymentPlanTemplate("Rent {date}", midnight, berlin, 1, 0))
+	assert.Equal(t, "Rent 2024-02-29", ExpandPaymentPlanTemplate("Rent {date}", midnight, time.UTC, 1, 0))
+}
+
+func TestDistributeAmount(t *testing.T) {
+	tests := []struct {
+		name   string
+		total  int
+		shares int
+		want   []int
+	}{
+		{name: "Even", total: 900, shares: 3, want: []int{300, 300, 300}},
+		{name: "Remainder", total: 1000, shares: 3, want: []int{334, 333, 333}},
+		{name: "Remainder of two", total: 1001, shares: 3, want: []int{334, 334, 333}},
+		{name: "Less than shares", total: 2, shares: 5, want: []int{1, 1, 0, 0, 0}},
+		{name: "Single share", total: 17, shares: 1, want: []int{17}},
+		{name: "Zero", total: 0, shares: 2, want: []int{0, 0}},
+		{name: "Negative", total: -1000, shares: 3, want: []int{-334, -333, -333}},
+		{name: "No shares", total: 100, shares: 0, want: nil},
+		{name: "Negative shares", total: 100, shares: -2, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DistributeAmount(tt.total, tt.shares))
+		})
+	}
+}
+
+func TestDistributeAmount_Sum(t *testing.T) {
+	for total := -250; total <= 250; total += 7 {
+		for shares := 1; shares <= 12; shares++ {
+			parts := DistributeAmount(total, shares)
+			assert.Len(t, parts, shares)
+
+			sum := 0
+			lowest, highest := parts[0], parts[0]
+			for _, p := range parts {
+				sum += p
+				lowest = min(lowest, p)
+				highest = max(highest, p)
+			}
+			assert.Equal(t, total, sum, "total %d, shares %d", total, shares)
+			assert.LessOrEqual(t, highest-lowest, 1, "total %d, shares %d", total, shares)
+		}
+	}
+}
+
+func TestMakeChange(t *testing.T) {
+	values := []int{500, 200, 100, 50}
+	tests := []struct {
+		name      string
+		amount    int
+		available []int
+		want      []int
+		wantOk    bool
+	}{
+		{name: "Exact", amount: 850, available: []int{1, 1, 1, 1}, want: []int{1, 1, 1, 1}, wantOk: true},
+		{name: "Prefer high values", amount: 1000, available: []int{2, 5, 10, 20}, want: []int{2, 0, 0, 0}, wantOk: true},
+		{name: "Limited count", amount: 1000, available: []int{1, 1, 10, 0}, want: []int{1, 1, 3, 0}, wantOk: true},
+		{name: "Zero", amount: 0, available: []int{1, 1, 1, 1}, want: []int{0, 0, 0, 0}, wantOk: true},
+		{name: "Not enough cash", amount: 1000, available: []int{1, 1, 0, 1}, want: []int{1, 1, 0, 1}, wantOk: false},
+		{name: "Not divisible", amount: 120, available: []int{1, 1, 1, 1}, want: []int{0, 0, 1, 0}, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := MakeChange(tt.amount, values, tt.available)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOk, ok)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+var vCardEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`)
+
+// Writes a minimal vCard 4.0 contact. The email is omitted if it is empty.
+func WriteVCard(w io.Writer, name, email string) error {
+	name = vCardEscaper.Replace(name)
+	card := "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:" + name + "\r\n"
+	if email != "" {
+		card += "EMAIL:" + vCardEscaper.Replace(email) + "\r\n"
+	}
+	card += "END:VCARD\r\n"
+
+	_, err := io.WriteString(w, card)
+	if err != nil {
+		return fmt.Errorf("write vcard: %w", err)
+	}
+	return nil
+}
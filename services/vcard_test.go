@@ -0,0 +1,29 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteVCard(t *testing.T) {
+	tests := []struct {
+		name  string
+		cName string
+		email string
+		want  string
+	}{
+		{name: "With email", cName: "bob", email: "bob@gmail.com", want: "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:bob\r\nEMAIL:bob@gmail.com\r\nEND:VCARD\r\n"},
+		{name: "Without email", cName: "bob", want: "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:bob\r\nEND:VCARD\r\n"},
+		{name: "Escaped", cName: "Doe, John; Jr.\\\n", want: "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:" + `Doe\, John\; Jr.\\\n` + "\r\nEND:VCARD\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			err := WriteVCard(&b, tt.cName, tt.email)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}